@@ -0,0 +1,66 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/discovery"
+)
+
+const discoverCommand = "discover"
+
+// runDiscover evaluates the discovery configuration of a collector configuration file against the resources of the
+// cluster and prints the matched rules and the encoded source configurations without collecting any metrics.
+func runDiscover(args []string) {
+	var configFile, output, kind, namespace, name string
+	var explain bool
+
+	fs := pflag.NewFlagSet(discoverCommand, pflag.ExitOnError)
+	fs.StringVar(&configFile, "config-file", "", "required configuration file")
+	fs.BoolVar(&explain, "explain", false, "explain why each rule matched or rejected a resource instead of listing the scraped resources only")
+	fs.StringVar(&output, "output", "text", "one of text or json")
+	fs.StringVar(&kind, "kind", "", "only report resources of the given type (pod, service or node)")
+	fs.StringVar(&namespace, "namespace", "", "only report resources in the given namespace")
+	fs.StringVar(&name, "name", "", "only report resources with the given name")
+	_ = fs.Parse(args)
+
+	// keep stdout for the report
+	log.SetOutput(os.Stderr)
+
+	if configFile == "" {
+		log.Fatalf("--config-file is required")
+	}
+	if output != "text" && output != "json" {
+		log.Fatalf("unsupported output: %s", output)
+	}
+	cfg := loadConfigOrDie(configFile)
+	if !cfg.EnableDiscovery {
+		log.Warning("discovery is disabled in the configuration file")
+	}
+
+	kubeClient := createKubeClientOrDie(*cfg.Sources.SummaryConfig)
+	report := discovery.Explain(cfg.DiscoveryConfig, discovery.NewClientResourceLister(kubeClient), kubeClient)
+	report = report.Select(kind, namespace, name)
+	if !explain {
+		report = report.Matched()
+	}
+
+	var err error
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing report: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -32,12 +32,16 @@ import (
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/manager"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/processors"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks"
-	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/wavefront"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sources"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sources/summary"
 
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/dynamic"
 	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	v1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 var (
@@ -50,6 +54,16 @@ func main() {
 	log.SetFormatter(&log.TextFormatter{})
 	log.SetLevel(log.InfoLevel)
 	log.SetOutput(os.Stdout)
+	log.AddHook(configuration.RedactionHook{})
+	configuration.SetSecretReaderFactory(kube_config.NewSecretReaderFactory())
+
+	if len(os.Args) > 1 && os.Args[1] == discoverCommand {
+		runDiscover(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == validateCommand {
+		os.Exit(runValidate(os.Args[2:]))
+	}
 
 	opt := options.Parse()
 
@@ -75,8 +89,10 @@ func main() {
 	preRegister(opt)
 	cfg := loadConfigOrDie(opt.ConfigFile)
 	cfg = convertOrDie(opt, cfg)
-	ag := createAgentOrDie(cfg)
-	registerListeners(ag, opt)
+	ag, sink := createAgentOrDie(cfg)
+	handler := &reloader{ag: ag, sink: sink, cfg: cfg}
+	registerDebugHandlers(handler)
+	registerListeners(handler, opt)
 	waitForStop()
 }
 
@@ -90,7 +106,7 @@ func preRegister(opt *options.CollectorRunOptions) {
 	registerVersion()
 }
 
-func createAgentOrDie(cfg *configuration.Config) *agent.Agent {
+func createAgentOrDie(cfg *configuration.Config) (*agent.Agent, sinks.Sink) {
 	experimental.DisableAll()
 	for _, feature := range cfg.Experimental {
 		experimental.EnableFeature(feature)
@@ -104,6 +120,9 @@ func createAgentOrDie(cfg *configuration.Config) *agent.Agent {
 	kubeClient := createKubeClientOrDie(*cfg.Sources.SummaryConfig)
 	if cfg.Sources.StateConfig != nil {
 		cfg.Sources.StateConfig.KubeClient = kubeClient
+		if len(cfg.Sources.StateConfig.CustomResources) > 0 {
+			cfg.Sources.StateConfig.DynamicClient = createDynamicClientOrDie(*cfg.Sources.SummaryConfig)
+		}
 	}
 
 	// create sources manager
@@ -149,7 +168,7 @@ func createAgentOrDie(cfg *configuration.Config) *agent.Agent {
 	// create and start agent
 	ag := agent.NewAgent(man, dm, eventRouter)
 	ag.Start()
-	return ag
+	return ag, sinkManager
 }
 
 func loadConfigOrDie(file string) *configuration.Config {
@@ -224,12 +243,14 @@ func setInternalSinkProperties(cfg *configuration.Config) {
 	}
 }
 
-func registerListeners(ag *agent.Agent, opt *options.CollectorRunOptions) {
-	handler := &reloader{ag: ag}
+func registerListeners(handler *reloader, opt *options.CollectorRunOptions) {
 	if opt.ConfigFile != "" {
 		listener := configuration.NewFileListener(handler)
 		watcher := util.NewFileWatcher(opt.ConfigFile, listener, 30*time.Second)
 		watcher.Watch()
+
+		// re-resolve environment, file and secret references to pick up rotated secrets
+		configuration.NewReferenceWatcher(opt.ConfigFile, handler, time.Minute).Watch()
 	}
 }
 
@@ -244,8 +265,14 @@ func createDiscoveryManagerOrDie(
 		serviceLister := getServiceListerOrDie(client)
 		nodeLister := getNodeListerOrDie(client)
 
+		var dynamicClient dynamic.Interface
+		if cfg.DiscoveryConfig.EnablePrometheusMonitors {
+			dynamicClient = createDynamicClientOrDie(*cfg.Sources.SummaryConfig)
+		}
+
 		return discovery.NewDiscoveryManager(discovery.RunConfig{
 			KubeClient:             client,
+			DynamicClient:          dynamicClient,
 			DiscoveryConfig:        cfg.DiscoveryConfig,
 			Handler:                handler,
 			InternalPluginProvider: internalPluginConfigProvider,
@@ -272,7 +299,7 @@ func getVersion() float64 {
 	return f
 }
 
-func createSinkManagerOrDie(cfgs []*configuration.WavefrontSinkConfig, sinkExportDataTimeout time.Duration) wavefront.WavefrontSink {
+func createSinkManagerOrDie(cfgs []*configuration.SinkConfig, sinkExportDataTimeout time.Duration) sinks.Sink {
 	sinksFactory := sinks.NewSinkFactory()
 	sinkList := sinksFactory.BuildAll(cfgs)
 
@@ -302,70 +329,62 @@ func createKubeClientOrDie(cfg configuration.SummarySourceConfig) *kube_client.C
 	return kube_client.NewForConfigOrDie(kubeConfig)
 }
 
-func createDataProcessorsOrDie(kubeClient *kube_client.Clientset, cluster string, podLister v1listers.PodLister, cfg *configuration.Config) []metrics.Processor {
-
-	labelCopier, err := util.NewLabelCopier(",", []string{}, []string{})
+func createDynamicClientOrDie(cfg configuration.SummarySourceConfig) dynamic.Interface {
+	kubeConfig, err := kube_config.GetKubeClientConfig(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize label copier: %v", err)
-	}
-
-	dataProcessors := []metrics.Processor{
-		processors.NewRateCalculator(metrics.RateMetricsMapping),
-		processors.NewDistributionRateCalculator(),
-		processors.NewCumulativeDistributionConverter(),
+		log.Fatalf("Failed to get client config: %v", err)
 	}
+	return dynamic.NewForConfigOrDie(kubeConfig)
+}
 
-	collectionInterval := calculateCollectionInterval(cfg)
-	podBasedEnricher := processors.NewPodBasedEnricher(podLister, labelCopier, collectionInterval)
-	dataProcessors = append(dataProcessors, podBasedEnricher)
+func createDataProcessorsOrDie(kubeClient *kube_client.Clientset, cluster string, podLister v1listers.PodLister, cfg *configuration.Config) []metrics.Processor {
 
-	namespaceBasedEnricher, err := processors.NewNamespaceBasedEnricher(kubeClient)
+	labelCopier, err := util.NewConfiguredLabelCopier(",", util.LabelCopierConfig{
+		AllowList:              cfg.Labels.AllowList,
+		DenyList:               cfg.Labels.DenyList,
+		Rename:                 cfg.Labels.Rename,
+		AnnotationAllowList:    cfg.Labels.AnnotationAllowList,
+		InheritNamespaceLabels: cfg.Labels.InheritNamespaceLabels,
+	})
 	if err != nil {
-		log.Fatalf("Failed to create NamespaceBasedEnricher: %v", err)
-	}
-	dataProcessors = append(dataProcessors, namespaceBasedEnricher)
-
-	metricsToAggregate := []string{
-		metrics.MetricCpuUsageRate.Name,
-		metrics.MetricMemoryUsage.Name,
-		metrics.MetricCpuRequest.Name,
-		metrics.MetricCpuLimit.Name,
-		metrics.MetricMemoryRequest.Name,
-		metrics.MetricMemoryLimit.Name,
-	}
-
-	metricsToAggregateForNode := []string{
-		metrics.MetricCpuRequest.Name,
-		metrics.MetricCpuLimit.Name,
-		metrics.MetricMemoryRequest.Name,
-		metrics.MetricMemoryLimit.Name,
-		metrics.MetricEphemeralStorageRequest.Name,
-		metrics.MetricEphemeralStorageLimit.Name,
+		log.Fatalf("Failed to initialize label copier: %v", err)
 	}
 
-	dataProcessors = append(dataProcessors,
-		processors.NewPodAggregator(),
-		processors.NewNamespaceAggregator(metricsToAggregate),
-		processors.NewNodeAggregator(metricsToAggregateForNode),
-		processors.NewClusterAggregator(metricsToAggregate),
-	)
-
-	nodeAutoscalingEnricher, err := processors.NewNodeAutoscalingEnricher(kubeClient, labelCopier)
+	dataProcessors, err := processors.Build(cfg.Processors, processors.Dependencies{
+		KubeClient:         kubeClient,
+		PodLister:          podLister,
+		LabelCopier:        labelCopier,
+		CollectionInterval: calculateCollectionInterval(cfg),
+	})
 	if err != nil {
-		log.Fatalf("Failed to create NodeAutoscalingEnricher: %v", err)
+		log.Fatalf("Failed to create processors: %v", err)
 	}
-	dataProcessors = append(dataProcessors, nodeAutoscalingEnricher)
 
-	// this always needs to be the last processor
+	// this always needs to follow the processors working on metric sets
 	wavefrontCoverter, err := summary.NewPointConverter(*cfg.Sources.SummaryConfig, cluster)
 	if err != nil {
 		log.Fatalf("Failed to create WavefrontPointConverter: %v", err)
 	}
 	dataProcessors = append(dataProcessors, wavefrontCoverter)
 
+	if cfg.CardinalityLimit != nil {
+		cardinalityLimiter, err := processors.NewCardinalityLimiter(*cfg.CardinalityLimit, createEventRecorder(kubeClient))
+		if err != nil {
+			log.Fatalf("Failed to create CardinalityLimiter: %v", err)
+		}
+		dataProcessors = append(dataProcessors, cardinalityLimiter)
+	}
+
 	return dataProcessors
 }
 
+// createEventRecorder returns a recorder for the Kubernetes events the collector reports about itself
+func createEventRecorder(kubeClient *kube_client.Clientset) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "wavefront-collector", Host: util.GetNodeName()})
+}
+
 func calculateCollectionInterval(cfg *configuration.Config) time.Duration {
 	collectionInterval := cfg.DefaultCollectionInterval
 	if cfg.Sources.SummaryConfig.Collection.Interval > 0 {
@@ -431,6 +450,7 @@ func enableProfiling(enable bool) {
 	if enable {
 		go func() {
 			log.Info("Starting pprof server at: http://localhost:9090/debug/pprof")
+			log.Info("Serving the discovery report at: http://localhost:9090/debug/discovery")
 			if err := http.ListenAndServe("localhost:9090", nil); err != nil {
 				log.Errorf("E! %v", err)
 			}
@@ -438,6 +458,11 @@ func enableProfiling(enable bool) {
 	}
 }
 
+// registerDebugHandlers adds the discovery report to the endpoints served when profiling is enabled
+func registerDebugHandlers(r *reloader) {
+	http.Handle("/debug/discovery", discovery.ExplainHandler(r.discoveryManager))
+}
+
 func enableForcedGC(enable bool) {
 	if enable {
 		log.Info("enabling forced garbage collection")
@@ -457,9 +482,11 @@ func waitForStop() {
 }
 
 type reloader struct {
-	mtx sync.Mutex
-	ag  *agent.Agent
-	opt *options.CollectorRunOptions
+	mtx  sync.Mutex
+	ag   *agent.Agent
+	sink sinks.Sink
+	cfg  *configuration.Config
+	opt  *options.CollectorRunOptions
 }
 
 // Handles changes to collector or discovery configuration
@@ -473,12 +500,79 @@ func (r *reloader) Handle(cfg interface{}) {
 	}
 }
 
+// discoveryManager returns the discovery manager of the current agent
+func (r *reloader) discoveryManager() *discovery.Manager {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.ag.DiscoveryManager()
+}
+
 func (r *reloader) handleCollectorCfg(cfg *configuration.Config) {
 	log.Infof("collector configuration changed")
 
 	fillDefaults(cfg)
 
+	plan := configuration.PlanReload(r.cfg, cfg)
+	if !plan.Restart {
+		err := r.reload(cfg, plan)
+		if err == nil {
+			r.cfg = cfg
+			return
+		}
+		log.Errorf("error applying configuration changes, restarting agent: %v", err)
+	}
+
 	// stop the previous agent and start a new agent
 	r.ag.Stop()
-	r.ag = createAgentOrDie(cfg)
+	r.ag, r.sink = createAgentOrDie(cfg)
+	r.cfg = cfg
+}
+
+// reload applies the changes to sinks, sources and discovery rules without restarting the agent
+func (r *reloader) reload(cfg *configuration.Config, plan configuration.ReloadPlan) error {
+	setInternalSinkProperties(cfg)
+	if cfg.Sources.StateConfig != nil {
+		cfg.Sources.StateConfig.KubeClient = r.kubeClient(cfg)
+		if len(cfg.Sources.StateConfig.CustomResources) > 0 {
+			cfg.Sources.StateConfig.DynamicClient = r.dynamicClient(cfg)
+		}
+	}
+
+	if plan.Sinks {
+		sink, ok := r.sink.(sinks.Reconfigurable)
+		if !ok {
+			return fmt.Errorf("sinks cannot be reconfigured")
+		}
+		if err := sink.Reconfigure(cfg.Sinks); err != nil {
+			return err
+		}
+	}
+	if plan.Sources {
+		if err := sources.Manager().ReloadProviders(*cfg.Sources); err != nil {
+			return err
+		}
+	}
+	if plan.Discovery {
+		if dm := r.ag.DiscoveryManager(); dm != nil {
+			dm.Reload(cfg.DiscoveryConfig)
+		}
+	}
+	log.Infof("applied configuration changes without restarting agent")
+	return nil
+}
+
+// kubeClient returns the client of the running kubernetes_state_source or creates one
+func (r *reloader) kubeClient(cfg *configuration.Config) *kube_client.Clientset {
+	if r.cfg.Sources.StateConfig != nil && r.cfg.Sources.StateConfig.KubeClient != nil {
+		return r.cfg.Sources.StateConfig.KubeClient
+	}
+	return createKubeClientOrDie(*cfg.Sources.SummaryConfig)
+}
+
+// dynamicClient returns the dynamic client of the running kubernetes_state_source or creates one
+func (r *reloader) dynamicClient(cfg *configuration.Config) dynamic.Interface {
+	if r.cfg.Sources.StateConfig != nil && r.cfg.Sources.StateConfig.DynamicClient != nil {
+		return r.cfg.Sources.StateConfig.DynamicClient
+	}
+	return createDynamicClientOrDie(*cfg.Sources.SummaryConfig)
 }
@@ -0,0 +1,118 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/validation"
+)
+
+const validateCommand = "validate"
+
+// runValidate checks collector and discovery configuration files without connecting to the cluster and returns
+// the exit code. Every error is printed with the file and line of the offending key.
+func runValidate(args []string) int {
+	var configFile, discoveryFile, schema string
+
+	fs := pflag.NewFlagSet(validateCommand, pflag.ExitOnError)
+	fs.StringVar(&configFile, "config", "", "collector configuration file to validate")
+	fs.StringVar(&configFile, "config-file", "", "collector configuration file to validate, same as -config")
+	fs.StringVar(&discoveryFile, "discovery", "", "discovery configuration file to validate")
+	fs.StringVar(&discoveryFile, "discovery-config", "", "discovery configuration file to validate, same as -discovery")
+	fs.StringVar(&schema, "schema", "", "print the JSON Schema of the collector or discovery configuration instead of validating files")
+	_ = fs.Parse(longFlags(fs, args))
+
+	if schema != "" {
+		return printSchema(schema)
+	}
+	if configFile == "" && discoveryFile == "" {
+		fmt.Fprintln(os.Stderr, "-config or -discovery is required")
+		return 2
+	}
+
+	code := 0
+	for _, file := range []struct {
+		name     string
+		validate func([]byte) []validation.Error
+	}{
+		{configFile, validation.Collector},
+		{discoveryFile, validation.Discovery},
+	} {
+		if file.name == "" {
+			continue
+		}
+		contents, err := ioutil.ReadFile(file.name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file.name, err)
+			code = 1
+			continue
+		}
+		errs := file.validate(contents)
+		for _, err := range errs {
+			fmt.Printf("%s:%s\n", file.name, formatError(err))
+		}
+		if len(errs) > 0 {
+			code = 1
+		} else {
+			fmt.Printf("%s: valid\n", file.name)
+		}
+	}
+	return code
+}
+
+// longFlags accepts the flags of fs with a single dash, such as -config, by rewriting them with two dashes
+func longFlags(fs *pflag.FlagSet, args []string) []string {
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(result, args[i:]...)
+		}
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") {
+			name := strings.SplitN(arg[1:], "=", 2)[0]
+			if len(name) > 1 && fs.Lookup(name) != nil {
+				arg = "-" + arg
+			}
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// formatError formats an error as file:line: path: message, the format understood by most editors
+func formatError(err validation.Error) string {
+	msg := err.Message
+	if err.Path != "" {
+		msg = err.Path + ": " + msg
+	}
+	if err.Line > 0 {
+		return fmt.Sprintf("%d: %s", err.Line, msg)
+	}
+	return " " + msg
+}
+
+func printSchema(kind string) int {
+	var schema map[string]interface{}
+	switch kind {
+	case "collector":
+		schema = validation.CollectorSchema()
+	case "discovery":
+		schema = validation.DiscoverySchema()
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported schema: %s, expected collector or discovery\n", kind)
+		return 2
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing schema: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -26,6 +26,11 @@ func NewAgent(pm manager.FlushManager, dm *discovery.Manager, er *events.EventRo
 	}
 }
 
+// DiscoveryManager returns the discovery manager of the agent or nil if discovery is disabled
+func (a *Agent) DiscoveryManager() *discovery.Manager {
+	return a.dm
+}
+
 func (a *Agent) Start() {
 	log.Infof("Starting agent")
 	a.pm.Start()
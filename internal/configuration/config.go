@@ -9,7 +9,9 @@ import (
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/httputil"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/relabel"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -33,8 +35,8 @@ type Config struct {
 	// Included as a point tag on all metrics sent to Wavefront.
 	ClusterName string `yaml:"clusterName"`
 
-	// list of Wavefront sinks. At least 1 is required.
-	Sinks []*WavefrontSinkConfig `yaml:"sinks"`
+	// list of sinks. At least 1 is required.
+	Sinks []*SinkConfig `yaml:"sinks"`
 
 	// list of sources. SummarySource is mandatory. Others are optional.
 	Sources *SourceConfig `yaml:"sources"`
@@ -49,6 +51,17 @@ type Config struct {
 
 	Experimental []string `yaml:"experimental"`
 
+	// optional cap on the number of distinct series reported per metric name and source.
+	CardinalityLimit *CardinalityLimitConfig `yaml:"cardinalityLimit"`
+
+	// optional ordered list of processors applied to the collected metric sets before they are converted to points.
+	// Defaults to the built-in processor chain.
+	Processors []ProcessorConfig `yaml:"processors"`
+
+	// optional selection of the Kubernetes labels and annotations reported as tags of pod, container,
+	// namespace and node metrics. Defaults to all labels and no annotations.
+	Labels LabelsConfig `yaml:"labels"`
+
 	// Internal use only
 	ScrapeCluster bool `yaml:"-"`
 }
@@ -73,6 +86,100 @@ type EventsFilter struct {
 	TagBlacklistSets []map[string][]string `yaml:"tagBlacklistSets"`
 }
 
+const (
+	CardinalityDropAction      = "drop"
+	CardinalityAggregateAction = "aggregate"
+)
+
+// CardinalityLimitConfig configures the cardinality limiter that runs before metrics are exported
+type CardinalityLimitConfig struct {
+	// The maximum number of distinct series per metric name and source seen within the window. Required.
+	MaxSeriesPerMetric int `yaml:"maxSeriesPerMetric"`
+
+	// The sliding window over which distinct series are counted. Defaults to 10 minutes.
+	Window time.Duration `yaml:"window"`
+
+	// What to do with a metric once its limit is crossed. Either 'drop' (default) to drop series
+	// not seen before the limit was reached or 'aggregate' to remove the tag with the most distinct
+	// values and sum the series that become identical.
+	Action string `yaml:"action"`
+}
+
+// LabelsConfig selects the labels and annotations copied from pods, namespaces and nodes to their metrics
+type LabelsConfig struct {
+	// Glob patterns of the labels reported as 'label.<name>' tags. Defaults to all labels.
+	AllowList []string `yaml:"allowList"`
+
+	// Glob patterns of the labels that are not reported.
+	DenyList []string `yaml:"denyList"`
+
+	// The names labels and annotations are reported as, keyed by their Kubernetes name.
+	// For example 'app.kubernetes.io/name: app' reports the label as 'label.app'.
+	Rename map[string]string `yaml:"rename"`
+
+	// Glob patterns of the annotations reported as 'annotation.<name>' tags. Defaults to none.
+	AnnotationAllowList []string `yaml:"annotationAllowList"`
+
+	// Whether pod and container metrics inherit the labels of their namespace. Pod labels take precedence.
+	InheritNamespaceLabels bool `yaml:"inheritNamespaceLabels"`
+}
+
+// ProcessorConfig configures a processor of the processor chain
+type ProcessorConfig struct {
+	// The processor type, for example rate_calculator or namespace_aggregator. Required.
+	Type string `yaml:"type"`
+
+	// The metrics summed by the workload_aggregator, namespace_aggregator, node_aggregator and cluster_aggregator.
+	// Defaults to the CPU and memory requests, limits and usage.
+	Metrics []string `yaml:"metrics"`
+
+	// The name of a sum_count_aggregator. Used as the name of the processor in logs and internal metrics.
+	Name string `yaml:"name"`
+
+	// The aggregations performed by a sum_count_aggregator.
+	Aggregations []AggregationConfig `yaml:"aggregations"`
+
+	// The settings of a resource_recommender.
+	Recommendation *RecommendationConfig `yaml:"recommendation"`
+}
+
+// RecommendationConfig configures the resource requests recommended for workloads
+type RecommendationConfig struct {
+	// The period of container usage recommendations are based on. Defaults to 168h (7 days).
+	Window time.Duration `yaml:"window"`
+
+	// The percentile of the CPU usage recommended as CPU request. Defaults to 90.
+	CPUPercentile float64 `yaml:"cpuPercentile"`
+
+	// The percentile of the memory working set recommended as memory request. Defaults to 95.
+	MemoryPercentile float64 `yaml:"memoryPercentile"`
+
+	// The share added to the usage percentiles. Defaults to 0.15.
+	SafetyMargin *float64 `yaml:"safetyMargin"`
+
+	// Optional file the usage history is persisted to, for example on a persistent volume.
+	StateFile string `yaml:"stateFile"`
+
+	// Optional namespace/name of a ConfigMap the usage history is persisted to.
+	StateConfigMap string `yaml:"stateConfigMap"`
+}
+
+// AggregationConfig sums the metrics of metric sets into the metric set of the group they belong to
+type AggregationConfig struct {
+	// The type of the aggregated metric sets. One of pod, pod_container, namespace or node.
+	// Only running pods and containers that are not terminated are aggregated.
+	ResourceType string `yaml:"resourceType"`
+
+	// The group the metric sets are aggregated into. One of namespace, node or cluster.
+	GroupBy string `yaml:"groupBy"`
+
+	// The metrics summed into the group.
+	SumMetrics []string `yaml:"sumMetrics"`
+
+	// Optional metric of the group counting the aggregated metric sets.
+	CountMetric string `yaml:"countMetric"`
+}
+
 // SourceConfig contains configuration for various sources
 type SourceConfig struct {
 	SummaryConfig      *SummarySourceConfig         `yaml:"kubernetes_source"`
@@ -85,6 +192,12 @@ type SourceConfig struct {
 	StateConfig        *KubernetesStateSourceConfig `yaml:"kubernetes_state_source"`
 }
 
+const (
+	ProtobufScrapeProtocol    = "protobuf"
+	OpenMetricsScrapeProtocol = "openmetrics"
+	TextScrapeProtocol        = "text"
+)
+
 // Transforms represents transformations that can be applied to metrics at sources or sinks
 type Transforms struct {
 	// The source to set for the metrics. Defaults to the name of the node on which the collector is running on.
@@ -100,6 +213,73 @@ type Transforms struct {
 	Filters filter.Config `yaml:"filters"`
 }
 
+const (
+	WavefrontSinkType             = "wavefront"
+	PrometheusRemoteWriteSinkType = "prometheus_remote_write"
+	OTLPSinkType                  = "otlp"
+	PrometheusExpositionSinkType  = "prometheus_exposition"
+)
+
+// Configuration options for a sink. Wavefront sink properties are inlined for backwards compatibility.
+// The common Transforms and internal properties of the Wavefront sink configuration apply to every sink type.
+type SinkConfig struct {
+	// The type of the sink. One of wavefront, prometheus_remote_write, otlp or prometheus_exposition.
+	// Defaults to wavefront.
+	Type string `yaml:"type"`
+
+	// Optional name of the sink used in logs and to tag internal metrics. Defaults to the name of the sink type.
+	Name string `yaml:"name"`
+
+	// Optional list of transforms applied in order to the metrics exported by this sink.
+	// Each sink receives its own copy of the metrics, so transforms do not affect other sinks.
+	TransformRules []TransformRule `yaml:"transforms"`
+
+	WavefrontSinkConfig `yaml:",inline"`
+
+	// Configuration specific to the prometheus_remote_write sink type.
+	RemoteWrite RemoteWriteSinkConfig `yaml:"remoteWrite"`
+
+	// Configuration specific to the otlp sink type.
+	OTLP OTLPSinkConfig `yaml:"otlp"`
+
+	// Configuration specific to the prometheus_exposition sink type.
+	Exposition ExpositionSinkConfig `yaml:"exposition"`
+}
+
+const (
+	RenameMetricAction    = "renameMetric"
+	AddTagAction          = "addTag"
+	DropTagAction         = "dropTag"
+	RenameTagAction       = "renameTag"
+	RewriteTagValueAction = "rewriteTagValue"
+	DropByTagValueAction  = "dropByTagValue"
+)
+
+// TransformRule is a single metric transform applied by a sink
+type TransformRule struct {
+	// One of renameMetric, addTag, dropTag, renameTag, rewriteTagValue or dropByTagValue.
+	Action string `yaml:"action"`
+
+	// Regular expression the full metric name must match for the rule to apply. Defaults to all metrics.
+	// For renameMetric the capture groups can be referenced in the replacement.
+	Match string `yaml:"match"`
+
+	// The tag the rule applies to. Required by all actions except renameMetric.
+	Tag string `yaml:"tag"`
+
+	// The new name of the tag for renameTag.
+	NewTag string `yaml:"newTag"`
+
+	// The tag value set by addTag. Existing values are overwritten.
+	Value string `yaml:"value"`
+
+	// Regular expression the full tag value must match for rewriteTagValue and dropByTagValue.
+	ValueMatch string `yaml:"valueMatch"`
+
+	// The replacement for renameMetric and rewriteTagValue. Supports $1 style references to capture groups.
+	Replacement string `yaml:"replacement"`
+}
+
 // Configuration options for the Wavefront sink
 type WavefrontSinkConfig struct {
 	Transforms `yaml:",inline"`
@@ -126,6 +306,10 @@ type WavefrontSinkConfig struct {
 	// Defaults to 0.01 or 1% of errors. Valid values are > 0.0 and <= 1.0.
 	ErrorLogPercent float32 `yaml:"errorLogPercent"`
 
+	// Optional disk-backed buffer for points and distributions that fail to send.
+	// Buffered data is replayed in order once the proxy or Wavefront service is reachable again.
+	RetryBuffer RetryBufferConfig `yaml:"retryBuffer"`
+
 	// Note: Properties below are for internal use only. These cannot be set via the configuration file.
 
 	// Internal: Cluster name pulled in from the top level property.
@@ -141,6 +325,42 @@ type WavefrontSinkConfig struct {
 	EventsEnabled bool `yaml:"-"`
 }
 
+// Configuration options for the Wavefront sink retry buffer
+type RetryBufferConfig struct {
+	// The directory where failed data is persisted, typically a mounted volume. The buffer is disabled when empty.
+	Dir string `yaml:"dir"`
+
+	// The maximum size of the buffer on disk. Oldest data is dropped first. Defaults to 256MiB.
+	MaxBytes int64 `yaml:"maxBytes"`
+
+	// The maximum age of buffered data. Older data is dropped. Defaults to 1 hour.
+	MaxAge time.Duration `yaml:"maxAge"`
+}
+
+// Configuration options for the Prometheus remote-write sink
+type RemoteWriteSinkConfig struct {
+	// The remote-write endpoint, for example http://prometheus.monitoring.svc:9090/api/v1/write.
+	URL string `yaml:"url"`
+
+	// Optional HTTP client configuration.
+	HTTPClientConfig httputil.ClientConfig `yaml:"httpConfig"`
+
+	// The maximum number of series sent per request. Defaults to 2000.
+	MaxSeriesPerRequest int `yaml:"maxSeriesPerRequest"`
+
+	// The number of times a request is retried on network errors, 5xx or 429 responses. Defaults to 3, 0 disables retries.
+	MaxRetries *int `yaml:"maxRetries"`
+
+	// The initial retry backoff, doubled on every retry. Defaults to 100ms.
+	MinBackoff time.Duration `yaml:"minBackoff"`
+
+	// The maximum retry backoff. Defaults to 5s.
+	MaxBackoff time.Duration `yaml:"maxBackoff"`
+
+	// The timeout for a single request. Defaults to 30s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
 type CollectionConfig struct {
 	Interval time.Duration `yaml:"interval"`
 	Timeout  time.Duration `yaml:"timeout"`
@@ -197,6 +417,30 @@ type PrometheusSourceConfig struct {
 	// Optional HTTP client configuration.
 	HTTPClientConfig httputil.ClientConfig `yaml:"httpConfig"`
 
+	// Optional ordered relabeling rules applied to the target labels before scraping. The reserved __address__,
+	// __scheme__ and __metrics_path__ labels can be rewritten to change the scraped URL.
+	Relabel []relabel.Config `yaml:"relabel"`
+
+	// Optional ordered relabeling rules applied to every scraped metric. The reserved __name__ label holds the
+	// Prometheus metric name.
+	MetricRelabel []relabel.Config `yaml:"metricRelabel"`
+
+	// Optional ordered list of exposition formats to request from the endpoint: protobuf, openmetrics and text.
+	// Defaults to all three in that order.
+	ScrapeProtocols []string `yaml:"scrapeProtocols"`
+
+	// Whether to report exemplars as '.exemplar' points tagged with the exemplar labels. Defaults to false.
+	Exemplars bool `yaml:"exemplars"`
+
+	// Whether to report samples at the timestamps set by the endpoint instead of the scrape time. Defaults to false.
+	HonorTimestamps bool `yaml:"honorTimestamps"`
+
+	// Optional maximum age of samples with a timestamp set by the endpoint. Older samples are dropped.
+	MaxSampleAge time.Duration `yaml:"maxSampleAge"`
+
+	// Whether to report a staleness marker for series that disappear between scrapes. Defaults to false.
+	StalenessMarkers bool `yaml:"stalenessMarkers"`
+
 	// internal use only
 	Discovered        string `yaml:"-"`
 	Name              string `yaml:"-"`
@@ -249,6 +493,93 @@ type KubernetesStateSourceConfig struct {
 
 	Collection CollectionConfig `yaml:"collection"`
 
+	// The custom resources to report state metrics for.
+	CustomResources []CustomResourceConfig `yaml:"customResources"`
+
+	// Reports the pod.lifecycle.* latency distributions. Requires watching every pod of the cluster and the image
+	// pull events. Defaults to false.
+	PodLifecycleMetrics bool `yaml:"podLifecycleMetrics"`
+
 	// internal use only
-	KubeClient *kubernetes.Clientset `yaml:"-"`
+	KubeClient    *kubernetes.Clientset `yaml:"-"`
+	DynamicClient dynamic.Interface     `yaml:"-"`
+}
+
+// Configuration options for reporting the state of a custom resource. Points are reported as
+// <prefix>cr.<kind>.<metric name> for every object of the resource.
+type CustomResourceConfig struct {
+	// The API group, version and plural resource name of the custom resource. The group is empty for core resources.
+	Group    string `yaml:"group"`
+	Version  string `yaml:"version"`
+	Resource string `yaml:"resource"`
+
+	// The name used in the metric names. Defaults to the resource name.
+	Kind string `yaml:"kind"`
+
+	// The values to report for each object.
+	Metrics []CustomResourceMetricConfig `yaml:"metrics"`
+
+	// Map of tag names to JSONPath expressions evaluated against each object, for example status.phase: '.status.phase'.
+	// The name and namespace of the object are always added as tags.
+	Tags map[string]string `yaml:"tags"`
+}
+
+type CustomResourceMetricConfig struct {
+	// The name of the metric appended to cr.<kind>.
+	Name string `yaml:"name"`
+
+	// The JSONPath expression selecting the value, for example '.status.replicas'.
+	Path string `yaml:"path"`
+
+	// Optional mapping of string values, such as conditions or phases, to the numeric values to report.
+	// Values that are neither numbers nor mapped are skipped.
+	ValueMap map[string]float64 `yaml:"valueMap"`
+}
+
+// Configuration options for the OpenTelemetry OTLP/HTTP sink
+type OTLPSinkConfig struct {
+	// The base URL of the OTLP/HTTP receiver, for example http://otel-collector.monitoring.svc:4318.
+	// Metrics are sent to /v1/metrics and events to /v1/logs.
+	Endpoint string `yaml:"endpoint"`
+
+	// Optional HTTP client configuration.
+	HTTPClientConfig httputil.ClientConfig `yaml:"httpConfig"`
+
+	// Optional headers added to every request, for example authentication headers.
+	Headers map[string]string `yaml:"headers"`
+
+	// Glob patterns of metric names exported as monotonic cumulative sums. All other points are exported as gauges.
+	// Defaults to *.count, *.counter, *.total and *.sum.
+	SumPatterns []string `yaml:"sumPatterns"`
+
+	// The histogram type distributions are exported as. One of exponential or explicit. Defaults to exponential.
+	HistogramType string `yaml:"histogramType"`
+
+	// The maximum number of data points sent per request. Defaults to 2000.
+	MaxDataPointsPerRequest int `yaml:"maxDataPointsPerRequest"`
+
+	// The number of times a request is retried on network errors, 5xx or 429 responses. Defaults to 3, 0 disables retries.
+	MaxRetries *int `yaml:"maxRetries"`
+
+	// The initial retry backoff, doubled on every retry. Defaults to 100ms.
+	MinBackoff time.Duration `yaml:"minBackoff"`
+
+	// The maximum retry backoff. Defaults to 5s.
+	MaxBackoff time.Duration `yaml:"maxBackoff"`
+
+	// The timeout for a single request. Defaults to 30s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Configuration options for the Prometheus exposition sink
+type ExpositionSinkConfig struct {
+	// The address the HTTP listener binds to. Defaults to :9102.
+	ListenAddress string `yaml:"listenAddress"`
+
+	// The HTTP path the latest batch is exposed on. Defaults to /metrics.
+	Path string `yaml:"path"`
+
+	// Whether the timestamps of the collected points are included in the exposition. Defaults to false,
+	// in which case the scraper assigns the scrape time.
+	IncludeTimestamps bool `yaml:"includeTimestamps"`
 }
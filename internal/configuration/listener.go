@@ -4,6 +4,10 @@
 package configuration
 
 import (
+	"reflect"
+	"sort"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
@@ -25,3 +29,57 @@ func (l *listener) Changed(file string) {
 		l.handler.Handle(cfg)
 	}
 }
+
+type referenceWatcher struct {
+	file     string
+	handler  util.ConfigHandler
+	interval time.Duration
+	values   []string
+	stopCh   chan struct{}
+}
+
+// NewReferenceWatcher returns a watcher that resolves the references of a configuration file again at the given
+// interval and passes the configuration to the handler when a resolved value changed, for example after a secret
+// was rotated. Changes to the file itself are reported by the file watcher.
+func NewReferenceWatcher(file string, handler util.ConfigHandler, interval time.Duration) util.FileWatcher {
+	return &referenceWatcher{
+		file:     file,
+		handler:  handler,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (w *referenceWatcher) Watch() {
+	w.stopCh = make(chan struct{})
+	if _, values, err := loadFile(w.file); err == nil {
+		w.values = sortedValues(values)
+	}
+	go util.Retry(w.check, w.interval, w.stopCh)
+}
+
+func (w *referenceWatcher) check() {
+	cfg, values, err := loadFile(w.file)
+	if err != nil {
+		log.Errorf("error resolving configuration references: %v", err)
+		return
+	}
+	values = sortedValues(values)
+	if reflect.DeepEqual(values, w.values) {
+		return
+	}
+	w.values = values
+	log.Info("resolved configuration references changed, reloading configuration")
+	w.handler.Handle(cfg)
+}
+
+func (w *referenceWatcher) Stop() {
+	close(w.stopCh)
+}
+
+// sortedValues orders resolved values since map values are resolved in random order
+func sortedValues(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
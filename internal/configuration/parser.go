@@ -12,18 +12,34 @@ import (
 
 // FromFile loads the configuration from a given file
 func FromFile(filename string) (*Config, error) {
+	cfg, _, err := loadFile(filename)
+	return cfg, err
+}
+
+// FromYAML loads the configuration from a blob of YAML. References to environment variables, files and secrets in
+// string values are resolved.
+func FromYAML(contents []byte) (*Config, error) {
+	cfg, _, err := load(contents)
+	return cfg, err
+}
+
+func loadFile(filename string) (*Config, []string, error) {
 	contents, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("unable to load configuration file: %v", err)
+		return nil, nil, fmt.Errorf("unable to load configuration file: %v", err)
 	}
-	return FromYAML(contents)
+	return load(contents)
 }
 
-// FromYAML loads the configuration from a blob of YAML.
-func FromYAML(contents []byte) (*Config, error) {
+// load returns the configuration and the values its references resolved to
+func load(contents []byte) (*Config, []string, error) {
 	var cfg Config
 	if err := yaml.UnmarshalStrict(contents, &cfg); err != nil {
-		return nil, fmt.Errorf("unable to parse configuration: %v", err)
+		return nil, nil, fmt.Errorf("unable to parse configuration: %v", err)
+	}
+	values, err := resolveReferences(&cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to resolve configuration reference %v", err)
 	}
-	return &cfg, nil
+	return &cfg, values, nil
 }
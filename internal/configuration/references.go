@@ -0,0 +1,269 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package configuration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// referencePattern matches ${env:VAR}, ${file:/path} and ${secret:namespace/name/key} references
+var referencePattern = regexp.MustCompile(`\$\{(env|file|secret):([^}]*)\}`)
+
+// SecretReader reads a key of a Kubernetes secret
+type SecretReader interface {
+	ReadSecret(namespace, name, key string) ([]byte, error)
+}
+
+// SecretReaderFactory creates the reader used to resolve secret references. It is called with the resolved
+// kubernetes_source configuration the first time a configuration references a secret.
+type SecretReaderFactory func(cfg SummarySourceConfig) (SecretReader, error)
+
+var (
+	secretReaderMtx     sync.Mutex
+	secretReaderFactory SecretReaderFactory
+)
+
+// SetSecretReaderFactory enables secret references. Loading a configuration referencing a secret fails without it.
+func SetSecretReaderFactory(factory SecretReaderFactory) {
+	secretReaderMtx.Lock()
+	defer secretReaderMtx.Unlock()
+	secretReaderFactory = factory
+}
+
+// resolver replaces the references in every string of a configuration and records the resolved values
+type resolver struct {
+	summary SummarySourceConfig
+	reader  SecretReader
+	values  []string
+}
+
+// resolveReferences resolves the references of a decoded configuration. References are resolved after decoding so
+// that resolved values cannot change the structure of the file. The resolved values are masked in the logs.
+func resolveReferences(cfg *Config) ([]string, error) {
+	r := &resolver{}
+	if cfg.Sources != nil && cfg.Sources.SummaryConfig != nil {
+		// the kubernetes_source settings are used to connect to the API server secrets are read from
+		if err := r.walk(reflect.ValueOf(cfg.Sources.SummaryConfig).Elem(), false); err != nil {
+			return nil, err
+		}
+		r.summary = *cfg.Sources.SummaryConfig
+	}
+	if err := r.walk(reflect.ValueOf(cfg).Elem(), true); err != nil {
+		return nil, err
+	}
+	redactions.add(r.values)
+	return r.values, nil
+}
+
+func (r *resolver) walk(v reflect.Value, secrets bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		s, err := r.expand(v.String(), secrets)
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return r.walk(v.Elem(), secrets)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			// internal properties are not decoded and may hold clients
+			if field.PkgPath != "" || field.Tag.Get("yaml") == "-" {
+				continue
+			}
+			if err := r.walk(v.Field(i), secrets); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := r.walk(v.Index(i), secrets); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			value := iter.Value()
+			if value.Kind() != reflect.String {
+				// slice elements are addressable even if the map value is not
+				if err := r.walk(value, secrets); err != nil {
+					return err
+				}
+				continue
+			}
+			s, err := r.expand(value.String(), secrets)
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), reflect.ValueOf(s).Convert(value.Type()))
+		}
+	}
+	return nil
+}
+
+// expand replaces the references in a string. Errors name the reference, never the resolved value.
+func (r *resolver) expand(s string, secrets bool) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var err error
+	result := referencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		match := referencePattern.FindStringSubmatch(ref)
+		value, lookupErr := r.lookup(match[1], match[2], secrets)
+		if lookupErr != nil {
+			err = fmt.Errorf("%s: %v", ref, lookupErr)
+			return ref
+		}
+		r.values = append(r.values, value)
+		return value
+	})
+	return result, err
+}
+
+func (r *resolver) lookup(kind, name string, secrets bool) (string, error) {
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable is not set")
+		}
+		return value, nil
+	case "file":
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", fmt.Errorf("unable to read file: %v", err)
+		}
+		return strings.TrimRight(string(contents), "\r\n"), nil
+	default:
+		if !secrets {
+			return "", fmt.Errorf("secret references are not supported in the kubernetes_source")
+		}
+		parts := strings.Split(name, "/")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return "", fmt.Errorf("expected namespace/name/key")
+		}
+		reader, err := r.secretReader()
+		if err != nil {
+			return "", err
+		}
+		value, err := reader.ReadSecret(parts[0], parts[1], parts[2])
+		if err != nil {
+			return "", err
+		}
+		return string(value), nil
+	}
+}
+
+func (r *resolver) secretReader() (SecretReader, error) {
+	if r.reader != nil {
+		return r.reader, nil
+	}
+	secretReaderMtx.Lock()
+	factory := secretReaderFactory
+	secretReaderMtx.Unlock()
+	if factory == nil {
+		return nil, fmt.Errorf("secret references require access to the Kubernetes API")
+	}
+	reader, err := factory(r.summary)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create secret reader: %v", err)
+	}
+	r.reader = reader
+	return reader, nil
+}
+
+const (
+	redacted = "<redacted>"
+
+	// shorter values are not masked since they would mask unrelated parts of the logs
+	minRedactedLength = 4
+)
+
+var redactions = &redactor{values: map[string]bool{}}
+
+// redactor masks the values resolved from references. Values of previous configurations are kept so that
+// rotated secrets are masked as well.
+type redactor struct {
+	mtx      sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+func (r *redactor) add(values []string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	changed := false
+	for _, value := range values {
+		if len(value) >= minRedactedLength && !r.values[value] {
+			r.values[value] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	// replace longer values first in case a value contains another
+	sorted := make([]string, 0, len(r.values))
+	for value := range r.values {
+		sorted = append(sorted, value)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, value := range sorted {
+		pairs = append(pairs, value, redacted)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact masks the values resolved from configuration references in a string
+func Redact(s string) string {
+	redactions.mtx.RLock()
+	defer redactions.mtx.RUnlock()
+	if redactions.replacer == nil {
+		return s
+	}
+	return redactions.replacer.Replace(s)
+}
+
+// RedactionHook is a logrus hook masking the values resolved from configuration references in log entries
+type RedactionHook struct{}
+
+func (RedactionHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (RedactionHook) Fire(entry *log.Entry) error {
+	entry.Message = Redact(entry.Message)
+	for key, value := range entry.Data {
+		switch value := value.(type) {
+		case string:
+			entry.Data[key] = Redact(value)
+		case error:
+			entry.Data[key] = Redact(value.Error())
+		}
+	}
+	return nil
+}
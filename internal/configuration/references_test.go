@@ -0,0 +1,146 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package configuration
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSecretReader map[string]string
+
+func (r fakeSecretReader) ReadSecret(namespace, name, key string) ([]byte, error) {
+	value, ok := r[namespace+"/"+name+"/"+key]
+	if !ok {
+		return nil, fmt.Errorf("secret not found")
+	}
+	return []byte(value), nil
+}
+
+type recordingHandler struct {
+	cfgs []*Config
+}
+
+func (h *recordingHandler) Handle(cfg interface{}) {
+	h.cfgs = append(h.cfgs, cfg.(*Config))
+}
+
+func TestResolveReferences(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("file-token-value\n"), 0600))
+	t.Setenv("CLUSTER_NAME", "prod-cluster")
+
+	var summaries []SummarySourceConfig
+	SetSecretReaderFactory(func(cfg SummarySourceConfig) (SecretReader, error) {
+		summaries = append(summaries, cfg)
+		return fakeSecretReader{"monitoring/wavefront/token": "secret-token-value"}, nil
+	})
+	defer SetSecretReaderFactory(nil)
+
+	cfg, err := FromYAML([]byte(fmt.Sprintf(`
+clusterName: ${env:CLUSTER_NAME}
+sinks:
+- server: https://example.wavefront.com
+  token: ${secret:monitoring/wavefront/token}
+  tags:
+    cluster: ${env:CLUSTER_NAME}
+sources:
+  kubernetes_source:
+    url: https://${env:CLUSTER_NAME}.example.com
+  prometheus_sources:
+  - url: http://app:9100/metrics
+    httpConfig:
+      bearer_token: ${file:%s}
+    filters:
+      metricTagAllowList:
+        env:
+        - ${env:CLUSTER_NAME}
+discovery:
+  plugins:
+  - name: redis
+    type: telegraf/redis
+    selectors:
+      images: ['redis:*']
+    port: 6379
+    conf: |
+      servers = ["tcp://:${secret:monitoring/wavefront/token}@${host}:${port}"]
+`, tokenFile)))
+	require.NoError(t, err)
+
+	assert.Equal(t, "prod-cluster", cfg.ClusterName)
+	assert.Equal(t, "secret-token-value", cfg.Sinks[0].Token)
+	assert.Equal(t, "prod-cluster", cfg.Sinks[0].Tags["cluster"])
+	assert.Equal(t, "https://prod-cluster.example.com", cfg.Sources.SummaryConfig.URL)
+	assert.Equal(t, "file-token-value", cfg.Sources.PrometheusConfigs[0].HTTPClientConfig.BearerToken)
+	assert.Equal(t, []string{"prod-cluster"}, cfg.Sources.PrometheusConfigs[0].Filters.MetricTagAllowList["env"])
+	assert.Equal(t, "servers = [\"tcp://:secret-token-value@${host}:${port}\"]\n", cfg.DiscoveryConfig.PluginConfigs[0].Conf,
+		"discovery placeholders are left for the discovery rules")
+
+	require.Len(t, summaries, 1, "the secret reader is created once per load")
+	assert.Equal(t, "https://prod-cluster.example.com", summaries[0].URL, "the secret reader uses the resolved kubernetes_source")
+
+	t.Run("errors name the reference", func(t *testing.T) {
+		_, err := FromYAML([]byte("clusterName: ${env:WAVEFRONT_TEST_UNSET}\n"))
+		assert.EqualError(t, err, "unable to resolve configuration reference ${env:WAVEFRONT_TEST_UNSET}: environment variable is not set")
+
+		_, err = FromYAML([]byte("sinks:\n- token: ${secret:monitoring/wavefront}\n"))
+		assert.EqualError(t, err, "unable to resolve configuration reference ${secret:monitoring/wavefront}: expected namespace/name/key")
+
+		_, err = FromYAML([]byte("sources:\n  kubernetes_source:\n    auth: ${secret:monitoring/wavefront/token}\n"))
+		assert.EqualError(t, err, "unable to resolve configuration reference ${secret:monitoring/wavefront/token}: secret references are not supported in the kubernetes_source")
+	})
+
+	t.Run("secret references require a reader", func(t *testing.T) {
+		SetSecretReaderFactory(nil)
+		_, err := FromYAML([]byte("sinks:\n- token: ${secret:monitoring/wavefront/token}\n"))
+		assert.EqualError(t, err, "unable to resolve configuration reference ${secret:monitoring/wavefront/token}: secret references require access to the Kubernetes API")
+	})
+
+	t.Run("resolved values are redacted", func(t *testing.T) {
+		assert.Equal(t, "sending to <redacted> with token <redacted>", Redact("sending to prod-cluster with token secret-token-value"))
+
+		var out bytes.Buffer
+		logger := log.New()
+		logger.SetOutput(&out)
+		logger.AddHook(RedactionHook{})
+		logger.WithField("token", "file-token-value").Errorf("invalid token %s", "secret-token-value")
+		assert.NotContains(t, out.String(), "token-value")
+		assert.Contains(t, out.String(), "invalid token <redacted>")
+	})
+}
+
+func TestReferenceWatcher(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	configFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("first-token"), 0600))
+	require.NoError(t, ioutil.WriteFile(configFile, []byte("sinks:\n- token: ${file:"+tokenFile+"}\n"), 0600))
+
+	handler := &recordingHandler{}
+	w := NewReferenceWatcher(configFile, handler, 0).(*referenceWatcher)
+	if _, values, err := loadFile(configFile); assert.NoError(t, err) {
+		w.values = sortedValues(values)
+	}
+
+	w.check()
+	assert.Empty(t, handler.cfgs, "unchanged references do not reload the configuration")
+
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("rotated-token"), 0600))
+	w.check()
+	require.Len(t, handler.cfgs, 1)
+	assert.Equal(t, "rotated-token", handler.cfgs[0].Sinks[0].Token)
+
+	require.NoError(t, os.Remove(tokenFile))
+	w.check()
+	assert.Len(t, handler.cfgs, 1, "configurations that cannot be resolved are not passed on")
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package configuration
+
+import (
+	"reflect"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+)
+
+// ReloadPlan describes how a configuration change is applied to a running collector
+type ReloadPlan struct {
+	// Restart is set when the change requires stopping and recreating every source, sink and processor
+	Restart bool
+
+	// Sources is set when source providers need to be added, removed or recreated
+	Sources bool
+
+	// Sinks is set when the transforms or filters of the sinks changed
+	Sinks bool
+
+	// Discovery is set when the discovery configuration changed. The discovery manager applies rule changes in place
+	// and restarts itself for other changes.
+	Discovery bool
+}
+
+// PlanReload compares a new configuration against the running one. Changes to discovery rules, to sources other
+// than the kubelet connection settings and to sink transforms and filters are applied in place. Any other change,
+// such as the cluster name or a sink address, requires a restart.
+func PlanReload(prev, cfg *Config) ReloadPlan {
+	if prev == nil || cfg == nil || prev.Sources == nil || cfg.Sources == nil {
+		return ReloadPlan{Restart: true}
+	}
+
+	prevTop, top := *prev, *cfg
+	prevTop.Sources, top.Sources = nil, nil
+	prevTop.Sinks, top.Sinks = nil, nil
+	prevTop.DiscoveryConfig, top.DiscoveryConfig = discovery.Config{}, discovery.Config{}
+	if !reflect.DeepEqual(prevTop, top) {
+		return ReloadPlan{Restart: true}
+	}
+
+	plan := ReloadPlan{
+		Discovery: !reflect.DeepEqual(prev.DiscoveryConfig, cfg.DiscoveryConfig),
+	}
+
+	var restart bool
+	if plan.Sources, restart = planSources(*prev.Sources, *cfg.Sources); restart {
+		return ReloadPlan{Restart: true}
+	}
+	if plan.Sinks, restart = planSinks(prev.Sinks, cfg.Sinks); restart {
+		return ReloadPlan{Restart: true}
+	}
+	return plan
+}
+
+// planSources returns whether the sources changed and whether the change requires a restart. The kubelet settings
+// are shared with the kubernetes client and processors, the internal stats prefix is used by the sinks.
+func planSources(prev, cfg SourceConfig) (changed, restart bool) {
+	if (prev.SummaryConfig == nil) != (cfg.SummaryConfig == nil) {
+		return true, true
+	}
+	if prev.SummaryConfig != nil {
+		prevSummary, summary := *prev.SummaryConfig, *cfg.SummaryConfig
+		prevSummary.Transforms, summary.Transforms = Transforms{}, Transforms{}
+		if !reflect.DeepEqual(prevSummary, summary) {
+			return true, true
+		}
+	}
+	if statsPrefix(prev.StatsConfig) != statsPrefix(cfg.StatsConfig) {
+		return true, true
+	}
+	return !reflect.DeepEqual(withoutKubeClient(prev), withoutKubeClient(cfg)), false
+}
+
+func statsPrefix(cfg *StatsSourceConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.Prefix
+}
+
+// withoutKubeClient omits the clients set on the kubernetes_state_source once the collector is running
+func withoutKubeClient(cfg SourceConfig) SourceConfig {
+	if cfg.StateConfig != nil {
+		state := *cfg.StateConfig
+		state.KubeClient = nil
+		state.DynamicClient = nil
+		cfg.StateConfig = &state
+	}
+	return cfg
+}
+
+// planSinks returns whether the sinks changed and whether the change requires a restart. Only the transforms and
+// filters of existing sinks can be changed in place.
+func planSinks(prev, cfgs []*SinkConfig) (changed, restart bool) {
+	if len(prev) != len(cfgs) {
+		return true, true
+	}
+	for i := range cfgs {
+		prevSink, sink := reloadableSink(*prev[i]), reloadableSink(*cfgs[i])
+		if !reflect.DeepEqual(prevSink, sink) {
+			changed = true
+		}
+		prevSink.TransformRules, sink.TransformRules = nil, nil
+		prevSink.Filters, sink.Filters = filter.Config{}, filter.Config{}
+		if !reflect.DeepEqual(prevSink, sink) {
+			return true, true
+		}
+	}
+	return changed, false
+}
+
+// reloadableSink omits the internal properties derived from the top level configuration
+func reloadableSink(cfg SinkConfig) SinkConfig {
+	cfg.ClusterName = ""
+	cfg.Version = 0
+	cfg.InternalStatsPrefix = ""
+	cfg.EventsEnabled = false
+	return cfg
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+)
+
+func reloadConfig() *Config {
+	return &Config{
+		ClusterName: "k8s-cluster",
+		Sinks: []*SinkConfig{{
+			WavefrontSinkConfig: WavefrontSinkConfig{ProxyAddress: "wavefront-proxy:2878", ClusterName: "k8s-cluster"},
+		}},
+		Sources: &SourceConfig{
+			SummaryConfig:     &SummarySourceConfig{KubeletPort: "10250"},
+			StatsConfig:       &StatsSourceConfig{Transforms: Transforms{Prefix: "kubernetes."}},
+			StateConfig:       &KubernetesStateSourceConfig{},
+			PrometheusConfigs: []*PrometheusSourceConfig{{URL: "http://app:9100/metrics"}},
+		},
+		DiscoveryConfig: discovery.Config{PluginConfigs: []discovery.PluginConfig{{Name: "redis", Type: "prometheus"}}},
+	}
+}
+
+func TestPlanReload(t *testing.T) {
+	for name, test := range map[string]struct {
+		change   func(cfg *Config)
+		expected ReloadPlan
+	}{
+		"no changes": {
+			change:   func(cfg *Config) {},
+			expected: ReloadPlan{},
+		},
+		"discovery rules": {
+			change: func(cfg *Config) {
+				cfg.DiscoveryConfig.PluginConfigs[0].Port = "9121"
+			},
+			expected: ReloadPlan{Discovery: true},
+		},
+		"prometheus source transforms": {
+			change: func(cfg *Config) {
+				cfg.Sources.PrometheusConfigs[0].Prefix = "app."
+				cfg.Sources.PrometheusConfigs = append(cfg.Sources.PrometheusConfigs, &PrometheusSourceConfig{URL: "http://db:9100"})
+			},
+			expected: ReloadPlan{Sources: true},
+		},
+		"kubernetes source transforms": {
+			change: func(cfg *Config) {
+				cfg.Sources.SummaryConfig.Tags = map[string]string{"env": "prod"}
+			},
+			expected: ReloadPlan{Sources: true},
+		},
+		"sink filters and transforms": {
+			change: func(cfg *Config) {
+				cfg.Sinks[0].Filters = filter.Config{MetricDenyList: []string{"kubernetes.sys_container.*"}}
+				cfg.Sinks[0].TransformRules = []TransformRule{{Action: DropTagAction, Tag: "pod_id"}}
+			},
+			expected: ReloadPlan{Sinks: true},
+		},
+		"internal sink properties": {
+			change: func(cfg *Config) {
+				cfg.Sinks[0].ClusterName = ""
+			},
+			expected: ReloadPlan{},
+		},
+		"cluster name": {
+			change: func(cfg *Config) {
+				cfg.ClusterName = "prod"
+			},
+			expected: ReloadPlan{Restart: true},
+		},
+		"sink address": {
+			change: func(cfg *Config) {
+				cfg.Sinks[0].ProxyAddress = "other-proxy:2878"
+			},
+			expected: ReloadPlan{Restart: true},
+		},
+		"added sink": {
+			change: func(cfg *Config) {
+				cfg.Sinks = append(cfg.Sinks, &SinkConfig{Type: OTLPSinkType})
+			},
+			expected: ReloadPlan{Restart: true},
+		},
+		"kubelet port": {
+			change: func(cfg *Config) {
+				cfg.Sources.SummaryConfig.KubeletPort = "10255"
+			},
+			expected: ReloadPlan{Restart: true},
+		},
+		"internal stats prefix": {
+			change: func(cfg *Config) {
+				cfg.Sources.StatsConfig.Prefix = "k8s."
+			},
+			expected: ReloadPlan{Restart: true},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := reloadConfig()
+			test.change(cfg)
+			assert.Equal(t, test.expected, PlanReload(reloadConfig(), cfg))
+		})
+	}
+
+	assert.Equal(t, ReloadPlan{Restart: true}, PlanReload(nil, reloadConfig()))
+}
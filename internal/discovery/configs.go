@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/relabel"
 )
 
 // configuration for auto discovery
@@ -29,6 +30,10 @@ type Config struct {
 	// disables annotation based discovery. Defaults to false.
 	DisableAnnotationDiscovery bool `yaml:"disable_annotation_discovery"`
 
+	// enables sourcing plugin configurations from Prometheus Operator ServiceMonitor and PodMonitor resources.
+	// Defaults to false.
+	EnablePrometheusMonitors bool `yaml:"enable_prometheus_monitors"`
+
 	// list of discovery rules
 	PluginConfigs []PluginConfig `yaml:"plugins"`
 
@@ -58,7 +63,7 @@ type PluginConfig struct {
 	// the selectors for identifying matching kubernetes resources
 	Selectors Selectors `yaml:"selectors"`
 
-	// the port to be monitored on the container
+	// the port to be monitored on the container. Either a number or the name of a container or service port.
 	Port string `yaml:"port"`
 
 	// the scheme to use. Defaults to "http".
@@ -87,6 +92,19 @@ type PluginConfig struct {
 	Filters    filter.Config    `yaml:"filters"`
 	Collection CollectionConfig `yaml:"collection"`
 
+	// Optional relabeling rules for the prometheus plugin type. See the prometheus source for details.
+	Relabel       []relabel.Config `yaml:"relabel"`
+	MetricRelabel []relabel.Config `yaml:"metricRelabel"`
+
+	// Optional exposition formats and exemplar reporting for the prometheus plugin type.
+	ScrapeProtocols []string `yaml:"scrapeProtocols"`
+	Exemplars       bool     `yaml:"exemplars"`
+
+	// Optional timestamp and staleness handling for the prometheus plugin type.
+	HonorTimestamps  bool          `yaml:"honorTimestamps"`
+	MaxSampleAge     time.Duration `yaml:"maxSampleAge"`
+	StalenessMarkers bool          `yaml:"stalenessMarkers"`
+
 	// Internal: whether this plugin config was produced internally
 	Internal bool `yaml:"-"`
 }
@@ -18,9 +18,10 @@ const (
 type ResourceType int
 
 const (
-	PodType     ResourceType = 1
-	ServiceType ResourceType = 2
-	NodeType    ResourceType = 3
+	PodType       ResourceType = 1
+	ServiceType   ResourceType = 2
+	NodeType      ResourceType = 3
+	EndpointsType ResourceType = 4
 )
 
 func (resType ResourceType) String() string {
@@ -31,6 +32,8 @@ func (resType ResourceType) String() string {
 		return "service"
 	case NodeType:
 		return "node"
+	case EndpointsType:
+		return "endpoints"
 	default:
 		return fmt.Sprintf("%d", int(resType))
 	}
@@ -45,6 +48,12 @@ type Resource struct {
 
 	// list of containers for a pod resource
 	Containers []v1.Container
+
+	// list of ports for a service or endpoints resource
+	Ports []v1.ServicePort
+
+	// additional tags for the metrics collected from the resource
+	Tags map[string]string
 }
 
 // Discoverer discovers endpoints from resources based on rules or annotations
@@ -6,6 +6,8 @@ package utils
 import (
 	"fmt"
 
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -22,7 +24,10 @@ func EncodeTags(destTags map[string]string, prefix string, tags map[string]strin
 }
 
 func EncodeMeta(tags map[string]string, kind string, meta metav1.ObjectMeta) {
-	tags[kind] = meta.Name
+	// endpoints are tagged with their service, pod and node instead
+	if kind != discovery.EndpointsType.String() {
+		tags[kind] = meta.Name
+	}
 	if meta.Namespace != "" {
 		tags["namespace"] = meta.Namespace
 	}
@@ -0,0 +1,64 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+)
+
+type secretReader struct {
+	client kube_client.Interface
+}
+
+// NewSecretReader returns a reader of Kubernetes secrets using the given client
+func NewSecretReader(client kube_client.Interface) configuration.SecretReader {
+	return &secretReader{client: client}
+}
+
+func (r *secretReader) ReadSecret(namespace, name, key string) ([]byte, error) {
+	secret, err := r.client.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in secret %s/%s", key, namespace, name)
+	}
+	return value, nil
+}
+
+// NewSecretReaderFactory returns a factory of secret readers connecting to the API server configured by the
+// kubernetes_source. The client is reused while the kubernetes_source configuration is unchanged.
+func NewSecretReaderFactory() configuration.SecretReaderFactory {
+	var (
+		mtx    sync.Mutex
+		prev   configuration.SummarySourceConfig
+		reader configuration.SecretReader
+	)
+	return func(cfg configuration.SummarySourceConfig) (configuration.SecretReader, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if reader != nil && reflect.DeepEqual(cfg, prev) {
+			return reader, nil
+		}
+		kubeConfig, err := GetKubeClientConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		client, err := kube_client.NewForConfig(kubeConfig)
+		if err != nil {
+			return nil, err
+		}
+		prev, reader = cfg, NewSecretReader(client)
+		return reader, nil
+	}
+}
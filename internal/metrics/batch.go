@@ -23,3 +23,24 @@ func (b *Batch) Points() int {
 	}
 	return total
 }
+
+// Clone returns a copy of the batch whose metrics can be modified without affecting the original batch.
+// Sets are shared with the original batch and must be treated as read only.
+func (b *Batch) Clone() *Batch {
+	clone := &Batch{
+		Timestamp: b.Timestamp,
+		Sets:      b.Sets,
+		Metrics:   make([]wf.Metric, 0, len(b.Metrics)),
+	}
+	for _, metric := range b.Metrics {
+		switch m := metric.(type) {
+		case *wf.Point:
+			clone.Metrics = append(clone.Metrics, m.Clone())
+		case *wf.Distribution:
+			clone.Metrics = append(clone.Metrics, m.Clone())
+		default:
+			clone.Metrics = append(clone.Metrics, metric)
+		}
+	}
+	return clone
+}
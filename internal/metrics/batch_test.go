@@ -58,4 +58,20 @@ func TestBatch(t *testing.T) {
 			assert.Equal(t, 4, b.Points())
 		})
 	})
+
+	t.Run("Clone", func(t *testing.T) {
+		point := wf.NewPoint("some.point", 50.0, 0.0, "somepointsource", map[string]string{"tag": "value"})
+		distribution := wf.NewFrequencyDistribution("some.distribution", "somedistrosource", map[string]string{"tag": "value"}, []wf.Centroid{}, time.Now())
+		b := &metrics.Batch{Timestamp: time.Now(), Metrics: []wf.Metric{point, distribution}}
+
+		clone := b.Clone()
+		for _, m := range clone.Metrics {
+			m.OverrideTag("tag", "other")
+		}
+
+		assert.Equal(t, b.Timestamp, clone.Timestamp)
+		assert.Len(t, clone.Metrics, 2)
+		assert.Equal(t, "value", point.Tags()["tag"])
+		assert.Equal(t, "value", distribution.Tags()["tag"])
+	})
 }
@@ -22,11 +22,12 @@ package metrics
 var (
 	LabelMetricSetType = LabelDescriptor{
 		Key:         "type",
-		Description: "Type of the metrics set (container, pod, namespace, node, cluster)",
+		Description: "Type of the metrics set (container, pod, workload, namespace, node, cluster)",
 	}
 	MetricSetTypeSystemContainer = "sys_container"
 	MetricSetTypePodContainer    = "pod_container"
 	MetricSetTypePod             = "pod"
+	MetricSetTypeWorkload        = "workload"
 	MetricSetTypeNamespace       = "ns"
 	MetricSetTypeNode            = "node"
 	MetricSetTypeCluster         = "cluster"
@@ -47,6 +48,14 @@ var (
 		Key:         "namespace_name",
 		Description: "The name of the namespace",
 	}
+	LabelWorkloadKind = LabelDescriptor{
+		Key:         "workload_kind",
+		Description: "The kind of the controller owning the pods of a workload, such as Deployment or CronJob",
+	}
+	LabelWorkloadName = LabelDescriptor{
+		Key:         "workload_name",
+		Description: "The name of the controller owning the pods of a workload",
+	}
 	LabelPodNamespaceUID = LabelDescriptor{
 		Key:         "namespace_id",
 		Description: "The UID of namespace of the pod",
@@ -104,6 +113,10 @@ var (
 		Key:         "volume_name",
 		Description: "The name of the volume.",
 	}
+	LabelPersistentVolumeClaimName = LabelDescriptor{
+		Key:         "pvc_name",
+		Description: "The name of the persistent volume claim of a volume.",
+	}
 	LabelAcceleratorMake = LabelDescriptor{
 		Key:         "make",
 		Description: "Make of the accelerator (nvidia, amd, google etc.)",
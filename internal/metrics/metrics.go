@@ -222,6 +222,16 @@ var MetricRestartCount = Metric{
 	},
 }
 
+var MetricOOMKills = Metric{
+	MetricDescriptor: MetricDescriptor{
+		Name:        "oom_kills",
+		Description: "Number of times the container was OOM killed",
+		Type:        Cumulative,
+		ValueType:   ValueInt64,
+		Units:       Count,
+	},
+}
+
 var MetricCpuLoad = Metric{
 	MetricDescriptor: MetricDescriptor{
 		Name:        "cpu/load",
@@ -539,6 +549,16 @@ var MetricEphemeralStorageLimit = Metric{
 }
 
 // Definition of Rate Metrics.
+var MetricCpuThrottledRatio = Metric{
+	MetricDescriptor: MetricDescriptor{
+		Name:        "cpu/throttled_ratio",
+		Description: "Share of the CFS periods of the container that were throttled since the previous collection",
+		Type:        Gauge,
+		ValueType:   ValueFloat,
+		Units:       Count,
+	},
+}
+
 var MetricCpuUsageRate = Metric{
 	MetricDescriptor: MetricDescriptor{
 		Name:        "cpu/usage_rate",
@@ -729,6 +749,56 @@ var MetricNodeEphemeralStorageReservation = Metric{
 	},
 }
 
+var MetricRecommendationCpuRequest = Metric{
+	MetricDescriptor: MetricDescriptor{
+		Name:        "recommendation/cpu/request",
+		Description: "Cpu request in millicores recommended for a workload based on the usage of its containers",
+		Type:        Gauge,
+		ValueType:   ValueFloat,
+		Units:       Count,
+	},
+}
+
+var MetricRecommendationMemoryRequest = Metric{
+	MetricDescriptor: MetricDescriptor{
+		Name:        "recommendation/memory/request",
+		Description: "Memory request in bytes recommended for a workload based on the working set of its containers",
+		Type:        Gauge,
+		ValueType:   ValueFloat,
+		Units:       Bytes,
+	},
+}
+
+var MetricRecommendationCpuProvisioningRatio = Metric{
+	MetricDescriptor: MetricDescriptor{
+		Name:        "recommendation/cpu/provisioning_ratio",
+		Description: "Ratio of the cpu request to the recommended cpu request. Over-provisioned above 1, under-provisioned below 1",
+		Type:        Gauge,
+		ValueType:   ValueFloat,
+		Units:       Count,
+	},
+}
+
+var MetricRecommendationMemoryProvisioningRatio = Metric{
+	MetricDescriptor: MetricDescriptor{
+		Name:        "recommendation/memory/provisioning_ratio",
+		Description: "Ratio of the memory request to the recommended memory request. Over-provisioned above 1, under-provisioned below 1",
+		Type:        Gauge,
+		ValueType:   ValueFloat,
+		Units:       Count,
+	},
+}
+
+var MetricQuotaUsageRatio = Metric{
+	MetricDescriptor: MetricDescriptor{
+		Name:        "quota/usage_ratio",
+		Description: "Ratio of the actual cpu or memory usage of a namespace to the hard limit of a resource quota",
+		Type:        Gauge,
+		ValueType:   ValueFloat,
+		Units:       Count,
+	},
+}
+
 var MetricNodeCondition = Metric{
 	MetricDescriptor: MetricDescriptor{
 		Name:        "status/condition",
@@ -42,6 +42,10 @@ func PodKey(namespace, podName string) ResourceKey {
 	return ResourceKey(fmt.Sprintf("namespace:%s/pod:%s", namespace, podName))
 }
 
+func WorkloadKey(namespace, kind, name string) ResourceKey {
+	return ResourceKey(fmt.Sprintf("namespace:%s/workload:%s/%s", namespace, kind, name))
+}
+
 func NamespaceKey(namespace string) ResourceKey {
 	return ResourceKey(fmt.Sprintf("namespace:%s", namespace))
 }
@@ -126,6 +126,13 @@ type Source interface {
 	Cleanup()
 }
 
+// StaleSource is implemented by sources that report staleness markers for the series they collected
+type StaleSource interface {
+	// StaleMarkers returns a staleness marker for every series of the last successful scrape.
+	// It is called when a scrape fails and when the source is removed.
+	StaleMarkers() *Batch
+}
+
 // SourceProvider produces metric sources
 type SourceProvider interface {
 	GetMetricsSources() []Source
@@ -160,6 +167,12 @@ type ProviderFactory interface {
 	Build(cfg interface{}) (SourceProvider, error)
 }
 
+// ProcessorFactory creates the processors of a type configured in the processors section of the configuration
+type ProcessorFactory interface {
+	Name() string
+	Build(cfg interface{}) (Processor, error)
+}
+
 type ConfigurableSourceProvider interface {
 	Configure(interval, timeout time.Duration)
 }
@@ -56,7 +56,7 @@ func NewCollectorRunOptions() *CollectorRunOptions {
 func (opts *CollectorRunOptions) Parse(fs *pflag.FlagSet, args []string) error {
 	// supported flags
 	fs.BoolVar(&opts.Version, "version", false, "print version info and exit")
-	fs.BoolVar(&opts.EnableProfiling, "profile", false, "enable pprof")
+	fs.BoolVar(&opts.EnableProfiling, "profile", false, "enable pprof and the discovery debug endpoint")
 	fs.BoolVar(&opts.daemon, "daemon", false, "enable daemon mode")
 	fs.Var(&opts.AgentType, "agent", "the agent type (node, cluster, all, legacy)")
 	fs.StringVar(&opts.ConfigFile, "config-file", "", "required configuration file")
@@ -0,0 +1,210 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package relabel implements label rewriting rules equivalent to Prometheus relabel_configs.
+package relabel
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	Replace   = "replace"
+	Keep      = "keep"
+	Drop      = "drop"
+	HashMod   = "hashmod"
+	LabelMap  = "labelmap"
+	LabelDrop = "labeldrop"
+	LabelKeep = "labelkeep"
+
+	// MetricNameLabel holds the metric name during metric relabeling
+	MetricNameLabel = "__name__"
+
+	// ReservedLabelPrefix is the prefix of labels that are removed once relabeling is complete
+	ReservedLabelPrefix = "__"
+
+	defaultSeparator   = ";"
+	defaultRegex       = "(.*)"
+	defaultReplacement = "$1"
+)
+
+// Config is a single relabeling rule. See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#relabel_config
+type Config struct {
+	// The labels whose values are concatenated using the separator and matched against the regex.
+	SourceLabels []string `yaml:"sourceLabels"`
+
+	// The separator placed between concatenated source label values. Defaults to ';'.
+	Separator string `yaml:"separator"`
+
+	// The label the result is written to for the replace and hashmod actions.
+	TargetLabel string `yaml:"targetLabel"`
+
+	// Regular expression the concatenated source label values, or the label names for labelmap, labeldrop and
+	// labelkeep, must fully match. Defaults to '(.*)'.
+	Regex string `yaml:"regex"`
+
+	// The modulus to take of the hash of the source label values for hashmod.
+	Modulus uint64 `yaml:"modulus"`
+
+	// The replacement for replace and labelmap. Supports $1 style references to capture groups. Defaults to '$1'.
+	Replacement *string `yaml:"replacement"`
+
+	// One of replace, keep, drop, hashmod, labelmap, labeldrop or labelkeep. Defaults to replace.
+	Action string `yaml:"action"`
+}
+
+type rule struct {
+	sourceLabels []string
+	separator    string
+	targetLabel  string
+	regex        *regexp.Regexp
+	modulus      uint64
+	replacement  string
+	action       string
+}
+
+// Rules is an ordered list of compiled relabeling rules
+type Rules []*rule
+
+// Compile validates and compiles relabeling rules
+func Compile(cfgs []Config) (Rules, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	rules := make(Rules, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		r, err := compile(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid relabel rule %d: %v", i, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func compile(cfg Config) (*rule, error) {
+	r := &rule{
+		sourceLabels: cfg.SourceLabels,
+		separator:    cfg.Separator,
+		targetLabel:  cfg.TargetLabel,
+		modulus:      cfg.Modulus,
+		replacement:  defaultReplacement,
+		action:       strings.ToLower(cfg.Action),
+	}
+	if r.separator == "" {
+		r.separator = defaultSeparator
+	}
+	if cfg.Replacement != nil {
+		r.replacement = *cfg.Replacement
+	}
+	if r.action == "" {
+		r.action = Replace
+	}
+	expr := cfg.Regex
+	if expr == "" {
+		expr = defaultRegex
+	}
+	regex, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %v", expr, err)
+	}
+	r.regex = regex
+
+	switch r.action {
+	case Replace:
+		if r.targetLabel == "" {
+			return nil, fmt.Errorf("%s requires targetLabel", r.action)
+		}
+	case HashMod:
+		if r.targetLabel == "" || r.modulus == 0 {
+			return nil, fmt.Errorf("%s requires targetLabel and a non-zero modulus", r.action)
+		}
+	case Keep, Drop:
+		if len(r.sourceLabels) == 0 {
+			return nil, fmt.Errorf("%s requires sourceLabels", r.action)
+		}
+	case LabelMap, LabelDrop, LabelKeep:
+	default:
+		return nil, fmt.Errorf("unknown action %q", cfg.Action)
+	}
+	return r, nil
+}
+
+// Process applies the rules in order to the labels, modifying them in place.
+// It returns false when the labels are dropped by a keep or drop rule.
+func (rules Rules) Process(labels map[string]string) bool {
+	for _, r := range rules {
+		if !r.process(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *rule) process(labels map[string]string) bool {
+	switch r.action {
+	case Replace:
+		value := r.sourceValue(labels)
+		match := r.regex.FindStringSubmatchIndex(value)
+		if match == nil {
+			return true
+		}
+		result := string(r.regex.ExpandString(nil, r.replacement, value, match))
+		if result == "" {
+			delete(labels, r.targetLabel)
+		} else {
+			labels[r.targetLabel] = result
+		}
+	case Keep:
+		return r.regex.MatchString(r.sourceValue(labels))
+	case Drop:
+		return !r.regex.MatchString(r.sourceValue(labels))
+	case HashMod:
+		sum := md5.Sum([]byte(r.sourceValue(labels)))
+		labels[r.targetLabel] = fmt.Sprintf("%d", binary.BigEndian.Uint64(sum[8:])%r.modulus)
+	case LabelMap:
+		mapped := map[string]string{}
+		for name, value := range labels {
+			if r.regex.MatchString(name) {
+				mapped[r.regex.ReplaceAllString(name, r.replacement)] = value
+			}
+		}
+		for name, value := range mapped {
+			labels[name] = value
+		}
+	case LabelDrop:
+		for name := range labels {
+			if r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	case LabelKeep:
+		for name := range labels {
+			if !r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
+
+func (r *rule) sourceValue(labels map[string]string) string {
+	values := make([]string, len(r.sourceLabels))
+	for i, name := range r.sourceLabels {
+		values[i] = labels[name]
+	}
+	return strings.Join(values, r.separator)
+}
+
+// DropReserved removes the labels with the reserved "__" prefix that are only available during relabeling
+func DropReserved(labels map[string]string) {
+	for name := range labels {
+		if strings.HasPrefix(name, ReservedLabelPrefix) {
+			delete(labels, name)
+		}
+	}
+}
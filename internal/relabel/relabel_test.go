@@ -0,0 +1,125 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package relabel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func process(t *testing.T, cfgs []Config, labels map[string]string) (map[string]string, bool) {
+	rules, err := Compile(cfgs)
+	require.NoError(t, err)
+	keep := rules.Process(labels)
+	return labels, keep
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("replace", func(t *testing.T) {
+		labels, keep := process(t, []Config{{
+			SourceLabels: []string{"pod_name", "container"},
+			Regex:        "(.*)-[a-z0-9]+;(.*)",
+			TargetLabel:  "workload",
+			Replacement:  strPtr("$1/$2"),
+		}}, map[string]string{"pod_name": "web-x7k2p", "container": "nginx"})
+		assert.True(t, keep)
+		assert.Equal(t, "web/nginx", labels["workload"])
+	})
+
+	t.Run("replace without a match leaves labels untouched", func(t *testing.T) {
+		labels, _ := process(t, []Config{{
+			SourceLabels: []string{"pod_name"},
+			Regex:        "db-.*",
+			TargetLabel:  "role",
+			Replacement:  strPtr("database"),
+		}}, map[string]string{"pod_name": "web"})
+		assert.NotContains(t, labels, "role")
+	})
+
+	t.Run("replace with an empty result deletes the target", func(t *testing.T) {
+		labels, _ := process(t, []Config{{
+			SourceLabels: []string{"missing"},
+			TargetLabel:  "pod_name",
+		}}, map[string]string{"pod_name": "web"})
+		assert.NotContains(t, labels, "pod_name")
+	})
+
+	t.Run("keep and drop", func(t *testing.T) {
+		_, keep := process(t, []Config{{Action: Keep, SourceLabels: []string{MetricNameLabel}, Regex: "http_.*"}},
+			map[string]string{MetricNameLabel: "go_goroutines"})
+		assert.False(t, keep)
+
+		_, keep = process(t, []Config{{Action: Keep, SourceLabels: []string{MetricNameLabel}, Regex: "http_.*"}},
+			map[string]string{MetricNameLabel: "http_requests_total"})
+		assert.True(t, keep)
+
+		_, keep = process(t, []Config{{Action: "DROP", SourceLabels: []string{"namespace", "pod"}, Regex: "kube-system;.*"}},
+			map[string]string{"namespace": "kube-system", "pod": "dns"})
+		assert.False(t, keep)
+	})
+
+	t.Run("hashmod", func(t *testing.T) {
+		labels, _ := process(t, []Config{{Action: HashMod, SourceLabels: []string{"pod"}, TargetLabel: "shard", Modulus: 4}},
+			map[string]string{"pod": "web-1"})
+		again, _ := process(t, []Config{{Action: HashMod, SourceLabels: []string{"pod"}, TargetLabel: "shard", Modulus: 4}},
+			map[string]string{"pod": "web-1"})
+		assert.Contains(t, []string{"0", "1", "2", "3"}, labels["shard"])
+		assert.Equal(t, labels["shard"], again["shard"])
+	})
+
+	t.Run("labelmap", func(t *testing.T) {
+		labels, _ := process(t, []Config{{Action: LabelMap, Regex: "label\\.(.+)"}},
+			map[string]string{"label.app": "web", "pod": "web-1"})
+		assert.Equal(t, map[string]string{"label.app": "web", "app": "web", "pod": "web-1"}, labels)
+	})
+
+	t.Run("labeldrop and labelkeep", func(t *testing.T) {
+		labels, _ := process(t, []Config{{Action: LabelDrop, Regex: "label\\..*"}},
+			map[string]string{"label.app": "web", "pod": "web-1"})
+		assert.Equal(t, map[string]string{"pod": "web-1"}, labels)
+
+		labels, _ = process(t, []Config{{Action: LabelKeep, Regex: "pod|__name__"}},
+			map[string]string{"label.app": "web", "pod": "web-1", MetricNameLabel: "up"})
+		assert.Equal(t, map[string]string{"pod": "web-1", MetricNameLabel: "up"}, labels)
+	})
+
+	t.Run("rules are applied in order", func(t *testing.T) {
+		labels, keep := process(t, []Config{
+			{SourceLabels: []string{"pod"}, Regex: "(.*)-[0-9]+", TargetLabel: "app"},
+			{Action: Keep, SourceLabels: []string{"app"}, Regex: "web"},
+		}, map[string]string{"pod": "web-1"})
+		assert.True(t, keep)
+		assert.Equal(t, "web", labels["app"])
+	})
+}
+
+func TestCompile(t *testing.T) {
+	rules, err := Compile(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, rules)
+	assert.True(t, rules.Process(map[string]string{}))
+
+	for _, cfg := range []Config{
+		{Action: "unknown"},
+		{Action: Replace},
+		{Action: HashMod, TargetLabel: "shard"},
+		{Action: Keep},
+		{Action: LabelDrop, Regex: "("},
+	} {
+		_, err := Compile([]Config{cfg})
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
+
+func TestDropReserved(t *testing.T) {
+	labels := map[string]string{MetricNameLabel: "up", "__address__": "10.0.0.1:8080", "pod": "web-1"}
+	DropReserved(labels)
+	assert.Equal(t, map[string]string{"pod": "web-1"}, labels)
+}
@@ -22,9 +22,14 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/gobwas/glob"
+
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
 )
 
+// AnnotationPrefix prefixes the names of the metric labels annotations are copied to
+const AnnotationPrefix = "annotation."
+
 // LabelCopier maps kubernetes objects' labels to metrics
 type LabelCopier struct {
 	// labelSeparator contains separator used to join labels into "labels" label
@@ -33,6 +38,29 @@ type LabelCopier struct {
 	storedLabels map[string]string
 	// ignoredLabels contains labels to be skipped during concatenation
 	ignoredLabels map[string]string
+	// allowedLabels and deniedLabels select the labels concatenated, all labels if both are nil
+	allowedLabels glob.Glob
+	deniedLabels  glob.Glob
+	// renamedLabels maps label and annotation names to the names used in metrics
+	renamedLabels map[string]string
+	// allowedAnnotations selects the annotations copied, none if nil
+	allowedAnnotations glob.Glob
+	// inheritLabels controls whether objects inherit the labels of their namespace
+	inheritLabels bool
+}
+
+// LabelCopierConfig selects and renames the labels and annotations copied by a LabelCopier
+type LabelCopierConfig struct {
+	// glob patterns of the labels copied, all labels if empty
+	AllowList []string
+	// glob patterns of the labels not copied
+	DenyList []string
+	// names labels and annotations are copied as, keyed by their Kubernetes name
+	Rename map[string]string
+	// glob patterns of the annotations copied, no annotations if empty
+	AnnotationAllowList []string
+	// whether pods and containers inherit the labels of their namespace
+	InheritNamespaceLabels bool
 }
 
 // Copy copies the given set of pod labels into a set of metric labels, using the following logic:
@@ -46,15 +74,69 @@ func (copier *LabelCopier) Copy(in map[string]string, out map[string]string) {
 			out[mappedKey] = value
 		}
 
-		if _, exists := copier.ignoredLabels[key]; !exists {
-			labels = append(labels, fmt.Sprintf("%s:%s", key, value))
+		if _, exists := copier.ignoredLabels[key]; !exists && copier.selected(key) {
+			labels = append(labels, fmt.Sprintf("%s:%s", copier.rename(key), value))
+		}
+	}
+
+	sort.Strings(labels)
+	out[metrics.LabelLabels.Key] = strings.Join(labels, copier.labelSeparator)
+}
+
+// CopyAnnotations copies the selected annotations into metric labels prefixed with AnnotationPrefix.
+// Annotations are not concatenated since their values may contain any character.
+func (copier *LabelCopier) CopyAnnotations(in map[string]string, out map[string]string) {
+	if copier.allowedAnnotations == nil {
+		return
+	}
+	for key, value := range in {
+		if copier.allowedAnnotations.Match(key) {
+			out[AnnotationPrefix+copier.rename(key)] = value
 		}
 	}
+}
 
+// Inherit adds the selected namespace labels to the labels previously copied from a namespaced object if
+// namespace label inheritance is enabled. Labels of the object take precedence.
+func (copier *LabelCopier) Inherit(namespaceLabels map[string]string, out map[string]string) {
+	if !copier.inheritLabels || len(namespaceLabels) == 0 {
+		return
+	}
+	var labels []string
+	present := map[string]bool{}
+	if existing := out[metrics.LabelLabels.Key]; existing != "" {
+		labels = strings.Split(existing, copier.labelSeparator)
+		for _, label := range labels {
+			present[strings.SplitN(label, ":", 2)[0]] = true
+		}
+	}
+	for key, value := range namespaceLabels {
+		if _, exists := copier.ignoredLabels[key]; exists || !copier.selected(key) {
+			continue
+		}
+		name := copier.rename(key)
+		if !present[name] {
+			labels = append(labels, fmt.Sprintf("%s:%s", name, value))
+		}
+	}
 	sort.Strings(labels)
 	out[metrics.LabelLabels.Key] = strings.Join(labels, copier.labelSeparator)
 }
 
+func (copier *LabelCopier) selected(key string) bool {
+	if copier.allowedLabels != nil && !copier.allowedLabels.Match(key) {
+		return false
+	}
+	return copier.deniedLabels == nil || !copier.deniedLabels.Match(key)
+}
+
+func (copier *LabelCopier) rename(key string) string {
+	if name, exists := copier.renamedLabels[key]; exists {
+		return name
+	}
+	return key
+}
+
 // makeStoredLabels converts labels into a map for quicker retrieval.
 // Incoming labels, if desired, may contain mappings in format "newName=oldName"
 func makeStoredLabels(labels []string) map[string]string {
@@ -79,6 +161,18 @@ func makeIgnoredLabels(labels []string) map[string]string {
 	return ignoredLabels
 }
 
+// compileGlobs compiles the given patterns into a single glob, nil if there are none
+func compileGlobs(patterns []string) (glob.Glob, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	g, err := glob.Compile("{" + strings.Join(patterns, ",") + "}")
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob patterns %v: %v", patterns, err)
+	}
+	return g, nil
+}
+
 // NewLabelCopier creates a new instance of LabelCopier type
 func NewLabelCopier(separator string, storedLabels, ignoredLabels []string) (*LabelCopier, error) {
 	return &LabelCopier{
@@ -87,3 +181,23 @@ func NewLabelCopier(separator string, storedLabels, ignoredLabels []string) (*La
 		ignoredLabels:  makeIgnoredLabels(ignoredLabels),
 	}, nil
 }
+
+// NewConfiguredLabelCopier creates a LabelCopier copying the labels and annotations selected by the given configuration
+func NewConfiguredLabelCopier(separator string, cfg LabelCopierConfig) (*LabelCopier, error) {
+	copier, err := NewLabelCopier(separator, []string{}, []string{})
+	if err != nil {
+		return nil, err
+	}
+	if copier.allowedLabels, err = compileGlobs(cfg.AllowList); err != nil {
+		return nil, err
+	}
+	if copier.deniedLabels, err = compileGlobs(cfg.DenyList); err != nil {
+		return nil, err
+	}
+	if copier.allowedAnnotations, err = compileGlobs(cfg.AnnotationAllowList); err != nil {
+		return nil, err
+	}
+	copier.renamedLabels = cfg.Rename
+	copier.inheritLabels = cfg.InheritNamespaceLabels
+	return copier, nil
+}
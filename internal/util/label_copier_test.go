@@ -118,3 +118,48 @@ func initializeAndCopy(t *testing.T, separator string, storedLabels []string, ig
 	lc.Copy(labels, out)
 	return out
 }
+
+func TestConfiguredLabelCopier(t *testing.T) {
+	lc, err := NewConfiguredLabelCopier(",", LabelCopierConfig{
+		AllowList:              []string{"app.kubernetes.io/*", "team", "colour"},
+		DenyList:               []string{"app.kubernetes.io/managed-by"},
+		Rename:                 map[string]string{"app.kubernetes.io/name": "app", "example.com/owner": "owner"},
+		AnnotationAllowList:    []string{"example.com/*"},
+		InheritNamespaceLabels: true,
+	})
+	assert.NoError(t, err)
+
+	out := map[string]string{}
+	lc.Copy(map[string]string{
+		"app.kubernetes.io/name":       "web",
+		"app.kubernetes.io/version":    "1.2",
+		"app.kubernetes.io/managed-by": "helm",
+		"colour":                       "red",
+		"pod-template-hash":            "5d8f",
+	}, out)
+	lc.CopyAnnotations(map[string]string{
+		"example.com/owner": "payments, billing",
+		"kubectl.kubernetes.io/last-applied-configuration": "{}",
+	}, out)
+	lc.Inherit(map[string]string{"team": "payments", "colour": "blue", "kubernetes.io/metadata.name": "ns1"}, out)
+
+	assert.Equal(t, map[string]string{
+		metrics.LabelLabels.Key: "app.kubernetes.io/version:1.2,app:web,colour:red,team:payments",
+		"annotation.owner":      "payments, billing",
+	}, out)
+
+	t.Run("defaults copy all labels and no annotations", func(t *testing.T) {
+		lc, err := NewConfiguredLabelCopier(",", LabelCopierConfig{})
+		assert.NoError(t, err)
+		out := map[string]string{}
+		lc.Copy(map[string]string{"name": "bike"}, out)
+		lc.CopyAnnotations(map[string]string{"note": "fast"}, out)
+		lc.Inherit(map[string]string{"team": "payments"}, out)
+		assert.Equal(t, map[string]string{metrics.LabelLabels.Key: "name:bike"}, out)
+	})
+
+	t.Run("invalid patterns", func(t *testing.T) {
+		_, err := NewConfiguredLabelCopier(",", LabelCopierConfig{DenyList: []string{"app[a"}})
+		assert.Error(t, err)
+	})
+}
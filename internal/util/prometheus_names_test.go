@@ -0,0 +1,16 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizePrometheusNames(t *testing.T) {
+	assert.Equal(t, "kubernetes_pod_cpu_usage_rate", SanitizePrometheusName("kubernetes.pod.cpu.usage-rate"))
+	assert.Equal(t, "_9lives:total", SanitizePrometheusName("9lives:total"))
+	assert.Equal(t, "label_app_kubernetes_io_name", SanitizePrometheusLabelName("label.app.kubernetes.io/name"))
+}
@@ -13,9 +13,14 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	kube_api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/kubernetes"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
 	v1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 )
@@ -37,6 +42,20 @@ const (
 	POD_PHASE_UNKNOWN
 )
 
+const (
+	PV_PHASE_PENDING = iota + 1
+	PV_PHASE_AVAILABLE
+	PV_PHASE_BOUND
+	PV_PHASE_RELEASED
+	PV_PHASE_FAILED
+)
+
+const (
+	PVC_PHASE_PENDING = iota + 1
+	PVC_PHASE_BOUND
+	PVC_PHASE_LOST
+)
+
 const (
 	CONTAINER_STATE_RUNNING = iota + 1
 	CONTAINER_STATE_WAITING
@@ -50,6 +69,10 @@ var (
 	podLister  v1listers.PodLister
 	nsStore    cache.Store
 	agentType  AgentType
+
+	replicaSetLister    appsv1listers.ReplicaSetLister
+	jobLister           batchv1listers.JobLister
+	resourceQuotaLister v1listers.ResourceQuotaLister
 )
 
 type AgentType interface {
@@ -119,6 +142,48 @@ func GetNamespaceStore(kubeClient kubernetes.Interface) cache.Store {
 	return nsStore
 }
 
+func GetReplicaSetLister(kubeClient kubernetes.Interface) appsv1listers.ReplicaSetLister {
+	lock.Lock()
+	defer lock.Unlock()
+
+	// init just one instance per collector agent
+	if replicaSetLister == nil {
+		replicaSetLister = appsv1listers.NewReplicaSetLister(startIndexer(kubeClient.AppsV1().RESTClient(), "replicasets", &appsv1.ReplicaSet{}))
+	}
+	return replicaSetLister
+}
+
+func GetJobLister(kubeClient kubernetes.Interface) batchv1listers.JobLister {
+	lock.Lock()
+	defer lock.Unlock()
+
+	// init just one instance per collector agent
+	if jobLister == nil {
+		jobLister = batchv1listers.NewJobLister(startIndexer(kubeClient.BatchV1().RESTClient(), "jobs", &batchv1.Job{}))
+	}
+	return jobLister
+}
+
+func GetResourceQuotaLister(kubeClient kubernetes.Interface) v1listers.ResourceQuotaLister {
+	lock.Lock()
+	defer lock.Unlock()
+
+	// init just one instance per collector agent
+	if resourceQuotaLister == nil {
+		resourceQuotaLister = v1listers.NewResourceQuotaLister(startIndexer(kubeClient.CoreV1().RESTClient(), "resourcequotas", &kube_api.ResourceQuota{}))
+	}
+	return resourceQuotaLister
+}
+
+// startIndexer watches all objects of a resource across namespaces
+func startIndexer(client cache.Getter, resource string, objType interface{}) cache.Indexer {
+	lw := cache.NewListWatchFromClient(client, resource, kube_api.NamespaceAll, fields.Everything())
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	reflector := cache.NewReflector(lw, objType, store, time.Hour)
+	go reflector.Run(NeverStop)
+	return store
+}
+
 func GetFieldSelector(resourceType string) fields.Selector {
 	fieldSelector := fields.Everything()
 	nodeName := GetNodeName()
@@ -306,3 +371,44 @@ func ConvertPodPhase(phase kube_api.PodPhase) int64 {
 		return POD_PHASE_UNKNOWN
 	}
 }
+
+func ConvertPersistentVolumePhase(phase kube_api.PersistentVolumePhase) int64 {
+	switch phase {
+	case kube_api.VolumePending:
+		return PV_PHASE_PENDING
+	case kube_api.VolumeAvailable:
+		return PV_PHASE_AVAILABLE
+	case kube_api.VolumeBound:
+		return PV_PHASE_BOUND
+	case kube_api.VolumeReleased:
+		return PV_PHASE_RELEASED
+	case kube_api.VolumeFailed:
+		return PV_PHASE_FAILED
+	default:
+		return 0
+	}
+}
+
+func ConvertPersistentVolumeClaimPhase(phase kube_api.PersistentVolumeClaimPhase) int64 {
+	switch phase {
+	case kube_api.ClaimPending:
+		return PVC_PHASE_PENDING
+	case kube_api.ClaimBound:
+		return PVC_PHASE_BOUND
+	case kube_api.ClaimLost:
+		return PVC_PHASE_LOST
+	default:
+		return 0
+	}
+}
+
+// ResourceQuantityValue returns a quantity of a resource or quota in the units of the collector metrics,
+// millicores for cpu and the plain value otherwise
+func ResourceQuantityValue(name kube_api.ResourceName, quantity resource.Quantity) int64 {
+	switch name {
+	case kube_api.ResourceCPU, kube_api.ResourceRequestsCPU, kube_api.ResourceLimitsCPU:
+		return quantity.MilliValue()
+	default:
+		return quantity.Value()
+	}
+}
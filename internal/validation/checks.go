@@ -0,0 +1,325 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/relabel"
+	telegrafdiscovery "github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/discovery/telegraf"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/processors"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sources/kstate"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sources/prometheus"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sources/telegraf"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const minFlushInterval = 5 * time.Second
+
+func (v *validator) collector(cfg *configuration.Config) {
+	if cfg.FlushInterval != 0 && cfg.FlushInterval < minFlushInterval {
+		v.add(path{"flushInterval"}, "must not be less than %v", minFlushInterval)
+	}
+	v.duration(path{"defaultCollectionInterval"}, cfg.DefaultCollectionInterval)
+	v.duration(path{"sinkExportDataTimeout"}, cfg.SinkExportDataTimeout)
+
+	if len(cfg.Sinks) == 0 {
+		v.add(path{"sinks"}, "at least one sink is required")
+	}
+	for i, sink := range cfg.Sinks {
+		if sink != nil {
+			v.sink(path{"sinks"}.index(i), *sink)
+		}
+	}
+
+	if cfg.Sources == nil {
+		v.add(path{"sources"}, "is required")
+	} else {
+		v.sources(path{"sources"}, *cfg.Sources)
+	}
+
+	if limit := cfg.CardinalityLimit; limit != nil {
+		p := path{"cardinalityLimit"}
+		if limit.MaxSeriesPerMetric <= 0 {
+			v.add(p.key("maxSeriesPerMetric"), "must be greater than 0")
+		}
+		v.duration(p.key("window"), limit.Window)
+		switch limit.Action {
+		case "", configuration.CardinalityDropAction, configuration.CardinalityAggregateAction:
+		default:
+			v.add(p.key("action"), "unknown action %q", limit.Action)
+		}
+	}
+
+	for _, list := range []struct {
+		key      string
+		patterns []string
+	}{
+		{"allowList", cfg.Labels.AllowList},
+		{"denyList", cfg.Labels.DenyList},
+		{"annotationAllowList", cfg.Labels.AnnotationAllowList},
+	} {
+		for i, pattern := range list.patterns {
+			v.glob(path{"labels"}.key(list.key).index(i), pattern)
+		}
+	}
+
+	for i, processor := range cfg.Processors {
+		p := path{"processors"}.index(i)
+		if _, ok := processors.Factory(processor.Type); !ok {
+			v.add(p.key("type"), "unknown processor type %q", processor.Type)
+		} else if err := processors.ValidateConfig(processor); err != nil {
+			v.add(p, "%v", err)
+		}
+	}
+
+	v.discovery(path{"discovery"}, cfg.DiscoveryConfig)
+}
+
+func (v *validator) sink(p path, cfg configuration.SinkConfig) {
+	switch cfg.Type {
+	case "", configuration.WavefrontSinkType, configuration.PrometheusRemoteWriteSinkType,
+		configuration.OTLPSinkType, configuration.PrometheusExpositionSinkType:
+	default:
+		v.add(p.key("type"), "unknown sink type %q", cfg.Type)
+	}
+	if cfg.ErrorLogPercent < 0 || cfg.ErrorLogPercent > 1 {
+		v.add(p.key("errorLogPercent"), "must be between 0 and 1")
+	}
+	v.filters(p.key("filters"), cfg.Filters)
+	for i, rule := range cfg.TransformRules {
+		if err := sinks.ValidateTransformRule(rule); err != nil {
+			v.add(p.key("transforms").index(i), "%v", err)
+		}
+	}
+	for _, d := range []struct {
+		path  path
+		value time.Duration
+	}{
+		{p.key("retryBuffer").key("maxAge"), cfg.RetryBuffer.MaxAge},
+		{p.key("remoteWrite").key("minBackoff"), cfg.RemoteWrite.MinBackoff},
+		{p.key("remoteWrite").key("maxBackoff"), cfg.RemoteWrite.MaxBackoff},
+		{p.key("remoteWrite").key("timeout"), cfg.RemoteWrite.Timeout},
+		{p.key("otlp").key("minBackoff"), cfg.OTLP.MinBackoff},
+		{p.key("otlp").key("maxBackoff"), cfg.OTLP.MaxBackoff},
+		{p.key("otlp").key("timeout"), cfg.OTLP.Timeout},
+	} {
+		v.duration(d.path, d.value)
+	}
+	for i, pattern := range cfg.OTLP.SumPatterns {
+		v.glob(p.key("otlp").key("sumPatterns").index(i), pattern)
+	}
+}
+
+func (v *validator) sources(p path, cfg configuration.SourceConfig) {
+	if cfg.SummaryConfig == nil {
+		v.add(p.key("kubernetes_source"), "is required")
+	} else {
+		v.transforms(p.key("kubernetes_source"), cfg.SummaryConfig.Transforms, cfg.SummaryConfig.Collection)
+		if port := cfg.SummaryConfig.KubeletPort; port != "" {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				v.add(p.key("kubernetes_source").key("kubeletPort"), "invalid port %q", port)
+			}
+		}
+	}
+	if cfg.CadvisorConfig != nil {
+		v.transforms(p.key("kubernetes_cadvisor_source"), cfg.CadvisorConfig.Transforms, cfg.CadvisorConfig.Collection)
+	}
+	if cfg.ControlPlaneConfig != nil {
+		v.collection(p.key("kubernetes_control_plane_source").key("collection"), cfg.ControlPlaneConfig.Collection)
+	}
+	if cfg.SystemdConfig != nil {
+		sp := p.key("systemd_source")
+		v.transforms(sp, cfg.SystemdConfig.Transforms, cfg.SystemdConfig.Collection)
+		for i, pattern := range cfg.SystemdConfig.UnitAllowList {
+			v.glob(sp.key("unitAllowList").index(i), pattern)
+		}
+		for i, pattern := range cfg.SystemdConfig.UnitDenyList {
+			v.glob(sp.key("unitDenyList").index(i), pattern)
+		}
+	}
+	if cfg.StatsConfig != nil {
+		v.transforms(p.key("internal_stats_source"), cfg.StatsConfig.Transforms, cfg.StatsConfig.Collection)
+	}
+	if cfg.StateConfig != nil {
+		sp := p.key("kubernetes_state_source")
+		v.transforms(sp, cfg.StateConfig.Transforms, cfg.StateConfig.Collection)
+		for i, cr := range cfg.StateConfig.CustomResources {
+			if err := kstate.ValidateCustomResource(cr); err != nil {
+				v.add(sp.key("customResources").index(i), "%v", err)
+			}
+		}
+	}
+	for i, prom := range cfg.PrometheusConfigs {
+		if prom == nil {
+			continue
+		}
+		pp := p.key("prometheus_sources").index(i)
+		v.transforms(pp, prom.Transforms, prom.Collection)
+		if prom.URL == "" {
+			v.add(pp.key("url"), "is required")
+		}
+		v.scrape(pp, prom.Relabel, prom.MetricRelabel, prom.ScrapeProtocols, prom.MaxSampleAge)
+	}
+	for i, tel := range cfg.TelegrafConfigs {
+		if tel == nil {
+			continue
+		}
+		tp := p.key("telegraf_sources").index(i)
+		v.transforms(tp, tel.Transforms, tel.Collection)
+		for j, plugin := range tel.Plugins {
+			v.telegraf(tp, tp.key("plugins").index(j), plugin, tel.Conf)
+		}
+	}
+}
+
+func (v *validator) transforms(p path, cfg configuration.Transforms, collection configuration.CollectionConfig) {
+	v.filters(p.key("filters"), cfg.Filters)
+	v.collection(p.key("collection"), collection)
+}
+
+func (v *validator) collection(p path, cfg configuration.CollectionConfig) {
+	v.duration(p.key("interval"), cfg.Interval)
+	v.duration(p.key("timeout"), cfg.Timeout)
+}
+
+func (v *validator) scrape(p path, relabels, metricRelabels []relabel.Config, protocols []string, maxSampleAge time.Duration) {
+	if _, err := relabel.Compile(relabels); err != nil {
+		v.add(p.key("relabel"), "%v", err)
+	}
+	if _, err := relabel.Compile(metricRelabels); err != nil {
+		v.add(p.key("metricRelabel"), "%v", err)
+	}
+	if err := prometheus.ValidateScrapeProtocols(protocols); err != nil {
+		v.add(p.key("scrapeProtocols"), "%v", err)
+	}
+	v.duration(p.key("maxSampleAge"), maxSampleAge)
+}
+
+// telegraf reports unknown plugins at the given path and configurations the plugin rejects at the conf key
+func (v *validator) telegraf(p, pluginPath path, plugin, conf string) {
+	if err := telegraf.ValidateConf(plugin, ""); err != nil {
+		v.add(pluginPath, "%v", err)
+	} else if err := telegraf.ValidateConf(plugin, conf); err != nil {
+		v.add(p.key("conf"), "%v", err)
+	}
+}
+
+func (v *validator) discovery(p path, cfg discovery.Config) {
+	v.duration(p.key("discovery_interval"), cfg.DiscoveryInterval)
+	for i, selectors := range cfg.AnnotationExcludes {
+		v.selectors(p.key("annotation_excludes").index(i), selectors)
+	}
+
+	names := map[string]bool{}
+	for i, plugin := range cfg.PluginConfigs {
+		pp := p.key("plugins").index(i)
+		if plugin.Name == "" {
+			v.add(pp.key("name"), "is required")
+		} else if names[plugin.Name] {
+			v.add(pp.key("name"), "duplicate rule name %q", plugin.Name)
+		}
+		names[plugin.Name] = true
+
+		switch {
+		case plugin.Type == "prometheus":
+			v.scrape(pp, plugin.Relabel, plugin.MetricRelabel, plugin.ScrapeProtocols, plugin.MaxSampleAge)
+		case strings.HasPrefix(plugin.Type, "telegraf/"):
+			// placeholders are expanded before the configuration is parsed
+			conf := telegrafdiscovery.ExpandConf(plugin.Conf, "http", "127.0.0.1", plugin.Port)
+			v.telegraf(pp, pp.key("type"), strings.TrimPrefix(plugin.Type, "telegraf/"), conf)
+		default:
+			v.add(pp.key("type"), "unknown plugin type %q, expected prometheus or telegraf/<plugin>", plugin.Type)
+		}
+
+		if plugin.Port != "" {
+			if n, err := strconv.Atoi(plugin.Port); err == nil {
+				if n < 1 || n > 65535 {
+					v.add(pp.key("port"), "port %d is out of range", n)
+				}
+			} else if errs := validation.IsValidPortName(plugin.Port); len(errs) > 0 {
+				v.add(pp.key("port"), "invalid port name %q: %s", plugin.Port, strings.Join(errs, ", "))
+			}
+		}
+		v.selectors(pp.key("selectors"), plugin.Selectors)
+		v.filters(pp.key("filters"), plugin.Filters)
+		v.collection(pp.key("collection"), configuration.CollectionConfig(plugin.Collection))
+	}
+}
+
+func (v *validator) selectors(p path, cfg discovery.Selectors) {
+	switch cfg.ResourceType {
+	case "", discovery.PodType.String(), discovery.ServiceType.String(), discovery.EndpointsType.String():
+		if len(cfg.Images) == 0 && len(cfg.Labels) == 0 && len(cfg.Namespaces) == 0 {
+			v.add(p, "at least one of images, labels or namespaces is required")
+		}
+	case discovery.NodeType.String():
+	default:
+		v.add(p.key("resourceType"), "unknown resource type %q", cfg.ResourceType)
+	}
+	for i, pattern := range cfg.Images {
+		v.glob(p.key("images").index(i), pattern)
+	}
+	v.globMap(p.key("labels"), cfg.Labels)
+	for i, pattern := range cfg.Namespaces {
+		v.glob(p.key("namespaces").index(i), pattern)
+	}
+}
+
+func (v *validator) filters(p path, cfg filter.Config) {
+	for _, list := range []struct {
+		key      string
+		patterns []string
+	}{
+		{"metricAllowList", cfg.MetricAllowList},
+		{"metricDenyList", cfg.MetricDenyList},
+		{"tagInclude", cfg.TagInclude},
+		{"tagExclude", cfg.TagExclude},
+		{"metricWhitelist", cfg.MetricWhitelist},
+		{"metricBlacklist", cfg.MetricBlacklist},
+	} {
+		for i, pattern := range list.patterns {
+			v.glob(p.key(list.key).index(i), pattern)
+		}
+	}
+	v.globMap(p.key("metricTagAllowList"), cfg.MetricTagAllowList)
+	v.globMap(p.key("metricTagDenyList"), cfg.MetricTagDenyList)
+	v.globMap(p.key("metricTagWhitelist"), cfg.MetricTagWhitelist)
+	v.globMap(p.key("metricTagBlacklist"), cfg.MetricTagBlacklist)
+}
+
+func (v *validator) globMap(p path, patterns map[string][]string) {
+	keys := make([]string, 0, len(patterns))
+	for key := range patterns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for i, pattern := range patterns[key] {
+			v.glob(p.key(key).index(i), pattern)
+		}
+	}
+}
+
+func (v *validator) glob(p path, pattern string) {
+	if _, err := glob.Compile(pattern); err != nil {
+		v.add(p, "invalid glob pattern %q: %v", pattern, err)
+	}
+}
+
+func (v *validator) duration(p path, d time.Duration) {
+	if d < 0 {
+		v.add(p, "must not be negative")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package validation checks collector and discovery configuration files beyond what is needed to decode them and
+// reports every problem found with the line of the offending key.
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Error is a single validation error. Line is zero if the error does not relate to a line of the file.
+type Error struct {
+	Line    int    `json:"line,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	msg := e.Message
+	if e.Path != "" {
+		msg = e.Path + ": " + msg
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, msg)
+	}
+	return msg
+}
+
+// Collector validates the contents of a collector configuration file
+func Collector(contents []byte) []Error {
+	var cfg configuration.Config
+	v, errs := decode(contents, &cfg)
+	if len(errs) > 0 {
+		return errs
+	}
+	v.collector(&cfg)
+	return v.result()
+}
+
+// Discovery validates the contents of a discovery configuration file
+func Discovery(contents []byte) []Error {
+	var cfg discovery.Config
+	v, errs := decode(contents, &cfg)
+	if len(errs) > 0 {
+		return errs
+	}
+	v.discovery(nil, cfg)
+	return v.result()
+}
+
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// decode strictly decodes the file the same way the collector does, so unknown and duplicate keys are errors
+func decode(contents []byte, out interface{}) (*validator, []Error) {
+	if err := yaml.UnmarshalStrict(contents, out); err != nil {
+		var messages []string
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			messages = typeErr.Errors
+		} else {
+			messages = []string{err.Error()}
+		}
+		errs := make([]Error, 0, len(messages))
+		for _, message := range messages {
+			errs = append(errs, yamlError(message))
+		}
+		return nil, errs
+	}
+
+	v := &validator{}
+	var root yaml3.Node
+	if err := yaml3.Unmarshal(contents, &root); err == nil && len(root.Content) > 0 {
+		v.root = root.Content[0]
+	}
+	return v, nil
+}
+
+func yamlError(message string) Error {
+	match := yamlErrorLine.FindStringSubmatch(message)
+	if match == nil {
+		return Error{Message: strings.TrimPrefix(message, "yaml: ")}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return Error{Line: line, Message: match[2]}
+}
+
+// path locates a value in a configuration file by its keys and sequence indexes
+type path []interface{}
+
+func (p path) key(key string) path {
+	return append(p[:len(p):len(p)], key)
+}
+
+func (p path) index(i int) path {
+	return append(p[:len(p):len(p)], i)
+}
+
+func (p path) String() string {
+	var sb strings.Builder
+	for _, elem := range p {
+		switch elem := elem.(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(elem) + "]")
+		case string:
+			if sb.Len() > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(elem)
+		}
+	}
+	return sb.String()
+}
+
+type validator struct {
+	root   *yaml3.Node
+	errors []Error
+}
+
+func (v *validator) add(p path, format string, args ...interface{}) {
+	v.errors = append(v.errors, Error{
+		Line:    v.line(p),
+		Path:    p.String(),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// line returns the line of the value at the given path. If the value is not set the line of the closest parent is used.
+func (v *validator) line(p path) int {
+	node := v.root
+	if node == nil {
+		return 0
+	}
+	line := 0
+	for _, elem := range p {
+		var next *yaml3.Node
+		switch elem := elem.(type) {
+		case string:
+			if node.Kind == yaml3.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == elem {
+						line, next = node.Content[i].Line, node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml3.SequenceNode && elem < len(node.Content) {
+				next = node.Content[elem]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
+
+func (v *validator) result() []Error {
+	sort.SliceStable(v.errors, func(i, j int) bool {
+		return v.errors[i].Line < v.errors[j].Line
+	})
+	return v.errors
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validCollectorConfig = `
+clusterName: test
+flushInterval: 30s
+sinks:
+- proxyAddress: wavefront-proxy:2878
+  filters:
+    metricDenyList:
+    - 'kubernetes.sys_container.*'
+sources:
+  kubernetes_source:
+    url: https://kubernetes.default.svc
+    kubeletPort: 10250
+  telegraf_sources:
+  - plugins: [mem]
+discovery:
+  plugins:
+  - name: redis
+    type: telegraf/redis
+    selectors:
+      images:
+      - 'redis:*'
+    port: 6379
+    conf: |
+      servers = ["tcp://${host}:${port}"]
+`
+
+func TestCollector(t *testing.T) {
+	t.Run("valid configuration", func(t *testing.T) {
+		assert.Empty(t, Collector([]byte(validCollectorConfig)))
+	})
+
+	t.Run("unknown keys are reported with their line", func(t *testing.T) {
+		errs := Collector([]byte(`
+flushInterval: 30s
+sinks:
+- proxyAddress: wavefront-proxy:2878
+  filters:
+    metricDenylist: ['a*']
+`))
+		require.Len(t, errs, 1)
+		assert.Equal(t, 6, errs[0].Line)
+		assert.Contains(t, errs[0].Message, "field metricDenylist not found")
+	})
+
+	t.Run("invalid values are reported with their path and line", func(t *testing.T) {
+		errs := Collector([]byte(`
+flushInterval: 1s
+sinks:
+- type: kafka
+  filters:
+    metricAllowList:
+    - 'kubernetes.*'
+    - 'kubernetes.[a'
+sources:
+  kubernetes_source:
+    kubeletPort: http
+  telegraf_sources:
+  - plugins: [mem, nope]
+discovery:
+  plugins:
+  - name: consul
+    type: telegraf/consul
+    selectors:
+      images: ['consul*']
+    port: 8500
+    conf: |
+      address = ["${host}:${port}"]
+  - name: web
+    type: prom
+    selectors:
+      resourceType: deployment
+    port: not_a_port
+processors:
+- type: rate_calculator
+- type: nope
+- type: sum_count_aggregator
+  name: deployment
+  aggregations:
+  - resourceType: pod
+    groupBy: deployment
+    sumMetrics: [cpu.usage_rate]
+`))
+		var got []string
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+		assert.Equal(t, []string{
+			"line 2: flushInterval: must not be less than 5s",
+			"line 4: sinks[0].type: unknown sink type \"kafka\"",
+			"line 8: sinks[0].filters.metricAllowList[1]: invalid glob pattern \"kubernetes.[a\": unexpected end of input",
+			"line 11: sources.kubernetes_source.kubeletPort: invalid port \"http\"",
+			"line 13: sources.telegraf_sources[0].plugins[1]: telegraf plugin not found: nope",
+			"line 21: discovery.plugins[0].conf: line 1: (consul.Consul.Address) cannot unmarshal TOML array into string (need slice)",
+			"line 24: discovery.plugins[1].type: unknown plugin type \"prom\", expected prometheus or telegraf/<plugin>",
+			"line 26: discovery.plugins[1].selectors.resourceType: unknown resource type \"deployment\"",
+			"line 27: discovery.plugins[1].port: invalid port name \"not_a_port\": must contain only alpha-numeric characters (a-z, 0-9), and hyphens (-)",
+			"line 30: processors[1].type: unknown processor type \"nope\"",
+			"line 31: processors[2]: aggregation 0: unknown groupBy \"deployment\"",
+		}, got)
+	})
+
+	t.Run("missing sections", func(t *testing.T) {
+		var got []string
+		for _, err := range Collector([]byte("clusterName: test\n")) {
+			got = append(got, err.Error())
+		}
+		assert.Equal(t, []string{"sinks: at least one sink is required", "sources: is required"}, got)
+	})
+
+	t.Run("syntax errors", func(t *testing.T) {
+		errs := Collector([]byte("sinks:\n- proxyAddress: a\n  - b\n"))
+		require.Len(t, errs, 1)
+		assert.Equal(t, 2, errs[0].Line)
+	})
+}
+
+func TestDiscovery(t *testing.T) {
+	errs := Discovery([]byte(`
+plugins:
+- name: web
+  type: prometheus
+  selectors:
+    labels:
+      app: ['web']
+  port: 70000
+  scrapeProtocols: [json]
+- name: web
+  type: prometheus
+  selectors:
+    resourceType: node
+`))
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	assert.Equal(t, []string{
+		"line 8: plugins[0].port: port 70000 is out of range",
+		"line 9: plugins[0].scrapeProtocols: unknown scrape protocol: \"json\"",
+		"line 10: plugins[1].name: duplicate rule name \"web\"",
+	}, got)
+}
+
+func TestSchema(t *testing.T) {
+	schema := CollectorSchema()
+	assert.Equal(t, schemaVersion, schema["$schema"])
+
+	properties := schema["properties"].(map[string]interface{})
+	sink := properties["sinks"].(map[string]interface{})["items"].(map[string]interface{})
+	sinkProperties := sink["properties"].(map[string]interface{})
+	assert.Contains(t, sinkProperties, "proxyAddress", "inlined properties are included")
+	assert.Contains(t, sinkProperties, "transforms")
+	assert.NotContains(t, sinkProperties, "ClusterName", "internal properties are omitted")
+	assert.Equal(t, false, sink["additionalProperties"])
+
+	flushInterval := properties["flushInterval"].(map[string]interface{})
+	assert.Equal(t, durationPattern, flushInterval["pattern"])
+
+	discovery := DiscoverySchema()["properties"].(map[string]interface{})
+	assert.Contains(t, discovery, "plugins")
+}
@@ -24,6 +24,9 @@ type Distribution struct {
 	Source     string
 	Centroids  []Centroid
 	Timestamp  time.Time
+
+	// StartTime is the time at which a cumulative distribution was created. Zero when unknown.
+	StartTime time.Time
 }
 
 // NewCumulativeDistribution encodes prometheus style distribution.
@@ -51,7 +54,9 @@ func newDistribution(cumulative bool, name string, source string, tags map[strin
 }
 
 func (d *Distribution) Clone() *Distribution {
-	return newDistribution(d.Cumulative, d.Name(), d.Source, d.clonedTags(), d.clonedCentroids(), d.Timestamp)
+	clone := newDistribution(d.Cumulative, d.Name(), d.Source, d.clonedTags(), d.clonedCentroids(), d.Timestamp)
+	clone.StartTime = d.StartTime
+	return clone
 }
 
 func (d *Distribution) clonedTags() map[string]string {
@@ -191,6 +196,15 @@ func (d *Distribution) Name() string {
 	return d.name
 }
 
+// SetName renames the distribution
+func (d *Distribution) SetName(name string) {
+	d.name = name
+}
+
+func (d *Distribution) SetTags(tags map[string]string) {
+	d.tags = tags
+}
+
 func (d *Distribution) Tags() map[string]string {
 	return d.tags
 }
@@ -266,6 +280,10 @@ func (d *Distribution) Rate(prev *Distribution) *Distribution {
 	if prev == nil || prev.Key() != d.Key() {
 		return nil
 	}
+	if !prev.StartTime.IsZero() && !d.StartTime.Equal(prev.StartTime) {
+		// the distribution was reset since the previous sample
+		return nil
+	}
 	centroidRate := CentroidRate(d.Centroids, prev.Centroids, d.Timestamp.Sub(prev.Timestamp))
 	if centroidRate == nil {
 		return nil
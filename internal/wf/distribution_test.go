@@ -65,6 +65,18 @@ func TestDistribution(t *testing.T) {
 			assert.Nil(t, currRate)
 		})
 
+		t.Run("doesn't calculate rate when the start time changed", func(t *testing.T) {
+			prevTimeStamp := time.Now()
+			prev := wf.NewCumulativeDistribution("name1", "source1", nil, []wf.Centroid{{Value: 1, Count: 2}}, prevTimeStamp)
+			prev.StartTime = prevTimeStamp.Add(-time.Hour)
+			current := wf.NewCumulativeDistribution("name1", "source1", nil, []wf.Centroid{{Value: 1, Count: 3}}, prevTimeStamp.Add(time.Minute))
+			current.StartTime = prevTimeStamp.Add(30 * time.Second)
+			assert.Nil(t, current.Rate(prev))
+
+			current.StartTime = prev.StartTime
+			assert.NotNil(t, current.Rate(prev))
+		})
+
 		t.Run("doesn't send rate if previous is nil", func(t *testing.T) {
 			prevTimeStamp := time.Now()
 			current := wf.NewCumulativeDistribution("name1", "source1", map[string]string{"btag": "bvalue", "atag": "avalue", "ctag": "cvalue"}, []wf.Centroid{{Value: 2, Count: 70}}, prevTimeStamp.Add(time.Minute))
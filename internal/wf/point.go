@@ -12,6 +12,9 @@ type Point struct {
 	Timestamp int64
 	Source    string
 
+	// StartTime is the unix time in seconds at which a cumulative counter was created. Zero when unknown.
+	StartTime int64
+
 	tags       map[string]string
 	labelPairs []LabelPair
 }
@@ -26,6 +29,23 @@ func NewPoint(metric string, value float64, timestamp int64, source string, tags
 	}
 }
 
+// Clone returns a copy of the point that can be modified without affecting the original
+func (m *Point) Clone() *Point {
+	clone := NewPoint(m.Metric, m.Value, m.Timestamp, m.Source, nil)
+	clone.StartTime = m.StartTime
+	if m.tags != nil {
+		clone.tags = make(map[string]string, len(m.tags))
+		for k, v := range m.tags {
+			clone.tags[k] = v
+		}
+	}
+	if m.labelPairs != nil {
+		clone.labelPairs = make([]LabelPair, len(m.labelPairs))
+		copy(clone.labelPairs, m.labelPairs)
+	}
+	return clone
+}
+
 func (m *Point) Points() int {
 	return 1
 }
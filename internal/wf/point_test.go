@@ -56,3 +56,21 @@ func TestGetTagsFromTagsAndLabelPairs(t *testing.T) {
 
 	assert.Equal(t, map[string]string{"tag": "tag_value", "label_pair": "label_pair_value"}, point.Tags(), "expect tags")
 }
+
+func TestClone(t *testing.T) {
+	point := NewPoint("test", 1, 0, "test.source", map[string]string{"tag": "tag_value"})
+	name := "label_pair"
+	value := "label_pair_value"
+	point.SetLabelPairs([]LabelPair{{Name: &name, Value: &value}})
+	point.StartTime = 100
+
+	clone := point.Clone()
+	assert.Equal(t, int64(100), clone.StartTime)
+	clone.OverrideTag("tag", "other")
+	clone.FilterTags(func(string) bool { return false })
+	clone.Metric = "renamed"
+
+	assert.Equal(t, "test", point.Name())
+	assert.Equal(t, map[string]string{"tag": "tag_value", "label_pair": "label_pair_value"}, point.Tags())
+	assert.Empty(t, clone.Tags())
+}
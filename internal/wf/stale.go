@@ -0,0 +1,14 @@
+package wf
+
+import "math"
+
+// staleNaNBits is the bit pattern Prometheus uses to mark a series as stale
+const staleNaNBits uint64 = 0x7ff0000000000002
+
+// StaleNaN is the value of a staleness marker, reported for a series that disappeared from its source
+var StaleNaN = math.Float64frombits(staleNaNBits)
+
+// IsStaleMarker reports whether the value is a staleness marker
+func IsStaleMarker(value float64) bool {
+	return math.Float64bits(value) == staleNaNBits
+}
@@ -168,6 +168,13 @@ func (handler *configHandler) Config() discovery.Config {
 	return combine(handler.wiredCfg, handler.runtimeCfgs, handler.internalPluginProvider)
 }
 
+// setWiredConfig replaces the wired configuration the runtime configurations are combined with
+func (handler *configHandler) setWiredConfig(cfg discovery.Config) {
+	handler.mtx.Lock()
+	defer handler.mtx.Unlock()
+	handler.wiredCfg = cfg
+}
+
 func (handler *configHandler) updated(configResource *configResource) {
 	if !annotated(configResource.meta.Annotations) {
 		// delegate to deleted and return
@@ -22,6 +22,7 @@ import (
 	gm "github.com/rcrowley/go-metrics"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type delegate struct {
@@ -41,15 +42,11 @@ type discoverer struct {
 	endpointHandler discovery.EndpointHandler
 
 	endpointCreator endpointCreator
+	nodePlugins     []discovery.PluginConfig
 }
 
-func newDiscoverer(handler metrics.ProviderHandler, discoveryCfg discovery.Config, lister discovery.ResourceLister) discovery.Discoverer {
-	ec := endpointCreator{
-		delegates:                  makeDelegates(discoveryCfg),
-		annotationExcludes:         makeAnnotationExclusions(discoveryCfg.AnnotationExcludes),
-		providers:                  makeProviders(handler, discoveryCfg),
-		disableAnnotationDiscovery: discoveryCfg.DisableAnnotationDiscovery,
-	}
+func newDiscoverer(handler metrics.ProviderHandler, discoveryCfg discovery.Config, lister discovery.ResourceLister) *discoverer {
+	ec := newEndpointCreator(handler, discoveryCfg)
 	d := &discoverer{
 		queue:           make(chan discovery.Resource, 1000),
 		lister:          lister,
@@ -57,13 +54,41 @@ func newDiscoverer(handler metrics.ProviderHandler, discoveryCfg discovery.Confi
 		endpoints:       make(map[string][]*discovery.Endpoint, 32),
 		endpointHandler: discovery.NewEndpointHandler(makeProviders(handler, discoveryCfg)),
 		endpointCreator: ec,
+		nodePlugins:     nodePlugins(discoveryCfg.PluginConfigs),
 	}
 	d.ruleCount.Update(int64(len(d.endpointCreator.delegates)))
 	go d.dequeue()
-	go d.discoverNodeEndpoints(discoveryCfg.PluginConfigs)
+	go d.discoverNodeEndpoints(d.nodePlugins)
 	return d
 }
 
+// update replaces the discovery rules. Callers submit the known resources again afterwards so that only the
+// endpoints affected by the new rules are deleted or added. Node endpoints are rediscovered if the node rules changed.
+func (d *discoverer) update(handler metrics.ProviderHandler, discoveryCfg discovery.Config) {
+	d.mtx.Lock()
+	d.endpointCreator = newEndpointCreator(handler, discoveryCfg)
+	d.ruleCount.Update(int64(len(d.endpointCreator.delegates)))
+	plugins := nodePlugins(discoveryCfg.PluginConfigs)
+	nodesChanged := !reflect.DeepEqual(plugins, d.nodePlugins)
+	d.nodePlugins = plugins
+	d.mtx.Unlock()
+
+	if nodesChanged {
+		d.deleteNodeEndpoints()
+		go d.discoverNodes(plugins)
+	}
+}
+
+func nodePlugins(plugins []discovery.PluginConfig) []discovery.PluginConfig {
+	var result []discovery.PluginConfig
+	for _, plugin := range plugins {
+		if plugin.Selectors.ResourceType == discovery.NodeType.String() {
+			result = append(result, plugin)
+		}
+	}
+	return result
+}
+
 func makeProviders(handler metrics.ProviderHandler, discoveryCfg discovery.Config) map[string]discovery.ProviderInfo {
 	providers := make(map[string]discovery.ProviderInfo, 2)
 	providers["prometheus"] = prometheus.NewProviderInfo(handler, discoveryCfg.AnnotationPrefix)
@@ -92,7 +117,7 @@ func makeDelegate(plugin discovery.PluginConfig) (*delegate, error) {
 	}
 	if plugin.Port != "" {
 		_, err := strconv.ParseInt(plugin.Port, 10, 32)
-		if err != nil {
+		if err != nil && len(validation.IsValidPortName(plugin.Port)) > 0 {
 			return nil, err
 		}
 	}
@@ -181,12 +206,26 @@ func (d *discoverer) internalDiscover(resource discovery.Resource) {
 		return
 	}
 
+	// only touch the endpoints that changed to keep the state of the others
 	for _, ep := range oldEps {
-		d.endpointHandler.Delete(ep)
+		if !containsEndpoint(eps, ep) {
+			d.endpointHandler.Delete(ep)
+		}
 	}
 	for _, ep := range eps {
-		d.endpointHandler.Add(ep)
+		if !containsEndpoint(oldEps, ep) {
+			d.endpointHandler.Add(ep)
+		}
+	}
+}
+
+func containsEndpoint(eps []*discovery.Endpoint, ep *discovery.Endpoint) bool {
+	for _, e := range eps {
+		if reflect.DeepEqual(e, ep) {
+			return true
+		}
 	}
+	return false
 }
 
 func (d *discoverer) internalDelete(resource discovery.Resource) {
@@ -203,23 +242,38 @@ func (d *discoverer) internalDelete(resource discovery.Resource) {
 }
 
 func (d *discoverer) discoverNodeEndpoints(plugins []discovery.PluginConfig) {
+	if len(plugins) == 0 {
+		return
+	}
 	// wait for listers to index
 	time.Sleep(30 * time.Second)
+	d.discoverNodes(plugins)
+}
 
+func (d *discoverer) discoverNodes(plugins []discovery.PluginConfig) {
 	for _, plugin := range plugins {
-		if plugin.Selectors.ResourceType == discovery.NodeType.String() {
-			err := d.discoverNodeEndpoint(plugin)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"error": err,
-					"name":  plugin.Name,
-					"type":  plugin.Type,
-				}).Error("error processing rule")
-			}
+		err := d.discoverNodeEndpoint(plugin)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"name":  plugin.Name,
+				"type":  plugin.Type,
+			}).Error("error processing rule")
 		}
 	}
 }
 
+func (d *discoverer) deleteNodeEndpoints() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	name := discovery.ResourceName(discovery.NodeType.String(), metav1.ObjectMeta{Name: util.GetNodeName()})
+	for _, ep := range d.endpoints[name] {
+		d.endpointHandler.Delete(ep)
+	}
+	delete(d.endpoints, name)
+}
+
 func (d *discoverer) discoverNodeEndpoint(plugin discovery.PluginConfig) error {
 	nodes, err := d.lister.ListNodes()
 	if err != nil {
@@ -0,0 +1,88 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"testing"
+
+	gm "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type recordingEndpointHandler struct {
+	added   []string
+	deleted []string
+}
+
+func (h *recordingEndpointHandler) Add(ep *discovery.Endpoint) {
+	h.added = append(h.added, ep.Name)
+}
+
+func (h *recordingEndpointHandler) Delete(ep *discovery.Endpoint) {
+	h.deleted = append(h.deleted, ep.Name)
+}
+
+func podRule(name, path string) discovery.PluginConfig {
+	return discovery.PluginConfig{
+		Name:      name,
+		Type:      "prometheus",
+		Selectors: discovery.Selectors{Labels: map[string][]string{"app": {"web"}}},
+		Port:      "9100",
+		Path:      path,
+	}
+}
+
+func TestDiscovererUpdate(t *testing.T) {
+	handler := util.NewDummyProviderHandler(1)
+	cfg := discovery.Config{
+		DisableAnnotationDiscovery: true,
+		PluginConfigs:              []discovery.PluginConfig{podRule("a", "/metrics"), podRule("b", "/stats")},
+	}
+	endpoints := &recordingEndpointHandler{}
+	d := &discoverer{
+		ruleCount:       gm.NewGauge(),
+		endpoints:       map[string][]*discovery.Endpoint{},
+		endpointHandler: endpoints,
+		endpointCreator: newEndpointCreator(handler, cfg),
+	}
+	pod := discovery.Resource{
+		Kind: discovery.PodType.String(),
+		IP:   "10.0.0.1",
+		Meta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}},
+	}
+
+	d.internalDiscover(pod)
+	assert.Len(t, endpoints.added, 2)
+	assert.Empty(t, endpoints.deleted)
+
+	t.Run("submitting an unchanged resource again does nothing", func(t *testing.T) {
+		*endpoints = recordingEndpointHandler{}
+		d.internalDiscover(pod)
+		assert.Empty(t, endpoints.added)
+		assert.Empty(t, endpoints.deleted)
+	})
+
+	t.Run("only the endpoints of changed rules are restarted", func(t *testing.T) {
+		*endpoints = recordingEndpointHandler{}
+		cfg.PluginConfigs = []discovery.PluginConfig{podRule("a", "/metrics"), podRule("b", "/vars")}
+		d.update(handler, cfg)
+		d.internalDiscover(pod)
+		assert.Equal(t, []string{"default-pod-web:9100/stats"}, endpoints.deleted)
+		assert.Equal(t, []string{"default-pod-web:9100/vars"}, endpoints.added)
+		assert.Equal(t, int64(2), d.ruleCount.Value())
+	})
+
+	t.Run("endpoints of removed rules are deleted", func(t *testing.T) {
+		*endpoints = recordingEndpointHandler{}
+		cfg.PluginConfigs = []discovery.PluginConfig{podRule("a", "/metrics")}
+		d.update(handler, cfg)
+		d.internalDiscover(pod)
+		assert.Equal(t, []string{"default-pod-web:9100/vars"}, endpoints.deleted)
+		assert.Empty(t, endpoints.added)
+		assert.Equal(t, int64(1), d.ruleCount.Value())
+	})
+}
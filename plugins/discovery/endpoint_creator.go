@@ -4,8 +4,11 @@
 package discovery
 
 import (
+	"strconv"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
 )
 
 type endpointCreator struct {
@@ -15,6 +18,15 @@ type endpointCreator struct {
 	annotationExcludes         []*resourceFilter
 }
 
+func newEndpointCreator(handler metrics.ProviderHandler, discoveryCfg discovery.Config) endpointCreator {
+	return endpointCreator{
+		delegates:                  makeDelegates(discoveryCfg),
+		annotationExcludes:         makeAnnotationExclusions(discoveryCfg.AnnotationExcludes),
+		providers:                  makeProviders(handler, discoveryCfg),
+		disableAnnotationDiscovery: discoveryCfg.DisableAnnotationDiscovery,
+	}
+}
+
 func (e *endpointCreator) discoverEndpointsWithRules(resource discovery.Resource) []*discovery.Endpoint {
 	var eps []*discovery.Endpoint
 	for _, delegate := range e.delegates {
@@ -50,6 +62,13 @@ func (e *endpointCreator) discoverEndpoints(resource discovery.Resource) []*disc
 }
 
 func (e *endpointCreator) makeEndpoint(resource discovery.Resource, plugin discovery.PluginConfig) *discovery.Endpoint {
+	port, ok := resolvePort(resource, plugin.Port)
+	if !ok {
+		log.Debugf("port %s not found on %s", plugin.Port, discovery.ResourceName(resource.Kind, resource.Meta))
+		return nil
+	}
+	plugin.Port = port
+	plugin.Tags = mergeTags(resource.Tags, plugin.Tags)
 	if name, cfg, ok := e.Encode(resource, plugin); ok {
 		return &discovery.Endpoint{
 			Name:       name,
@@ -78,3 +97,38 @@ func (e *endpointCreator) Encode(resource discovery.Resource, rule discovery.Plu
 	}
 	return "", nil, false
 }
+
+// resolvePort returns the number of a named container or service port. Numeric and empty ports are returned as is.
+func resolvePort(resource discovery.Resource, port string) (string, bool) {
+	if _, err := strconv.Atoi(port); port == "" || err == nil {
+		return port, true
+	}
+	for _, container := range resource.Containers {
+		for _, containerPort := range container.Ports {
+			if containerPort.Name == port {
+				return strconv.Itoa(int(containerPort.ContainerPort)), true
+			}
+		}
+	}
+	for _, servicePort := range resource.Ports {
+		if servicePort.Name == port {
+			return strconv.Itoa(int(servicePort.Port)), true
+		}
+	}
+	return "", false
+}
+
+// mergeTags returns the tags of a resource overridden by the tags of a rule
+func mergeTags(resourceTags, ruleTags map[string]string) map[string]string {
+	if len(resourceTags) == 0 {
+		return ruleTags
+	}
+	tags := make(map[string]string, len(resourceTags)+len(ruleTags))
+	for k, v := range resourceTags {
+		tags[k] = v
+	}
+	for k, v := range ruleTags {
+		tags[k] = v
+	}
+	return tags
+}
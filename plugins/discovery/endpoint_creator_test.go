@@ -156,3 +156,21 @@ func makePromResource(containers []v1.Container, labels map[string]string, ns st
 	}
 	return resource
 }
+
+func Test_resolvePort(t *testing.T) {
+	service := discovery.Resource{
+		Kind:  discovery.ServiceType.String(),
+		Ports: []v1.ServicePort{{Name: "web", Port: 80}, {Name: "metrics", Port: 9090}},
+	}
+
+	port, ok := resolvePort(service, "metrics")
+	assert.True(t, ok)
+	assert.Equal(t, "9090", port)
+
+	port, ok = resolvePort(service, "8080")
+	assert.True(t, ok)
+	assert.Equal(t, "8080", port)
+
+	_, ok = resolvePort(service, "grpc")
+	assert.False(t, ok)
+}
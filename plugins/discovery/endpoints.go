@@ -0,0 +1,182 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+type endpointsHandler struct {
+	ch       chan struct{}
+	informer cache.SharedInformer
+	backends *sliceBackends
+}
+
+// sliceBackends remembers the backends discovered for each EndpointSlice to delete the ones that disappear
+type sliceBackends struct {
+	mtx        sync.Mutex
+	discoverer discovery.Discoverer
+	backends   map[string][]discovery.Resource
+}
+
+func newEndpointsHandler(kubeClient kubernetes.Interface, discoverer discovery.Discoverer) *endpointsHandler {
+	s := kubeClient.DiscoveryV1().EndpointSlices(v1.NamespaceAll)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return s.List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return s.Watch(context.Background(), options)
+		},
+	}
+	inf := cache.NewSharedInformer(lw, &discoveryv1.EndpointSlice{}, 1*time.Hour)
+
+	backends := newSliceBackends(discoverer)
+	inf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			updateEndpointSliceIfValid(obj, backends)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			updateEndpointSliceIfValid(obj, backends)
+		},
+		DeleteFunc: func(obj interface{}) {
+			deleteEndpointSliceIfValid(obj, backends)
+		},
+	})
+	return &endpointsHandler{
+		informer: inf,
+		backends: backends,
+	}
+}
+
+func newSliceBackends(discoverer discovery.Discoverer) *sliceBackends {
+	return &sliceBackends{
+		discoverer: discoverer,
+		backends:   make(map[string][]discovery.Resource),
+	}
+}
+
+func deleteEndpointSliceIfValid(obj interface{}, backends *sliceBackends) {
+	slice, ok := obj.(*discoveryv1.EndpointSlice)
+	if ok {
+		backends.update(slice.Namespace+"/"+slice.Name, nil)
+	}
+}
+
+func updateEndpointSliceIfValid(obj interface{}, backends *sliceBackends) {
+	slice, ok := obj.(*discoveryv1.EndpointSlice)
+	if ok {
+		backends.update(slice.Namespace+"/"+slice.Name, endpointResources(slice))
+	}
+}
+
+// update discovers the current backends of a slice and deletes the previous ones that are gone
+func (s *sliceBackends) update(key string, resources []discovery.Resource) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	current := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		current[discovery.ResourceName(resource.Kind, resource.Meta)] = true
+	}
+	for _, resource := range s.backends[key] {
+		if !current[discovery.ResourceName(resource.Kind, resource.Meta)] {
+			s.discoverer.Delete(resource)
+		}
+	}
+	for _, resource := range resources {
+		s.discoverer.Discover(resource)
+	}
+
+	if len(resources) == 0 {
+		delete(s.backends, key)
+	} else {
+		s.backends[key] = resources
+	}
+}
+
+// endpointResources returns a resource per ready endpoint of a slice owned by a service.
+// The resources are named after the slice, since a dual-stack service has a slice per address family.
+func endpointResources(slice *discoveryv1.EndpointSlice) []discovery.Resource {
+	service := slice.Labels[discoveryv1.LabelServiceName]
+	if service == "" {
+		return nil
+	}
+
+	ports := make([]v1.ServicePort, 0, len(slice.Ports))
+	for _, port := range slice.Ports {
+		if port.Port == nil {
+			continue
+		}
+		servicePort := v1.ServicePort{Port: *port.Port}
+		if port.Name != nil {
+			servicePort.Name = *port.Name
+		}
+		ports = append(ports, servicePort)
+	}
+
+	var resources []discovery.Resource
+	for _, endpoint := range slice.Endpoints {
+		if len(endpoint.Addresses) == 0 || (endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready) {
+			continue
+		}
+		address := endpoint.Addresses[0]
+		tags := map[string]string{"service": service}
+		backend := strings.NewReplacer(".", "-", ":", "-").Replace(address)
+		if ref := endpoint.TargetRef; ref != nil && ref.Kind == "Pod" {
+			tags["pod"] = ref.Name
+			backend = ref.Name
+		}
+		if endpoint.NodeName != nil {
+			tags["node"] = *endpoint.NodeName
+		}
+		resources = append(resources, discovery.Resource{
+			Kind: discovery.EndpointsType.String(),
+			IP:   address,
+			Meta: metav1.ObjectMeta{
+				Name:      slice.Name + "-" + backend,
+				Namespace: slice.Namespace,
+				Labels:    slice.Labels,
+			},
+			Ports: ports,
+			Tags:  tags,
+		})
+	}
+	return resources
+}
+
+func (handler *endpointsHandler) start() {
+	handler.ch = make(chan struct{})
+	go handler.informer.Run(handler.ch)
+}
+
+func (handler *endpointsHandler) stop() {
+	if handler.ch != nil {
+		close(handler.ch)
+		handler.ch = nil
+	}
+}
+
+// resync submits the backends of the cached slices to the discoverer again when the handler is running
+func (handler *endpointsHandler) resync() {
+	if handler.ch == nil {
+		return
+	}
+	for _, obj := range handler.informer.GetStore().List() {
+		updateEndpointSliceIfValid(obj, handler.backends)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type recordingDiscoverer struct {
+	DummyDiscoverer
+	discovered []string
+	deleted    []string
+}
+
+func (r *recordingDiscoverer) Discover(resource discovery.Resource) {
+	r.discovered = append(r.discovered, resource.Meta.Name)
+}
+
+func (r *recordingDiscoverer) Delete(resource discovery.Resource) {
+	r.deleted = append(r.deleted, resource.Meta.Name)
+}
+
+func makeEndpoint(address, pod, node string, ready bool) discoveryv1.Endpoint {
+	endpoint := discoveryv1.Endpoint{
+		Addresses:  []string{address},
+		Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+		NodeName:   &node,
+	}
+	if pod != "" {
+		endpoint.TargetRef = &v1.ObjectReference{Kind: "Pod", Name: pod}
+	}
+	return endpoint
+}
+
+func makeSlice(endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+	name, port := "metrics", int32(9100)
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "checkout-abcde",
+			Namespace: "shop",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "checkout", "app": "checkout"},
+		},
+		Ports:     []discoveryv1.EndpointPort{{Name: &name, Port: &port}},
+		Endpoints: endpoints,
+	}
+}
+
+func TestEndpointResources(t *testing.T) {
+	resources := endpointResources(makeSlice(
+		makeEndpoint("10.0.0.1", "checkout-1", "node-a", true),
+		makeEndpoint("10.0.0.2", "checkout-2", "node-b", false),
+		makeEndpoint("10.0.0.3", "", "node-b", true),
+	))
+
+	require.Len(t, resources, 2)
+	assert.Equal(t, "endpoints", resources[0].Kind)
+	assert.Equal(t, "10.0.0.1", resources[0].IP)
+	assert.Equal(t, "checkout-abcde-checkout-1", resources[0].Meta.Name)
+	assert.Equal(t, "shop", resources[0].Meta.Namespace)
+	assert.Equal(t, map[string]string{"service": "checkout", "pod": "checkout-1", "node": "node-a"}, resources[0].Tags)
+	assert.Equal(t, []v1.ServicePort{{Name: "metrics", Port: 9100}}, resources[0].Ports)
+	assert.Equal(t, "checkout-abcde-10-0-0-3", resources[1].Meta.Name)
+	assert.Equal(t, map[string]string{"service": "checkout", "node": "node-b"}, resources[1].Tags)
+
+	unowned := makeSlice(makeEndpoint("10.0.0.1", "checkout-1", "node-a", true))
+	delete(unowned.Labels, discoveryv1.LabelServiceName)
+	assert.Empty(t, endpointResources(unowned))
+}
+
+func TestSliceBackends(t *testing.T) {
+	discoverer := &recordingDiscoverer{}
+	backends := newSliceBackends(discoverer)
+
+	updateEndpointSliceIfValid(makeSlice(
+		makeEndpoint("10.0.0.1", "checkout-1", "node-a", true),
+		makeEndpoint("10.0.0.2", "checkout-2", "node-b", true),
+	), backends)
+	assert.Equal(t, []string{"checkout-abcde-checkout-1", "checkout-abcde-checkout-2"}, discoverer.discovered)
+	assert.Empty(t, discoverer.deleted)
+
+	discoverer.discovered = nil
+	updateEndpointSliceIfValid(makeSlice(
+		makeEndpoint("10.0.0.1", "checkout-1", "node-a", true),
+		makeEndpoint("10.0.0.2", "checkout-2", "node-b", false),
+	), backends)
+	assert.Equal(t, []string{"checkout-abcde-checkout-1"}, discoverer.discovered)
+	assert.Equal(t, []string{"checkout-abcde-checkout-2"}, discoverer.deleted)
+
+	discoverer.deleted = nil
+	deleteEndpointSliceIfValid(makeSlice(), backends)
+	assert.Equal(t, []string{"checkout-abcde-checkout-1"}, discoverer.deleted)
+	assert.Empty(t, backends.backends)
+}
+
+func TestSliceBackendsDualStack(t *testing.T) {
+	discoverer := &recordingDiscoverer{}
+	backends := newSliceBackends(discoverer)
+
+	ipv4 := makeSlice(makeEndpoint("10.0.0.1", "checkout-1", "node-a", true))
+	ipv6 := makeSlice(makeEndpoint("fd00::1", "checkout-1", "node-a", true))
+	ipv6.Name = "checkout-fghij"
+	updateEndpointSliceIfValid(ipv4, backends)
+	updateEndpointSliceIfValid(ipv6, backends)
+	assert.Equal(t, []string{"checkout-abcde-checkout-1", "checkout-fghij-checkout-1"}, discoverer.discovered)
+
+	deleteEndpointSliceIfValid(ipv6, backends)
+	assert.Equal(t, []string{"checkout-fghij-checkout-1"}, discoverer.deleted)
+	assert.Len(t, backends.backends, 1)
+}
+
+func TestEndpointsRule(t *testing.T) {
+	e := &endpointCreator{
+		delegates: makeDelegates(discovery.Config{PluginConfigs: []discovery.PluginConfig{{
+			Name:      "checkout",
+			Type:      "prometheus",
+			Selectors: discovery.Selectors{ResourceType: "endpoints", Labels: map[string][]string{"app": {"checkout"}}},
+			Port:      "metrics",
+			Tags:      map[string]string{"team": "shop"},
+		}}}),
+		providers: makeDummyProviders(util.NewDummyProviderHandler(1)),
+	}
+	resources := endpointResources(makeSlice(makeEndpoint("10.0.0.1", "checkout-1", "node-a", true)))
+	require.Len(t, resources, 1)
+
+	eps := e.discoverEndpoints(resources[0])
+	require.Len(t, eps, 1)
+	cfg := eps[0].Config.(configuration.PrometheusSourceConfig)
+	assert.Equal(t, "http://10.0.0.1:9100/metrics", cfg.URL)
+	assert.True(t, cfg.UseLeaderElection)
+	assert.Equal(t, "checkout", cfg.Tags["service"])
+	assert.Equal(t, "checkout-1", cfg.Tags["pod"])
+	assert.Equal(t, "node-a", cfg.Tags["node"])
+	assert.Equal(t, "shop", cfg.Tags["team"])
+	assert.NotContains(t, cfg.Tags, "endpoints")
+}
@@ -0,0 +1,373 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/httputil"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"gopkg.in/yaml.v2"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+const annotationsRule = "annotations"
+
+// Report explains how the discovery configuration applies to the resources of a cluster
+type Report struct {
+	Resources []Explanation `json:"resources"`
+	Errors    []string      `json:"errors,omitempty"`
+}
+
+// Explanation lists the rules evaluated for a single resource
+type Explanation struct {
+	Kind      string            `json:"kind"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name"`
+	Reason    string            `json:"reason,omitempty"`
+	Rules     []RuleExplanation `json:"rules,omitempty"`
+}
+
+// RuleExplanation describes why a rule matched or rejected a resource and the resulting source configuration
+type RuleExplanation struct {
+	Rule    string                 `json:"rule"`
+	Matched bool                   `json:"matched"`
+	Reason  string                 `json:"reason,omitempty"`
+	Config  map[string]interface{} `json:"config,omitempty"`
+}
+
+// Explain evaluates the discovery configuration against the pods, services and nodes returned by the lister and the
+// backends of the EndpointSlices listed with the client without scraping anything. Endpoints are skipped without a client.
+func Explain(cfg discovery.Config, lister discovery.ResourceLister, kubeClient kubernetes.Interface) Report {
+	report := Report{}
+	for _, plugin := range cfg.PluginConfigs {
+		if _, err := makeDelegate(plugin); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("invalid rule %s: %v", plugin.Name, err))
+		}
+	}
+	e := newEndpointCreator(nil, cfg)
+
+	pods, err := lister.ListPods("", nil)
+	if err != nil {
+		report.Errors = append(report.Errors, listError("pods", err))
+	}
+	for _, pod := range pods {
+		resource := discovery.Resource{
+			Kind:       discovery.PodType.String(),
+			IP:         pod.Status.PodIP,
+			Meta:       pod.ObjectMeta,
+			Containers: pod.Spec.Containers,
+		}
+		if !podReady(pod) {
+			report.Resources = append(report.Resources, skipped(resource, "pod is not running or has no IP"))
+			continue
+		}
+		report.Resources = append(report.Resources, e.explain(resource))
+	}
+
+	services, err := lister.ListServices("", nil)
+	if err != nil {
+		report.Errors = append(report.Errors, listError("services", err))
+	}
+	for _, service := range services {
+		resource := discovery.Resource{
+			Kind:  discovery.ServiceType.String(),
+			IP:    service.Spec.ClusterIP,
+			Meta:  service.ObjectMeta,
+			Ports: service.Spec.Ports,
+		}
+		if !hasIP(service.Spec.ClusterIP) {
+			report.Resources = append(report.Resources, skipped(resource, "service has no cluster IP"))
+			continue
+		}
+		report.Resources = append(report.Resources, e.explain(resource))
+	}
+
+	if kubeClient != nil {
+		slices, err := kubeClient.DiscoveryV1().EndpointSlices(v1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			report.Errors = append(report.Errors, listError("endpointslices", err))
+		} else {
+			report.Resources = append(report.Resources, e.explainEndpointSlices(slices.Items)...)
+		}
+	}
+
+	nodes, err := lister.ListNodes()
+	if err != nil {
+		report.Errors = append(report.Errors, listError("nodes", err))
+	}
+	for _, node := range nodes {
+		resource := discovery.Resource{
+			Kind: discovery.NodeType.String(),
+			Meta: metav1.ObjectMeta{Name: node.Name, Labels: node.Labels},
+		}
+		_, ip, err := util.GetNodeHostnameAndIP(node)
+		if err != nil {
+			report.Resources = append(report.Resources, skipped(resource, fmt.Sprintf("error getting node IP: %v", err)))
+			continue
+		}
+		resource.IP = ip.String()
+		report.Resources = append(report.Resources, e.explain(resource))
+	}
+
+	sort.SliceStable(report.Resources, func(i, j int) bool {
+		a, b := report.Resources[i], report.Resources[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return report
+}
+
+// explainEndpointSlices evaluates the ready backends of the slices the same way the endpoints handler discovers them
+func (e *endpointCreator) explainEndpointSlices(slices []discoveryv1.EndpointSlice) []Explanation {
+	var explanations []Explanation
+	for i := range slices {
+		slice := &slices[i]
+		resources := endpointResources(slice)
+		if len(resources) == 0 {
+			resource := discovery.Resource{Kind: discovery.EndpointsType.String(), Meta: slice.ObjectMeta}
+			reason := "endpoint slice has no ready endpoints"
+			if slice.Labels[discoveryv1.LabelServiceName] == "" {
+				reason = "endpoint slice is not owned by a service"
+			}
+			explanations = append(explanations, skipped(resource, reason))
+			continue
+		}
+		for _, resource := range resources {
+			explanations = append(explanations, e.explain(resource))
+		}
+	}
+	return explanations
+}
+
+func listError(kind string, err error) string {
+	if apierrors.IsForbidden(err) {
+		return fmt.Sprintf("error listing %s: %v (check the RBAC permissions of the collector)", kind, err)
+	}
+	return fmt.Sprintf("error listing %s: %v", kind, err)
+}
+
+func skipped(resource discovery.Resource, reason string) Explanation {
+	return Explanation{
+		Kind:      resource.Kind,
+		Namespace: resource.Meta.Namespace,
+		Name:      resource.Meta.Name,
+		Reason:    reason,
+	}
+}
+
+// explain evaluates every rule and the annotation based discovery against a resource in the same order as discoverEndpoints
+func (e *endpointCreator) explain(resource discovery.Resource) Explanation {
+	explanation := skipped(resource, "")
+
+	names := make([]string, 0, len(e.delegates))
+	for name := range e.delegates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	matched := false
+	for _, name := range names {
+		delegate := e.delegates[name]
+		result := RuleExplanation{Rule: name}
+		if reason := delegate.filter.mismatch(resource); reason != "" {
+			result.Reason = reason
+		} else {
+			result = e.explainEndpoint(resource, delegate.plugin, result, "invalid rule configuration, check the collector logs")
+			matched = matched || result.Matched
+		}
+		explanation.Rules = append(explanation.Rules, result)
+	}
+
+	if result, ok := e.explainAnnotations(resource, matched); ok {
+		explanation.Rules = append(explanation.Rules, result)
+	}
+	return explanation
+}
+
+func (e *endpointCreator) explainAnnotations(resource discovery.Resource, matched bool) (RuleExplanation, bool) {
+	result := RuleExplanation{Rule: annotationsRule}
+	switch {
+	case resource.Kind == discovery.NodeType.String():
+		return result, false
+	case e.disableAnnotationDiscovery:
+		result.Reason = "annotation discovery is disabled"
+	case matched:
+		result.Reason = "skipped since a rule matched"
+	default:
+		for _, exclude := range e.annotationExcludes {
+			if exclude.matches(resource) {
+				result.Reason = "excluded by annotation_excludes"
+				return result, true
+			}
+		}
+		result = e.explainEndpoint(resource, discovery.PluginConfig{Type: "prometheus"}, result, "not annotated for scraping or the annotations are invalid")
+	}
+	return result, true
+}
+
+func (e *endpointCreator) explainEndpoint(resource discovery.Resource, plugin discovery.PluginConfig, result RuleExplanation, invalid string) RuleExplanation {
+	if _, ok := resolvePort(resource, plugin.Port); !ok {
+		result.Reason = fmt.Sprintf("port %s not found", plugin.Port)
+		return result
+	}
+	ep := e.makeEndpoint(resource, plugin)
+	if ep == nil {
+		result.Reason = invalid
+		if plugin.Conf != "" && pluginType(plugin) == "prometheus" {
+			if _, err := httputil.FromYAML([]byte(plugin.Conf)); err != nil {
+				result.Reason = fmt.Sprintf("invalid authentication configuration: %v", err)
+			}
+		}
+		return result
+	}
+	if cfg, ok := ep.Config.(configuration.PrometheusSourceConfig); ok {
+		if err := resolveAuth(cfg.HTTPClientConfig); err != nil {
+			result.Reason = fmt.Sprintf("error resolving authentication: %v", err)
+			return result
+		}
+	}
+	result.Matched = true
+	config, err := configMap(redact(ep.Config))
+	if err != nil {
+		result.Reason = fmt.Sprintf("error encoding configuration: %v", err)
+	}
+	result.Config = config
+	return result
+}
+
+// resolveAuth loads the TLS certificates and the bearer token file of a client configuration like a scrape would
+func resolveAuth(cfg httputil.ClientConfig) error {
+	if _, err := httputil.NewClient(cfg); err != nil {
+		return err
+	}
+	if cfg.BearerToken == "" && cfg.BearerTokenFile != "" {
+		if _, err := ioutil.ReadFile(cfg.BearerTokenFile); err != nil {
+			return fmt.Errorf("unable to read bearer token file %s: %v", cfg.BearerTokenFile, err)
+		}
+	}
+	return nil
+}
+
+// redact hides the credentials of an encoded source configuration
+func redact(cfg interface{}) interface{} {
+	switch cfg := cfg.(type) {
+	case configuration.PrometheusSourceConfig:
+		if cfg.HTTPClientConfig.BearerToken != "" {
+			cfg.HTTPClientConfig.BearerToken = "<redacted>"
+		}
+		return cfg
+	case configuration.TelegrafSourceConfig:
+		cfg.Conf = configuration.Redact(cfg.Conf)
+		return cfg
+	}
+	return cfg
+}
+
+// configMap converts a source configuration to a map keyed by its yaml field names
+func configMap(cfg interface{}) (map[string]interface{}, error) {
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{}
+	return result, k8syaml.Unmarshal(out, &result)
+}
+
+// Select returns the explanations of the resources matching the given kind, namespace and name. Empty values match everything.
+func (r Report) Select(kind, namespace, name string) Report {
+	result := Report{Errors: r.Errors}
+	for _, explanation := range r.Resources {
+		if (kind == "" || explanation.Kind == kind) &&
+			(namespace == "" || explanation.Namespace == namespace) &&
+			(name == "" || explanation.Name == name) {
+			result.Resources = append(result.Resources, explanation)
+		}
+	}
+	return result
+}
+
+// Matched returns the explanations of the resources that would be scraped, keeping only the matching rules
+func (r Report) Matched() Report {
+	result := Report{Errors: r.Errors}
+	for _, explanation := range r.Resources {
+		var rules []RuleExplanation
+		for _, rule := range explanation.Rules {
+			if rule.Matched {
+				rules = append(rules, rule)
+			}
+		}
+		if len(rules) > 0 {
+			explanation.Rules = rules
+			result.Resources = append(result.Resources, explanation)
+		}
+	}
+	return result
+}
+
+// WriteText writes a human readable form of the report
+func (r Report) WriteText(w io.Writer) error {
+	for _, err := range r.Errors {
+		if _, err := fmt.Fprintf(w, "error: %s\n", err); err != nil {
+			return err
+		}
+	}
+	for _, explanation := range r.Resources {
+		name := explanation.Name
+		if explanation.Namespace != "" {
+			name = explanation.Namespace + "/" + name
+		}
+		line := fmt.Sprintf("%s %s", explanation.Kind, name)
+		if explanation.Reason != "" {
+			line += ": " + explanation.Reason
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, rule := range explanation.Rules {
+			status := "rejected"
+			if rule.Matched {
+				status = "matched"
+			}
+			line = fmt.Sprintf("  %s %s", status, rule.Rule)
+			if rule.Reason != "" {
+				line += ": " + rule.Reason
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+			if rule.Config == nil {
+				continue
+			}
+			out, err := k8syaml.Marshal(rule.Config)
+			if err != nil {
+				return err
+			}
+			indent := "    "
+			config := indent + strings.ReplaceAll(strings.TrimRight(string(out), "\n"), "\n", "\n"+indent)
+			if _, err := fmt.Fprintln(w, config); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
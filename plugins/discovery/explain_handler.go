@@ -0,0 +1,40 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ExplainHandler serves the discovery report of the current manager. The report can be narrowed down with the kind,
+// namespace and name query parameters, limited to the scraped resources with matched=true and rendered with format=text.
+func ExplainHandler(manager func() *Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		dm := manager()
+		if dm == nil {
+			http.Error(w, "discovery is not enabled", http.StatusNotFound)
+			return
+		}
+
+		query := req.URL.Query()
+		report := dm.Explain().Select(query.Get("kind"), query.Get("namespace"), query.Get("name"))
+		if query.Get("matched") == "true" {
+			report = report.Matched()
+		}
+
+		switch query.Get("format") {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(report)
+		case "text":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_ = report.WriteText(w)
+		default:
+			http.Error(w, "unsupported format: "+query.Get("format"), http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,219 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type stubResourceLister struct {
+	pods     []*v1.Pod
+	services []*v1.Service
+	err      error
+}
+
+func (s *stubResourceLister) ListPods(ns string, labels map[string]string) ([]*v1.Pod, error) {
+	return s.pods, nil
+}
+
+func (s *stubResourceLister) ListServices(ns string, labels map[string]string) ([]*v1.Service, error) {
+	return s.services, nil
+}
+
+func (s *stubResourceLister) ListNodes() ([]*v1.Node, error) {
+	return nil, s.err
+}
+
+func makeExplainPod(name string, labels, annotations map[string]string, image string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: labels, Annotations: annotations},
+		Spec:       v1.PodSpec{Containers: []v1.Container{makeContainer(image, []int32{9100})}},
+		Status:     v1.PodStatus{Phase: v1.PodRunning, PodIP: "10.0.0.1"},
+	}
+}
+
+func explainConfig() discovery.Config {
+	return discovery.Config{
+		AnnotationPrefix: "prometheus.io",
+		PluginConfigs: []discovery.PluginConfig{
+			{
+				Name:      "redis",
+				Type:      "prometheus",
+				Selectors: discovery.Selectors{Images: []string{"redis:*"}},
+				Port:      "9100",
+				Conf:      "bearer_token: secret\n",
+			},
+			{
+				Name:      "checkout",
+				Type:      "prometheus",
+				Selectors: discovery.Selectors{Labels: map[string][]string{"app": {"checkout"}}},
+				Port:      "metrics",
+			},
+			{
+				Name:      "broken",
+				Type:      "unknown",
+				Selectors: discovery.Selectors{Images: []string{"*"}},
+			},
+		},
+		AnnotationExcludes: []discovery.Selectors{{Namespaces: []string{"kube-system"}}},
+	}
+}
+
+func TestExplain(t *testing.T) {
+	pending := makeExplainPod("pending", nil, nil, "busybox")
+	pending.Status.Phase = v1.PodPending
+	lister := &stubResourceLister{
+		pods: []*v1.Pod{
+			makeExplainPod("redis", map[string]string{"app": "cache"}, nil, "redis:6"),
+			makeExplainPod("checkout", map[string]string{"app": "checkout"}, nil, "shop/checkout"),
+			makeExplainPod("annotated", nil, map[string]string{"prometheus.io/scrape": "true"}, "shop/web"),
+			pending,
+		},
+		services: []*v1.Service{{ObjectMeta: metav1.ObjectMeta{Name: "headless", Namespace: "shop"}, Spec: v1.ServiceSpec{ClusterIP: "None"}}},
+		err:      apierrors.NewForbidden(schema.GroupResource{Resource: "nodes"}, "", errors.New("denied")),
+	}
+
+	report := Explain(explainConfig(), lister, nil)
+	require.Len(t, report.Errors, 2)
+	assert.Contains(t, report.Errors[0], "invalid rule broken")
+	assert.Contains(t, report.Errors[1], "RBAC")
+
+	require.Len(t, report.Resources, 5)
+	annotated := report.Select("pod", "shop", "annotated").Resources[0]
+	require.Len(t, annotated.Rules, 3)
+	assert.Equal(t, RuleExplanation{Rule: "checkout", Reason: "no labels"}, annotated.Rules[0])
+	assert.Equal(t, RuleExplanation{Rule: "redis", Reason: "no container image matches"}, annotated.Rules[1])
+	assert.Equal(t, annotationsRule, annotated.Rules[2].Rule)
+	assert.True(t, annotated.Rules[2].Matched)
+	assert.Equal(t, "http://10.0.0.1/metrics", annotated.Rules[2].Config["url"])
+
+	checkout := report.Select("pod", "shop", "checkout").Resources[0]
+	assert.Equal(t, "port metrics not found", checkout.Rules[0].Reason)
+	assert.Equal(t, "not annotated for scraping or the annotations are invalid", checkout.Rules[2].Reason)
+
+	redis := report.Select("pod", "shop", "redis").Resources[0]
+	assert.Equal(t, "label app=cache does not match", redis.Rules[0].Reason)
+	assert.True(t, redis.Rules[1].Matched)
+	assert.Equal(t, "<redacted>", redis.Rules[1].Config["httpConfig"].(map[string]interface{})["bearer_token"])
+	assert.Equal(t, "skipped since a rule matched", redis.Rules[2].Reason)
+
+	assert.Equal(t, "pod is not running or has no IP", report.Select("pod", "", "pending").Resources[0].Reason)
+	assert.Equal(t, "service has no cluster IP", report.Select("service", "", "").Resources[0].Reason)
+
+	matched := report.Matched()
+	require.Len(t, matched.Resources, 2)
+	assert.Equal(t, "annotated", matched.Resources[0].Name)
+	assert.Equal(t, "redis", matched.Resources[1].Name)
+	assert.Len(t, matched.Resources[1].Rules, 1)
+
+	var buf bytes.Buffer
+	require.NoError(t, matched.WriteText(&buf))
+	assert.Contains(t, buf.String(), "pod shop/redis\n  matched redis\n")
+	assert.Contains(t, buf.String(), "    url: http://10.0.0.1:9100/metrics\n")
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestExplainAnnotationExcludes(t *testing.T) {
+	pod := makeExplainPod("dns", nil, map[string]string{"prometheus.io/scrape": "true"}, "coredns")
+	pod.Namespace = "kube-system"
+
+	report := Explain(explainConfig(), &stubResourceLister{pods: []*v1.Pod{pod}}, nil)
+	rules := report.Resources[0].Rules
+	assert.Equal(t, "excluded by annotation_excludes", rules[len(rules)-1].Reason)
+
+	cfg := explainConfig()
+	cfg.DisableAnnotationDiscovery = true
+	report = Explain(cfg, &stubResourceLister{pods: []*v1.Pod{pod}}, nil)
+	rules = report.Resources[0].Rules
+	assert.Equal(t, "annotation discovery is disabled", rules[len(rules)-1].Reason)
+}
+
+func TestExplainEndpoints(t *testing.T) {
+	cfg := discovery.Config{PluginConfigs: []discovery.PluginConfig{
+		{
+			Name:      "checkout",
+			Type:      "prometheus",
+			Selectors: discovery.Selectors{ResourceType: "endpoints", Labels: map[string][]string{"app": {"checkout"}}},
+			Port:      "metrics",
+		},
+		{
+			Name:      "checkout-auth",
+			Type:      "prometheus",
+			Selectors: discovery.Selectors{ResourceType: "endpoints", Labels: map[string][]string{"app": {"checkout"}}},
+			Port:      "metrics",
+			Conf:      "bearer_token_file: /nonexistent/token\n",
+		},
+	}}
+	ready := makeSlice(makeEndpoint("10.0.0.1", "checkout-1", "node-a", true))
+	notReady := makeSlice(makeEndpoint("10.0.0.2", "checkout-2", "node-b", false))
+	notReady.Name = "checkout-fghij"
+	unowned := makeSlice(makeEndpoint("10.0.0.3", "", "node-b", true))
+	unowned.Name = "manual"
+	delete(unowned.Labels, discoveryv1.LabelServiceName)
+	kubeClient := fake.NewSimpleClientset(ready, notReady, unowned)
+
+	report := Explain(cfg, &stubResourceLister{}, kubeClient)
+	assert.Empty(t, report.Errors)
+	require.Len(t, report.Resources, 3)
+
+	checkout := report.Select("endpoints", "shop", "checkout-abcde-checkout-1").Resources[0]
+	require.Len(t, checkout.Rules, 3)
+	assert.True(t, checkout.Rules[0].Matched)
+	assert.Equal(t, "http://10.0.0.1:9100/metrics", checkout.Rules[0].Config["url"])
+	assert.False(t, checkout.Rules[1].Matched)
+	assert.Contains(t, checkout.Rules[1].Reason, "error resolving authentication: unable to read bearer token file /nonexistent/token")
+
+	assert.Equal(t, "endpoint slice has no ready endpoints", report.Select("endpoints", "", "checkout-fghij").Resources[0].Reason)
+	assert.Equal(t, "endpoint slice is not owned by a service", report.Select("endpoints", "", "manual").Resources[0].Reason)
+
+	assert.Empty(t, Explain(cfg, &stubResourceLister{}, nil).Resources)
+}
+
+func TestExplainHandler(t *testing.T) {
+	dm := NewDiscoveryManager(RunConfig{
+		DiscoveryConfig: explainConfig(),
+		Lister: &stubResourceLister{pods: []*v1.Pod{
+			makeExplainPod("redis", nil, nil, "redis:6"),
+			makeExplainPod("web", nil, nil, "shop/web"),
+		}},
+	})
+	handler := ExplainHandler(func() *Manager { return dm })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/discovery?matched=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	report := Report{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Len(t, report.Resources, 1)
+	assert.Equal(t, "redis", report.Resources[0].Name)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/discovery?name=web&format=text", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "pod shop/web\n")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/discovery?format=xml", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	ExplainHandler(func() *Manager { return nil }).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/discovery", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -43,7 +43,7 @@ func resourceType(kind string) (string, error) {
 		return discovery.PodType.String(), nil
 	}
 	switch kind {
-	case discovery.PodType.String(), discovery.ServiceType.String(), discovery.NodeType.String():
+	case discovery.PodType.String(), discovery.ServiceType.String(), discovery.NodeType.String(), discovery.EndpointsType.String():
 		return kind, nil
 	default:
 		return "", fmt.Errorf("invalid resource type: %s", kind)
@@ -51,38 +51,52 @@ func resourceType(kind string) (string, error) {
 }
 
 func (r *resourceFilter) matches(resource discovery.Resource) bool {
+	return r.mismatch(resource) == ""
+}
+
+// mismatch returns why the resource does not match the filter or an empty string if it matches
+func (r *resourceFilter) mismatch(resource discovery.Resource) string {
 	if r == nil {
-		return false
+		return "no selectors"
 	}
 	if r.kind != resource.Kind {
-		return false
+		return fmt.Sprintf("resource type %s does not match %s", resource.Kind, r.kind)
 	}
-	if r.labels != nil && !matchesTags(r.labels, resource.Meta.Labels) {
-		return false
+	if r.labels != nil {
+		if reason := tagsMismatch(r.labels, resource.Meta.Labels); reason != "" {
+			return reason
+		}
 	}
 	if r.namespaces != nil && !r.namespaces.Match(resource.Meta.Namespace) {
-		return false
+		return fmt.Sprintf("namespace %s does not match", resource.Meta.Namespace)
 	}
 	if r.images != nil {
 		for _, container := range resource.Containers {
 			if r.images.Match(container.Image) {
-				return true
+				return ""
 			}
 		}
-		return false
+		return "no container image matches"
 	}
-	return true
+	return ""
 }
 
 func matchesTags(matchers map[string]glob.Glob, tags map[string]string) bool {
+	return tagsMismatch(matchers, tags) == ""
+}
+
+func tagsMismatch(matchers map[string]glob.Glob, tags map[string]string) string {
 	if tags == nil || len(tags) == 0 {
-		return false
+		return "no labels"
 	}
 	for k, matcher := range matchers {
 		val, ok := tags[k]
-		if !ok || !matcher.Match(val) {
-			return false
+		if !ok {
+			return fmt.Sprintf("label %s is missing", k)
+		}
+		if !matcher.Match(val) {
+			return fmt.Sprintf("label %s=%s does not match", k, val)
 		}
 	}
-	return true
+	return ""
 }
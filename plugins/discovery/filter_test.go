@@ -184,3 +184,33 @@ func makeContainer(image string, ports []int32) v1.Container {
 	}
 	return c
 }
+
+func TestMismatch(t *testing.T) {
+	rf, err := newResourceFilter(discovery.Selectors{
+		Images:     []string{"redis:*"},
+		Labels:     map[string][]string{"app": {"cache"}},
+		Namespaces: []string{"shop"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redis := makeContainer("redis:6", nil)
+	web := makeContainer("web:1", nil)
+
+	for expected, resource := range map[string]discovery.Resource{
+		"":                                 makeResource([]v1.Container{redis}, map[string]string{"app": "cache"}, "shop"),
+		"no labels":                        makeResource([]v1.Container{redis}, nil, "shop"),
+		"label app is missing":             makeResource([]v1.Container{redis}, map[string]string{"tier": "db"}, "shop"),
+		"label app=web does not match":     makeResource([]v1.Container{redis}, map[string]string{"app": "web"}, "shop"),
+		"namespace default does not match": makeResource([]v1.Container{redis}, map[string]string{"app": "cache"}, "default"),
+		"no container image matches":       makeResource([]v1.Container{web}, map[string]string{"app": "cache"}, "shop"),
+		"resource type service does not match pod": {Kind: discovery.ServiceType.String()},
+	} {
+		if reason := rf.mismatch(resource); reason != expected {
+			t.Errorf("expected %q but got %q", expected, reason)
+		}
+	}
+	if reason := (*resourceFilter)(nil).mismatch(discovery.Resource{}); reason != "no selectors" {
+		t.Errorf("unexpected reason for nil filter: %q", reason)
+	}
+}
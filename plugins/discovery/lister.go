@@ -4,10 +4,14 @@
 package discovery
 
 import (
+	"context"
+
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
 
 	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
 	v1listers "k8s.io/client-go/listers/core/v1"
 )
 
@@ -44,3 +48,52 @@ func (rl *resourceLister) ListServices(ns string, l map[string]string) ([]*apiv1
 func (rl *resourceLister) ListNodes() ([]*apiv1.Node, error) {
 	return rl.nodeLister.List(labels.Everything())
 }
+
+type clientResourceLister struct {
+	kubeClient kubernetes.Interface
+}
+
+// NewClientResourceLister returns a lister that queries the API server directly instead of a local cache
+func NewClientResourceLister(kubeClient kubernetes.Interface) discovery.ResourceLister {
+	return &clientResourceLister{kubeClient: kubeClient}
+}
+
+func (rl *clientResourceLister) ListPods(ns string, l map[string]string) ([]*apiv1.Pod, error) {
+	list, err := rl.kubeClient.CoreV1().Pods(ns).List(context.Background(), listOptions(l))
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*apiv1.Pod, len(list.Items))
+	for i := range list.Items {
+		pods[i] = &list.Items[i]
+	}
+	return pods, nil
+}
+
+func (rl *clientResourceLister) ListServices(ns string, l map[string]string) ([]*apiv1.Service, error) {
+	list, err := rl.kubeClient.CoreV1().Services(ns).List(context.Background(), listOptions(l))
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*apiv1.Service, len(list.Items))
+	for i := range list.Items {
+		services[i] = &list.Items[i]
+	}
+	return services, nil
+}
+
+func (rl *clientResourceLister) ListNodes() ([]*apiv1.Node, error) {
+	list, err := rl.kubeClient.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*apiv1.Node, len(list.Items))
+	for i := range list.Items {
+		nodes[i] = &list.Items[i]
+	}
+	return nodes, nil
+}
+
+func listOptions(l map[string]string) metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: labels.SelectorFromSet(l).String()}
+}
@@ -5,6 +5,7 @@ package discovery
 
 import (
 	"reflect"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -15,6 +16,7 @@ import (
 
 	gm "github.com/rcrowley/go-metrics"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -33,6 +35,7 @@ func init() {
 // RunConfig encapsulates the runtime configuration required for a discovery manager
 type RunConfig struct {
 	KubeClient             kubernetes.Interface
+	DynamicClient          dynamic.Interface
 	DiscoveryConfig        discovery.Config
 	Handler                metrics.ProviderHandler
 	InternalPluginProvider discovery.PluginProvider
@@ -42,13 +45,16 @@ type RunConfig struct {
 
 // Manager manages the discovery of kubernetes targets based on annotations or configuration rules.
 type Manager struct {
-	runConfig       RunConfig
-	discoverer      discovery.Discoverer
-	configListener  *configHandler
-	podListener     *podHandler
-	serviceListener *serviceHandler
-	leadershipMgr   *leadership.Manager
-	stopCh          chan struct{}
+	mtx               sync.RWMutex // guards the discovery configuration of the run config
+	runConfig         RunConfig
+	discoverer        *discoverer
+	configListener    *configHandler
+	monitorListener   *monitorHandler
+	podListener       *podHandler
+	serviceListener   *serviceHandler
+	endpointsListener *endpointsHandler
+	leadershipMgr     *leadership.Manager
+	stopCh            chan struct{}
 }
 
 // NewDiscoveryManager creates a new instance of a discovery manager based on the given configuration.
@@ -79,8 +85,15 @@ func (dm *Manager) Start() {
 		if !dm.configListener.start() {
 			log.Error("timed out waiting for configmap caches to sync")
 		}
-		cfg = dm.configListener.Config()
 	}
+	if cfg.EnablePrometheusMonitors {
+		log.Info("prometheus monitors enabled")
+		dm.monitorListener = newMonitorHandler(dm.runConfig.DynamicClient, dm.runConfig.KubeClient)
+		if !dm.monitorListener.start() {
+			log.Error("timed out waiting for monitor caches to sync")
+		}
+	}
+	cfg = dm.config()
 	dm.discoverer = newDiscoverer(dm.runConfig.Handler, cfg, dm.runConfig.Lister)
 	dm.startResyncConfig()
 
@@ -90,6 +103,7 @@ func (dm *Manager) Start() {
 		dm.podListener.start()
 	}
 	dm.serviceListener = newServiceHandler(dm.runConfig.KubeClient, dm.discoverer)
+	dm.endpointsListener = newEndpointsHandler(dm.runConfig.KubeClient, dm.discoverer)
 
 	if dm.runConfig.ScrapeCluster {
 		dm.leadershipMgr.Start()
@@ -104,8 +118,12 @@ func (dm *Manager) Stop() {
 	if dm.configListener != nil {
 		dm.configListener.stop()
 	}
+	if dm.monitorListener != nil {
+		dm.monitorListener.stop()
+	}
 	dm.podListener.stop()
 	dm.serviceListener.stop()
+	dm.endpointsListener.stop()
 	close(dm.stopCh)
 
 	dm.discoverer.Stop()
@@ -115,17 +133,19 @@ func (dm *Manager) Stop() {
 func (dm *Manager) Resume() {
 	log.Infof("elected leader: %s starting service discovery", leadership.Leader())
 	dm.serviceListener.start()
+	dm.endpointsListener.start()
 }
 
 func (dm *Manager) Pause() {
 	log.Infof("stopping service discovery. new leader: %s", leadership.Leader())
 	dm.serviceListener.stop()
+	dm.endpointsListener.stop()
 }
 
 // startResyncConfig periodically checks for changes to the discovery config.
 // It stops monitoring existing resources and reloads the discovery manager on changes
 func (dm *Manager) startResyncConfig() {
-	if !dm.runConfig.DiscoveryConfig.EnableRuntimePlugins {
+	if !dm.runConfig.DiscoveryConfig.EnableRuntimePlugins && !dm.runConfig.DiscoveryConfig.EnablePrometheusMonitors {
 		log.Info("runtime plugins disabled")
 		return
 	}
@@ -135,13 +155,75 @@ func (dm *Manager) startResyncConfig() {
 
 	go NotifyOfChanges(func() discovery.Config {
 		log.Info("checking for runtime plugin changes")
-		return dm.configListener.Config()
+		return dm.config()
 	}, func() {
 		log.Info("found new runtime plugins")
+		dm.reloadRules()
+	}, interval, dm.stopCh)
+}
+
+// Reload applies a changed discovery configuration. Rule changes are applied in place and only affect the
+// endpoints discovered by the changed rules. Other changes restart the discovery manager.
+func (dm *Manager) Reload(cfg discovery.Config) {
+	dm.mtx.Lock()
+	prev := dm.runConfig.DiscoveryConfig
+	dm.runConfig.DiscoveryConfig = cfg
+	dm.mtx.Unlock()
+
+	if !rulesChangedOnly(prev, cfg) {
+		log.Info("discovery configuration changed, restarting discovery manager")
 		dm.Stop()
 		dm.Start()
+		return
+	}
+	log.Info("discovery rules changed")
+	if dm.configListener != nil {
+		dm.configListener.setWiredConfig(cfg)
+	}
+	dm.reloadRules()
+}
 
-	}, interval, dm.stopCh)
+// rulesChangedOnly returns whether the configurations only differ in settings used to match and encode resources
+func rulesChangedOnly(prev, cfg discovery.Config) bool {
+	for _, c := range []*discovery.Config{&prev, &cfg} {
+		c.PluginConfigs = nil
+		c.AnnotationPrefix = ""
+		c.AnnotationExcludes = nil
+		c.DisableAnnotationDiscovery = false
+	}
+	return reflect.DeepEqual(prev, cfg)
+}
+
+// reloadRules updates the rules of the discoverer and evaluates the known resources again
+func (dm *Manager) reloadRules() {
+	dm.discoverer.update(dm.runConfig.Handler, dm.config())
+	dm.podListener.resync(dm.discoverer)
+	dm.serviceListener.resync(dm.discoverer)
+	dm.endpointsListener.resync()
+}
+
+// config combines the wired discovery configuration with the runtime plugins and the rules of Prometheus monitors
+func (dm *Manager) config() discovery.Config {
+	dm.mtx.RLock()
+	cfg := dm.runConfig.DiscoveryConfig
+	dm.mtx.RUnlock()
+	if dm.configListener != nil {
+		cfg = dm.configListener.Config()
+	}
+	if dm.monitorListener != nil {
+		rules := dm.monitorListener.Rules()
+		cfg.PluginConfigs = append(append(make([]discovery.PluginConfig, 0, len(cfg.PluginConfigs)+len(rules)), cfg.PluginConfigs...), rules...)
+	}
+	return cfg
+}
+
+// Explain evaluates the current discovery configuration, including runtime plugins and monitor rules, against the listed resources
+func (dm *Manager) Explain() Report {
+	report := Explain(dm.config(), dm.runConfig.Lister, dm.runConfig.KubeClient)
+	if dm.monitorListener != nil {
+		report.Errors = append(report.Errors, dm.monitorListener.Errors()...)
+	}
+	return report
 }
 
 func NotifyOfChanges(get func() discovery.Config, notify func(), interval time.Duration, stopCh chan struct{}) {
@@ -149,6 +231,7 @@ func NotifyOfChanges(get func() discovery.Config, notify func(), interval time.D
 	util.Retry(func() {
 		val := get()
 		if !reflect.DeepEqual(val, prevVal) {
+			prevVal = val
 			notify()
 		}
 	}, interval, stopCh)
@@ -0,0 +1,222 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/httputil"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/relabel"
+	"gopkg.in/yaml.v2"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	serviceMonitorKind = "ServiceMonitor"
+	podMonitorKind     = "PodMonitor"
+)
+
+var (
+	serviceMonitorResource = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "servicemonitors"}
+	podMonitorResource     = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "podmonitors"}
+)
+
+// monitorSpec holds the fields of the ServiceMonitor and PodMonitor specs used for discovery
+type monitorSpec struct {
+	Selector            metav1.LabelSelector     `json:"selector"`
+	NamespaceSelector   monitorNamespaceSelector `json:"namespaceSelector"`
+	Endpoints           []monitorEndpoint        `json:"endpoints"`
+	PodMetricsEndpoints []monitorEndpoint        `json:"podMetricsEndpoints"`
+}
+
+type monitorNamespaceSelector struct {
+	Any        bool     `json:"any"`
+	MatchNames []string `json:"matchNames"`
+}
+
+type monitorEndpoint struct {
+	Port              string                 `json:"port"`
+	TargetPort        *intstr.IntOrString    `json:"targetPort"`
+	Path              string                 `json:"path"`
+	Scheme            string                 `json:"scheme"`
+	Interval          string                 `json:"interval"`
+	ScrapeTimeout     string                 `json:"scrapeTimeout"`
+	HonorTimestamps   *bool                  `json:"honorTimestamps"`
+	TLSConfig         *monitorTLSConfig      `json:"tlsConfig"`
+	BearerTokenFile   string                 `json:"bearerTokenFile"`
+	BearerTokenSecret *v1.SecretKeySelector  `json:"bearerTokenSecret"`
+	Relabelings       []monitorRelabelConfig `json:"relabelings"`
+	MetricRelabelings []monitorRelabelConfig `json:"metricRelabelings"`
+}
+
+type monitorTLSConfig struct {
+	CAFile             string                 `json:"caFile"`
+	CertFile           string                 `json:"certFile"`
+	KeyFile            string                 `json:"keyFile"`
+	ServerName         string                 `json:"serverName"`
+	InsecureSkipVerify bool                   `json:"insecureSkipVerify"`
+	CA                 map[string]interface{} `json:"ca"`
+	Cert               map[string]interface{} `json:"cert"`
+	KeySecret          *v1.SecretKeySelector  `json:"keySecret"`
+}
+
+type monitorRelabelConfig struct {
+	SourceLabels []string `json:"sourceLabels"`
+	Separator    string   `json:"separator"`
+	TargetLabel  string   `json:"targetLabel"`
+	Regex        string   `json:"regex"`
+	Modulus      uint64   `json:"modulus"`
+	Replacement  *string  `json:"replacement"`
+	Action       string   `json:"action"`
+}
+
+// secretKeyReader returns the value of a secret key in the given namespace
+type secretKeyReader func(namespace string, selector v1.SecretKeySelector) (string, error)
+
+// monitorRules translates every endpoint of a ServiceMonitor or PodMonitor into a prometheus discovery rule
+func monitorRules(monitor *unstructured.Unstructured, readSecret secretKeyReader) ([]discovery.PluginConfig, error) {
+	content, _, _ := unstructured.NestedMap(monitor.Object, "spec")
+	spec := monitorSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, &spec); err != nil {
+		return nil, err
+	}
+
+	resourceType := discovery.EndpointsType.String()
+	endpoints := spec.Endpoints
+	if monitor.GetKind() == podMonitorKind {
+		resourceType = discovery.PodType.String()
+		endpoints = spec.PodMetricsEndpoints
+	}
+	selectors, err := monitorSelectors(resourceType, monitor.GetNamespace(), spec)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]discovery.PluginConfig, 0, len(endpoints))
+	for i, endpoint := range endpoints {
+		rule, err := monitorRule(monitor, selectors, endpoint, readSecret)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %d: %v", i, err)
+		}
+		rule.Name = fmt.Sprintf("%s/%s/%s/%d", monitor.GetKind(), monitor.GetNamespace(), monitor.GetName(), i)
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// monitorSelectors converts the label and namespace selectors of a monitor. Only the In operator can be expressed
+// as glob patterns, other match expressions are rejected.
+func monitorSelectors(resourceType, namespace string, spec monitorSpec) (discovery.Selectors, error) {
+	selectors := discovery.Selectors{ResourceType: resourceType}
+	labels := make(map[string][]string, len(spec.Selector.MatchLabels)+len(spec.Selector.MatchExpressions))
+	for k, v := range spec.Selector.MatchLabels {
+		labels[k] = []string{v}
+	}
+	for _, expr := range spec.Selector.MatchExpressions {
+		if expr.Operator != metav1.LabelSelectorOpIn {
+			return selectors, fmt.Errorf("unsupported selector operator: %s", expr.Operator)
+		}
+		if _, ok := labels[expr.Key]; ok {
+			return selectors, fmt.Errorf("label %s is selected more than once", expr.Key)
+		}
+		labels[expr.Key] = expr.Values
+	}
+	if len(labels) > 0 {
+		selectors.Labels = labels
+	}
+
+	switch {
+	case spec.NamespaceSelector.Any:
+	case len(spec.NamespaceSelector.MatchNames) > 0:
+		selectors.Namespaces = spec.NamespaceSelector.MatchNames
+	default:
+		selectors.Namespaces = []string{namespace}
+	}
+	if selectors.Labels == nil && selectors.Namespaces == nil {
+		// an empty selector matches every resource
+		selectors.Namespaces = []string{"*"}
+	}
+	return selectors, nil
+}
+
+func monitorRule(monitor *unstructured.Unstructured, selectors discovery.Selectors, endpoint monitorEndpoint, readSecret secretKeyReader) (discovery.PluginConfig, error) {
+	rule := discovery.PluginConfig{
+		Type:      "prometheus",
+		Selectors: selectors,
+		Port:      endpoint.Port,
+		Path:      endpoint.Path,
+		Scheme:    endpoint.Scheme,
+		// the Prometheus Operator honors scraped timestamps unless disabled
+		HonorTimestamps: endpoint.HonorTimestamps == nil || *endpoint.HonorTimestamps,
+		Relabel:         relabelConfigs(endpoint.Relabelings),
+		MetricRelabel:   relabelConfigs(endpoint.MetricRelabelings),
+	}
+	if rule.Port == "" && endpoint.TargetPort != nil {
+		rule.Port = endpoint.TargetPort.String()
+	}
+
+	var err error
+	if rule.Collection.Interval, err = monitorDuration(endpoint.Interval); err != nil {
+		return rule, err
+	}
+	if rule.Collection.Timeout, err = monitorDuration(endpoint.ScrapeTimeout); err != nil {
+		return rule, err
+	}
+
+	httpCfg := httputil.ClientConfig{BearerTokenFile: endpoint.BearerTokenFile}
+	if endpoint.BearerTokenSecret != nil {
+		httpCfg.BearerToken, err = readSecret(monitor.GetNamespace(), *endpoint.BearerTokenSecret)
+		if err != nil {
+			return rule, fmt.Errorf("error reading bearer token: %v", err)
+		}
+	}
+	if tlsCfg := endpoint.TLSConfig; tlsCfg != nil {
+		if tlsCfg.CA != nil || tlsCfg.Cert != nil || tlsCfg.KeySecret != nil {
+			log.Warningf("%s %s/%s: TLS certificates from secrets or configmaps are not supported, use file paths instead",
+				monitor.GetKind(), monitor.GetNamespace(), monitor.GetName())
+		}
+		httpCfg.TLSConfig = httputil.TLSConfig{
+			CAFile:             tlsCfg.CAFile,
+			CertFile:           tlsCfg.CertFile,
+			KeyFile:            tlsCfg.KeyFile,
+			ServerName:         tlsCfg.ServerName,
+			InsecureSkipVerify: tlsCfg.InsecureSkipVerify,
+		}
+	}
+	if httpCfg != (httputil.ClientConfig{}) {
+		conf, err := yaml.Marshal(httpCfg)
+		if err != nil {
+			return rule, err
+		}
+		rule.Conf = string(conf)
+	}
+	return rule, nil
+}
+
+func monitorDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func relabelConfigs(configs []monitorRelabelConfig) []relabel.Config {
+	if len(configs) == 0 {
+		return nil
+	}
+	result := make([]relabel.Config, len(configs))
+	for i, cfg := range configs {
+		result[i] = relabel.Config(cfg)
+	}
+	return result
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func makeMonitor(kind, namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name,
+		},
+		"spec": spec,
+	}}
+}
+
+func readTestSecret(namespace string, selector v1.SecretKeySelector) (string, error) {
+	if selector.Name != "token" {
+		return "", fmt.Errorf("secret %s/%s not found", namespace, selector.Name)
+	}
+	return namespace + "-" + selector.Key, nil
+}
+
+func TestServiceMonitorRules(t *testing.T) {
+	monitor := makeMonitor(serviceMonitorKind, "shop", "checkout", map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{"app": "checkout"},
+			"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "tier", "operator": "In", "values": []interface{}{"web", "api"}},
+			},
+		},
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"port":              "metrics",
+				"path":              "/stats",
+				"scheme":            "https",
+				"interval":          "15s",
+				"scrapeTimeout":     "5s",
+				"bearerTokenSecret": map[string]interface{}{"name": "token", "key": "value"},
+				"tlsConfig":         map[string]interface{}{"caFile": "/etc/ca.crt", "serverName": "checkout.shop"},
+				"relabelings": []interface{}{
+					map[string]interface{}{"action": "drop", "sourceLabels": []interface{}{"label.env"}, "regex": "dev"},
+				},
+				"metricRelabelings": []interface{}{
+					map[string]interface{}{"action": "labeldrop", "regex": "instance"},
+				},
+			},
+			map[string]interface{}{"targetPort": int64(9090), "honorTimestamps": false},
+		},
+	})
+
+	rules, err := monitorRules(monitor, readTestSecret)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+
+	rule := rules[0]
+	assert.Equal(t, "ServiceMonitor/shop/checkout/0", rule.Name)
+	assert.Equal(t, "prometheus", rule.Type)
+	assert.Equal(t, discovery.Selectors{
+		ResourceType: "endpoints",
+		Labels:       map[string][]string{"app": {"checkout"}, "tier": {"web", "api"}},
+		Namespaces:   []string{"shop"},
+	}, rule.Selectors)
+	assert.Equal(t, "metrics", rule.Port)
+	assert.Equal(t, "/stats", rule.Path)
+	assert.Equal(t, "https", rule.Scheme)
+	assert.Equal(t, discovery.CollectionConfig{Interval: 15 * time.Second, Timeout: 5 * time.Second}, rule.Collection)
+	assert.True(t, rule.HonorTimestamps)
+	assert.Contains(t, rule.Conf, "bearer_token: shop-value")
+	assert.Contains(t, rule.Conf, "ca_file: /etc/ca.crt")
+	assert.Contains(t, rule.Conf, "server_name: checkout.shop")
+	require.Len(t, rule.Relabel, 1)
+	assert.Equal(t, "drop", rule.Relabel[0].Action)
+	assert.Equal(t, []string{"label.env"}, rule.Relabel[0].SourceLabels)
+	require.Len(t, rule.MetricRelabel, 1)
+	assert.Equal(t, "instance", rule.MetricRelabel[0].Regex)
+
+	assert.Equal(t, "9090", rules[1].Port)
+	assert.False(t, rules[1].HonorTimestamps)
+	assert.Empty(t, rules[1].Conf)
+}
+
+func TestPodMonitorRules(t *testing.T) {
+	monitor := makeMonitor(podMonitorKind, "shop", "all", map[string]interface{}{
+		"selector":            map[string]interface{}{},
+		"namespaceSelector":   map[string]interface{}{"any": true},
+		"podMetricsEndpoints": []interface{}{map[string]interface{}{"port": "http"}},
+	})
+
+	rules, err := monitorRules(monitor, readTestSecret)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, discovery.Selectors{ResourceType: "pod", Namespaces: []string{"*"}}, rules[0].Selectors)
+
+	_, err = makeDelegate(rules[0])
+	require.NoError(t, err)
+}
+
+func TestMonitorRulesErrors(t *testing.T) {
+	for name, spec := range map[string]map[string]interface{}{
+		"unsupported operator": {
+			"selector": map[string]interface{}{"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "app", "operator": "NotIn", "values": []interface{}{"a"}},
+			}},
+		},
+		"invalid interval": {
+			"endpoints": []interface{}{map[string]interface{}{"port": "http", "interval": "often"}},
+		},
+		"missing secret": {
+			"endpoints": []interface{}{map[string]interface{}{"port": "http", "bearerTokenSecret": map[string]interface{}{"name": "other", "key": "value"}}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := monitorRules(makeMonitor(serviceMonitorKind, "shop", "broken", spec), readTestSecret)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMonitorRuleEndpoints(t *testing.T) {
+	monitor := makeMonitor(podMonitorKind, "shop", "checkout", map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "checkout"}},
+		"podMetricsEndpoints": []interface{}{
+			map[string]interface{}{"port": "metrics", "bearerTokenSecret": map[string]interface{}{"name": "token", "key": "value"}},
+		},
+	})
+	rules, err := monitorRules(monitor, readTestSecret)
+	require.NoError(t, err)
+
+	e := &endpointCreator{
+		delegates: makeDelegates(discovery.Config{PluginConfigs: rules}),
+		providers: makeDummyProviders(util.NewDummyProviderHandler(1)),
+	}
+	container := makeContainer("shop/checkout", nil)
+	container.Ports = []v1.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: "metrics", ContainerPort: 9100}}
+	resource := discovery.Resource{
+		Kind:       discovery.PodType.String(),
+		IP:         "10.0.0.1",
+		Meta:       metav1.ObjectMeta{Name: "checkout-1", Namespace: "shop", Labels: map[string]string{"app": "checkout"}},
+		Containers: []v1.Container{container},
+	}
+
+	eps := e.discoverEndpoints(resource)
+	require.Len(t, eps, 1)
+	cfg := eps[0].Config.(configuration.PrometheusSourceConfig)
+	assert.Equal(t, "http://10.0.0.1:9100/metrics", cfg.URL)
+	assert.Equal(t, "shop-value", cfg.HTTPClientConfig.BearerToken)
+
+	resource.Containers[0].Ports = resource.Containers[0].Ports[:1]
+	assert.Empty(t, e.discoverEndpoints(resource), "pods without the named port are not scraped")
+}
+
+func TestMonitorHandler(t *testing.T) {
+	handler := &monitorHandler{rules: map[string][]discovery.PluginConfig{}, errors: map[string]string{}, readSecret: readTestSecret}
+	spec := map[string]interface{}{
+		"selector":  map[string]interface{}{"matchLabels": map[string]interface{}{"app": "checkout"}},
+		"endpoints": []interface{}{map[string]interface{}{"port": "metrics"}},
+	}
+	handler.updated(makeMonitor(serviceMonitorKind, "shop", "b", spec))
+	handler.updated(makeMonitor(serviceMonitorKind, "shop", "a", spec))
+
+	rules := handler.Rules()
+	require.Len(t, rules, 2)
+	assert.Equal(t, "ServiceMonitor/shop/a/0", rules[0].Name)
+	assert.Equal(t, "ServiceMonitor/shop/b/0", rules[1].Name)
+
+	spec["endpoints"] = []interface{}{map[string]interface{}{"port": "metrics", "interval": "often"}}
+	handler.updated(makeMonitor(serviceMonitorKind, "shop", "b", spec))
+	assert.Len(t, handler.Rules(), 1, "invalid monitors are removed")
+	require.Len(t, handler.Errors(), 1)
+	assert.Contains(t, handler.Errors()[0], "ServiceMonitor/shop/b")
+
+	spec["endpoints"] = []interface{}{map[string]interface{}{"port": "metrics", "bearerTokenSecret": map[string]interface{}{"name": "missing", "key": "token"}}}
+	handler.updated(makeMonitor(serviceMonitorKind, "shop", "b", spec))
+	require.Len(t, handler.Errors(), 1)
+	assert.Contains(t, handler.Errors()[0], "error reading bearer token: secret shop/missing not found")
+	deleteMonitorIfValid(makeMonitor(serviceMonitorKind, "shop", "b", spec), handler)
+	assert.Empty(t, handler.Errors())
+
+	deleteMonitorIfValid(makeMonitor(serviceMonitorKind, "shop", "a", spec), handler)
+	assert.Empty(t, handler.Rules())
+}
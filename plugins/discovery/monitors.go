@@ -0,0 +1,197 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// monitorHandler watches Prometheus Operator ServiceMonitor and PodMonitor resources and
+// translates them into discovery rules
+type monitorHandler struct {
+	stopCh    chan struct{}
+	informers []cache.SharedInformer
+
+	mtx        sync.RWMutex
+	rules      map[string][]discovery.PluginConfig // rules keyed by monitor
+	errors     map[string]string                   // errors loading the rules keyed by monitor
+	readSecret secretKeyReader
+}
+
+func newMonitorHandler(dynamicClient dynamic.Interface, kubeClient kubernetes.Interface) *monitorHandler {
+	handler := &monitorHandler{
+		rules:  make(map[string][]discovery.PluginConfig),
+		errors: make(map[string]string),
+		readSecret: func(namespace string, selector v1.SecretKeySelector) (string, error) {
+			secret, err := kubeClient.CoreV1().Secrets(namespace).Get(context.Background(), selector.Name, metav1.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			value, ok := secret.Data[selector.Key]
+			if !ok {
+				return "", fmt.Errorf("key %s not found in secret %s/%s", selector.Key, namespace, selector.Name)
+			}
+			return string(value), nil
+		},
+	}
+
+	for _, resource := range []schema.GroupVersionResource{serviceMonitorResource, podMonitorResource} {
+		if !monitorResourceExists(kubeClient, resource) {
+			log.Warningf("%s not found, is the Prometheus Operator installed?", resource.String())
+			continue
+		}
+		handler.informers = append(handler.informers, newMonitorInformer(dynamicClient, resource, handler))
+	}
+	return handler
+}
+
+func monitorResourceExists(kubeClient kubernetes.Interface, resource schema.GroupVersionResource) bool {
+	resources, err := kubeClient.Discovery().ServerResourcesForGroupVersion(resource.GroupVersion().String())
+	if err != nil {
+		return false
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == resource.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+func newMonitorInformer(dynamicClient dynamic.Interface, resource schema.GroupVersionResource, handler *monitorHandler) cache.SharedInformer {
+	r := dynamicClient.Resource(resource).Namespace(v1.NamespaceAll)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return r.List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return r.Watch(context.Background(), options)
+		},
+	}
+
+	inf := cache.NewSharedInformer(lw, &unstructured.Unstructured{}, 1*time.Hour)
+	inf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			updateMonitorIfValid(obj, handler)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			updateMonitorIfValid(obj, handler)
+		},
+		DeleteFunc: func(obj interface{}) {
+			deleteMonitorIfValid(obj, handler)
+		},
+	})
+	return inf
+}
+
+func updateMonitorIfValid(obj interface{}, handler *monitorHandler) {
+	monitor, ok := obj.(*unstructured.Unstructured)
+	if ok {
+		handler.updated(monitor)
+	}
+}
+
+func deleteMonitorIfValid(obj interface{}, handler *monitorHandler) {
+	monitor, ok := obj.(*unstructured.Unstructured)
+	if ok {
+		handler.deleted(monitorKey(monitor))
+	}
+}
+
+func monitorKey(monitor *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s/%s/%s", monitor.GetKind(), monitor.GetNamespace(), monitor.GetName())
+}
+
+func (handler *monitorHandler) updated(monitor *unstructured.Unstructured) {
+	key := monitorKey(monitor)
+	rules, err := monitorRules(monitor, handler.readSecret)
+	if err != nil {
+		log.Errorf("error loading %s error: %v", key, err)
+		handler.deleted(key)
+		handler.mtx.Lock()
+		defer handler.mtx.Unlock()
+		handler.errors[key] = err.Error()
+		return
+	}
+	log.Infof("loaded %d discovery rules from %s", len(rules), key)
+
+	handler.mtx.Lock()
+	defer handler.mtx.Unlock()
+	handler.rules[key] = rules
+	delete(handler.errors, key)
+}
+
+func (handler *monitorHandler) deleted(key string) {
+	handler.mtx.Lock()
+	defer handler.mtx.Unlock()
+	delete(handler.errors, key)
+	if _, found := handler.rules[key]; found {
+		log.Infof("deleted discovery rules from %s", key)
+		delete(handler.rules, key)
+	}
+}
+
+// Rules returns the discovery rules of all monitors in a consistent order
+func (handler *monitorHandler) Rules() []discovery.PluginConfig {
+	handler.mtx.RLock()
+	defer handler.mtx.RUnlock()
+
+	keys := make([]string, 0, len(handler.rules))
+	for k := range handler.rules {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var rules []discovery.PluginConfig
+	for _, key := range keys {
+		rules = append(rules, handler.rules[key]...)
+	}
+	return rules
+}
+
+// Errors returns the errors of the monitors whose rules could not be loaded, such as unreadable bearer token secrets
+func (handler *monitorHandler) Errors() []string {
+	handler.mtx.RLock()
+	defer handler.mtx.RUnlock()
+
+	errors := make([]string, 0, len(handler.errors))
+	for key, err := range handler.errors {
+		errors = append(errors, fmt.Sprintf("error loading rules from %s: %s", key, err))
+	}
+	sort.Strings(errors)
+	return errors
+}
+
+func (handler *monitorHandler) start() bool {
+	handler.stopCh = make(chan struct{})
+	synced := make([]cache.InformerSynced, len(handler.informers))
+	for i, inf := range handler.informers {
+		go inf.Run(handler.stopCh)
+		synced[i] = inf.HasSynced
+	}
+	return cache.WaitForCacheSync(handler.stopCh, synced...)
+}
+
+func (handler *monitorHandler) stop() {
+	if handler.stopCh != nil {
+		close(handler.stopCh)
+	}
+}
@@ -89,5 +89,16 @@ func (handler *podHandler) start() {
 func (handler *podHandler) stop() {
 	if handler.ch != nil {
 		close(handler.ch)
+		handler.ch = nil
+	}
+}
+
+// resync submits the cached resources to the discoverer again when the handler is running
+func (handler *podHandler) resync(discoverer discovery.Discoverer) {
+	if handler.ch == nil {
+		return
+	}
+	for _, obj := range handler.informer.GetStore().List() {
+		updatePodIfValid(obj, discoverer)
 	}
 }
@@ -95,7 +95,7 @@ func (e prometheusEncoder) Encode(ip, kind string, meta metav1.ObjectMeta, cfg i
 	}
 	result.Discovered = discoveryType
 
-	if kind == discovery.ServiceType.String() {
+	if kind == discovery.ServiceType.String() || kind == discovery.EndpointsType.String() {
 		// always use leader election for cluster level resources
 		result.UseLeaderElection = true
 	}
@@ -147,6 +147,13 @@ func (e prometheusEncoder) Encode(ip, kind string, meta metav1.ObjectMeta, cfg i
 		utils.EncodeTags(result.Tags, "label.", meta.Labels)
 	}
 	result.Filters = rule.Filters
+	result.Relabel = rule.Relabel
+	result.MetricRelabel = rule.MetricRelabel
+	result.ScrapeProtocols = rule.ScrapeProtocols
+	result.Exemplars = rule.Exemplars
+	result.HonorTimestamps = rule.HonorTimestamps
+	result.MaxSampleAge = rule.MaxSampleAge
+	result.StalenessMarkers = rule.StalenessMarkers
 
 	err = encodeHTTPConf(&result, rule.Conf, insecureSkipVerify, serverName)
 	if err != nil {
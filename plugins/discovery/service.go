@@ -54,9 +54,10 @@ func deleteServiceIfValid(obj interface{}, discoverer discovery.Discoverer) {
 	service, ok := obj.(*v1.Service)
 	if ok {
 		discoverer.Discover(discovery.Resource{
-			Kind: discovery.ServiceType.String(),
-			IP:   service.Spec.ClusterIP,
-			Meta: service.ObjectMeta,
+			Kind:  discovery.ServiceType.String(),
+			IP:    service.Spec.ClusterIP,
+			Meta:  service.ObjectMeta,
+			Ports: service.Spec.Ports,
 		})
 	}
 }
@@ -65,9 +66,10 @@ func updateServiceIfValid(obj interface{}, discoverer discovery.Discoverer) {
 	service, ok := obj.(*v1.Service)
 	if ok && hasIP(service.Spec.ClusterIP) {
 		discoverer.Discover(discovery.Resource{
-			Kind: discovery.ServiceType.String(),
-			IP:   service.Spec.ClusterIP,
-			Meta: service.ObjectMeta,
+			Kind:  discovery.ServiceType.String(),
+			IP:    service.Spec.ClusterIP,
+			Meta:  service.ObjectMeta,
+			Ports: service.Spec.Ports,
 		})
 	}
 }
@@ -84,5 +86,16 @@ func (handler *serviceHandler) start() {
 func (handler *serviceHandler) stop() {
 	if handler.ch != nil {
 		close(handler.ch)
+		handler.ch = nil
+	}
+}
+
+// resync submits the cached resources to the discoverer again when the handler is running
+func (handler *serviceHandler) resync(discoverer discovery.Discoverer) {
+	if handler.ch == nil {
+		return
+	}
+	for _, obj := range handler.informer.GetStore().List() {
+		updateServiceIfValid(obj, discoverer)
 	}
 }
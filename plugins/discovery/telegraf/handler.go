@@ -44,7 +44,7 @@ func (e telegrafEncoder) Encode(ip, kind string, meta metav1.ObjectMeta, rule in
 		},
 	}
 
-	if kind == discovery.ServiceType.String() {
+	if kind == discovery.ServiceType.String() || kind == discovery.EndpointsType.String() {
 		// always use leader election for cluster level resources
 		result.UseLeaderElection = true
 	}
@@ -60,11 +60,7 @@ func (e telegrafEncoder) Encode(ip, kind string, meta metav1.ObjectMeta, rule in
 
 	// parse telegraf configuration
 	scheme := utils.Param(meta, "", cfg.Scheme, "http")
-	server := fmt.Sprintf("%s://%s:%s", scheme, ip, cfg.Port)
-	conf := strings.Replace(cfg.Conf, "${server}", server, -1)
-	conf = strings.Replace(conf, "${host}", ip, -1)
-	conf = strings.Replace(conf, "${port}", cfg.Port, -1)
-	result.Conf = conf
+	result.Conf = ExpandConf(cfg.Conf, scheme, ip, cfg.Port)
 
 	// parse prefix, tags, labels and filters
 	prefix := utils.Param(meta, discovery.PrefixAnnotation, cfg.Prefix, "")
@@ -92,3 +88,11 @@ func uniqueName(name, port string) string {
 	}
 	return fmt.Sprintf("%s:%s", name, port)
 }
+
+// ExpandConf replaces the ${server}, ${host} and ${port} placeholders of a telegraf rule configuration
+func ExpandConf(conf, scheme, ip, port string) string {
+	server := fmt.Sprintf("%s://%s:%s", scheme, ip, port)
+	conf = strings.Replace(conf, "${server}", server, -1)
+	conf = strings.Replace(conf, "${host}", ip, -1)
+	return strings.Replace(conf, "${port}", port, -1)
+}
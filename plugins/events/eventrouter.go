@@ -9,7 +9,7 @@ import (
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/events"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/leadership"
-	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/wavefront"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks"
 	"github.com/wavefronthq/wavefront-sdk-go/event"
 
 	gometrics "github.com/rcrowley/go-metrics"
@@ -33,7 +33,7 @@ type EventRouter struct {
 	kubeClient        kubernetes.Interface
 	eLister           corelisters.EventLister
 	eListerSynced     cache.InformerSynced
-	sink              wavefront.WavefrontSink
+	sink              sinks.Sink
 	sharedInformers   informers.SharedInformerFactory
 	stop              chan struct{}
 	scrapeCluster     bool
@@ -41,7 +41,7 @@ type EventRouter struct {
 	filters           eventFilter
 }
 
-func NewEventRouter(clientset kubernetes.Interface, cfg configuration.EventsConfig, sink wavefront.WavefrontSink, scrapeCluster bool) *EventRouter {
+func NewEventRouter(clientset kubernetes.Interface, cfg configuration.EventsConfig, sink sinks.Sink, scrapeCluster bool) *EventRouter {
 	sharedInformers := informers.NewSharedInformerFactory(clientset, time.Minute)
 	eventsInformer := sharedInformers.Core().V1().Events()
 
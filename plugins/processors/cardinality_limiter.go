@@ -0,0 +1,369 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gm "github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+const (
+	defaultCardinalityWindow = 10 * time.Minute
+
+	CardinalityLimitExceededReason = "CardinalityLimitExceeded"
+)
+
+type cardinalityKey struct {
+	name   string
+	source string
+}
+
+type trackedSeries struct {
+	tags     map[string]string
+	lastSeen time.Time
+}
+
+// seriesTracker holds the distinct series seen for a single metric name and source
+type seriesTracker struct {
+	series       map[string]*trackedSeries
+	limitedUntil time.Time
+	strippedTags []string
+}
+
+// CardinalityLimiter caps the number of distinct series reported per metric name and source within a sliding window.
+// It must run after all metric sets have been converted to wavefront metrics.
+type CardinalityLimiter struct {
+	maxSeries int
+	window    time.Duration
+	aggregate bool
+	recorder  record.EventRecorder
+	ref       *v1.ObjectReference
+	now       func() time.Time
+
+	lock     sync.Mutex
+	trackers map[cardinalityKey]*seriesTracker
+
+	dropped    gm.Counter
+	aggregated gm.Counter
+	limited    gm.Gauge
+}
+
+func NewCardinalityLimiter(cfg configuration.CardinalityLimitConfig, recorder record.EventRecorder) (*CardinalityLimiter, error) {
+	if cfg.MaxSeriesPerMetric <= 0 {
+		return nil, fmt.Errorf("maxSeriesPerMetric must be greater than zero")
+	}
+	switch cfg.Action {
+	case "", configuration.CardinalityDropAction, configuration.CardinalityAggregateAction:
+	default:
+		return nil, fmt.Errorf("unknown cardinality limit action: %q", cfg.Action)
+	}
+	window := cfg.Window
+	if window <= 0 {
+		window = defaultCardinalityWindow
+	}
+	return &CardinalityLimiter{
+		maxSeries:  cfg.MaxSeriesPerMetric,
+		window:     window,
+		aggregate:  cfg.Action == configuration.CardinalityAggregateAction,
+		recorder:   recorder,
+		ref:        collectorPodReference(),
+		now:        time.Now,
+		trackers:   map[cardinalityKey]*seriesTracker{},
+		dropped:    gm.GetOrRegisterCounter("processor.cardinality.dropped", gm.DefaultRegistry),
+		aggregated: gm.GetOrRegisterCounter("processor.cardinality.aggregated", gm.DefaultRegistry),
+		limited:    gm.GetOrRegisterGauge("processor.cardinality.limited", gm.DefaultRegistry),
+	}, nil
+}
+
+// collectorPodReference refers to the collector pod that events about limited metrics are reported against.
+func collectorPodReference() *v1.ObjectReference {
+	name, _ := os.Hostname()
+	return &v1.ObjectReference{
+		Kind:       "Pod",
+		APIVersion: "v1",
+		Namespace:  util.GetNamespaceName(),
+		Name:       name,
+	}
+}
+
+func (cl *CardinalityLimiter) Name() string {
+	return "cardinality limiter"
+}
+
+func (cl *CardinalityLimiter) Process(batch *metrics.Batch) (*metrics.Batch, error) {
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+
+	now := cl.now()
+	cl.prune(now)
+
+	// first admit every series so that all metrics of a limited metric name are treated alike within the batch
+	for _, metric := range batch.Metrics {
+		if !limitable(metric) {
+			continue
+		}
+		cl.admit(metric, now)
+	}
+
+	merged := map[cardinalityKey]map[string]wf.Metric{}
+	batch.Metrics = filterMapInPlace(func(metric wf.Metric) (wf.Metric, bool) {
+		if !limitable(metric) {
+			return metric, true
+		}
+		key := keyOf(metric)
+		tracker := cl.trackers[key]
+		if len(tracker.strippedTags) == 0 {
+			if _, ok := tracker.series[seriesID(metric.Tags())]; !ok {
+				cl.dropped.Inc(1)
+				return nil, false
+			}
+			return metric, true
+		}
+
+		tags := stripTags(metric.Tags(), tracker.strippedTags)
+		id := seriesID(tags)
+		if _, ok := tracker.series[id]; !ok {
+			cl.dropped.Inc(1)
+			return nil, false
+		}
+		if merged[key] == nil {
+			merged[key] = map[string]wf.Metric{}
+		}
+		if existing, ok := merged[key][id]; ok {
+			mergeInto(existing, metric)
+			cl.aggregated.Inc(1)
+			return nil, false
+		}
+		metric = withTags(metric, tags)
+		merged[key][id] = metric
+		return metric, true
+	}, batch.Metrics)
+
+	cl.limited.Update(int64(cl.limitedCount(now)))
+	return batch, nil
+}
+
+// admit tracks the series of the metric, limiting its metric name once the cap is crossed.
+func (cl *CardinalityLimiter) admit(metric wf.Metric, now time.Time) {
+	key := keyOf(metric)
+	tracker, ok := cl.trackers[key]
+	if !ok {
+		tracker = &seriesTracker{series: map[string]*trackedSeries{}}
+		cl.trackers[key] = tracker
+	}
+
+	tags := stripTags(metric.Tags(), tracker.strippedTags)
+	for {
+		id := seriesID(tags)
+		if series, ok := tracker.series[id]; ok {
+			series.lastSeen = now
+			return
+		}
+		if len(tracker.series) < cl.maxSeries {
+			tracker.series[id] = &trackedSeries{tags: tags, lastSeen: now}
+			return
+		}
+
+		tag := ""
+		if cl.aggregate {
+			tag = tracker.mostDistinctTag(tags)
+		}
+		if now.After(tracker.limitedUntil) {
+			tracker.limitedUntil = now.Add(cl.window)
+			cl.report(key, tag)
+		}
+		if tag == "" {
+			return
+		}
+		tracker.strip(tag)
+		delete(tags, tag)
+	}
+}
+
+func (cl *CardinalityLimiter) report(key cardinalityKey, tag string) {
+	message := fmt.Sprintf("metric %s from source %s exceeded %d series within %v", key.name, key.source, cl.maxSeries, cl.window)
+	if tag != "" {
+		message += fmt.Sprintf(", aggregating away tag %s", tag)
+	} else {
+		message += ", dropping new series"
+	}
+	log.Warnf("cardinality limit: %s", message)
+	if cl.recorder != nil {
+		cl.recorder.Event(cl.ref, v1.EventTypeWarning, CardinalityLimitExceededReason, message)
+	}
+}
+
+// prune forgets series not seen within the window and lifts limits that have expired.
+func (cl *CardinalityLimiter) prune(now time.Time) {
+	expiry := now.Add(-cl.window)
+	for key, tracker := range cl.trackers {
+		if len(tracker.strippedTags) > 0 && now.After(tracker.limitedUntil) {
+			// the tracked series are aggregated, start counting the original series again
+			tracker.strippedTags = nil
+			tracker.series = map[string]*trackedSeries{}
+		}
+		for id, series := range tracker.series {
+			if series.lastSeen.Before(expiry) {
+				delete(tracker.series, id)
+			}
+		}
+		if len(tracker.series) == 0 && now.After(tracker.limitedUntil) {
+			delete(cl.trackers, key)
+		}
+	}
+}
+
+func (cl *CardinalityLimiter) limitedCount(now time.Time) int {
+	count := 0
+	for _, tracker := range cl.trackers {
+		if !now.After(tracker.limitedUntil) {
+			count++
+		}
+	}
+	return count
+}
+
+// mostDistinctTag returns the tag with the most distinct values across the tracked series and the given tags.
+func (t *seriesTracker) mostDistinctTag(tags map[string]string) string {
+	values := map[string]map[string]struct{}{}
+	add := func(tags map[string]string) {
+		for k, v := range tags {
+			if values[k] == nil {
+				values[k] = map[string]struct{}{}
+			}
+			values[k][v] = struct{}{}
+		}
+	}
+	add(tags)
+	for _, series := range t.series {
+		add(series.tags)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best, most := "", 1
+	for _, name := range names {
+		if len(values[name]) > most {
+			best, most = name, len(values[name])
+		}
+	}
+	return best
+}
+
+// strip removes the tag from all tracked series, merging the series that become identical.
+func (t *seriesTracker) strip(tag string) {
+	t.strippedTags = append(t.strippedTags, tag)
+	series := make(map[string]*trackedSeries, len(t.series))
+	for _, s := range t.series {
+		delete(s.tags, tag)
+		id := seriesID(s.tags)
+		if existing, ok := series[id]; ok && existing.lastSeen.After(s.lastSeen) {
+			continue
+		}
+		series[id] = s
+	}
+	t.series = series
+}
+
+func limitable(metric wf.Metric) bool {
+	switch metric.(type) {
+	case *wf.Point, *wf.Distribution:
+		return true
+	}
+	return false
+}
+
+func keyOf(metric wf.Metric) cardinalityKey {
+	switch m := metric.(type) {
+	case *wf.Point:
+		return cardinalityKey{name: m.Metric, source: m.Source}
+	case *wf.Distribution:
+		return cardinalityKey{name: m.Name(), source: m.Source}
+	}
+	return cardinalityKey{name: metric.Name()}
+}
+
+func seriesID(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+// stripTags returns a copy of the tags without the given tag names
+func stripTags(tags map[string]string, names []string) map[string]string {
+	stripped := make(map[string]string, len(tags))
+	for k, v := range tags {
+		stripped[k] = v
+	}
+	for _, name := range names {
+		delete(stripped, name)
+	}
+	return stripped
+}
+
+func withTags(metric wf.Metric, tags map[string]string) wf.Metric {
+	switch m := metric.(type) {
+	case *wf.Point:
+		m.SetLabelPairs(nil)
+		m.SetTags(tags)
+		return m
+	case *wf.Distribution:
+		// clone so that merging does not modify the centroids of the scraped distribution
+		clone := m.Clone()
+		clone.SetTags(tags)
+		return clone
+	}
+	return metric
+}
+
+// mergeInto sums the value of a point or the centroids of a frequency distribution into dst.
+// The centroids of a cumulative distribution hold running totals, so a cumulative dst is replaced by src instead.
+// Staleness markers are not summed, the aggregate is only stale when all its series are.
+func mergeInto(dst, src wf.Metric) {
+	switch d := dst.(type) {
+	case *wf.Point:
+		if s, ok := src.(*wf.Point); ok {
+			if wf.IsStaleMarker(s.Value) {
+				return
+			}
+			if wf.IsStaleMarker(d.Value) {
+				d.Value = 0
+			}
+			d.Value += s.Value
+			if s.Timestamp > d.Timestamp {
+				d.Timestamp = s.Timestamp
+			}
+		}
+	case *wf.Distribution:
+		if s, ok := src.(*wf.Distribution); ok {
+			if d.Cumulative {
+				d.Centroids = append(d.Centroids[:0], s.Centroids...)
+				d.Timestamp = s.Timestamp
+				d.StartTime = s.StartTime
+				return
+			}
+			d.Centroids = append(d.Centroids, s.Centroids...)
+		}
+	}
+}
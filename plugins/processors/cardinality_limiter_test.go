@@ -0,0 +1,164 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+func podPoints(name string, pods int) []wf.Metric {
+	points := make([]wf.Metric, 0, pods)
+	for i := 0; i < pods; i++ {
+		points = append(points, wf.NewPoint(name, 1, 0, "node1", map[string]string{
+			"namespace_name": "default",
+			"pod_name":       fmt.Sprintf("web-%d", i),
+		}))
+	}
+	return points
+}
+
+func newTestLimiter(t *testing.T, cfg configuration.CardinalityLimitConfig) (*CardinalityLimiter, *record.FakeRecorder, *time.Time) {
+	recorder := record.NewFakeRecorder(10)
+	limiter, err := NewCardinalityLimiter(cfg, recorder)
+	require.NoError(t, err)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	return limiter, recorder, &now
+}
+
+func TestCardinalityLimiter(t *testing.T) {
+	t.Run("passes metrics within the limit", func(t *testing.T) {
+		limiter, recorder, _ := newTestLimiter(t, configuration.CardinalityLimitConfig{MaxSeriesPerMetric: 3})
+
+		batch, err := limiter.Process(&metrics.Batch{Metrics: append(podPoints("http.requests", 3), podPoints("http.errors", 3)...)})
+
+		require.NoError(t, err)
+		assert.Len(t, batch.Metrics, 6)
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("drops new series once the limit is crossed", func(t *testing.T) {
+		limiter, recorder, _ := newTestLimiter(t, configuration.CardinalityLimitConfig{MaxSeriesPerMetric: 3})
+		before := limiter.dropped.Count()
+
+		batch, _ := limiter.Process(&metrics.Batch{Metrics: append(podPoints("http.requests", 5), podPoints("http.errors", 1)...)})
+
+		assert.Len(t, batch.Metrics, 4)
+		assert.Equal(t, before+2, limiter.dropped.Count())
+		require.Len(t, recorder.Events, 1)
+		event := <-recorder.Events
+		assert.Contains(t, event, CardinalityLimitExceededReason)
+		assert.Contains(t, event, "metric http.requests from source node1")
+
+		batch, _ = limiter.Process(&metrics.Batch{Metrics: podPoints("http.requests", 5)})
+		assert.Len(t, batch.Metrics, 3)
+		assert.Empty(t, recorder.Events, "reports once per window")
+	})
+
+	t.Run("forgets series outside the window", func(t *testing.T) {
+		limiter, _, now := newTestLimiter(t, configuration.CardinalityLimitConfig{MaxSeriesPerMetric: 2, Window: time.Minute})
+		limiter.Process(&metrics.Batch{Metrics: podPoints("http.requests", 2)})
+
+		*now = now.Add(2 * time.Minute)
+		batch, _ := limiter.Process(&metrics.Batch{Metrics: podPoints("http.requests", 4)[2:]})
+
+		assert.Len(t, batch.Metrics, 2)
+	})
+
+	t.Run("aggregates away the tag with the most distinct values", func(t *testing.T) {
+		limiter, recorder, _ := newTestLimiter(t, configuration.CardinalityLimitConfig{
+			MaxSeriesPerMetric: 3,
+			Action:             configuration.CardinalityAggregateAction,
+		})
+
+		batch, _ := limiter.Process(&metrics.Batch{Metrics: podPoints("http.requests", 5)})
+
+		require.Len(t, batch.Metrics, 1)
+		point := batch.Metrics[0].(*wf.Point)
+		assert.Equal(t, map[string]string{"namespace_name": "default"}, point.Tags())
+		assert.Equal(t, float64(5), point.Value)
+		require.Len(t, recorder.Events, 1)
+		assert.Contains(t, <-recorder.Events, "aggregating away tag pod_name")
+	})
+
+	t.Run("does not sum staleness markers", func(t *testing.T) {
+		limiter, _, _ := newTestLimiter(t, configuration.CardinalityLimitConfig{
+			MaxSeriesPerMetric: 3,
+			Action:             configuration.CardinalityAggregateAction,
+		})
+		points := podPoints("http.requests", 5)
+		points[0].(*wf.Point).Value = wf.StaleNaN
+		points[3].(*wf.Point).Value = wf.StaleNaN
+
+		batch, _ := limiter.Process(&metrics.Batch{Metrics: points})
+
+		require.Len(t, batch.Metrics, 1)
+		assert.Equal(t, float64(3), batch.Metrics[0].(*wf.Point).Value)
+
+		stale := podPoints("http.requests", 5)
+		for _, point := range stale {
+			point.(*wf.Point).Value = wf.StaleNaN
+		}
+		batch, _ = limiter.Process(&metrics.Batch{Metrics: stale})
+
+		require.Len(t, batch.Metrics, 1)
+		assert.True(t, wf.IsStaleMarker(batch.Metrics[0].(*wf.Point).Value), "the aggregate is stale once all its series are")
+	})
+
+	t.Run("aggregates distributions", func(t *testing.T) {
+		limiter, _, _ := newTestLimiter(t, configuration.CardinalityLimitConfig{
+			MaxSeriesPerMetric: 1,
+			Action:             configuration.CardinalityAggregateAction,
+		})
+		distribution := func(pod string) wf.Metric {
+			return wf.NewFrequencyDistribution("latency", "node1", map[string]string{"pod_name": pod},
+				[]wf.Centroid{{Value: 1, Count: 1}}, time.Now())
+		}
+
+		batch, _ := limiter.Process(&metrics.Batch{Metrics: []wf.Metric{distribution("a"), distribution("b")}})
+
+		require.Len(t, batch.Metrics, 1)
+		assert.Empty(t, batch.Metrics[0].Tags())
+		assert.Len(t, batch.Metrics[0].(*wf.Distribution).Centroids, 2)
+	})
+
+	t.Run("replaces cumulative distributions and keeps the start time", func(t *testing.T) {
+		limiter, _, _ := newTestLimiter(t, configuration.CardinalityLimitConfig{
+			MaxSeriesPerMetric: 1,
+			Action:             configuration.CardinalityAggregateAction,
+		})
+		start := time.Now().Add(-time.Hour)
+		distribution := func(pod string, count float64) wf.Metric {
+			d := wf.NewCumulativeDistribution("latency", "node1", map[string]string{"pod_name": pod},
+				[]wf.Centroid{{Value: 1, Count: count}, {Value: 2, Count: count}}, time.Now())
+			d.StartTime = start
+			return d
+		}
+
+		batch, _ := limiter.Process(&metrics.Batch{Metrics: []wf.Metric{distribution("a", 1), distribution("b", 3)}})
+
+		require.Len(t, batch.Metrics, 1)
+		merged := batch.Metrics[0].(*wf.Distribution)
+		assert.Empty(t, merged.Tags())
+		assert.Equal(t, start, merged.StartTime)
+		assert.Equal(t, []wf.Centroid{{Value: 1, Count: 3}, {Value: 2, Count: 3}}, merged.Centroids)
+	})
+
+	t.Run("rejects invalid configuration", func(t *testing.T) {
+		_, err := NewCardinalityLimiter(configuration.CardinalityLimitConfig{}, nil)
+		assert.Error(t, err)
+		_, err = NewCardinalityLimiter(configuration.CardinalityLimitConfig{MaxSeriesPerMetric: 1, Action: "unknown"}, nil)
+		assert.Error(t, err)
+	})
+}
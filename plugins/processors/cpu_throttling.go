@@ -0,0 +1,64 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"strings"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+// names of the cumulative CFS counters reported by cAdvisor as converted by the prometheus source, matched
+// regardless of the configured prefix
+const (
+	cfsThrottledPeriodsMetric = "container.cpu.cfs.throttled.periods.total.counter"
+	cfsPeriodsMetric          = "container.cpu.cfs.periods.total.counter"
+)
+
+// cfsPeriods are the CFS period counters of a container
+type cfsPeriods struct {
+	throttled float64
+	total     float64
+}
+
+// collectCFSPeriods returns the CFS period counters scraped from cAdvisor by container key
+func collectCFSPeriods(batchMetrics []wf.Metric) map[metrics.ResourceKey]cfsPeriods {
+	result := map[metrics.ResourceKey]cfsPeriods{}
+	for _, metric := range batchMetrics {
+		point, ok := metric.(*wf.Point)
+		if !ok {
+			continue
+		}
+		throttled := strings.HasSuffix(point.Metric, cfsThrottledPeriodsMetric)
+		if !throttled && !strings.HasSuffix(point.Metric, cfsPeriodsMetric) {
+			continue
+		}
+		tags := point.Tags()
+		// the pod level cgroup is reported without a container name
+		if tags["container"] == "" || tags["pod"] == "" || tags["namespace"] == "" {
+			continue
+		}
+		key := metrics.PodContainerKey(tags["namespace"], tags["pod"], tags["container"])
+		periods := result[key]
+		if throttled {
+			periods.throttled = point.Value
+		} else {
+			periods.total = point.Value
+		}
+		result[key] = periods
+	}
+	return result
+}
+
+// throttledRatio returns the share of the CFS periods elapsed between two scrapes that were throttled. False is
+// returned if no period elapsed or the counters were reset.
+func throttledRatio(previous, current cfsPeriods) (float64, bool) {
+	periods := current.total - previous.total
+	throttled := current.throttled - previous.throttled
+	if periods <= 0 || throttled < 0 {
+		return 0, false
+	}
+	return throttled / periods, true
+}
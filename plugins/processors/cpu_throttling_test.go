@@ -0,0 +1,113 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+func TestCFSThrottledRatio(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		previous cfsPeriods
+		current  cfsPeriods
+		ratio    float64
+		ok       bool
+	}{
+		{
+			name:     "share of the elapsed periods that were throttled",
+			previous: cfsPeriods{throttled: 10, total: 100},
+			current:  cfsPeriods{throttled: 35, total: 200},
+			ratio:    0.25,
+			ok:       true,
+		},
+		{
+			name:     "no throttling",
+			previous: cfsPeriods{throttled: 10, total: 100},
+			current:  cfsPeriods{throttled: 10, total: 150},
+			ratio:    0,
+			ok:       true,
+		},
+		{
+			name:     "no elapsed periods",
+			previous: cfsPeriods{throttled: 10, total: 100},
+			current:  cfsPeriods{throttled: 10, total: 100},
+		},
+		{
+			name:     "total counter reset",
+			previous: cfsPeriods{throttled: 10, total: 100},
+			current:  cfsPeriods{throttled: 12, total: 20},
+		},
+		{
+			name:     "throttled counter reset",
+			previous: cfsPeriods{throttled: 10, total: 100},
+			current:  cfsPeriods{throttled: 2, total: 120},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ratio, ok := throttledRatio(tc.previous, tc.current)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.ratio, ratio)
+		})
+	}
+}
+
+func TestCollectCFSPeriods(t *testing.T) {
+	tags := func(namespace, pod, container string) map[string]string {
+		return map[string]string{"namespace": namespace, "pod": pod, "container": container}
+	}
+	for _, tc := range []struct {
+		name     string
+		points   []wf.Metric
+		expected map[metrics.ResourceKey]cfsPeriods
+	}{
+		{
+			name: "matches the counters regardless of the prefix",
+			points: []wf.Metric{
+				wf.NewPoint("kubernetes.cadvisor.container.cpu.cfs.throttled.periods.total.counter", 5, 0, "node1", tags("ns1", "pod1", "c1")),
+				wf.NewPoint("custom.container.cpu.cfs.periods.total.counter", 50, 0, "node1", tags("ns1", "pod1", "c1")),
+			},
+			expected: map[metrics.ResourceKey]cfsPeriods{
+				metrics.PodContainerKey("ns1", "pod1", "c1"): {throttled: 5, total: 50},
+			},
+		},
+		{
+			name: "keys the counters by container",
+			points: []wf.Metric{
+				wf.NewPoint("container.cpu.cfs.periods.total.counter", 50, 0, "node1", tags("ns1", "pod1", "c1")),
+				wf.NewPoint("container.cpu.cfs.periods.total.counter", 60, 0, "node1", tags("ns1", "pod1", "c2")),
+			},
+			expected: map[metrics.ResourceKey]cfsPeriods{
+				metrics.PodContainerKey("ns1", "pod1", "c1"): {total: 50},
+				metrics.PodContainerKey("ns1", "pod1", "c2"): {total: 60},
+			},
+		},
+		{
+			name: "skips the pod level cgroup without a container",
+			points: []wf.Metric{
+				wf.NewPoint("container.cpu.cfs.periods.total.counter", 50, 0, "node1", tags("ns1", "pod1", "")),
+				wf.NewPoint("container.cpu.cfs.throttled.periods.total.counter", 5, 0, "node1", tags("ns1", "pod1", "")),
+			},
+			expected: map[metrics.ResourceKey]cfsPeriods{},
+		},
+		{
+			name: "skips other metrics",
+			points: []wf.Metric{
+				wf.NewPoint("container.cpu.cfs.throttled.seconds.total.counter", 1, 0, "node1", tags("ns1", "pod1", "c1")),
+				wf.NewFrequencyDistribution("container.cpu.cfs.periods.total.counter", "node1", tags("ns1", "pod1", "c1"), nil, time.Now()),
+			},
+			expected: map[metrics.ResourceKey]cfsPeriods{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, collectCFSPeriods(tc.points))
+		})
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+
+	kube_client "k8s.io/client-go/kubernetes"
+	v1listers "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	RateCalculatorType                  = "rate_calculator"
+	DistributionRateCalculatorType      = "distribution_rate_calculator"
+	CumulativeDistributionConverterType = "cumulative_distribution_converter"
+	PodBasedEnricherType                = "pod_based_enricher"
+	NamespaceBasedEnricherType          = "namespace_based_enricher"
+	PodAggregatorType                   = "pod_aggregator"
+	WorkloadAggregatorType              = "workload_aggregator"
+	NamespaceAggregatorType             = "namespace_aggregator"
+	NodeAggregatorType                  = "node_aggregator"
+	ClusterAggregatorType               = "cluster_aggregator"
+	NodeAutoscalingEnricherType         = "node_autoscaling_enricher"
+	SumCountAggregatorType              = "sum_count_aggregator"
+	ResourceRecommenderType             = "resource_recommender"
+	NamespaceQuotaEnricherType          = "namespace_quota_enricher"
+)
+
+// defaultMetricsToAggregate are summed by the workload, namespace and cluster aggregators unless configured otherwise
+var defaultMetricsToAggregate = []string{
+	metrics.MetricCpuUsageRate.Name,
+	metrics.MetricMemoryUsage.Name,
+	metrics.MetricCpuRequest.Name,
+	metrics.MetricCpuLimit.Name,
+	metrics.MetricMemoryRequest.Name,
+	metrics.MetricMemoryLimit.Name,
+}
+
+// defaultMetricsToAggregateForNode are summed by the node aggregator unless configured otherwise
+var defaultMetricsToAggregateForNode = []string{
+	metrics.MetricCpuRequest.Name,
+	metrics.MetricCpuLimit.Name,
+	metrics.MetricMemoryRequest.Name,
+	metrics.MetricMemoryLimit.Name,
+	metrics.MetricEphemeralStorageRequest.Name,
+	metrics.MetricEphemeralStorageLimit.Name,
+}
+
+// DefaultConfigs returns the processor chain used when the configuration does not list any processors
+func DefaultConfigs() []configuration.ProcessorConfig {
+	types := []string{
+		RateCalculatorType,
+		DistributionRateCalculatorType,
+		CumulativeDistributionConverterType,
+		PodBasedEnricherType,
+		NamespaceBasedEnricherType,
+		PodAggregatorType,
+		NamespaceAggregatorType,
+		NodeAggregatorType,
+		ClusterAggregatorType,
+		NodeAutoscalingEnricherType,
+	}
+	cfgs := make([]configuration.ProcessorConfig, 0, len(types))
+	for _, t := range types {
+		cfgs = append(cfgs, configuration.ProcessorConfig{Type: t})
+	}
+	return cfgs
+}
+
+// Dependencies are the clients and settings shared by the processors of a chain
+type Dependencies struct {
+	KubeClient         *kube_client.Clientset
+	PodLister          v1listers.PodLister
+	LabelCopier        *util.LabelCopier
+	CollectionInterval time.Duration
+}
+
+// FactoryConfig is passed to the Build method of the processor factories
+type FactoryConfig struct {
+	configuration.ProcessorConfig
+	Dependencies
+}
+
+type factory struct {
+	name     string
+	validate func(cfg configuration.ProcessorConfig) error
+	build    func(cfg FactoryConfig) (metrics.Processor, error)
+}
+
+func (f factory) Name() string {
+	return f.name
+}
+
+func (f factory) Build(cfg interface{}) (metrics.Processor, error) {
+	c := cfg.(FactoryConfig)
+	if err := f.Validate(c.ProcessorConfig); err != nil {
+		return nil, err
+	}
+	return f.build(c)
+}
+
+// Validate checks the configuration without creating the processor
+func (f factory) Validate(cfg configuration.ProcessorConfig) error {
+	if f.validate == nil {
+		return supports(cfg)
+	}
+	return f.validate(cfg)
+}
+
+var (
+	factoriesMtx sync.RWMutex
+	factories    = map[string]metrics.ProcessorFactory{}
+)
+
+// RegisterFactory makes a processor type available to the processors section of the configuration
+func RegisterFactory(f metrics.ProcessorFactory) {
+	factoriesMtx.Lock()
+	defer factoriesMtx.Unlock()
+	factories[f.Name()] = f
+}
+
+// Factory returns the factory of a processor type
+func Factory(name string) (metrics.ProcessorFactory, bool) {
+	factoriesMtx.RLock()
+	defer factoriesMtx.RUnlock()
+	f, ok := factories[name]
+	return f, ok
+}
+
+// FactoryNames returns the registered processor types in alphabetical order
+func FactoryNames() []string {
+	factoriesMtx.RLock()
+	defer factoriesMtx.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateConfig checks that the type of a processor is registered and, for the built-in types, that its
+// parameters are valid
+func ValidateConfig(cfg configuration.ProcessorConfig) error {
+	f, ok := Factory(cfg.Type)
+	if !ok {
+		return fmt.Errorf("unknown processor type %q", cfg.Type)
+	}
+	if v, ok := f.(interface {
+		Validate(configuration.ProcessorConfig) error
+	}); ok {
+		return v.Validate(cfg)
+	}
+	return nil
+}
+
+// Build creates the processors of a chain in order. The default chain is used if no processors are configured.
+func Build(cfgs []configuration.ProcessorConfig, deps Dependencies) ([]metrics.Processor, error) {
+	if len(cfgs) == 0 {
+		cfgs = DefaultConfigs()
+	}
+	result := make([]metrics.Processor, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		f, ok := Factory(cfg.Type)
+		if !ok {
+			return nil, fmt.Errorf("processor %d: unknown processor type %q", i, cfg.Type)
+		}
+		processor, err := f.Build(FactoryConfig{ProcessorConfig: cfg, Dependencies: deps})
+		if err != nil {
+			return nil, fmt.Errorf("processor %d (%s): %v", i, cfg.Type, err)
+		}
+		result = append(result, processor)
+	}
+	return result, nil
+}
+
+func init() {
+	for _, f := range []factory{
+		{name: RateCalculatorType, build: func(FactoryConfig) (metrics.Processor, error) {
+			return NewRateCalculator(metrics.RateMetricsMapping), nil
+		}},
+		{name: DistributionRateCalculatorType, build: func(FactoryConfig) (metrics.Processor, error) {
+			return NewDistributionRateCalculator(), nil
+		}},
+		{name: CumulativeDistributionConverterType, build: func(FactoryConfig) (metrics.Processor, error) {
+			return NewCumulativeDistributionConverter(), nil
+		}},
+		{name: PodBasedEnricherType, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			return NewPodBasedEnricher(cfg.KubeClient, cfg.PodLister, cfg.LabelCopier, cfg.CollectionInterval), nil
+		}},
+		{name: NamespaceBasedEnricherType, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			return NewNamespaceBasedEnricher(cfg.KubeClient, cfg.LabelCopier)
+		}},
+		{name: PodAggregatorType, build: func(FactoryConfig) (metrics.Processor, error) {
+			return NewPodAggregator(), nil
+		}},
+		{name: WorkloadAggregatorType, validate: validateMetrics, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			return NewWorkloadAggregator(cfg.KubeClient, cfg.PodLister, metricsOrDefault(cfg.Metrics, defaultMetricsToAggregate)), nil
+		}},
+		{name: NamespaceAggregatorType, validate: validateMetrics, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			var enricher *NamespaceBasedEnricher
+			if cfg.KubeClient != nil {
+				var err error
+				if enricher, err = NewNamespaceBasedEnricher(cfg.KubeClient, cfg.LabelCopier); err != nil {
+					return nil, err
+				}
+			}
+			return NewNamespaceAggregator(metricsOrDefault(cfg.Metrics, defaultMetricsToAggregate), enricher), nil
+		}},
+		{name: NodeAggregatorType, validate: validateMetrics, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			return NewNodeAggregator(metricsOrDefault(cfg.Metrics, defaultMetricsToAggregateForNode)), nil
+		}},
+		{name: ClusterAggregatorType, validate: validateMetrics, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			return NewClusterAggregator(metricsOrDefault(cfg.Metrics, defaultMetricsToAggregate)), nil
+		}},
+		{name: NodeAutoscalingEnricherType, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			return NewNodeAutoscalingEnricher(cfg.KubeClient, cfg.LabelCopier)
+		}},
+		{name: ResourceRecommenderType, validate: validateRecommendation, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			return NewResourceRecommender(cfg.KubeClient, cfg.PodLister, cfg.Recommendation)
+		}},
+		{name: NamespaceQuotaEnricherType, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			return NewNamespaceQuotaEnricher(cfg.KubeClient), nil
+		}},
+		{name: SumCountAggregatorType, validate: validateSumCount, build: func(cfg FactoryConfig) (metrics.Processor, error) {
+			specs, err := sumCountSpecs(cfg.Aggregations)
+			if err != nil {
+				return nil, err
+			}
+			return NewSumCountAggregator(cfg.Name, specs), nil
+		}},
+	} {
+		RegisterFactory(f)
+	}
+}
+
+// supports returns an error if parameters other than the given ones are set
+func supports(cfg configuration.ProcessorConfig, parameters ...string) error {
+	set := map[string]bool{
+		"metrics":        len(cfg.Metrics) > 0,
+		"name":           cfg.Name != "",
+		"aggregations":   len(cfg.Aggregations) > 0,
+		"recommendation": cfg.Recommendation != nil,
+	}
+	for _, parameter := range parameters {
+		delete(set, parameter)
+	}
+	var unsupported []string
+	for parameter, isSet := range set {
+		if isSet {
+			unsupported = append(unsupported, parameter)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("%s does not support %s", cfg.Type, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+func validateMetrics(cfg configuration.ProcessorConfig) error {
+	return supports(cfg, "metrics")
+}
+
+func metricsOrDefault(names, defaults []string) []string {
+	if len(names) == 0 {
+		return defaults
+	}
+	return names
+}
+
+func validateSumCount(cfg configuration.ProcessorConfig) error {
+	if cfg.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if err := supports(cfg, "name", "aggregations"); err != nil {
+		return err
+	}
+	if len(cfg.Aggregations) == 0 {
+		return fmt.Errorf("at least one aggregation is required")
+	}
+	_, err := sumCountSpecs(cfg.Aggregations)
+	return err
+}
+
+// sumCountSpecs converts configured aggregations to the specs of a SumCountAggregator
+func sumCountSpecs(cfgs []configuration.AggregationConfig) ([]SumCountAggregateSpec, error) {
+	specs := make([]SumCountAggregateSpec, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		var spec SumCountAggregateSpec
+		switch cfg.ResourceType {
+		case "pod":
+			spec.IsPartOfGroup = isAggregatablePod
+		case "pod_container":
+			spec.IsPartOfGroup = isAggregatablePodContainer
+		case "namespace":
+			spec.IsPartOfGroup = isType(metrics.MetricSetTypeNamespace)
+		case "node":
+			spec.IsPartOfGroup = isType(metrics.MetricSetTypeNode)
+		default:
+			return nil, fmt.Errorf("aggregation %d: unknown resourceType %q", i, cfg.ResourceType)
+		}
+		switch cfg.GroupBy {
+		case "namespace":
+			spec.Group = namespaceGroup(nil)
+		case "node":
+			spec.Group = nodeGroup
+		case "cluster":
+			spec.Group = clusterGroup
+		default:
+			return nil, fmt.Errorf("aggregation %d: unknown groupBy %q", i, cfg.GroupBy)
+		}
+		if cfg.ResourceType == cfg.GroupBy {
+			return nil, fmt.Errorf("aggregation %d: cannot aggregate %s metric sets by %s", i, cfg.ResourceType, cfg.GroupBy)
+		}
+		if len(cfg.SumMetrics) == 0 && cfg.CountMetric == "" {
+			return nil, fmt.Errorf("aggregation %d: sumMetrics or countMetric is required", i)
+		}
+		spec.ResourceSumMetrics = cfg.SumMetrics
+		spec.ResourceCountMetric = cfg.CountMetric
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
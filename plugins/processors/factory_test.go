@@ -0,0 +1,135 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestDefaultConfigs(t *testing.T) {
+	for _, cfg := range DefaultConfigs() {
+		assert.NoError(t, ValidateConfig(cfg), cfg.Type)
+		assert.NotEqual(t, WorkloadAggregatorType, cfg.Type)
+	}
+	var types []string
+	for _, cfg := range DefaultConfigs() {
+		types = append(types, cfg.Type)
+	}
+	assert.Equal(t, []string{
+		RateCalculatorType,
+		DistributionRateCalculatorType,
+		CumulativeDistributionConverterType,
+		PodBasedEnricherType,
+		NamespaceBasedEnricherType,
+		PodAggregatorType,
+		NamespaceAggregatorType,
+		NodeAggregatorType,
+		ClusterAggregatorType,
+		NodeAutoscalingEnricherType,
+	}, types)
+}
+
+func TestBuild(t *testing.T) {
+	chain, err := Build([]configuration.ProcessorConfig{
+		{Type: PodAggregatorType},
+		{Type: NamespaceAggregatorType, Metrics: []string{"m1"}},
+		{Type: SumCountAggregatorType, Name: "node_pods", Aggregations: []configuration.AggregationConfig{{
+			ResourceType: "pod",
+			GroupBy:      "node",
+			SumMetrics:   []string{"m2"},
+		}}},
+	}, Dependencies{})
+	require.NoError(t, err)
+	require.Len(t, chain, 3)
+	assert.Equal(t, "node_pods_aggregator", chain[2].Name())
+
+	batch := &metrics.Batch{
+		Timestamp: time.Now(),
+		Sets: map[metrics.ResourceKey]*metrics.Set{
+			metrics.PodKey("ns1", "pod1"): {
+				Labels: map[string]string{
+					metrics.LabelMetricSetType.Key: metrics.MetricSetTypePod,
+					metrics.LabelNamespaceName.Key: "ns1",
+					metrics.LabelNodename.Key:      "node1",
+				},
+				Values: map[string]metrics.Value{
+					"m1": {ValueType: metrics.ValueInt64, IntValue: 10},
+					"m2": {ValueType: metrics.ValueInt64, IntValue: 20},
+				},
+				LabeledValues: []metrics.LabeledValue{{
+					Name:   metrics.MetricPodPhase.Name,
+					Labels: map[string]string{"phase": string(corev1.PodRunning)},
+					Value:  metrics.Value{ValueType: metrics.ValueInt64, IntValue: util.ConvertPodPhase(corev1.PodRunning)},
+				}},
+			},
+			metrics.NodeKey("node1"): {
+				Labels: map[string]string{metrics.LabelMetricSetType.Key: metrics.MetricSetTypeNode},
+				Values: map[string]metrics.Value{},
+			},
+		},
+	}
+	for _, processor := range chain {
+		batch, err = processor.Process(batch)
+		require.NoError(t, err)
+	}
+
+	namespace := batch.Sets[metrics.NamespaceKey("ns1")]
+	require.NotNil(t, namespace)
+	assert.Equal(t, int64(10), namespace.Values["m1"].IntValue)
+	assert.NotContains(t, namespace.Values, "m2", "only the configured metrics are aggregated")
+
+	node := batch.Sets[metrics.NodeKey("node1")]
+	assert.Equal(t, int64(20), node.Values["m2"].IntValue)
+	assert.Len(t, node.Values, 1, "no count metric is reported without a countMetric")
+}
+
+func TestBuildErrors(t *testing.T) {
+	_, err := Build([]configuration.ProcessorConfig{{Type: RateCalculatorType}, {Type: "nope"}}, Dependencies{})
+	assert.EqualError(t, err, `processor 1: unknown processor type "nope"`)
+
+	_, err = Build([]configuration.ProcessorConfig{{Type: RateCalculatorType, Metrics: []string{"m1"}}}, Dependencies{})
+	assert.EqualError(t, err, "processor 0 (rate_calculator): rate_calculator does not support metrics")
+
+	for _, tc := range []struct {
+		cfg configuration.ProcessorConfig
+		err string
+	}{
+		{
+			cfg: configuration.ProcessorConfig{Type: SumCountAggregatorType},
+			err: "name is required",
+		},
+		{
+			cfg: configuration.ProcessorConfig{Type: SumCountAggregatorType, Name: "a"},
+			err: "at least one aggregation is required",
+		},
+		{
+			cfg: configuration.ProcessorConfig{Type: SumCountAggregatorType, Name: "a", Aggregations: []configuration.AggregationConfig{
+				{ResourceType: "node", GroupBy: "node", CountMetric: "node.count"},
+			}},
+			err: "aggregation 0: cannot aggregate node metric sets by node",
+		},
+		{
+			cfg: configuration.ProcessorConfig{Type: SumCountAggregatorType, Name: "a", Aggregations: []configuration.AggregationConfig{
+				{ResourceType: "pod", GroupBy: "cluster"},
+			}},
+			err: "aggregation 0: sumMetrics or countMetric is required",
+		},
+		{
+			cfg: configuration.ProcessorConfig{Type: NodeAggregatorType, Name: "a"},
+			err: "node_aggregator does not support name",
+		},
+	} {
+		assert.EqualError(t, ValidateConfig(tc.cfg), tc.err)
+	}
+}
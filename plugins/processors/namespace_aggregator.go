@@ -23,35 +23,43 @@ import (
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
 )
 
-func NewNamespaceAggregator(metricsToAggregate []string) metrics.Processor {
+// NewNamespaceAggregator sums the metrics of pods by namespace. The namespace metric sets it creates are labeled
+// by the given enricher, as the namespace based enricher runs before the aggregators. No labels are added when nil.
+func NewNamespaceAggregator(metricsToAggregate []string, enricher *NamespaceBasedEnricher) metrics.Processor {
+	group := namespaceGroup(enricher)
 	return NewSumCountAggregator("namespace", []SumCountAggregateSpec{
 		{
 			ResourceSumMetrics:  metricsToAggregate,
 			ResourceCountMetric: metrics.MetricPodCount.Name,
 			IsPartOfGroup:       isAggregatablePod,
-			Group:               namespaceGroup,
+			Group:               group,
 		},
 		{
 			ResourceSumMetrics:  []string{},
 			ResourceCountMetric: metrics.MetricPodContainerCount.Name,
 			IsPartOfGroup:       isAggregatablePodContainer,
-			Group:               namespaceGroup,
+			Group:               group,
 		},
 	})
 }
 
-func namespaceGroup(batch *metrics.Batch, resourceKey metrics.ResourceKey, resourceSet *metrics.Set) (metrics.ResourceKey, *metrics.Set) {
-	namespaceName, found := resourceSet.Labels[metrics.LabelNamespaceName.Key]
-	if !found {
-		log.Errorf("no namespace info in pod %s: %v", resourceKey, resourceSet.Labels)
-		return "", nil
+func namespaceGroup(enricher *NamespaceBasedEnricher) func(*metrics.Batch, metrics.ResourceKey, *metrics.Set) (metrics.ResourceKey, *metrics.Set) {
+	return func(batch *metrics.Batch, resourceKey metrics.ResourceKey, resourceSet *metrics.Set) (metrics.ResourceKey, *metrics.Set) {
+		namespaceName, found := resourceSet.Labels[metrics.LabelNamespaceName.Key]
+		if !found {
+			log.Errorf("no namespace info in pod %s: %v", resourceKey, resourceSet.Labels)
+			return "", nil
+		}
+		namespaceKey := metrics.NamespaceKey(namespaceName)
+		namespaceSet := batch.Sets[namespaceKey]
+		if namespaceSet == nil {
+			namespaceSet = namespaceMetricSet(namespaceName, resourceSet.Labels[metrics.LabelPodNamespaceUID.Key])
+			if enricher != nil {
+				enricher.addNamespaceInfo(namespaceSet)
+			}
+		}
+		return namespaceKey, namespaceSet
 	}
-	namespaceKey := metrics.NamespaceKey(namespaceName)
-	namespaceSet := batch.Sets[namespaceKey]
-	if namespaceSet == nil {
-		namespaceSet = namespaceMetricSet(namespaceName, resourceSet.Labels[metrics.LabelPodNamespaceUID.Key])
-	}
-	return namespaceKey, namespaceSet
 }
 
 func namespaceMetricSet(namespaceName, uid string) *metrics.Set {
@@ -106,7 +106,7 @@ func TestNamespaceAggregate(t *testing.T) {
 			},
 		},
 	}
-	processor := NewNamespaceAggregator([]string{"m1", "m3"})
+	processor := NewNamespaceAggregator([]string{"m1", "m3"}, nil)
 
 	result, err := processor.Process(&batch)
 	assert.NoError(t, err)
@@ -28,7 +28,8 @@ import (
 )
 
 type NamespaceBasedEnricher struct {
-	store cache.Store
+	store       cache.Store
+	labelCopier *util.LabelCopier
 }
 
 func (nbe *NamespaceBasedEnricher) Name() string {
@@ -42,7 +43,8 @@ func (nbe *NamespaceBasedEnricher) Process(batch *metrics.Batch) (*metrics.Batch
 	return batch, nil
 }
 
-// Adds UID to all namespaced elements.
+// Adds UID to all namespaced elements, the namespace labels and annotations to namespaces and, if enabled,
+// the inherited namespace labels to pods and containers.
 func (nbe *NamespaceBasedEnricher) addNamespaceInfo(metricSet *metrics.Set) {
 	metricSetType, found := metricSet.Labels[metrics.LabelMetricSetType.Key]
 	if !found {
@@ -50,6 +52,7 @@ func (nbe *NamespaceBasedEnricher) addNamespaceInfo(metricSet *metrics.Set) {
 	}
 	if metricSetType != metrics.MetricSetTypePodContainer &&
 		metricSetType != metrics.MetricSetTypePod &&
+		metricSetType != metrics.MetricSetTypeWorkload &&
 		metricSetType != metrics.MetricSetTypeNamespace {
 		return
 	}
@@ -64,6 +67,13 @@ func (nbe *NamespaceBasedEnricher) addNamespaceInfo(metricSet *metrics.Set) {
 		namespace, ok := nsObj.(*kube_api.Namespace)
 		if ok {
 			metricSet.Labels[metrics.LabelPodNamespaceUID.Key] = string(namespace.UID)
+			switch metricSetType {
+			case metrics.MetricSetTypeNamespace:
+				nbe.labelCopier.Copy(namespace.Labels, metricSet.Labels)
+				nbe.labelCopier.CopyAnnotations(namespace.Annotations, metricSet.Labels)
+			case metrics.MetricSetTypePod, metrics.MetricSetTypePodContainer:
+				nbe.labelCopier.Inherit(namespace.Labels, metricSet.Labels)
+			}
 		} else {
 			log.Errorf("Wrong namespace store content")
 		}
@@ -74,8 +84,9 @@ func (nbe *NamespaceBasedEnricher) addNamespaceInfo(metricSet *metrics.Set) {
 	}
 }
 
-func NewNamespaceBasedEnricher(kubeClient *kube_client.Clientset) (*NamespaceBasedEnricher, error) {
+func NewNamespaceBasedEnricher(kubeClient *kube_client.Clientset, labelCopier *util.LabelCopier) (*NamespaceBasedEnricher, error) {
 	return &NamespaceBasedEnricher{
-		store: util.GetNamespaceStore(kubeClient),
+		store:       util.GetNamespaceStore(kubeClient),
+		labelCopier: labelCopier,
 	}, nil
 }
@@ -0,0 +1,95 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+
+	kube_api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNamespaceBasedEnricher(t *testing.T) {
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, store.Add(&kube_api.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "ns1",
+		UID:         "ns1-uid",
+		Labels:      map[string]string{"team": "payments", "app": "shared"},
+		Annotations: map[string]string{"example.com/owner": "payments"},
+	}}))
+	labelCopier, err := util.NewConfiguredLabelCopier(",", util.LabelCopierConfig{
+		AnnotationAllowList:    []string{"example.com/*"},
+		InheritNamespaceLabels: true,
+	})
+	require.NoError(t, err)
+	enricher := &NamespaceBasedEnricher{store: store, labelCopier: labelCopier}
+
+	batch, err := enricher.Process(&metrics.Batch{Sets: map[metrics.ResourceKey]*metrics.Set{
+		metrics.NamespaceKey("ns1"): namespaceMetricSet("ns1", ""),
+		metrics.PodKey("ns1", "pod1"): {Labels: map[string]string{
+			metrics.LabelMetricSetType.Key: metrics.MetricSetTypePod,
+			metrics.LabelNamespaceName.Key: "ns1",
+			metrics.LabelLabels.Key:        "app:web",
+		}},
+	}})
+	require.NoError(t, err)
+
+	namespace := batch.Sets[metrics.NamespaceKey("ns1")].Labels
+	assert.Equal(t, "ns1-uid", namespace[metrics.LabelPodNamespaceUID.Key])
+	assert.Equal(t, "app:shared,team:payments", namespace[metrics.LabelLabels.Key])
+	assert.Equal(t, "payments", namespace["annotation.example.com/owner"])
+
+	pod := batch.Sets[metrics.PodKey("ns1", "pod1")].Labels
+	assert.Equal(t, "ns1-uid", pod[metrics.LabelPodNamespaceUID.Key])
+	assert.Equal(t, "app:web,team:payments", pod[metrics.LabelLabels.Key], "pod labels take precedence")
+	assert.NotContains(t, pod, "annotation.example.com/owner", "namespace annotations are not inherited")
+}
+
+func TestNamespaceAggregatorLabelsNamespaces(t *testing.T) {
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, store.Add(&kube_api.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "ns1",
+		UID:    "ns1-uid",
+		Labels: map[string]string{"team": "payments"},
+	}}))
+	labelCopier, err := util.NewConfiguredLabelCopier(",", util.LabelCopierConfig{})
+	require.NoError(t, err)
+	enricher := &NamespaceBasedEnricher{store: store, labelCopier: labelCopier}
+
+	// the enricher runs before the aggregators, as in the default processor chain
+	batch := &metrics.Batch{Sets: map[metrics.ResourceKey]*metrics.Set{
+		metrics.PodKey("ns1", "pod1"): {
+			Labels: map[string]string{
+				metrics.LabelMetricSetType.Key: metrics.MetricSetTypePod,
+				metrics.LabelNamespaceName.Key: "ns1",
+			},
+			Values: map[string]metrics.Value{},
+			LabeledValues: []metrics.LabeledValue{{
+				Name:   metrics.MetricPodPhase.Name,
+				Labels: map[string]string{"phase": string(kube_api.PodRunning)},
+			}},
+		},
+	}}
+	for _, processor := range []metrics.Processor{enricher, NewNamespaceAggregator(nil, enricher)} {
+		batch, err = processor.Process(batch)
+		require.NoError(t, err)
+	}
+
+	namespace := batch.Sets[metrics.NamespaceKey("ns1")]
+	require.NotNil(t, namespace)
+	assert.Equal(t, "ns1-uid", namespace.Labels[metrics.LabelPodNamespaceUID.Key])
+	assert.Equal(t, "team:payments", namespace.Labels[metrics.LabelLabels.Key])
+	assert.Equal(t, int64(1), namespace.Values[metrics.MetricPodCount.Name].IntValue)
+
+	pod := batch.Sets[metrics.PodKey("ns1", "pod1")].Labels
+	assert.Equal(t, "ns1-uid", pod[metrics.LabelPodNamespaceUID.Key])
+	assert.NotContains(t, pod, metrics.LabelLabels.Key, "namespace labels are not inherited unless enabled")
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+
+	kube_api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kube_client "k8s.io/client-go/kubernetes"
+	v1listers "k8s.io/client-go/listers/core/v1"
+)
+
+// quotaUsageMetrics are the namespace metrics the hard limit of a quota is compared to by resource
+var quotaUsageMetrics = map[kube_api.ResourceName]string{
+	kube_api.ResourceCPU:            metrics.MetricCpuUsageRate.Name,
+	kube_api.ResourceRequestsCPU:    metrics.MetricCpuUsageRate.Name,
+	kube_api.ResourceLimitsCPU:      metrics.MetricCpuUsageRate.Name,
+	kube_api.ResourceMemory:         metrics.MetricMemoryUsage.Name,
+	kube_api.ResourceRequestsMemory: metrics.MetricMemoryUsage.Name,
+	kube_api.ResourceLimitsMemory:   metrics.MetricMemoryUsage.Name,
+}
+
+// NamespaceQuotaEnricher adds the ratio of the actual usage of a namespace to its cpu and memory quotas to its
+// metric set. The hard limits and usage accounted by Kubernetes are reported once per cluster by the
+// kubernetes_state_source, while the namespace metric sets of each node only add their share of the usage.
+type NamespaceQuotaEnricher struct {
+	quotaLister v1listers.ResourceQuotaLister
+}
+
+func (nqe *NamespaceQuotaEnricher) Name() string {
+	return "namespace_quota_enricher"
+}
+
+func (nqe *NamespaceQuotaEnricher) Process(batch *metrics.Batch) (*metrics.Batch, error) {
+	for _, ms := range batch.Sets {
+		if ms.Labels[metrics.LabelMetricSetType.Key] != metrics.MetricSetTypeNamespace {
+			continue
+		}
+		namespace := ms.Labels[metrics.LabelNamespaceName.Key]
+		quotas, err := nqe.quotaLister.ResourceQuotas(namespace).List(labels.Everything())
+		if err != nil {
+			log.Debugf("Failed to list the resource quotas of namespace %s: %v", namespace, err)
+			continue
+		}
+		for _, quota := range quotas {
+			addQuota(ms, quota)
+		}
+	}
+	return batch, nil
+}
+
+// addQuota adds the ratio of the actual usage to the hard limit of the cpu and memory resources of a quota.
+// The ratio of every node is a share of the namespace usage, so the ratios can be summed across nodes.
+func addQuota(ms *metrics.Set, quota *kube_api.ResourceQuota) {
+	for name, hardQuantity := range quota.Status.Hard {
+		usage, found := ms.Values[quotaUsageMetrics[name]]
+		hard := util.ResourceQuantityValue(name, hardQuantity)
+		if !found || hard <= 0 {
+			continue
+		}
+		quotaLabels := map[string]string{
+			"resource":      string(name),
+			"resourcequota": quota.Name,
+		}
+		addLabeledFloatMetric(ms, &metrics.MetricQuotaUsageRatio, quotaLabels, floatValue(usage)/float64(hard))
+	}
+}
+
+// addLabeledFloatMetric is a convenience method for adding the labeled metric and float value to the metric set.
+func addLabeledFloatMetric(ms *metrics.Set, metric *metrics.Metric, labels map[string]string, value float64) {
+	ms.LabeledValues = append(ms.LabeledValues, metrics.LabeledValue{
+		Name:   metric.Name,
+		Labels: labels,
+		Value: metrics.Value{
+			ValueType:  metrics.ValueFloat,
+			FloatValue: value,
+		},
+	})
+}
+
+// NewNamespaceQuotaEnricher returns a processor adding the quota usage ratios of a namespace to the namespace
+// metric sets created by the namespace aggregator
+func NewNamespaceQuotaEnricher(kubeClient *kube_client.Clientset) *NamespaceQuotaEnricher {
+	return newNamespaceQuotaEnricher(util.GetResourceQuotaLister(kubeClient))
+}
+
+func newNamespaceQuotaEnricher(quotaLister v1listers.ResourceQuotaLister) *NamespaceQuotaEnricher {
+	return &NamespaceQuotaEnricher{quotaLister: quotaLister}
+}
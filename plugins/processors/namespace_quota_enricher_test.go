@@ -0,0 +1,73 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNamespaceQuotaEnricher(t *testing.T) {
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	require.NoError(t, store.Add(&corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "compute"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{
+				corev1.ResourceRequestsCPU: resource.MustParse("2"),
+				corev1.ResourcePods:        resource.MustParse("10"),
+			},
+			Used: corev1.ResourceList{
+				corev1.ResourceRequestsCPU: resource.MustParse("500m"),
+				corev1.ResourcePods:        resource.MustParse("4"),
+			},
+		},
+	}))
+	enricher := newNamespaceQuotaEnricher(v1listers.NewResourceQuotaLister(store))
+
+	batch := &metrics.Batch{
+		Timestamp: time.Now(),
+		Sets: map[metrics.ResourceKey]*metrics.Set{
+			metrics.NamespaceKey("ns1"): {
+				Labels: map[string]string{
+					metrics.LabelMetricSetType.Key: metrics.MetricSetTypeNamespace,
+					metrics.LabelNamespaceName.Key: "ns1",
+				},
+				Values: map[string]metrics.Value{
+					metrics.MetricCpuUsageRate.Name: {ValueType: metrics.ValueInt64, IntValue: 1000},
+				},
+			},
+			metrics.NamespaceKey("ns2"): {
+				Labels: map[string]string{
+					metrics.LabelMetricSetType.Key: metrics.MetricSetTypeNamespace,
+					metrics.LabelNamespaceName.Key: "ns2",
+				},
+				Values: map[string]metrics.Value{},
+			},
+		},
+	}
+	batch, err := enricher.Process(batch)
+	require.NoError(t, err)
+
+	values := map[string]float64{}
+	for _, value := range batch.Sets[metrics.NamespaceKey("ns1")].LabeledValues {
+		assert.Equal(t, "compute", value.Labels["resourcequota"])
+		values[value.Name+"/"+value.Labels["resource"]] = floatValue(value.Value)
+	}
+	assert.Equal(t, map[string]float64{
+		"quota/usage_ratio/requests.cpu": 0.5,
+	}, values, "the hard limits and usage are reported by the kubernetes_state_source")
+
+	assert.Empty(t, batch.Sets[metrics.NamespaceKey("ns2")].LabeledValues)
+}
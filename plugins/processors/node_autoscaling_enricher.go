@@ -46,6 +46,7 @@ func (nae *NodeAutoscalingEnricher) Process(batch *metrics.Batch) (*metrics.Batc
 	for _, node := range nodes {
 		if metricSet, found := batch.Sets[metrics.NodeKey(node.Name)]; found {
 			nae.labelCopier.Copy(node.Labels, metricSet.Labels)
+			nae.labelCopier.CopyAnnotations(node.Annotations, metricSet.Labels)
 			metricSet.Labels[metrics.LabelNodeRole.Key] = util.GetNodeRole(node)
 			capacityCpu, _ := node.Status.Capacity[kube_api.ResourceCPU]
 			capacityMem, _ := node.Status.Capacity[kube_api.ResourceMemory]
@@ -19,6 +19,7 @@ package processors
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -27,13 +28,33 @@ import (
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
 
 	kube_api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
 	v1listers "k8s.io/client-go/listers/core/v1"
 )
 
+// oomKilledReason is the termination reason of containers killed by the kernel OOM killer
+const oomKilledReason = "OOMKilled"
+
+// oomKillCountExpiry is how long the OOM kill count of a container that is no longer reported is kept
+const oomKillCountExpiry = time.Hour
+
 type PodBasedEnricher struct {
 	podLister          v1listers.PodLister
 	labelCopier        *util.LabelCopier
 	collectionInterval time.Duration
+	workloads          *workloadResolver
+
+	lock          sync.Mutex
+	cfsPeriods    map[metrics.ResourceKey]cfsPeriods
+	oomKillCounts map[metrics.ResourceKey]*oomKillCount
+}
+
+// oomKillCount is the cumulative number of OOM kills of a container and the restart count it was last updated at
+type oomKillCount struct {
+	restarts int32
+	kills    int64
+	seen     time.Time
 }
 
 func (pbe *PodBasedEnricher) Name() string {
@@ -41,6 +62,17 @@ func (pbe *PodBasedEnricher) Name() string {
 }
 
 func (pbe *PodBasedEnricher) Process(batch *metrics.Batch) (*metrics.Batch, error) {
+	pbe.lock.Lock()
+	defer pbe.lock.Unlock()
+
+	previousPeriods := pbe.cfsPeriods
+	pbe.cfsPeriods = collectCFSPeriods(batch.Metrics)
+	for key, count := range pbe.oomKillCounts {
+		if batch.Timestamp.Sub(count.seen) > oomKillCountExpiry {
+			delete(pbe.oomKillCounts, key)
+		}
+	}
+
 	newMs := make(map[metrics.ResourceKey]*metrics.Set, len(batch.Sets))
 	for k, v := range batch.Sets {
 		switch v.Labels[metrics.LabelMetricSetType.Key] {
@@ -64,6 +96,7 @@ func (pbe *PodBasedEnricher) Process(batch *metrics.Batch) (*metrics.Batch, erro
 				continue
 			}
 			pbe.addContainerInfo(k, v, pod, batch, newMs)
+			pbe.addThrottledRatio(k, v, pod, previousPeriods)
 		}
 	}
 	for k, v := range newMs {
@@ -100,13 +133,13 @@ func (pbe *PodBasedEnricher) addContainerInfo(key metrics.ResourceKey, container
 			if !pod.Status.StartTime.IsZero() {
 				containerMs.EntityCreateTime = pod.Status.StartTime.Time
 			}
-			pbe.addContainerStatus(batch.Timestamp, containerMs, &metrics.MetricContainerStatus, containerStatus)
+			pbe.addContainerStatus(batch.Timestamp, containerMs, pod, containerStatus)
 			break
 		}
 	}
 
 	containerMs.Labels[metrics.LabelPodId.Key] = string(pod.UID)
-	pbe.labelCopier.Copy(pod.Labels, containerMs.Labels)
+	pbe.copyLabels(pod, containerMs)
 
 	namespace := containerMs.Labels[metrics.LabelNamespaceName.Key]
 	podName := containerMs.Labels[metrics.LabelPodName.Key]
@@ -148,7 +181,7 @@ func (pbe *PodBasedEnricher) addPodInfo(podMs *metrics.Set, pod *kube_api.Pod, b
 	if !pod.Status.StartTime.IsZero() {
 		podMs.EntityCreateTime = pod.Status.StartTime.Time
 	}
-	pbe.labelCopier.Copy(pod.Labels, podMs.Labels)
+	pbe.copyLabels(pod, podMs)
 
 	// Add pod phase
 	addLabeledIntMetric(podMs, &metrics.MetricPodPhase, map[string]string{"phase": string(pod.Status.Phase)}, util.ConvertPodPhase(pod.Status.Phase))
@@ -178,13 +211,19 @@ func (pbe *PodBasedEnricher) addPodInfo(podMs *metrics.Set, pod *kube_api.Pod, b
 			},
 			EntityCreateTime: podMs.CollectionStartTime,
 		}
-		pbe.labelCopier.Copy(pod.Labels, containerMs.Labels)
+		pbe.copyLabels(pod, containerMs)
 		updateContainerResourcesAndLimits(containerMs, container)
 		newMs[containerKey] = containerMs
 	}
 	pbe.updateContainerStatus(newMs, pod, pod.Status.ContainerStatuses, batch.Timestamp)
 }
 
+// copyLabels copies the selected labels and annotations of a pod into the labels of its metric set
+func (pbe *PodBasedEnricher) copyLabels(pod *kube_api.Pod, ms *metrics.Set) {
+	pbe.labelCopier.Copy(pod.Labels, ms.Labels)
+	pbe.labelCopier.CopyAnnotations(pod.Annotations, ms.Labels)
+}
+
 func updateContainerResourcesAndLimits(metricSet *metrics.Set, container kube_api.Container) {
 	requests := container.Resources.Requests
 
@@ -243,13 +282,74 @@ func updateContainerResourcesAndLimits(metricSet *metrics.Set, container kube_ap
 	}
 }
 
-func (pbe *PodBasedEnricher) addContainerStatus(collectionTime time.Time, containerMs *metrics.Set, metric *metrics.Metric, status kube_api.ContainerStatus) {
+func (pbe *PodBasedEnricher) addContainerStatus(collectionTime time.Time, containerMs *metrics.Set, pod *kube_api.Pod, status kube_api.ContainerStatus) {
 	labels := make(map[string]string, 2)
 
 	containerStateInfo := pbe.findContainerState(collectionTime, status)
 	containerStateInfo.AddMetricTags(labels)
 
-	addLabeledIntMetric(containerMs, metric, labels, int64(containerStateInfo.Value))
+	addLabeledIntMetric(containerMs, &metrics.MetricContainerStatus, labels, int64(containerStateInfo.Value))
+
+	oomKills := pbe.oomKills(collectionTime, metrics.PodContainerKey(pod.Namespace, pod.Name, status.Name), status)
+	addLabeledIntMetric(containerMs, &metrics.MetricOOMKills, pbe.workloadLabels(pod), oomKills)
+}
+
+// oomKills returns the number of times the container was OOM killed since the enricher first saw it. The restarts
+// since the container was last seen are attributed to the OOM killer when the last termination was an OOM kill,
+// so the count stays correct across missed collections. A container first seen after an OOM kill starts at one.
+func (pbe *PodBasedEnricher) oomKills(collectionTime time.Time, key metrics.ResourceKey, status kube_api.ContainerStatus) int64 {
+	terminated := status.LastTerminationState.Terminated
+	oomKilled := terminated != nil && terminated.Reason == oomKilledReason
+
+	count, found := pbe.oomKillCounts[key]
+	if !found {
+		count = &oomKillCount{restarts: status.RestartCount}
+		if oomKilled {
+			count.kills = 1
+		}
+		pbe.oomKillCounts[key] = count
+	} else if restarts := status.RestartCount - count.restarts; restarts > 0 && oomKilled {
+		count.kills += int64(restarts)
+	}
+	// the restart count starts over when a pod is recreated with the same name
+	count.restarts = status.RestartCount
+	count.seen = collectionTime
+	return count.kills
+}
+
+// addThrottledRatio adds the share of throttled CFS periods since the previous collection to a container
+func (pbe *PodBasedEnricher) addThrottledRatio(key metrics.ResourceKey, containerMs *metrics.Set, pod *kube_api.Pod, previousPeriods map[metrics.ResourceKey]cfsPeriods) {
+	current, found := pbe.cfsPeriods[key]
+	if !found {
+		return
+	}
+	previous, found := previousPeriods[key]
+	if !found {
+		return
+	}
+	ratio, ok := throttledRatio(previous, current)
+	if !ok {
+		return
+	}
+	containerMs.LabeledValues = append(containerMs.LabeledValues, metrics.LabeledValue{
+		Name:   metrics.MetricCpuThrottledRatio.Name,
+		Labels: pbe.workloadLabels(pod),
+		Value: metrics.Value{
+			ValueType:  metrics.ValueFloat,
+			FloatValue: ratio,
+		},
+	})
+}
+
+// workloadLabels returns the labels identifying the workload of a pod, empty if the pod has no controller
+func (pbe *PodBasedEnricher) workloadLabels(pod *kube_api.Pod) map[string]string {
+	labels := make(map[string]string, 2)
+	kind, name := pbe.workloads.workload(pod.Namespace, metav1.GetControllerOf(pod))
+	if kind != "" {
+		labels[metrics.LabelWorkloadKind.Key] = kind
+		labels[metrics.LabelWorkloadName.Key] = name
+	}
+	return labels
 }
 
 func (pbe *PodBasedEnricher) findContainerState(collectionTime time.Time, status kube_api.ContainerStatus) util.ContainerStateInfo {
@@ -277,7 +377,7 @@ func (pbe *PodBasedEnricher) updateContainerStatus(metricSets map[metrics.Resour
 			log.Debugf("Container key %s not found", containerKey)
 			continue
 		}
-		pbe.addContainerStatus(collectionTime, containerMs, &metrics.MetricContainerStatus, status)
+		pbe.addContainerStatus(collectionTime, containerMs, pod, status)
 	}
 }
 
@@ -301,10 +401,17 @@ func intValue(value int64) metrics.Value {
 	}
 }
 
-func NewPodBasedEnricher(podLister v1listers.PodLister, labelCopier *util.LabelCopier, collectionInterval time.Duration) *PodBasedEnricher {
+func NewPodBasedEnricher(kubeClient *kube_client.Clientset, podLister v1listers.PodLister, labelCopier *util.LabelCopier, collectionInterval time.Duration) *PodBasedEnricher {
+	return newPodBasedEnricher(newWorkloadResolver(kubeClient, podLister), podLister, labelCopier, collectionInterval)
+}
+
+func newPodBasedEnricher(resolver *workloadResolver, podLister v1listers.PodLister, labelCopier *util.LabelCopier, collectionInterval time.Duration) *PodBasedEnricher {
 	return &PodBasedEnricher{
 		podLister:          podLister,
 		labelCopier:        labelCopier,
 		collectionInterval: collectionInterval,
+		workloads:          resolver,
+		cfsPeriods:         map[metrics.ResourceKey]cfsPeriods{},
+		oomKillCounts:      map[metrics.ResourceKey]*oomKillCount{},
 	}
 }
@@ -18,16 +18,24 @@
 package processors
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/httputil"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sources/prometheus"
 
 	kube_api "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
 	v1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 )
@@ -291,6 +299,117 @@ func TestStatusPassedTermination(t *testing.T) {
 	assert.Equal(t, expectedStatus, processBatch(t, podBasedEnricher, batch2))
 }
 
+func TestOOMKills(t *testing.T) {
+	tc := setup()
+	tc.pod.OwnerReferences = controlledBy("StatefulSet", "db")
+
+	now := time.Now()
+	oomState := createCrashState(now.Add(-10*time.Minute), now.Add(-30*time.Second))
+	oomState.Terminated.Reason = "OOMKilled"
+	tc.pod.Status.ContainerStatuses = []kube_api.ContainerStatus{
+		{
+			Name:                 "c1",
+			State:                createGoodState(now.Add(-5 * time.Second)),
+			LastTerminationState: oomState,
+			RestartCount:         1,
+		},
+	}
+	podBasedEnricher := createEnricher(t, tc)
+
+	tc.batch.Timestamp = now
+	batch, err := podBasedEnricher.Process(tc.batch)
+	assert.NoError(t, err)
+
+	containerMs := batch.Sets[metrics.PodContainerKey("ns1", "pod1", "c1")]
+	expected := metrics.LabeledValue{
+		Name: metrics.MetricOOMKills.Name,
+		Labels: map[string]string{
+			metrics.LabelWorkloadKind.Key: "StatefulSet",
+			metrics.LabelWorkloadName.Key: "db",
+		},
+		Value: metrics.Value{ValueType: metrics.ValueInt64, IntValue: 1},
+	}
+	assert.Contains(t, containerMs.LabeledValues, expected)
+
+	batch2 := createContainerBatch()
+	batch2.Timestamp = now.Add(tc.collectionInterval)
+	batch2, err = podBasedEnricher.Process(batch2)
+	assert.NoError(t, err)
+
+	containerMs = batch2.Sets[metrics.PodContainerKey("ns1", "pod1", "c1")]
+	assert.Contains(t, containerMs.LabeledValues, expected, "the OOM kill is only counted once")
+
+	// a missed collection does not lose the restarts in between
+	tc.pod.Status.ContainerStatuses[0].RestartCount = 4
+	batch3 := createContainerBatch()
+	batch3.Timestamp = now.Add(3 * tc.collectionInterval)
+	batch3, err = podBasedEnricher.Process(batch3)
+	assert.NoError(t, err)
+
+	containerMs = batch3.Sets[metrics.PodContainerKey("ns1", "pod1", "c1")]
+	expected.Value.IntValue = 4
+	assert.Contains(t, containerMs.LabeledValues, expected, "every restart since the container was last seen is counted")
+
+	tc.pod.Status.ContainerStatuses[0].LastTerminationState = createCrashState(now.Add(-time.Minute), now)
+	tc.pod.Status.ContainerStatuses[0].RestartCount = 5
+	batch4 := createContainerBatch()
+	batch4.Timestamp = now.Add(4 * tc.collectionInterval)
+	batch4, err = podBasedEnricher.Process(batch4)
+	assert.NoError(t, err)
+
+	containerMs = batch4.Sets[metrics.PodContainerKey("ns1", "pod1", "c1")]
+	assert.Contains(t, containerMs.LabeledValues, expected, "restarts after other terminations are not counted")
+}
+
+func TestThrottledRatio(t *testing.T) {
+	tc := setup()
+	podBasedEnricher := createEnricher(t, tc)
+
+	// the points are built by the prometheus source as configured for the cAdvisor endpoint
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	src, err := prometheus.NewPrometheusMetricsSource(server.URL, "kubernetes.cadvisor.", "node1", "", nil, nil, prometheus.ScrapeOptions{}, httputil.ClientConfig{})
+	require.NoError(t, err)
+
+	cfsBatch := func(throttled, total float64) *metrics.Batch {
+		body = fmt.Sprintf(`# TYPE container_cpu_cfs_periods_total counter
+container_cpu_cfs_periods_total{container="c1",namespace="ns1",pod="pod1"} %v
+container_cpu_cfs_periods_total{container="",namespace="ns1",pod="pod1"} 1000
+# TYPE container_cpu_cfs_throttled_periods_total counter
+container_cpu_cfs_throttled_periods_total{container="c1",namespace="ns1",pod="pod1"} %v
+`, total, throttled)
+		scraped, err := src.Scrape()
+		require.NoError(t, err)
+		batch := createContainerBatch()
+		batch.Metrics = scraped.Metrics
+		return batch
+	}
+	throttledRatio := func(batch *metrics.Batch) (metrics.LabeledValue, bool) {
+		batch, err := podBasedEnricher.Process(batch)
+		assert.NoError(t, err)
+		for _, value := range batch.Sets[metrics.PodContainerKey("ns1", "pod1", "c1")].LabeledValues {
+			if value.Name == metrics.MetricCpuThrottledRatio.Name {
+				return value, true
+			}
+		}
+		return metrics.LabeledValue{}, false
+	}
+
+	_, found := throttledRatio(cfsBatch(10, 100))
+	assert.False(t, found, "a ratio requires a previous collection")
+
+	ratio, found := throttledRatio(cfsBatch(40, 200))
+	assert.True(t, found)
+	assert.Equal(t, 0.3, ratio.FloatValue)
+
+	_, found = throttledRatio(cfsBatch(0, 10))
+	assert.False(t, found, "no ratio is reported when the counters are reset")
+}
+
 func processBatch(t assert.TestingT, podBasedEnricher *PodBasedEnricher, batch *metrics.Batch) metrics.LabeledValue {
 	var err error
 	batch, err = podBasedEnricher.Process(batch)
@@ -313,7 +432,12 @@ func createEnricher(t *testing.T, tc *enricherTestContext) *PodBasedEnricher {
 	labelCopier, err := util.NewLabelCopier(",", []string{}, []string{})
 	assert.NoError(t, err)
 
-	return NewPodBasedEnricher(podLister, labelCopier, tc.collectionInterval)
+	resolver := &workloadResolver{
+		podLister:        podLister,
+		replicaSetLister: appsv1listers.NewReplicaSetLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		jobLister:        batchv1listers.NewJobLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+	}
+	return newPodBasedEnricher(resolver, podLister, labelCopier, tc.collectionInterval)
 }
 
 func checkRequests(t *testing.T, ms *metrics.Set, cpu, mem, storage, other int64) {
@@ -0,0 +1,131 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+)
+
+// recommenderStateKey is the ConfigMap key holding the gzipped usage history
+const recommenderStateKey = "state.json.gz"
+
+// recommenderStore persists the usage history of the resource recommender across restarts
+type recommenderStore interface {
+	// load returns the persisted state, nil if nothing was persisted yet
+	load() (*recommenderState, error)
+	// save persists a state encoded with encodeState
+	save(data []byte) error
+}
+
+func encodeState(state *recommenderState) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeState(data []byte) (*recommenderState, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var state recommenderState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// fileStore persists the state to a local file, for example on a persistent volume
+type fileStore struct {
+	path string
+}
+
+func (s fileStore) load() (*recommenderState, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeState(data)
+}
+
+func (s fileStore) save(data []byte) error {
+	// replace the file atomically so that a crash does not leave a partial state
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// configMapStore persists the state to a ConfigMap the collector creates if needed
+type configMapStore struct {
+	client    kube_client.Interface
+	namespace string
+	name      string
+}
+
+func (s *configMapStore) load() (*recommenderState, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(context.Background(), s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := cm.BinaryData[recommenderStateKey]
+	if !ok {
+		return nil, nil
+	}
+	return decodeState(data)
+}
+
+func (s *configMapStore) save(data []byte) error {
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(context.Background(), s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(context.Background(), &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+			BinaryData: map[string][]byte{recommenderStateKey: data},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.BinaryData == nil {
+		cm.BinaryData = map[string][]byte{}
+	}
+	cm.BinaryData[recommenderStateKey] = data
+	_, err = configMaps.Update(context.Background(), cm, metav1.UpdateOptions{})
+	return err
+}
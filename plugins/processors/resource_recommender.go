@@ -0,0 +1,389 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+
+	kube_client "k8s.io/client-go/kubernetes"
+	v1listers "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	defaultRecommendationWindow = 7 * 24 * time.Hour
+	defaultCPUPercentile        = 90
+	defaultMemoryPercentile     = 95
+	defaultSafetyMargin         = 0.15
+
+	// the window is divided into slots so that usage older than the window can be forgotten
+	recommendationSlots     = 14
+	recommendationSaveEvery = 10 * time.Minute
+	usageBucketGrowth       = 1.1
+	minCPUUsageBucket       = 1.0     // millicores
+	minMemoryUsageBucket    = 1 << 20 // bytes
+)
+
+// usageHistogram counts samples in exponentially growing buckets. Bucket 0 holds the samples below the minimum
+// and bucket i the samples up to min*growth^i.
+type usageHistogram map[int]float64
+
+func (h usageHistogram) add(value, min float64) {
+	bucket := 0
+	if value >= min {
+		bucket = int(math.Floor(math.Log(value/min)/math.Log(usageBucketGrowth))) + 1
+	}
+	h[bucket]++
+}
+
+// percentile returns the upper bound of the bucket holding the given percentile of the samples
+func percentile(histograms []usageHistogram, p, min float64) (float64, bool) {
+	merged := usageHistogram{}
+	total := 0.0
+	for _, h := range histograms {
+		for bucket, count := range h {
+			merged[bucket] += count
+			total += count
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	buckets := make([]int, 0, len(merged))
+	for bucket := range merged {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+	threshold := p / 100 * total
+	seen := 0.0
+	for _, bucket := range buckets {
+		seen += merged[bucket]
+		if seen >= threshold {
+			return min * math.Pow(usageBucketGrowth, float64(bucket)), true
+		}
+	}
+	return min * math.Pow(usageBucketGrowth, float64(buckets[len(buckets)-1])), true
+}
+
+type usageSlot struct {
+	Start  int64          `json:"start"`
+	CPU    usageHistogram `json:"cpu"`
+	Memory usageHistogram `json:"memory"`
+}
+
+// containerUsage is the usage history of a container of a workload, merged across its pods
+type containerUsage struct {
+	Namespace     string       `json:"namespace"`
+	Kind          string       `json:"kind"`
+	Workload      string       `json:"workload"`
+	Container     string       `json:"container"`
+	CPURequest    int64        `json:"cpuRequest"`
+	MemoryRequest int64        `json:"memoryRequest"`
+	Slots         []*usageSlot `json:"slots"`
+}
+
+func (u *containerUsage) slot(start int64) *usageSlot {
+	if n := len(u.Slots); n > 0 && u.Slots[n-1].Start == start {
+		return u.Slots[n-1]
+	}
+	slot := &usageSlot{Start: start, CPU: usageHistogram{}, Memory: usageHistogram{}}
+	u.Slots = append(u.Slots, slot)
+	return slot
+}
+
+// expire removes the slots that started before the given time
+func (u *containerUsage) expire(before int64) {
+	i := 0
+	for i < len(u.Slots) && u.Slots[i].Start < before {
+		i++
+	}
+	u.Slots = u.Slots[i:]
+}
+
+type recommenderState struct {
+	Containers map[string]*containerUsage `json:"containers"`
+}
+
+// ResourceRecommender keeps a rolling window of the CPU usage and memory working set of the containers of each
+// workload and reports the requests recommended for the workload
+type ResourceRecommender struct {
+	resolver         *workloadResolver
+	store            recommenderStore
+	window           time.Duration
+	cpuPercentile    float64
+	memoryPercentile float64
+	safetyMargin     float64
+	state            *recommenderState
+	lastSave         time.Time
+
+	// the state waiting to be saved in the background, only the latest snapshot is kept
+	saveMtx sync.Mutex
+	pending []byte
+	saving  bool
+	saves   sync.WaitGroup
+}
+
+func (r *ResourceRecommender) Name() string {
+	return "resource_recommender"
+}
+
+func (r *ResourceRecommender) Process(batch *metrics.Batch) (*metrics.Batch, error) {
+	slotDuration := r.window / recommendationSlots
+	slotStart := batch.Timestamp.Truncate(slotDuration).Unix()
+	windowStart := batch.Timestamp.Add(-r.window).Unix()
+
+	active := map[metrics.ResourceKey][]*containerUsage{}
+	namespaceUIDs := map[metrics.ResourceKey]string{}
+	for _, set := range batch.Sets {
+		if !isType(metrics.MetricSetTypePodContainer)(set) || !podContainerTakesUpResources(set) {
+			continue
+		}
+		namespace := set.Labels[metrics.LabelNamespaceName.Key]
+		kind, name := r.resolver.resolve(namespace, set.Labels[metrics.LabelPodName.Key])
+		if kind == "" {
+			continue
+		}
+		container := set.Labels[metrics.LabelContainerName.Key]
+		key := strings.Join([]string{namespace, kind, name, container}, "/")
+		usage := r.state.Containers[key]
+		if usage == nil {
+			usage = &containerUsage{Namespace: namespace, Kind: kind, Workload: name, Container: container}
+			r.state.Containers[key] = usage
+		}
+
+		slot := usage.slot(slotStart)
+		if value, found := set.Values[metrics.MetricCpuUsageRate.Name]; found {
+			slot.CPU.add(floatValue(value), minCPUUsageBucket)
+		}
+		if value, found := set.Values[metrics.MetricMemoryWorkingSet.Name]; found {
+			slot.Memory.add(floatValue(value), minMemoryUsageBucket)
+		}
+		usage.CPURequest = getInt(set, &metrics.MetricCpuRequest)
+		usage.MemoryRequest = getInt(set, &metrics.MetricMemoryRequest)
+
+		workloadKey := metrics.WorkloadKey(namespace, kind, name)
+		if !containsUsage(active[workloadKey], usage) {
+			active[workloadKey] = append(active[workloadKey], usage)
+		}
+		namespaceUIDs[workloadKey] = set.Labels[metrics.LabelPodNamespaceUID.Key]
+	}
+
+	for key, usage := range r.state.Containers {
+		usage.expire(windowStart)
+		if len(usage.Slots) == 0 {
+			delete(r.state.Containers, key)
+		}
+	}
+
+	for workloadKey, containers := range active {
+		workloadSet := batch.Sets[workloadKey]
+		if workloadSet == nil {
+			first := containers[0]
+			workloadSet = workloadMetricSet(first.Namespace, namespaceUIDs[workloadKey], first.Kind, first.Workload)
+			batch.Sets[workloadKey] = workloadSet
+		}
+		r.recommend(workloadSet, containers)
+	}
+
+	if batch.Timestamp.Sub(r.lastSave) >= recommendationSaveEvery {
+		r.save(batch.Timestamp)
+	}
+	return batch, nil
+}
+
+// recommend sets the recommended requests of a workload, the sum of the recommendations of its containers
+func (r *ResourceRecommender) recommend(workloadSet *metrics.Set, containers []*containerUsage) {
+	var cpu, memory float64
+	var cpuRequest, memoryRequest int64
+	cpuFound, memoryFound := false, false
+	for _, usage := range containers {
+		cpuHistograms := make([]usageHistogram, 0, len(usage.Slots))
+		memoryHistograms := make([]usageHistogram, 0, len(usage.Slots))
+		for _, slot := range usage.Slots {
+			cpuHistograms = append(cpuHistograms, slot.CPU)
+			memoryHistograms = append(memoryHistograms, slot.Memory)
+		}
+		if value, ok := percentile(cpuHistograms, r.cpuPercentile, minCPUUsageBucket); ok {
+			cpu += value
+			cpuFound = true
+		}
+		if value, ok := percentile(memoryHistograms, r.memoryPercentile, minMemoryUsageBucket); ok {
+			memory += value
+			memoryFound = true
+		}
+		cpuRequest += usage.CPURequest
+		memoryRequest += usage.MemoryRequest
+	}
+	if cpuFound {
+		cpu *= 1 + r.safetyMargin
+		setFloat(workloadSet, &metrics.MetricRecommendationCpuRequest, cpu)
+		if cpuRequest > 0 {
+			setFloat(workloadSet, &metrics.MetricRecommendationCpuProvisioningRatio, float64(cpuRequest)/cpu)
+		}
+	}
+	if memoryFound {
+		memory *= 1 + r.safetyMargin
+		setFloat(workloadSet, &metrics.MetricRecommendationMemoryRequest, memory)
+		if memoryRequest > 0 {
+			setFloat(workloadSet, &metrics.MetricRecommendationMemoryProvisioningRatio, float64(memoryRequest)/memory)
+		}
+	}
+}
+
+// save encodes a snapshot of the state and persists it in the background so that a slow store does not delay
+// the processing of the batch
+func (r *ResourceRecommender) save(now time.Time) {
+	r.lastSave = now
+	if r.store == nil {
+		return
+	}
+	data, err := encodeState(r.state)
+	if err != nil {
+		log.Warningf("unable to encode the resource recommender state: %v", err)
+		return
+	}
+	r.saveMtx.Lock()
+	defer r.saveMtx.Unlock()
+	r.pending = data
+	if !r.saving {
+		r.saving = true
+		r.saves.Add(1)
+		go r.savePending()
+	}
+}
+
+// savePending saves the pending snapshots until none is left
+func (r *ResourceRecommender) savePending() {
+	defer r.saves.Done()
+	for {
+		r.saveMtx.Lock()
+		data := r.pending
+		r.pending = nil
+		if data == nil {
+			r.saving = false
+			r.saveMtx.Unlock()
+			return
+		}
+		r.saveMtx.Unlock()
+		if err := r.store.save(data); err != nil {
+			log.Warningf("unable to save the resource recommender state: %v", err)
+		}
+	}
+}
+
+func containsUsage(usages []*containerUsage, usage *containerUsage) bool {
+	for _, u := range usages {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
+
+func floatValue(value metrics.Value) float64 {
+	if value.ValueType == metrics.ValueFloat {
+		return value.FloatValue
+	}
+	return float64(value.IntValue)
+}
+
+// NewResourceRecommender returns a processor recommending the requests of workloads. The usage history is
+// loaded from and persisted to the configured file or ConfigMap.
+func NewResourceRecommender(kubeClient *kube_client.Clientset, podLister v1listers.PodLister, cfg *configuration.RecommendationConfig) (*ResourceRecommender, error) {
+	var store recommenderStore
+	if cfg != nil && cfg.StateFile != "" {
+		store = fileStore{path: cfg.StateFile}
+	} else if cfg != nil && cfg.StateConfigMap != "" {
+		namespace, name, _ := splitNamespacedName(cfg.StateConfigMap)
+		if util.ScrapeOnlyOwnNode() {
+			// every agent of the DaemonSet keeps the history of the containers of its node
+			name = name + "-" + util.GetNodeName()
+		}
+		store = &configMapStore{client: kubeClient, namespace: namespace, name: name}
+	}
+	return newResourceRecommender(newWorkloadResolver(kubeClient, podLister), store, cfg), nil
+}
+
+func newResourceRecommender(resolver *workloadResolver, store recommenderStore, cfg *configuration.RecommendationConfig) *ResourceRecommender {
+	r := &ResourceRecommender{
+		resolver:         resolver,
+		store:            store,
+		window:           defaultRecommendationWindow,
+		cpuPercentile:    defaultCPUPercentile,
+		memoryPercentile: defaultMemoryPercentile,
+		safetyMargin:     defaultSafetyMargin,
+		state:            &recommenderState{Containers: map[string]*containerUsage{}},
+	}
+	if cfg != nil {
+		if cfg.Window > 0 {
+			r.window = cfg.Window
+		}
+		if cfg.CPUPercentile > 0 {
+			r.cpuPercentile = cfg.CPUPercentile
+		}
+		if cfg.MemoryPercentile > 0 {
+			r.memoryPercentile = cfg.MemoryPercentile
+		}
+		if cfg.SafetyMargin != nil {
+			r.safetyMargin = *cfg.SafetyMargin
+		}
+	}
+	if store != nil {
+		state, err := store.load()
+		if err != nil {
+			log.Warningf("unable to load the resource recommender state, starting without usage history: %v", err)
+		} else if state != nil && state.Containers != nil {
+			r.state = state
+		}
+	}
+	return r
+}
+
+func validateRecommendation(cfg configuration.ProcessorConfig) error {
+	if err := supports(cfg, "recommendation"); err != nil {
+		return err
+	}
+	rc := cfg.Recommendation
+	if rc == nil {
+		return nil
+	}
+	if rc.Window < 0 {
+		return fmt.Errorf("window must not be negative")
+	}
+	for name, p := range map[string]float64{"cpuPercentile": rc.CPUPercentile, "memoryPercentile": rc.MemoryPercentile} {
+		if p < 0 || p > 100 {
+			return fmt.Errorf("%s must be between 0 and 100", name)
+		}
+	}
+	if rc.SafetyMargin != nil && *rc.SafetyMargin < 0 {
+		return fmt.Errorf("safetyMargin must not be negative")
+	}
+	if rc.StateFile != "" && rc.StateConfigMap != "" {
+		return fmt.Errorf("only one of stateFile and stateConfigMap can be set")
+	}
+	if rc.StateConfigMap != "" {
+		if _, _, err := splitNamespacedName(rc.StateConfigMap); err != nil {
+			return fmt.Errorf("stateConfigMap: %v", err)
+		}
+	}
+	return nil
+}
+
+func splitNamespacedName(s string) (string, string, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("expected namespace/name, got %q", s)
+	}
+	return parts[0], parts[1], nil
+}
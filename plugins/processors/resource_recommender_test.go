@@ -0,0 +1,157 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
+	v1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func recommenderResolver(t *testing.T) *workloadResolver {
+	pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, name := range []string{"db-0", "db-1"} {
+		require.NoError(t, pods.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1", Name: name, OwnerReferences: controlledBy("StatefulSet", "db"),
+		}}))
+	}
+	empty := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	return &workloadResolver{
+		podLister:        v1listers.NewPodLister(pods),
+		replicaSetLister: appsv1listers.NewReplicaSetLister(empty),
+		jobLister:        batchv1listers.NewJobLister(empty),
+	}
+}
+
+func recommenderBatch(ts time.Time, cpu, memory int64) *metrics.Batch {
+	batch := &metrics.Batch{Timestamp: ts, Sets: map[metrics.ResourceKey]*metrics.Set{}}
+	for _, pod := range []string{"db-0", "db-1"} {
+		batch.Sets[metrics.PodContainerKey("ns1", pod, "db")] = &metrics.Set{
+			Labels: map[string]string{
+				metrics.LabelMetricSetType.Key: metrics.MetricSetTypePodContainer,
+				metrics.LabelNamespaceName.Key: "ns1",
+				metrics.LabelPodName.Key:       pod,
+				metrics.LabelContainerName.Key: "db",
+			},
+			Values: map[string]metrics.Value{
+				metrics.MetricCpuUsageRate.Name:     intValue(cpu),
+				metrics.MetricMemoryWorkingSet.Name: intValue(memory),
+				metrics.MetricCpuRequest.Name:       intValue(1000),
+				metrics.MetricMemoryRequest.Name:    intValue(256 << 20),
+			},
+		}
+	}
+	return batch
+}
+
+func TestResourceRecommender(t *testing.T) {
+	zero := 0.0
+	recommender := newResourceRecommender(recommenderResolver(t), nil, &configuration.RecommendationConfig{
+		Window:        24 * time.Hour,
+		CPUPercentile: 90,
+		SafetyMargin:  &zero,
+	})
+
+	start := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	var batch *metrics.Batch
+	for i := int64(1); i <= 100; i++ {
+		var err error
+		batch, err = recommender.Process(recommenderBatch(start.Add(time.Duration(i)*time.Minute), i*10, 100<<20))
+		require.NoError(t, err)
+	}
+
+	workload := batch.Sets[metrics.WorkloadKey("ns1", "StatefulSet", "db")]
+	require.NotNil(t, workload)
+	assert.Equal(t, "db", workload.Labels[metrics.LabelWorkloadName.Key])
+
+	// the 90th percentile of 10..1000 millicores is 900, reported as the upper bound of its bucket
+	cpu := workload.Values[metrics.MetricRecommendationCpuRequest.Name].FloatValue
+	assert.InDelta(t, 900, cpu, 900*(usageBucketGrowth-1))
+	assert.InDelta(t, 1000/cpu, workload.Values[metrics.MetricRecommendationCpuProvisioningRatio.Name].FloatValue, 0.001)
+
+	memory := workload.Values[metrics.MetricRecommendationMemoryRequest.Name].FloatValue
+	assert.InDelta(t, 100<<20, memory, (100<<20)*(usageBucketGrowth-1))
+	assert.Greater(t, workload.Values[metrics.MetricRecommendationMemoryProvisioningRatio.Name].FloatValue, 2.0,
+		"requesting 256MiB for a 100MiB working set is over-provisioned")
+
+	t.Run("usage older than the window is forgotten", func(t *testing.T) {
+		batch, err := recommender.Process(recommenderBatch(start.Add(48*time.Hour), 50, 10<<20))
+		require.NoError(t, err)
+		workload := batch.Sets[metrics.WorkloadKey("ns1", "StatefulSet", "db")]
+		assert.InDelta(t, 50, workload.Values[metrics.MetricRecommendationCpuRequest.Name].FloatValue, 50*(usageBucketGrowth-1))
+		assert.Len(t, recommender.state.Containers, 1)
+	})
+}
+
+func TestRecommenderStores(t *testing.T) {
+	for name, store := range map[string]recommenderStore{
+		"file":      fileStore{path: filepath.Join(t.TempDir(), "state")},
+		"configmap": &configMapStore{client: fake.NewSimpleClientset(), namespace: "wavefront", name: "recommender"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			state, err := store.load()
+			require.NoError(t, err)
+			assert.Nil(t, state, "nothing is loaded before the first save")
+
+			recommender := newResourceRecommender(recommenderResolver(t), store, nil)
+			start := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+			_, err = recommender.Process(recommenderBatch(start, 500, 100<<20))
+			require.NoError(t, err)
+			_, err = recommender.Process(recommenderBatch(start.Add(recommendationSaveEvery), 500, 100<<20))
+			require.NoError(t, err)
+			recommender.saves.Wait()
+
+			restarted := newResourceRecommender(recommenderResolver(t), store, nil)
+			require.Contains(t, restarted.state.Containers, "ns1/StatefulSet/db/db")
+			usage := restarted.state.Containers["ns1/StatefulSet/db/db"]
+			assert.Equal(t, int64(1000), usage.CPURequest)
+			require.Len(t, usage.Slots, 1)
+			assert.Equal(t, 4.0, sumCounts(usage.Slots[0].CPU), "both saves are persisted")
+		})
+	}
+}
+
+func TestValidateRecommendation(t *testing.T) {
+	negative := -0.1
+	for _, tc := range []struct {
+		cfg configuration.RecommendationConfig
+		err string
+	}{
+		{cfg: configuration.RecommendationConfig{Window: 72 * time.Hour, StateConfigMap: "wavefront/recommender"}},
+		{cfg: configuration.RecommendationConfig{CPUPercentile: 101}, err: "cpuPercentile must be between 0 and 100"},
+		{cfg: configuration.RecommendationConfig{SafetyMargin: &negative}, err: "safetyMargin must not be negative"},
+		{cfg: configuration.RecommendationConfig{StateFile: "/a", StateConfigMap: "b/c"}, err: "only one of stateFile and stateConfigMap can be set"},
+		{cfg: configuration.RecommendationConfig{StateConfigMap: "recommender"}, err: `stateConfigMap: expected namespace/name, got "recommender"`},
+	} {
+		cfg := tc.cfg
+		err := ValidateConfig(configuration.ProcessorConfig{Type: ResourceRecommenderType, Recommendation: &cfg})
+		if tc.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tc.err)
+		}
+	}
+}
+
+func sumCounts(h usageHistogram) float64 {
+	total := 0.0
+	for _, count := range h {
+		total += count
+	}
+	return total
+}
@@ -37,7 +37,9 @@ func (a *SumCountAggregator) Process(batch *metrics.Batch) (*metrics.Batch, erro
 			if groupSet == nil {
 				continue
 			}
-			aggregateCount(resourceSet, groupSet, spec.ResourceCountMetric)
+			if spec.ResourceCountMetric != "" {
+				aggregateCount(resourceSet, groupSet, spec.ResourceCountMetric)
+			}
 			if err := aggregate(resourceSet, groupSet, spec.ResourceSumMetrics); err != nil {
 				return nil, err
 			}
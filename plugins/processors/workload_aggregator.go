@@ -0,0 +1,115 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
+	v1listers "k8s.io/client-go/listers/core/v1"
+)
+
+// workloadResolver resolves the workload of a pod by following its controller owner references
+type workloadResolver struct {
+	podLister        v1listers.PodLister
+	replicaSetLister appsv1listers.ReplicaSetLister
+	jobLister        batchv1listers.JobLister
+}
+
+// NewWorkloadAggregator returns a processor summing the metrics of running pods into the metric set of the
+// workload owning them. Pods owned by a ReplicaSet are aggregated into its Deployment and pods owned by a Job
+// into its CronJob. Pods without a controller are not aggregated.
+func NewWorkloadAggregator(kubeClient *kube_client.Clientset, podLister v1listers.PodLister, metricsToAggregate []string) *SumCountAggregator {
+	return newWorkloadAggregator(newWorkloadResolver(kubeClient, podLister), metricsToAggregate)
+}
+
+func newWorkloadResolver(kubeClient *kube_client.Clientset, podLister v1listers.PodLister) *workloadResolver {
+	return &workloadResolver{
+		podLister:        podLister,
+		replicaSetLister: util.GetReplicaSetLister(kubeClient),
+		jobLister:        util.GetJobLister(kubeClient),
+	}
+}
+
+func newWorkloadAggregator(resolver *workloadResolver, metricsToAggregate []string) *SumCountAggregator {
+	return NewSumCountAggregator("workload", []SumCountAggregateSpec{
+		{
+			ResourceSumMetrics:  metricsToAggregate,
+			ResourceCountMetric: metrics.MetricPodCount.Name,
+			IsPartOfGroup:       isAggregatablePod,
+			Group:               resolver.group,
+		},
+	})
+}
+
+func (r *workloadResolver) group(batch *metrics.Batch, resourceKey metrics.ResourceKey, resourceSet *metrics.Set) (metrics.ResourceKey, *metrics.Set) {
+	namespace := resourceSet.Labels[metrics.LabelNamespaceName.Key]
+	podName := resourceSet.Labels[metrics.LabelPodName.Key]
+	if namespace == "" || podName == "" {
+		log.Errorf("no namespace and/or pod info in pod %s: %v", resourceKey, resourceSet.Labels)
+		return "", nil
+	}
+	kind, name := r.resolve(namespace, podName)
+	if kind == "" {
+		return "", nil
+	}
+	workloadKey := metrics.WorkloadKey(namespace, kind, name)
+	workloadSet := batch.Sets[workloadKey]
+	if workloadSet == nil {
+		workloadSet = workloadMetricSet(namespace, resourceSet.Labels[metrics.LabelPodNamespaceUID.Key], kind, name)
+	}
+	return workloadKey, workloadSet
+}
+
+// resolve returns the kind and name of the workload of a pod, empty if the pod has no controller
+func (r *workloadResolver) resolve(namespace, podName string) (string, string) {
+	pod, err := r.podLister.Pods(namespace).Get(podName)
+	if err != nil {
+		log.Debugf("unable to find pod %s: %v", metrics.PodKey(namespace, podName), err)
+		return "", ""
+	}
+	return r.workload(namespace, metav1.GetControllerOf(pod))
+}
+
+// workload returns the kind and name of the top level controller of an owner. The owner itself is returned
+// if its controller is not cached yet.
+func (r *workloadResolver) workload(namespace string, owner *metav1.OwnerReference) (string, string) {
+	if owner == nil {
+		return "", ""
+	}
+	switch owner.Kind {
+	case "ReplicaSet":
+		if rs, err := r.replicaSetLister.ReplicaSets(namespace).Get(owner.Name); err == nil {
+			if ref := metav1.GetControllerOf(rs); ref != nil && ref.Kind == "Deployment" {
+				return ref.Kind, ref.Name
+			}
+		}
+	case "Job":
+		if job, err := r.jobLister.Jobs(namespace).Get(owner.Name); err == nil {
+			if ref := metav1.GetControllerOf(job); ref != nil && ref.Kind == "CronJob" {
+				return ref.Kind, ref.Name
+			}
+		}
+	}
+	return owner.Kind, owner.Name
+}
+
+func workloadMetricSet(namespace, namespaceUID, kind, name string) *metrics.Set {
+	return &metrics.Set{
+		Values: make(map[string]metrics.Value),
+		Labels: map[string]string{
+			metrics.LabelMetricSetType.Key:   metrics.MetricSetTypeWorkload,
+			metrics.LabelNamespaceName.Key:   namespace,
+			metrics.LabelPodNamespaceUID.Key: namespaceUID,
+			metrics.LabelWorkloadKind.Key:    kind,
+			metrics.LabelWorkloadName.Key:    name,
+		},
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package processors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
+	v1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func controlledBy(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func workloadPodSet(namespace, name string, phase corev1.PodPhase, cpu int64) *metrics.Set {
+	return &metrics.Set{
+		Labels: map[string]string{
+			metrics.LabelMetricSetType.Key: metrics.MetricSetTypePod,
+			metrics.LabelNamespaceName.Key: namespace,
+			metrics.LabelPodName.Key:       name,
+		},
+		Values: map[string]metrics.Value{
+			metrics.MetricCpuRequest.Name: {ValueType: metrics.ValueInt64, IntValue: cpu},
+		},
+		LabeledValues: []metrics.LabeledValue{{
+			Name:   metrics.MetricPodPhase.Name,
+			Labels: map[string]string{"phase": string(phase)},
+			Value:  metrics.Value{ValueType: metrics.ValueInt64, IntValue: util.ConvertPodPhase(phase)},
+		}},
+	}
+}
+
+func TestWorkloadAggregator(t *testing.T) {
+	pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	replicaSets := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	jobs := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pod := range []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "web-1", OwnerReferences: controlledBy("ReplicaSet", "web-5d8f")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "web-2", OwnerReferences: controlledBy("ReplicaSet", "web-5d8f")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "web-3", OwnerReferences: controlledBy("ReplicaSet", "web-5d8f")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "db-0", OwnerReferences: controlledBy("StatefulSet", "db")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "backup-1", OwnerReferences: controlledBy("Job", "backup-27")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "static"}},
+	} {
+		require.NoError(t, pods.Add(pod))
+	}
+	require.NoError(t, replicaSets.Add(&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "ns1", Name: "web-5d8f", OwnerReferences: controlledBy("Deployment", "web"),
+	}}))
+	require.NoError(t, jobs.Add(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "ns1", Name: "backup-27", OwnerReferences: controlledBy("CronJob", "backup"),
+	}}))
+
+	aggregator := newWorkloadAggregator(&workloadResolver{
+		podLister:        v1listers.NewPodLister(pods),
+		replicaSetLister: appsv1listers.NewReplicaSetLister(replicaSets),
+		jobLister:        batchv1listers.NewJobLister(jobs),
+	}, []string{metrics.MetricCpuRequest.Name})
+	assert.Equal(t, "workload_aggregator", aggregator.Name())
+
+	batch, err := aggregator.Process(&metrics.Batch{
+		Timestamp: time.Now(),
+		Sets: map[metrics.ResourceKey]*metrics.Set{
+			metrics.PodKey("ns1", "web-1"):    workloadPodSet("ns1", "web-1", corev1.PodRunning, 100),
+			metrics.PodKey("ns1", "web-2"):    workloadPodSet("ns1", "web-2", corev1.PodRunning, 200),
+			metrics.PodKey("ns1", "web-3"):    workloadPodSet("ns1", "web-3", corev1.PodSucceeded, 400),
+			metrics.PodKey("ns1", "db-0"):     workloadPodSet("ns1", "db-0", corev1.PodRunning, 500),
+			metrics.PodKey("ns1", "backup-1"): workloadPodSet("ns1", "backup-1", corev1.PodRunning, 50),
+			metrics.PodKey("ns1", "static"):   workloadPodSet("ns1", "static", corev1.PodRunning, 10),
+		},
+	})
+	require.NoError(t, err)
+	assert.Len(t, batch.Sets, 9, "pods without a controller are not aggregated")
+
+	for _, tc := range []struct {
+		kind, name string
+		cpu, pods  int64
+	}{
+		{kind: "Deployment", name: "web", cpu: 300, pods: 2},
+		{kind: "StatefulSet", name: "db", cpu: 500, pods: 1},
+		{kind: "CronJob", name: "backup", cpu: 50, pods: 1},
+	} {
+		set := batch.Sets[metrics.WorkloadKey("ns1", tc.kind, tc.name)]
+		require.NotNil(t, set, tc.kind)
+		assert.Equal(t, metrics.MetricSetTypeWorkload, set.Labels[metrics.LabelMetricSetType.Key])
+		assert.Equal(t, tc.kind, set.Labels[metrics.LabelWorkloadKind.Key])
+		assert.Equal(t, tc.name, set.Labels[metrics.LabelWorkloadName.Key])
+		assert.Equal(t, "ns1", set.Labels[metrics.LabelNamespaceName.Key])
+		assert.Equal(t, tc.cpu, set.Values[metrics.MetricCpuRequest.Name].IntValue, tc.kind)
+		assert.Equal(t, tc.pods, set.Values[metrics.MetricPodCount.Name].IntValue, tc.kind)
+	}
+}
+
+func TestWorkloadResolverUncachedOwner(t *testing.T) {
+	empty := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	resolver := &workloadResolver{
+		replicaSetLister: appsv1listers.NewReplicaSetLister(empty),
+		jobLister:        batchv1listers.NewJobLister(empty),
+	}
+	kind, name := resolver.workload("ns1", &controlledBy("ReplicaSet", "web-5d8f")[0])
+	assert.Equal(t, "ReplicaSet", kind)
+	assert.Equal(t, "web-5d8f", name)
+}
@@ -0,0 +1,308 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package exposition
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	gm "github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/wavefronthq/go-metrics-wavefront/reporting"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/events"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/sinkutil"
+)
+
+const (
+	defaultListenAddress = ":9102"
+	defaultPath          = "/metrics"
+
+	sourceLabel = "source"
+)
+
+const sinkName = "prometheus_exposition_sink"
+
+// sinkCounters are the internal metrics of a sink, tagged with the name of the sink
+type sinkCounters struct {
+	exposedSeries  gm.Gauge
+	filteredSeries gm.Counter
+	scrapes        gm.Counter
+}
+
+func newSinkCounters(name string) sinkCounters {
+	tags := map[string]string{"sink": name}
+	counter := func(key string) gm.Counter {
+		return gm.GetOrRegisterCounter(reporting.EncodeKey(key, tags), gm.DefaultRegistry)
+	}
+	gauge := func(key string) gm.Gauge {
+		return gm.GetOrRegisterGauge(reporting.EncodeKey(key, tags), gm.DefaultRegistry)
+	}
+	return sinkCounters{
+		exposedSeries:  gauge("exposition.series"),
+		filteredSeries: counter("exposition.series.filtered.count"),
+		scrapes:        counter("exposition.scrapes.count"),
+	}
+}
+
+type expositionSink struct {
+	clusterName       string
+	prefix            string
+	globalTags        map[string]string
+	filters           filter.Filter
+	includeTimestamps bool
+	server            *http.Server
+	counters          sinkCounters
+
+	mtx      sync.RWMutex
+	families []*prom.MetricFamily
+}
+
+// NewExpositionSink creates a sink that serves the latest exported batch in the Prometheus text and OpenMetrics
+// formats. The HTTP listener is bound before returning so that address conflicts surface as errors.
+func NewExpositionSink(cfg configuration.SinkConfig) (*expositionSink, error) {
+	expCfg := cfg.Exposition
+	sink := &expositionSink{
+		clusterName:       configuration.GetStringValue(cfg.ClusterName, "k8s-cluster"),
+		globalTags:        cfg.Tags,
+		filters:           filter.FromConfig(cfg.Filters),
+		includeTimestamps: expCfg.IncludeTimestamps,
+		counters:          newSinkCounters(configuration.GetStringValue(cfg.Name, sinkName)),
+	}
+	if cfg.Prefix != "" {
+		sink.prefix = strings.Trim(cfg.Prefix, ".") + "."
+	}
+
+	addr := configuration.GetStringValue(expCfg.ListenAddress, defaultListenAddress)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error starting prometheus exposition listener: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(configuration.GetStringValue(expCfg.Path, defaultPath), sink)
+	sink.server = &http.Server{Handler: mux}
+	go func() {
+		if err := sink.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.WithField("name", sink.Name()).Errorf("prometheus exposition listener failed: %v", err)
+		}
+	}()
+	log.WithField("name", sink.Name()).Infof("exposing metrics on %s", listener.Addr())
+	return sink, nil
+}
+
+// SetFilters replaces the filters of the sink. It is called from the goroutine exporting to the sink.
+func (sink *expositionSink) SetFilters(filters filter.Filter) {
+	sink.filters = filters
+}
+
+func (sink *expositionSink) Name() string {
+	return sinkName
+}
+
+func (sink *expositionSink) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sink.server.Shutdown(ctx); err != nil {
+		log.WithField("name", sink.Name()).Errorf("error stopping prometheus exposition listener: %v", err)
+	}
+}
+
+// Export replaces the exposed metric families with those of the batch.
+func (sink *expositionSink) Export(batch *metrics.Batch) {
+	log.WithField("name", sink.Name()).Debugf("received metric points: %d", len(batch.Metrics))
+
+	families := map[string]*prom.MetricFamily{}
+	seen := map[string]bool{}
+	var series int64
+	for _, metric := range batch.Metrics {
+		if metric == nil {
+			continue
+		}
+		name, labels, ok := sink.series(metric)
+		if !ok {
+			continue
+		}
+		key := seriesKey(name, labels)
+		if seen[key] {
+			log.WithField("name", sink.Name()).Debugf("dropping duplicate series %s", key)
+			continue
+		}
+
+		var m *prom.Metric
+		var metricType prom.MetricType
+		switch mt := metric.(type) {
+		case *wf.Point:
+			if wf.IsStaleMarker(mt.Value) {
+				// stale series are not exposed at all
+				continue
+			}
+			metricType = prom.MetricType_UNTYPED
+			m = &prom.Metric{Untyped: &prom.Untyped{Value: float64Ptr(mt.Value)}}
+			if sink.includeTimestamps && mt.Timestamp > 0 {
+				m.TimestampMs = int64Ptr(sinkutil.ToMillis(mt.Timestamp, time.Time{}))
+			}
+		case *wf.Distribution:
+			metricType = prom.MetricType_HISTOGRAM
+			m = &prom.Metric{Histogram: toHistogram(mt)}
+			if sink.includeTimestamps && !mt.Timestamp.IsZero() {
+				m.TimestampMs = int64Ptr(mt.Timestamp.UnixNano() / int64(time.Millisecond))
+			}
+		default:
+			log.Debugf("unsupported metric type for prometheus exposition: %T", metric)
+			continue
+		}
+		m.Label = labels
+
+		family, exists := families[name]
+		if !exists {
+			family = &prom.MetricFamily{Name: stringPtr(name), Type: &metricType}
+			families[name] = family
+		} else if family.GetType() != metricType {
+			log.WithField("name", sink.Name()).Debugf("dropping %s series with conflicting type %s", name, metricType)
+			continue
+		}
+		family.Metric = append(family.Metric, m)
+		seen[key] = true
+		series++
+	}
+
+	sorted := make([]*prom.MetricFamily, 0, len(families))
+	for _, family := range families {
+		sorted = append(sorted, family)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+
+	sink.mtx.Lock()
+	sink.families = sorted
+	sink.mtx.Unlock()
+	sink.counters.exposedSeries.Update(series)
+}
+
+// ExportEvent is a no-op. The Prometheus exposition formats have no representation for events.
+func (sink *expositionSink) ExportEvent(*events.Event) {
+}
+
+// ServeHTTP writes the latest batch in the format negotiated from the Accept header.
+func (sink *expositionSink) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	sink.counters.scrapes.Inc(1)
+	sink.mtx.RLock()
+	families := sink.families
+	sink.mtx.RUnlock()
+
+	format := expfmt.NegotiateIncludingOpenMetrics(req.Header)
+	w.Header().Set("Content-Type", string(format))
+	enc := expfmt.NewEncoder(w, format)
+	for _, family := range families {
+		if err := enc.Encode(family); err != nil {
+			log.WithField("name", sink.Name()).Errorf("error encoding metric family %s: %v", family.GetName(), err)
+			return
+		}
+	}
+	if closer, ok := enc.(expfmt.Closer); ok {
+		closer.Close()
+	}
+}
+
+// series returns the sanitized name and sorted labels of a metric without modifying the shared metric.
+func (sink *expositionSink) series(metric wf.Metric) (string, []*prom.LabelPair, bool) {
+	tags, ok := sinkutil.Tags(metric, sink.clusterName, sink.globalTags, sink.filters, sink.counters.filteredSeries)
+	if !ok {
+		return "", nil, false
+	}
+
+	var source string
+	switch mt := metric.(type) {
+	case *wf.Point:
+		source = mt.Source
+	case *wf.Distribution:
+		source = mt.Source
+	}
+	if source != "" {
+		tags[sourceLabel] = source
+	}
+
+	labels := make([]*prom.LabelPair, 0, len(tags))
+	for k, v := range tags {
+		if v == "" {
+			continue
+		}
+		labels = append(labels, &prom.LabelPair{Name: stringPtr(util.SanitizePrometheusLabelName(k)), Value: stringPtr(v)})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].GetName() < labels[j].GetName() })
+	return util.SanitizePrometheusName(sink.prefix + metric.Name()), labels, true
+}
+
+// toHistogram converts a distribution into a classic Prometheus histogram. Prometheus style cumulative distributions
+// keep their original buckets. For frequency distributions every centroid value becomes a bucket upper bound.
+func toHistogram(d *wf.Distribution) *prom.Histogram {
+	h := &prom.Histogram{}
+	var count float64
+	if d.Cumulative {
+		// the sum of the observations is not retained by cumulative distributions
+		h.SampleSum = float64Ptr(math.NaN())
+		for _, centroid := range d.Centroids {
+			count = centroid.Count
+			if !math.IsInf(centroid.Value, 1) {
+				h.Bucket = append(h.Bucket, &prom.Bucket{UpperBound: float64Ptr(centroid.Value), CumulativeCount: uint64Ptr(sinkutil.RoundCount(count))})
+			}
+		}
+	} else {
+		var sum float64
+		for _, centroid := range d.Centroids {
+			count += centroid.Count
+			sum += centroid.Value * centroid.Count
+			if n := len(h.Bucket); n > 0 && h.Bucket[n-1].GetUpperBound() == centroid.Value {
+				h.Bucket[n-1].CumulativeCount = uint64Ptr(sinkutil.RoundCount(count))
+				continue
+			}
+			h.Bucket = append(h.Bucket, &prom.Bucket{UpperBound: float64Ptr(centroid.Value), CumulativeCount: uint64Ptr(sinkutil.RoundCount(count))})
+		}
+		h.SampleSum = float64Ptr(sum)
+	}
+	h.SampleCount = uint64Ptr(sinkutil.RoundCount(count))
+	return h
+}
+
+func seriesKey(name string, labels []*prom.LabelPair) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, l := range labels {
+		b.WriteByte(0)
+		b.WriteString(l.GetName())
+		b.WriteByte('=')
+		b.WriteString(l.GetValue())
+	}
+	return b.String()
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func uint64Ptr(u uint64) *uint64 {
+	return &u
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package exposition
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+func newTestSink(t *testing.T, cfg configuration.SinkConfig) *expositionSink {
+	cfg.Exposition.ListenAddress = "127.0.0.1:0"
+	cfg.ClusterName = "test-cluster"
+	sink, err := NewExpositionSink(cfg)
+	require.NoError(t, err)
+	t.Cleanup(sink.Stop)
+	return sink
+}
+
+func scrape(sink *expositionSink, accept string) (string, string) {
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, req)
+	body, _ := ioutil.ReadAll(w.Body)
+	return w.Header().Get("Content-Type"), string(body)
+}
+
+func TestExpositionSink(t *testing.T) {
+	t.Run("exposes points in the text format", func(t *testing.T) {
+		sink := newTestSink(t, configuration.SinkConfig{
+			WavefrontSinkConfig: configuration.WavefrontSinkConfig{
+				Transforms: configuration.Transforms{Prefix: "k8s.", Tags: map[string]string{"env": "test"}},
+			},
+		})
+		point := wf.NewPoint("cpu.usage-rate", 2.5, 1600000000, "node1", map[string]string{"label.app": "web"})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{point}})
+
+		contentType, body := scrape(sink, "")
+		assert.Contains(t, contentType, "text/plain")
+		assert.Equal(t, "# TYPE k8s_cpu_usage_rate untyped\n"+
+			"k8s_cpu_usage_rate{cluster=\"test-cluster\",env=\"test\",label_app=\"web\",source=\"node1\"} 2.5\n", body)
+
+		// the shared point is left untouched for other sinks
+		assert.NotContains(t, point.Tags(), "cluster")
+	})
+
+	t.Run("exposes the openmetrics format when accepted", func(t *testing.T) {
+		sink := newTestSink(t, configuration.SinkConfig{})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 0, "node1", nil)}})
+
+		contentType, body := scrape(sink, "application/openmetrics-text; version=0.0.1")
+		assert.Contains(t, contentType, "application/openmetrics-text")
+		assert.Contains(t, body, "# TYPE metric unknown\n")
+		assert.Contains(t, body, "# EOF\n")
+	})
+
+	t.Run("includes timestamps when enabled", func(t *testing.T) {
+		sink := newTestSink(t, configuration.SinkConfig{Exposition: configuration.ExpositionSinkConfig{IncludeTimestamps: true}})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 1600000000, "node1", nil)}})
+		_, body := scrape(sink, "")
+		assert.Contains(t, body, "} 1 1600000000000\n")
+	})
+
+	t.Run("replaces the previous batch", func(t *testing.T) {
+		sink := newTestSink(t, configuration.SinkConfig{})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("old.metric", 1, 0, "node1", nil)}})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("new.metric", 1, 0, "node1", nil)}})
+		_, body := scrape(sink, "")
+		assert.NotContains(t, body, "old_metric")
+		assert.Contains(t, body, "new_metric")
+	})
+
+	t.Run("applies filters and drops duplicates", func(t *testing.T) {
+		sink := newTestSink(t, configuration.SinkConfig{
+			WavefrontSinkConfig: configuration.WavefrontSinkConfig{
+				Transforms: configuration.Transforms{Filters: filter.Config{MetricDenyList: []string{"dropped.*"}}},
+			},
+		})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			wf.NewPoint("dropped.metric", 1, 0, "node1", nil),
+			wf.NewPoint("kept.metric", 1, 0, "node1", nil),
+			wf.NewPoint("kept.metric", 2, 0, "node1", nil),
+		}})
+		_, body := scrape(sink, "")
+		assert.Equal(t, "# TYPE kept_metric untyped\nkept_metric{cluster=\"test-cluster\",source=\"node1\"} 1\n", body)
+	})
+
+	t.Run("serves over http", func(t *testing.T) {
+		sink := newTestSink(t, configuration.SinkConfig{})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 0, "node1", nil)}})
+
+		server := httptest.NewServer(sink.server.Handler)
+		defer server.Close()
+		resp, err := http.Get(server.URL + "/metrics")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, err = http.Get(server.URL + "/other")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestToHistogram(t *testing.T) {
+	t.Run("frequency distribution", func(t *testing.T) {
+		h := toHistogram(wf.NewFrequencyDistribution("latency", "node1", nil, []wf.Centroid{
+			{Value: 1, Count: 2},
+			{Value: 4, Count: 1},
+			{Value: 4, Count: 2},
+		}, time.Now()))
+		assert.Equal(t, uint64(5), h.GetSampleCount())
+		assert.Equal(t, 14.0, h.GetSampleSum())
+		require.Len(t, h.Bucket, 2)
+		assert.Equal(t, 4.0, h.Bucket[1].GetUpperBound())
+		assert.Equal(t, uint64(5), h.Bucket[1].GetCumulativeCount())
+	})
+
+	t.Run("cumulative distribution", func(t *testing.T) {
+		h := toHistogram(wf.NewCumulativeDistribution("latency", "node1", nil, []wf.Centroid{
+			{Value: 0.1, Count: 2},
+			{Value: 1, Count: 5},
+			{Value: math.Inf(1), Count: 6},
+		}, time.Now()))
+		assert.Equal(t, uint64(6), h.GetSampleCount())
+		assert.True(t, math.IsNaN(h.GetSampleSum()))
+		require.Len(t, h.Bucket, 2)
+		assert.Equal(t, uint64(2), h.Bucket[0].GetCumulativeCount())
+		assert.Equal(t, uint64(5), h.Bucket[1].GetCumulativeCount())
+	})
+}
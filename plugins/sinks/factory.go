@@ -18,21 +18,45 @@
 package sinks
 
 import (
+	"fmt"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/exposition"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/otlp"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/remotewrite"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/wavefront"
 )
 
 type SinkFactory struct {
 }
 
-func (factory *SinkFactory) Build(cfg configuration.WavefrontSinkConfig) (wavefront.WavefrontSink, error) {
-	return wavefront.NewWavefrontSink(cfg)
+func (factory *SinkFactory) Build(cfg configuration.SinkConfig) (Sink, error) {
+	sink, err := factory.build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newTransformSink(cfg, sink)
+}
+
+func (factory *SinkFactory) build(cfg configuration.SinkConfig) (Sink, error) {
+	switch cfg.Type {
+	case "", configuration.WavefrontSinkType:
+		return wavefront.NewWavefrontSink(cfg.Name, cfg.WavefrontSinkConfig)
+	case configuration.PrometheusRemoteWriteSinkType:
+		return remotewrite.NewRemoteWriteSink(cfg)
+	case configuration.OTLPSinkType:
+		return otlp.NewOTLPSink(cfg)
+	case configuration.PrometheusExpositionSinkType:
+		return exposition.NewExpositionSink(cfg)
+	default:
+		return nil, fmt.Errorf("unknown sink type: %s", cfg.Type)
+	}
 }
 
-func (factory *SinkFactory) BuildAll(cfgs []*configuration.WavefrontSinkConfig) []wavefront.WavefrontSink {
-	result := make([]wavefront.WavefrontSink, 0, len(cfgs))
+func (factory *SinkFactory) BuildAll(cfgs []*configuration.SinkConfig) []Sink {
+	result := make([]Sink, 0, len(cfgs))
 
 	for _, cfg := range cfgs {
 		sink, err := factory.Build(*cfg)
@@ -21,55 +21,60 @@
 package sinks
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
-	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/wavefront"
-
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/events"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
 
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
 
 	gm "github.com/rcrowley/go-metrics"
 	log "github.com/sirupsen/logrus"
+	"github.com/wavefronthq/go-metrics-wavefront/reporting"
 )
 
 const (
 	DefaultSinkStopTimeout = 60 * time.Second
 )
 
-var (
-	sinkTimeouts gm.Counter
-)
-
-func init() {
-	sinkTimeouts = gm.GetOrRegisterCounter("sink.manager.timeouts", gm.DefaultRegistry)
-}
-
 type sinkHolder struct {
-	sink              wavefront.WavefrontSink
+	sink              Sink
 	dataBatchChannel  chan *metrics.Batch
 	eventBatchChannel chan *events.Event
+	updateChannel     chan sinkUpdate
 	stopChannel       chan bool
+	timeouts          gm.Counter
+}
+
+// sinkUpdate holds the compiled transforms and filters applied to a running sink
+type sinkUpdate struct {
+	rules   []transformRule
+	filters filter.Filter
 }
 
 // Sink Manager - a special sink that distributes data to other sinks. It pushes data
 // only to these sinks that completed their previous exports. Data that could not be
-// pushed in the defined time is dropped and not retried.
+// pushed in the defined time is dropped and not retried. When there is more than one
+// sink, every sink receives its own copy of the batch so that sinks can modify metrics.
 type sinkManager struct {
 	sinkHolders       []sinkHolder
 	exportDataTimeout time.Duration
 	stopTimeout       time.Duration
 }
 
-func NewSinkManager(sinks []wavefront.WavefrontSink, exportDataTimeout, stopTimeout time.Duration) (wavefront.WavefrontSink, error) {
+func NewSinkManager(sinks []Sink, exportDataTimeout, stopTimeout time.Duration) (Sink, error) {
 	var sinkHolders []sinkHolder
 	for _, sink := range sinks {
 		sh := sinkHolder{
 			sink:              sink,
 			dataBatchChannel:  make(chan *metrics.Batch),
 			eventBatchChannel: make(chan *events.Event),
+			updateChannel:     make(chan sinkUpdate),
 			stopChannel:       make(chan bool),
+			timeouts:          gm.GetOrRegisterCounter(reporting.EncodeKey("sink.manager.timeouts", map[string]string{"sink": sink.Name()}), gm.DefaultRegistry),
 		}
 		sinkHolders = append(sinkHolders, sh)
 		go func(sh sinkHolder) {
@@ -79,6 +84,8 @@ func NewSinkManager(sinks []wavefront.WavefrontSink, exportDataTimeout, stopTime
 					sh.sink.Export(data)
 				case event := <-sh.eventBatchChannel:
 					sh.sink.ExportEvent(event)
+				case update := <-sh.updateChannel:
+					sh.sink.(*transformSink).update(update)
 				case isStop := <-sh.stopChannel:
 					log.WithField("name", sh.sink.Name()).Info("Sink stop received")
 					if isStop {
@@ -99,20 +106,24 @@ func NewSinkManager(sinks []wavefront.WavefrontSink, exportDataTimeout, stopTime
 // Guarantees that the export will complete in sinkExportDataTimeout.
 func (sm *sinkManager) Export(data *metrics.Batch) {
 	var wg sync.WaitGroup
-	for _, sh := range sm.sinkHolders {
+	for i, sh := range sm.sinkHolders {
+		batch := data
+		if i < len(sm.sinkHolders)-1 {
+			batch = data.Clone()
+		}
 		wg.Add(1)
-		go func(sh sinkHolder, wg *sync.WaitGroup) {
+		go func(sh sinkHolder, batch *metrics.Batch, wg *sync.WaitGroup) {
 			defer wg.Done()
 			log.WithField("name", sh.sink.Name()).Debug("Pushing data to sink")
 			select {
-			case sh.dataBatchChannel <- data:
+			case sh.dataBatchChannel <- batch:
 				log.WithField("name", sh.sink.Name()).Info("Data push complete")
 				// everything ok
 			case <-time.After(sm.exportDataTimeout):
-				sinkTimeouts.Inc(1)
+				sh.timeouts.Inc(1)
 				log.WithField("name", sh.sink.Name()).Info("Data push timed out. Increasing sinkExportDataTimeout may help.")
 			}
-		}(sh, &wg)
+		}(sh, batch, &wg)
 	}
 	// Wait for all pushes to complete or timeout.
 	wg.Wait()
@@ -130,7 +141,7 @@ func (sm *sinkManager) ExportEvent(event *events.Event) {
 				log.WithField("name", sh.sink.Name()).Debug("Events push complete")
 				// everything ok
 			case <-time.After(sm.exportDataTimeout):
-				sinkTimeouts.Inc(1)
+				sh.timeouts.Inc(1)
 				log.WithField("name", sh.sink.Name()).Info("Events push failed")
 			}
 		}(sh, &wg)
@@ -139,6 +150,35 @@ func (sm *sinkManager) ExportEvent(event *events.Event) {
 	wg.Wait()
 }
 
+// Reconfigure replaces the transforms and filters of the running sinks. The configurations must be in the order the
+// sinks were created in. Nothing is changed if any of the transforms is invalid.
+func (sm *sinkManager) Reconfigure(cfgs []*configuration.SinkConfig) error {
+	if len(cfgs) != len(sm.sinkHolders) {
+		return fmt.Errorf("expected %d sink configurations but got %d", len(sm.sinkHolders), len(cfgs))
+	}
+	updates := make([]sinkUpdate, len(cfgs))
+	for i, cfg := range cfgs {
+		if _, ok := sm.sinkHolders[i].sink.(*transformSink); !ok {
+			return fmt.Errorf("sink %s cannot be reconfigured", sm.sinkHolders[i].sink.Name())
+		}
+		rules, err := compileTransformRules(cfg.TransformRules)
+		if err != nil {
+			return fmt.Errorf("sink %s: %v", sm.sinkHolders[i].sink.Name(), err)
+		}
+		updates[i] = sinkUpdate{rules: rules, filters: filter.FromConfig(cfg.Filters)}
+	}
+
+	for i, sh := range sm.sinkHolders {
+		select {
+		case sh.updateChannel <- updates[i]:
+			log.WithField("name", sh.sink.Name()).Info("Sink reconfigured")
+		case <-time.After(sm.stopTimeout):
+			return fmt.Errorf("timed out reconfiguring sink %s", sh.sink.Name())
+		}
+	}
+	return nil
+}
+
 func (sm *sinkManager) Name() string {
 	return "Manager"
 }
@@ -0,0 +1,110 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package otlp
+
+import (
+	"math"
+	"sort"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/sinkutil"
+)
+
+const (
+	exponentialHistogramType = "exponential"
+	explicitHistogramType    = "explicit"
+
+	// exponentialScale is the resolution of the exponential buckets. Bucket boundaries grow by a factor of 2^(2^-3).
+	exponentialScale = 3
+
+	// centroids with an absolute value at or below this threshold are counted in the zero bucket
+	zeroThreshold = 1e-128
+)
+
+// toExplicitHistogram maps a distribution onto explicit bucket boundaries. Prometheus style cumulative
+// distributions keep their original bucket boundaries. For frequency distributions every centroid value
+// becomes the upper bound of a bucket.
+func toExplicitHistogram(d *wf.Distribution) histogramDataPoint {
+	var dp histogramDataPoint
+	if d.Cumulative {
+		previous := 0.0
+		for _, centroid := range d.Centroids {
+			dp.BucketCounts = append(dp.BucketCounts, sinkutil.RoundCount(centroid.Count-previous))
+			previous = centroid.Count
+			if !math.IsInf(centroid.Value, 1) {
+				dp.ExplicitBounds = append(dp.ExplicitBounds, centroid.Value)
+			}
+		}
+		dp.Count = sinkutil.RoundCount(previous)
+	} else {
+		dp.HasSum = true
+		for _, centroid := range d.Centroids {
+			count := sinkutil.RoundCount(centroid.Count)
+			dp.Count += count
+			dp.Sum += centroid.Value * centroid.Count
+			if n := len(dp.ExplicitBounds); n > 0 && dp.ExplicitBounds[n-1] == centroid.Value {
+				dp.BucketCounts[n-1] += count
+				continue
+			}
+			dp.BucketCounts = append(dp.BucketCounts, count)
+			dp.ExplicitBounds = append(dp.ExplicitBounds, centroid.Value)
+		}
+	}
+	// the number of buckets is always one more than the number of bounds; the last bucket is (bounds[n-1], +Inf)
+	if len(dp.BucketCounts) == len(dp.ExplicitBounds) {
+		dp.BucketCounts = append(dp.BucketCounts, 0)
+	}
+	return dp
+}
+
+// toExponentialHistogram maps the centroids of a distribution onto exponential buckets.
+// Cumulative distributions are converted to frequency distributions first.
+func toExponentialHistogram(d *wf.Distribution) exponentialHistogramDataPoint {
+	dp := exponentialHistogramDataPoint{Scale: exponentialScale, HasSum: !d.Cumulative}
+	positive := map[int32]uint64{}
+	negative := map[int32]uint64{}
+	for _, centroid := range d.ToFrequency().Centroids {
+		count := sinkutil.RoundCount(centroid.Count)
+		if count == 0 {
+			continue
+		}
+		dp.Count += count
+		dp.Sum += centroid.Value * centroid.Count
+		switch {
+		case math.Abs(centroid.Value) <= zeroThreshold:
+			dp.ZeroCount += count
+		case centroid.Value > 0:
+			positive[bucketIndex(centroid.Value)] += count
+		default:
+			negative[bucketIndex(-centroid.Value)] += count
+		}
+	}
+	dp.Positive = toBuckets(positive)
+	dp.Negative = toBuckets(negative)
+	return dp
+}
+
+// bucketIndex returns the index of the bucket (base^i, base^(i+1)] containing v for the exponential scale.
+func bucketIndex(v float64) int32 {
+	return int32(math.Ceil(math.Log2(v)*math.Exp2(exponentialScale))) - 1
+}
+
+// toBuckets converts sparse bucket counts into an offset and a dense list of counts.
+func toBuckets(sparse map[int32]uint64) buckets {
+	if len(sparse) == 0 {
+		return buckets{}
+	}
+	indexes := make([]int32, 0, len(sparse))
+	for index := range sparse {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	offset := indexes[0]
+	counts := make([]uint64, indexes[len(indexes)-1]-offset+1)
+	for _, index := range indexes {
+		counts[index-offset] = sparse[index]
+	}
+	return buckets{Offset: offset, BucketCounts: counts}
+}
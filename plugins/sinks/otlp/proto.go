@@ -0,0 +1,327 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package otlp
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The types below mirror the subset of the OpenTelemetry protobuf messages used by this sink.
+// See https://github.com/open-telemetry/opentelemetry-proto/tree/main/opentelemetry/proto
+// (collector/metrics/v1, collector/logs/v1, metrics/v1, logs/v1, resource/v1 and common/v1).
+
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+// AggregationTemporality values
+const (
+	temporalityDelta      = 1
+	temporalityCumulative = 2
+)
+
+// DataPointFlags value marking a data point without a recorded value
+const flagNoRecordedValue = 1
+
+// SeverityNumber values used for events
+const (
+	severityInfo = 9
+	severityWarn = 13
+)
+
+type metricKind int
+
+const (
+	gaugeKind metricKind = iota
+	sumKind
+	histogramKind
+	exponentialHistogramKind
+)
+
+type numberDataPoint struct {
+	Attributes        []keyValue
+	StartTimeUnixNano uint64
+	TimeUnixNano      uint64
+	Value             float64
+	// Flags is set to flagNoRecordedValue for staleness markers
+	Flags uint32
+}
+
+type histogramDataPoint struct {
+	Attributes        []keyValue
+	StartTimeUnixNano uint64
+	TimeUnixNano      uint64
+	Count             uint64
+	Sum               float64
+	HasSum            bool
+	BucketCounts      []uint64
+	ExplicitBounds    []float64
+}
+
+type buckets struct {
+	Offset       int32
+	BucketCounts []uint64
+}
+
+type exponentialHistogramDataPoint struct {
+	Attributes        []keyValue
+	StartTimeUnixNano uint64
+	TimeUnixNano      uint64
+	Count             uint64
+	Sum               float64
+	HasSum            bool
+	Scale             int32
+	ZeroCount         uint64
+	Positive          buckets
+	Negative          buckets
+}
+
+// metric holds the data points of a single metric. Only the data points matching Kind are encoded.
+type metric struct {
+	Name                  string
+	Kind                  metricKind
+	Temporality           int32
+	Monotonic             bool
+	NumberPoints          []numberDataPoint
+	HistogramPoints       []histogramDataPoint
+	ExponentialHistPoints []exponentialHistogramDataPoint
+}
+
+type resourceMetrics struct {
+	Resource []keyValue
+	Metrics  []*metric
+}
+
+type logRecord struct {
+	TimeUnixNano         uint64
+	ObservedTimeUnixNano uint64
+	SeverityNumber       int32
+	SeverityText         string
+	Body                 string
+	Attributes           []keyValue
+}
+
+type resourceLogs struct {
+	Resource []keyValue
+	Records  []logRecord
+}
+
+// encodeMetricsRequest encodes an ExportMetricsServiceRequest message.
+func encodeMetricsRequest(rms []*resourceMetrics, scope scope) []byte {
+	var b []byte
+	for _, rm := range rms {
+		var rb []byte
+		rb = appendMessage(rb, 1, encodeResource(rm.Resource))
+		var sb []byte
+		sb = appendMessage(sb, 1, scope.encode())
+		for _, m := range rm.Metrics {
+			sb = appendMessage(sb, 2, encodeMetric(m))
+		}
+		rb = appendMessage(rb, 2, sb) // scope_metrics
+		b = appendMessage(b, 1, rb)
+	}
+	return b
+}
+
+// encodeLogsRequest encodes an ExportLogsServiceRequest message.
+func encodeLogsRequest(rls []*resourceLogs, scope scope) []byte {
+	var b []byte
+	for _, rl := range rls {
+		var rb []byte
+		rb = appendMessage(rb, 1, encodeResource(rl.Resource))
+		var sb []byte
+		sb = appendMessage(sb, 1, scope.encode())
+		for _, r := range rl.Records {
+			sb = appendMessage(sb, 2, encodeLogRecord(r))
+		}
+		rb = appendMessage(rb, 2, sb) // scope_logs
+		b = appendMessage(b, 1, rb)
+	}
+	return b
+}
+
+// scope is the InstrumentationScope reported with all data.
+type scope struct {
+	Name    string
+	Version string
+}
+
+func (s scope) encode() []byte {
+	var b []byte
+	b = appendString(b, 1, s.Name)
+	if s.Version != "" {
+		b = appendString(b, 2, s.Version)
+	}
+	return b
+}
+
+func encodeResource(attributes []keyValue) []byte {
+	return appendAttributes(nil, 1, attributes)
+}
+
+func encodeMetric(m *metric) []byte {
+	var b []byte
+	b = appendString(b, 1, m.Name)
+	var data []byte
+	switch m.Kind {
+	case gaugeKind:
+		for _, dp := range m.NumberPoints {
+			data = appendMessage(data, 1, encodeNumberDataPoint(dp))
+		}
+		b = appendMessage(b, 5, data)
+	case sumKind:
+		for _, dp := range m.NumberPoints {
+			data = appendMessage(data, 1, encodeNumberDataPoint(dp))
+		}
+		data = appendVarint(data, 2, uint64(m.Temporality))
+		if m.Monotonic {
+			data = appendVarint(data, 3, 1)
+		}
+		b = appendMessage(b, 7, data)
+	case histogramKind:
+		for _, dp := range m.HistogramPoints {
+			data = appendMessage(data, 1, encodeHistogramDataPoint(dp))
+		}
+		data = appendVarint(data, 2, uint64(m.Temporality))
+		b = appendMessage(b, 9, data)
+	case exponentialHistogramKind:
+		for _, dp := range m.ExponentialHistPoints {
+			data = appendMessage(data, 1, encodeExponentialHistogramDataPoint(dp))
+		}
+		data = appendVarint(data, 2, uint64(m.Temporality))
+		b = appendMessage(b, 10, data)
+	}
+	return b
+}
+
+func encodeNumberDataPoint(dp numberDataPoint) []byte {
+	var b []byte
+	b = appendFixed64(b, 2, dp.StartTimeUnixNano)
+	b = appendFixed64(b, 3, dp.TimeUnixNano)
+	b = appendDouble(b, 4, dp.Value) // as_double
+	b = appendAttributes(b, 7, dp.Attributes)
+	if dp.Flags != 0 {
+		b = appendVarint(b, 8, uint64(dp.Flags))
+	}
+	return b
+}
+
+func encodeHistogramDataPoint(dp histogramDataPoint) []byte {
+	var b []byte
+	b = appendFixed64(b, 2, dp.StartTimeUnixNano)
+	b = appendFixed64(b, 3, dp.TimeUnixNano)
+	b = appendFixed64(b, 4, dp.Count)
+	if dp.HasSum {
+		b = appendDouble(b, 5, dp.Sum)
+	}
+	b = appendPackedFixed64s(b, 6, dp.BucketCounts)
+	b = appendPackedDoubles(b, 7, dp.ExplicitBounds)
+	return appendAttributes(b, 9, dp.Attributes)
+}
+
+func encodeExponentialHistogramDataPoint(dp exponentialHistogramDataPoint) []byte {
+	var b []byte
+	b = appendAttributes(b, 1, dp.Attributes)
+	b = appendFixed64(b, 2, dp.StartTimeUnixNano)
+	b = appendFixed64(b, 3, dp.TimeUnixNano)
+	b = appendFixed64(b, 4, dp.Count)
+	if dp.HasSum {
+		b = appendDouble(b, 5, dp.Sum)
+	}
+	b = appendVarint(b, 6, protowire.EncodeZigZag(int64(dp.Scale)))
+	b = appendFixed64(b, 7, dp.ZeroCount)
+	if len(dp.Positive.BucketCounts) > 0 {
+		b = appendMessage(b, 8, encodeBuckets(dp.Positive))
+	}
+	if len(dp.Negative.BucketCounts) > 0 {
+		b = appendMessage(b, 9, encodeBuckets(dp.Negative))
+	}
+	return b
+}
+
+func encodeBuckets(bk buckets) []byte {
+	var b []byte
+	b = appendVarint(b, 1, protowire.EncodeZigZag(int64(bk.Offset)))
+	var packed []byte
+	for _, count := range bk.BucketCounts {
+		packed = protowire.AppendVarint(packed, count)
+	}
+	return appendMessage(b, 2, packed)
+}
+
+func encodeLogRecord(r logRecord) []byte {
+	var b []byte
+	b = appendFixed64(b, 1, r.TimeUnixNano)
+	b = appendVarint(b, 2, uint64(r.SeverityNumber))
+	if r.SeverityText != "" {
+		b = appendString(b, 3, r.SeverityText)
+	}
+	b = appendMessage(b, 5, encodeStringValue(r.Body))
+	b = appendAttributes(b, 6, r.Attributes)
+	return appendFixed64(b, 11, r.ObservedTimeUnixNano)
+}
+
+// appendAttributes appends each attribute as a KeyValue message with a string AnyValue.
+func appendAttributes(b []byte, num protowire.Number, attributes []keyValue) []byte {
+	for _, kv := range attributes {
+		var kb []byte
+		kb = appendString(kb, 1, kv.Key)
+		kb = appendMessage(kb, 2, encodeStringValue(kv.Value))
+		b = appendMessage(b, num, kb)
+	}
+	return b
+}
+
+func encodeStringValue(v string) []byte {
+	return appendString(nil, 1, v)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	return appendFixed64(b, num, math.Float64bits(v))
+}
+
+func appendPackedFixed64s(b []byte, num protowire.Number, vs []uint64) []byte {
+	if len(vs) == 0 {
+		return b
+	}
+	var packed []byte
+	for _, v := range vs {
+		packed = protowire.AppendFixed64(packed, v)
+	}
+	return appendMessage(b, num, packed)
+}
+
+func appendPackedDoubles(b []byte, num protowire.Number, vs []float64) []byte {
+	if len(vs) == 0 {
+		return b
+	}
+	var packed []byte
+	for _, v := range vs {
+		packed = protowire.AppendFixed64(packed, math.Float64bits(v))
+	}
+	return appendMessage(b, num, packed)
+}
@@ -0,0 +1,412 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package otlp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+
+	gm "github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/wavefronthq/go-metrics-wavefront/reporting"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/events"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/httputil"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/sinkutil"
+)
+
+const (
+	defaultMaxDataPointsPerRequest = 2000
+	defaultMaxRetries              = 3
+	defaultMinBackoff              = 100 * time.Millisecond
+	defaultMaxBackoff              = 5 * time.Second
+	defaultTimeout                 = 30 * time.Second
+
+	metricsPath = "/v1/metrics"
+	logsPath    = "/v1/logs"
+
+	scopeName       = "github.com/wavefronthq/wavefront-collector-for-kubernetes"
+	sourceAttribute = "source"
+)
+
+var defaultSumPatterns = []string{"*.count", "*.counter", "*.total", "*.sum"}
+
+// resourceTags are the tags promoted to resource attributes, mapped to their OpenTelemetry semantic convention names.
+var resourceTags = []struct {
+	tag       string
+	attribute string
+}{
+	{metrics.LabelCluster.Key, "k8s.cluster.name"},
+	{metrics.LabelNodename.Key, "k8s.node.name"},
+	{metrics.LabelNamespaceName.Key, "k8s.namespace.name"},
+	{metrics.LabelPodName.Key, "k8s.pod.name"},
+	{metrics.LabelContainerName.Key, "k8s.container.name"},
+}
+
+const sinkName = "otlp_sink"
+
+// sinkCounters are the internal metrics of a sink, tagged with the name of the sink
+type sinkCounters struct {
+	sentPoints     gm.Counter
+	errPoints      gm.Counter
+	filteredPoints gm.Counter
+	sentEvents     gm.Counter
+	errEvents      gm.Counter
+	retries        gm.Counter
+}
+
+func newSinkCounters(name string) sinkCounters {
+	tags := map[string]string{"sink": name}
+	counter := func(key string) gm.Counter {
+		return gm.GetOrRegisterCounter(reporting.EncodeKey(key, tags), gm.DefaultRegistry)
+	}
+	return sinkCounters{
+		sentPoints:     counter("otlp.points.sent.count"),
+		errPoints:      counter("otlp.points.errors.count"),
+		filteredPoints: counter("otlp.points.filtered.count"),
+		sentEvents:     counter("otlp.events.sent.count"),
+		errEvents:      counter("otlp.events.errors.count"),
+		retries:        counter("otlp.requests.retries.count"),
+	}
+}
+
+type otlpSink struct {
+	endpoint                string
+	poster                  *sinkutil.Poster
+	headers                 map[string]string
+	clusterName             string
+	prefix                  string
+	globalTags              map[string]string
+	filters                 filter.Filter
+	sumPatterns             glob.Glob
+	histogramType           string
+	scope                   scope
+	maxDataPointsPerRequest int
+
+	// startTime is reported as the start of cumulative sums and histograms
+	startTime time.Time
+	// lastExport is reported as the start of delta histograms
+	lastExport time.Time
+	counters   sinkCounters
+}
+
+// NewOTLPSink creates a sink that ships metrics and events to an OpenTelemetry receiver using OTLP/HTTP.
+func NewOTLPSink(cfg configuration.SinkConfig) (*otlpSink, error) {
+	otlpCfg := cfg.OTLP
+	if otlpCfg.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint missing for otlp sink")
+	}
+	histogramType := configuration.GetStringValue(otlpCfg.HistogramType, exponentialHistogramType)
+	if histogramType != exponentialHistogramType && histogramType != explicitHistogramType {
+		return nil, fmt.Errorf("invalid otlp histogram type: %s", histogramType)
+	}
+	sumPatterns := otlpCfg.SumPatterns
+	if len(sumPatterns) == 0 {
+		sumPatterns = defaultSumPatterns
+	}
+	for _, pattern := range sumPatterns {
+		if _, err := glob.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid otlp sum pattern %q: %v", pattern, err)
+		}
+	}
+	client, err := httputil.NewClient(otlpCfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating otlp client: %v", err)
+	}
+	client.Timeout = configuration.GetDurationValue(otlpCfg.Timeout, defaultTimeout)
+
+	counters := newSinkCounters(configuration.GetStringValue(cfg.Name, sinkName))
+	sink := &otlpSink{
+		endpoint: strings.TrimSuffix(otlpCfg.Endpoint, "/"),
+		poster: &sinkutil.Poster{
+			Name:       sinkName,
+			Client:     client,
+			MaxRetries: defaultMaxRetries,
+			MinBackoff: configuration.GetDurationValue(otlpCfg.MinBackoff, defaultMinBackoff),
+			MaxBackoff: configuration.GetDurationValue(otlpCfg.MaxBackoff, defaultMaxBackoff),
+			Retries:    counters.retries,
+		},
+		headers:                 otlpCfg.Headers,
+		clusterName:             configuration.GetStringValue(cfg.ClusterName, "k8s-cluster"),
+		globalTags:              cfg.Tags,
+		filters:                 filter.FromConfig(cfg.Filters),
+		sumPatterns:             filter.Compile(sumPatterns),
+		histogramType:           histogramType,
+		scope:                   scope{Name: scopeName},
+		maxDataPointsPerRequest: otlpCfg.MaxDataPointsPerRequest,
+		startTime:               time.Now(),
+		counters:                counters,
+	}
+	if cfg.Version > 0 {
+		sink.scope.Version = strconv.FormatFloat(cfg.Version, 'f', -1, 64)
+	}
+	if cfg.Prefix != "" {
+		sink.prefix = strings.Trim(cfg.Prefix, ".") + "."
+	}
+	if sink.maxDataPointsPerRequest <= 0 {
+		sink.maxDataPointsPerRequest = defaultMaxDataPointsPerRequest
+	}
+	if otlpCfg.MaxRetries != nil {
+		sink.poster.MaxRetries = *otlpCfg.MaxRetries
+	}
+	return sink, nil
+}
+
+// SetFilters replaces the filters of the sink. It is called from the goroutine exporting to the sink.
+func (sink *otlpSink) SetFilters(filters filter.Filter) {
+	sink.filters = filters
+}
+
+func (sink *otlpSink) Name() string {
+	return sinkName
+}
+
+func (sink *otlpSink) Stop() {
+	sink.poster.Client.CloseIdleConnections()
+}
+
+// dataPoint is a converted point or distribution along with the resource it belongs to.
+type dataPoint struct {
+	resource []keyValue
+	metric   *metric
+}
+
+func (sink *otlpSink) Export(batch *metrics.Batch) {
+	log.WithField("name", sink.Name()).Debugf("received metric points: %d", len(batch.Metrics))
+
+	batchTime := batch.Timestamp
+	if batchTime.IsZero() {
+		batchTime = time.Now()
+	}
+	deltaStart := sink.lastExport
+	if deltaStart.IsZero() {
+		deltaStart = sink.startTime
+	}
+	sink.lastExport = batchTime
+
+	points := make([]dataPoint, 0, len(batch.Metrics))
+	for _, m := range batch.Metrics {
+		if m == nil {
+			continue
+		}
+		if dp, ok := sink.toDataPoint(m, batchTime, deltaStart); ok {
+			points = append(points, dp)
+		}
+	}
+
+	for start := 0; start < len(points); start += sink.maxDataPointsPerRequest {
+		end := start + sink.maxDataPointsPerRequest
+		if end > len(points) {
+			end = len(points)
+		}
+		body := encodeMetricsRequest(groupByResource(points[start:end]), sink.scope)
+		if err := sink.send(metricsPath, body); err != nil {
+			sink.counters.errPoints.Inc(int64(end - start))
+			log.WithField("name", sink.Name()).Errorf("error sending metrics: %v", err)
+		} else {
+			sink.counters.sentPoints.Inc(int64(end - start))
+		}
+	}
+}
+
+// ExportEvent sends the event as a log record. The message is the body and the event annotations become attributes.
+func (sink *otlpSink) ExportEvent(ev *events.Event) {
+	fields := map[string]interface{}{"annotations": map[string]string{}}
+	for _, option := range ev.Options {
+		option(fields)
+	}
+	tags := make(map[string]string, len(ev.Tags))
+	for k, v := range fields["annotations"].(map[string]string) {
+		tags[k] = v
+	}
+	for k, v := range ev.Tags {
+		tags[k] = v
+	}
+	tags[metrics.LabelCluster.Key] = sink.clusterName
+	if ev.Host != "" {
+		tags[metrics.LabelNodename.Key] = ev.Host
+	}
+
+	record := logRecord{
+		TimeUnixNano:         uint64(ev.Ts.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       severityInfo,
+		SeverityText:         tags["type"],
+		Body:                 ev.Message,
+	}
+	if ev.Ts.IsZero() {
+		record.TimeUnixNano = record.ObservedTimeUnixNano
+	}
+	if strings.EqualFold(record.SeverityText, "warning") {
+		record.SeverityNumber = severityWarn
+	}
+	resource, attributes := splitAttributes(tags)
+	record.Attributes = attributes
+
+	body := encodeLogsRequest([]*resourceLogs{{Resource: resource, Records: []logRecord{record}}}, sink.scope)
+	if err := sink.send(logsPath, body); err != nil {
+		sink.counters.errEvents.Inc(1)
+		log.WithField("name", sink.Name()).Errorf("error sending event: %v", err)
+	} else {
+		sink.counters.sentEvents.Inc(1)
+	}
+}
+
+// toDataPoint converts a point or distribution into a single-point metric without modifying the shared metric.
+func (sink *otlpSink) toDataPoint(m wf.Metric, batchTime, deltaStart time.Time) (dataPoint, bool) {
+	tags, ok := sinkutil.Tags(m, sink.clusterName, sink.globalTags, sink.filters, sink.counters.filteredPoints)
+	if !ok {
+		return dataPoint{}, false
+	}
+
+	switch p := m.(type) {
+	case *wf.Point:
+		if p.Source != "" {
+			tags[sourceAttribute] = p.Source
+		}
+		resource, attributes := splitAttributes(tags)
+		ts := sinkutil.ToUnixNano(p.Timestamp, batchTime)
+		dp := numberDataPoint{Attributes: attributes, TimeUnixNano: ts, Value: p.Value}
+		if wf.IsStaleMarker(p.Value) {
+			dp.Value, dp.Flags = 0, flagNoRecordedValue
+		}
+		out := &metric{Name: sink.prefix + p.Name(), Kind: gaugeKind}
+		if sink.sumPatterns != nil && sink.sumPatterns.Match(p.Name()) {
+			out.Kind = sumKind
+			out.Temporality = temporalityCumulative
+			out.Monotonic = true
+			start := sink.startTime
+			if p.StartTime > 0 {
+				start = time.Unix(p.StartTime, 0)
+			}
+			dp.StartTimeUnixNano = startTime(start, ts)
+		}
+		out.NumberPoints = []numberDataPoint{dp}
+		return dataPoint{resource: resource, metric: out}, true
+	case *wf.Distribution:
+		if p.Source != "" {
+			tags[sourceAttribute] = p.Source
+		}
+		resource, attributes := splitAttributes(tags)
+		ts := uint64(p.Timestamp.UnixNano())
+		if p.Timestamp.IsZero() {
+			ts = uint64(batchTime.UnixNano())
+		}
+		out := &metric{Name: sink.prefix + p.Name(), Temporality: temporalityDelta}
+		start := startTime(deltaStart, ts)
+		if p.Cumulative {
+			out.Temporality = temporalityCumulative
+			start = startTime(sink.startTime, ts)
+		}
+		if sink.histogramType == explicitHistogramType {
+			dp := toExplicitHistogram(p)
+			dp.Attributes, dp.StartTimeUnixNano, dp.TimeUnixNano = attributes, start, ts
+			out.Kind = histogramKind
+			out.HistogramPoints = []histogramDataPoint{dp}
+		} else {
+			dp := toExponentialHistogram(p)
+			dp.Attributes, dp.StartTimeUnixNano, dp.TimeUnixNano = attributes, start, ts
+			out.Kind = exponentialHistogramKind
+			out.ExponentialHistPoints = []exponentialHistogramDataPoint{dp}
+		}
+		return dataPoint{resource: resource, metric: out}, true
+	default:
+		log.Debugf("unsupported metric type for otlp: %T", m)
+		return dataPoint{}, false
+	}
+}
+
+// splitAttributes separates the tags promoted to resource attributes from the remaining data point attributes.
+// Both are sorted by key.
+func splitAttributes(tags map[string]string) ([]keyValue, []keyValue) {
+	var resource []keyValue
+	for _, rt := range resourceTags {
+		if v := tags[rt.tag]; v != "" {
+			resource = append(resource, keyValue{Key: rt.attribute, Value: v})
+		}
+	}
+	attributes := make([]keyValue, 0, len(tags))
+	for k, v := range tags {
+		if v == "" || isResourceTag(k) {
+			continue
+		}
+		attributes = append(attributes, keyValue{Key: k, Value: v})
+	}
+	sort.Slice(resource, func(i, j int) bool { return resource[i].Key < resource[j].Key })
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].Key < attributes[j].Key })
+	return resource, attributes
+}
+
+func isResourceTag(tag string) bool {
+	for _, rt := range resourceTags {
+		if rt.tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// groupByResource groups data points by resource, merging the data points of metrics with the same name and kind.
+func groupByResource(points []dataPoint) []*resourceMetrics {
+	var result []*resourceMetrics
+	resources := map[string]*resourceMetrics{}
+	metricsByKey := map[string]*metric{}
+	for _, p := range points {
+		resourceKey := attributesKey(p.resource)
+		rm, ok := resources[resourceKey]
+		if !ok {
+			rm = &resourceMetrics{Resource: p.resource}
+			resources[resourceKey] = rm
+			result = append(result, rm)
+		}
+		metricKey := fmt.Sprintf("%s\x00%s\x00%d\x00%d", resourceKey, p.metric.Name, p.metric.Kind, p.metric.Temporality)
+		m, ok := metricsByKey[metricKey]
+		if !ok {
+			metricsByKey[metricKey] = p.metric
+			rm.Metrics = append(rm.Metrics, p.metric)
+			continue
+		}
+		m.NumberPoints = append(m.NumberPoints, p.metric.NumberPoints...)
+		m.HistogramPoints = append(m.HistogramPoints, p.metric.HistogramPoints...)
+		m.ExponentialHistPoints = append(m.ExponentialHistPoints, p.metric.ExponentialHistPoints...)
+	}
+	return result
+}
+
+func attributesKey(attributes []keyValue) string {
+	var b strings.Builder
+	for _, kv := range attributes {
+		b.WriteString(kv.Key)
+		b.WriteByte('=')
+		b.WriteString(kv.Value)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func (sink *otlpSink) send(path string, body []byte) error {
+	headers := make(map[string]string, len(sink.headers)+1)
+	for k, v := range sink.headers {
+		headers[k] = v
+	}
+	headers["Content-Type"] = "application/x-protobuf"
+	return sink.poster.Post(sink.endpoint+path, headers, body)
+}
+
+// startTime returns start in unix nanoseconds, capped at the data point time.
+func startTime(start time.Time, ts uint64) uint64 {
+	if nanos := uint64(start.UnixNano()); nanos < ts {
+		return nanos
+	}
+	return ts
+}
@@ -0,0 +1,359 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package otlp
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/events"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+	"github.com/wavefronthq/wavefront-sdk-go/event"
+)
+
+// fields is a decoded protobuf message: the raw values of each field number in order.
+type fields map[protowire.Number][][]byte
+
+func decode(b []byte) fields {
+	f := fields{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		value := b[:n]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+		f[num] = append(f[num], value)
+		b = b[n:]
+	}
+	return f
+}
+
+func (f fields) messages(num protowire.Number) []fields {
+	var result []fields
+	for _, v := range f[num] {
+		result = append(result, decode(v))
+	}
+	return result
+}
+
+func (f fields) message(num protowire.Number) fields {
+	if len(f[num]) == 0 {
+		return fields{}
+	}
+	return decode(f[num][0])
+}
+
+func (f fields) str(num protowire.Number) string {
+	if len(f[num]) == 0 {
+		return ""
+	}
+	return string(f[num][0])
+}
+
+func (f fields) double(num protowire.Number) float64 {
+	v, _ := protowire.ConsumeFixed64(f[num][0])
+	return math.Float64frombits(v)
+}
+
+func (f fields) varint(num protowire.Number) uint64 {
+	if len(f[num]) == 0 {
+		return 0
+	}
+	v, _ := protowire.ConsumeVarint(f[num][0])
+	return v
+}
+
+// attributes decodes repeated KeyValue messages with string values.
+func (f fields) attributes(num protowire.Number) map[string]string {
+	result := map[string]string{}
+	for _, kv := range f.messages(num) {
+		result[kv.str(1)] = kv.message(2).str(1)
+	}
+	return result
+}
+
+type receiver struct {
+	mtx      sync.Mutex
+	statuses []int
+	requests map[string][]fields
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	if req.Header.Get("Content-Type") != "application/x-protobuf" || req.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	if r.requests == nil {
+		r.requests = map[string][]fields{}
+	}
+	r.requests[req.URL.Path] = append(r.requests[req.URL.Path], decode(body))
+}
+
+// resources returns the resource attributes and metrics of every ResourceMetrics sent to the receiver.
+func (r *receiver) resources() ([]map[string]string, [][]fields) {
+	var resources []map[string]string
+	var metrics [][]fields
+	for _, req := range r.requests[metricsPath] {
+		for _, rm := range req.messages(1) {
+			resources = append(resources, rm.message(1).attributes(1))
+			metrics = append(metrics, rm.message(2).messages(2))
+		}
+	}
+	return resources, metrics
+}
+
+func newTestSink(t *testing.T, r *receiver, cfg configuration.SinkConfig) *otlpSink {
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	cfg.OTLP.Endpoint = server.URL + "/"
+	cfg.OTLP.Headers = map[string]string{"Authorization": "Bearer token"}
+	cfg.OTLP.MinBackoff = time.Millisecond
+	cfg.ClusterName = "test-cluster"
+	sink, err := NewOTLPSink(cfg)
+	require.NoError(t, err)
+	return sink
+}
+
+func TestOTLPSink(t *testing.T) {
+	t.Run("requires an endpoint", func(t *testing.T) {
+		_, err := NewOTLPSink(configuration.SinkConfig{Type: configuration.OTLPSinkType})
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects unknown histogram types", func(t *testing.T) {
+		_, err := NewOTLPSink(configuration.SinkConfig{OTLP: configuration.OTLPSinkConfig{
+			Endpoint:      "http://localhost:4318",
+			HistogramType: "linear",
+		}})
+		assert.Error(t, err)
+	})
+
+	t.Run("sends gauges and sums with resource attributes", func(t *testing.T) {
+		r := &receiver{}
+		sink := newTestSink(t, r, configuration.SinkConfig{
+			WavefrontSinkConfig: configuration.WavefrontSinkConfig{
+				Transforms: configuration.Transforms{Prefix: "k8s.", Tags: map[string]string{"env": "test"}},
+			},
+		})
+		podTags := map[string]string{"pod_name": "web-1", "namespace_name": "default", "nodename": "node1", "label.app": "web"}
+		gauge := wf.NewPoint("pod.cpu.usage_rate", 2.5, 1600000000, "node1", podTags)
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			gauge,
+			wf.NewPoint("pod.network.rx.count", 10, 1600000000, "node1", podTags),
+			wf.NewPoint("node.cpu.usage_rate", 1, 1600000000, "node1", map[string]string{"nodename": "node1"}),
+		}})
+
+		resources, ms := r.resources()
+		require.Len(t, resources, 2)
+		assert.Equal(t, map[string]string{
+			"k8s.cluster.name":   "test-cluster",
+			"k8s.node.name":      "node1",
+			"k8s.namespace.name": "default",
+			"k8s.pod.name":       "web-1",
+		}, resources[0])
+		assert.Equal(t, map[string]string{"k8s.cluster.name": "test-cluster", "k8s.node.name": "node1"}, resources[1])
+
+		require.Len(t, ms[0], 2)
+		assert.Equal(t, "k8s.pod.cpu.usage_rate", ms[0][0].str(1))
+		dp := ms[0][0].message(5).message(1)
+		assert.Equal(t, 2.5, dp.double(4))
+		assert.Equal(t, map[string]string{"source": "node1", "label.app": "web", "env": "test"}, dp.attributes(7))
+
+		assert.Equal(t, "k8s.pod.network.rx.count", ms[0][1].str(1))
+		sum := ms[0][1].message(7)
+		assert.Equal(t, uint64(temporalityCumulative), sum.varint(2))
+		assert.Equal(t, uint64(1), sum.varint(3))
+
+		// the shared point is left untouched for other sinks
+		assert.NotContains(t, gauge.Tags(), "cluster")
+	})
+
+	t.Run("merges data points of the same metric and resource", func(t *testing.T) {
+		r := &receiver{}
+		sink := newTestSink(t, r, configuration.SinkConfig{})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			wf.NewPoint("metric", 1, 0, "node1", map[string]string{"a": "1"}),
+			wf.NewPoint("metric", 2, 0, "node1", map[string]string{"a": "2"}),
+		}})
+		_, ms := r.resources()
+		require.Len(t, ms, 1)
+		require.Len(t, ms[0], 1)
+		assert.Len(t, ms[0][0].message(5).messages(1), 2)
+	})
+
+	t.Run("sends staleness markers and created timestamps", func(t *testing.T) {
+		r := &receiver{}
+		sink := newTestSink(t, r, configuration.SinkConfig{})
+		counter := wf.NewPoint("requests.count", 5, 1600000100, "node1", map[string]string{"a": "1"})
+		counter.StartTime = 1600000000
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			counter,
+			wf.NewPoint("requests.count", wf.StaleNaN, 1600000100, "node1", map[string]string{"a": "2"}),
+		}})
+		_, ms := r.resources()
+		require.Len(t, ms, 1)
+		dps := ms[0][0].message(7).messages(1)
+		require.Len(t, dps, 2)
+		start, _ := protowire.ConsumeFixed64(dps[0][2][0])
+		assert.Equal(t, uint64(1600000000*1e9), start)
+		assert.Equal(t, uint64(0), dps[0].varint(8))
+		assert.Equal(t, uint64(flagNoRecordedValue), dps[1].varint(8))
+		assert.Equal(t, 0.0, dps[1].double(4))
+	})
+
+	t.Run("sends distributions as histograms", func(t *testing.T) {
+		centroids := []wf.Centroid{{Value: 1, Count: 2}, {Value: 4, Count: 1}}
+
+		r := &receiver{}
+		sink := newTestSink(t, r, configuration.SinkConfig{})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			wf.NewFrequencyDistribution("latency", "node1", nil, centroids, time.Now()),
+		}})
+		_, ms := r.resources()
+		require.Len(t, ms, 1)
+		hist := ms[0][0].message(10)
+		assert.Equal(t, uint64(temporalityDelta), hist.varint(2))
+		assert.Len(t, hist.messages(1), 1)
+
+		r = &receiver{}
+		sink = newTestSink(t, r, configuration.SinkConfig{OTLP: configuration.OTLPSinkConfig{HistogramType: "explicit"}})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			wf.NewCumulativeDistribution("latency", "node1", nil, centroids, time.Now()),
+		}})
+		_, ms = r.resources()
+		require.Len(t, ms, 1)
+		hist = ms[0][0].message(9)
+		assert.Equal(t, uint64(temporalityCumulative), hist.varint(2))
+		assert.Len(t, hist.messages(1), 1)
+	})
+
+	t.Run("sends events as log records", func(t *testing.T) {
+		r := &receiver{}
+		sink := newTestSink(t, r, configuration.SinkConfig{})
+		ts := time.Unix(1600000000, 0)
+		sink.ExportEvent(&events.Event{
+			Message: "Back-off restarting failed container",
+			Ts:      ts,
+			Host:    "node1",
+			Options: []event.Option{
+				event.Type("Warning"),
+				event.Annotate("pod_name", "web-1"),
+				event.Annotate("reason", "BackOff"),
+			},
+		})
+
+		require.Len(t, r.requests[logsPath], 1)
+		rl := r.requests[logsPath][0].message(1)
+		assert.Equal(t, map[string]string{
+			"k8s.cluster.name": "test-cluster",
+			"k8s.node.name":    "node1",
+			"k8s.pod.name":     "web-1",
+		}, rl.message(1).attributes(1))
+		record := rl.message(2).message(2)
+		v, _ := protowire.ConsumeFixed64(record[1][0])
+		assert.Equal(t, uint64(ts.UnixNano()), v)
+		assert.Equal(t, uint64(severityWarn), record.varint(2))
+		assert.Equal(t, "Back-off restarting failed container", record.message(5).str(1))
+		assert.Equal(t, map[string]string{"type": "Warning", "reason": "BackOff"}, record.attributes(6))
+	})
+
+	t.Run("retries recoverable errors", func(t *testing.T) {
+		r := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+		sink := newTestSink(t, r, configuration.SinkConfig{})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 0, "node1", nil)}})
+		assert.Len(t, r.requests[metricsPath], 1)
+	})
+
+	t.Run("does not retry when maxRetries is 0", func(t *testing.T) {
+		r := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+		maxRetries := 0
+		sink := newTestSink(t, r, configuration.SinkConfig{OTLP: configuration.OTLPSinkConfig{MaxRetries: &maxRetries}})
+		before := sink.counters.errPoints.Count()
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 0, "node1", nil)}})
+		assert.Empty(t, r.requests[metricsPath])
+		assert.Equal(t, before+1, sink.counters.errPoints.Count())
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		r := &receiver{statuses: []int{http.StatusBadRequest}}
+		sink := newTestSink(t, r, configuration.SinkConfig{})
+		before := sink.counters.errPoints.Count()
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 0, "node1", nil)}})
+		assert.Empty(t, r.requests[metricsPath])
+		assert.Equal(t, before+1, sink.counters.errPoints.Count())
+	})
+}
+
+func TestToExplicitHistogram(t *testing.T) {
+	t.Run("frequency distribution", func(t *testing.T) {
+		d := wf.NewFrequencyDistribution("latency", "node1", nil, []wf.Centroid{
+			{Value: 1, Count: 2},
+			{Value: 4, Count: 1},
+			{Value: 4, Count: 2},
+		}, time.Now())
+		dp := toExplicitHistogram(d)
+		assert.Equal(t, []float64{1, 4}, dp.ExplicitBounds)
+		assert.Equal(t, []uint64{2, 3, 0}, dp.BucketCounts)
+		assert.Equal(t, uint64(5), dp.Count)
+		assert.True(t, dp.HasSum)
+		assert.Equal(t, 14.0, dp.Sum)
+	})
+
+	t.Run("cumulative distribution", func(t *testing.T) {
+		d := wf.NewCumulativeDistribution("latency", "node1", nil, []wf.Centroid{
+			{Value: 0.1, Count: 2},
+			{Value: 1, Count: 5},
+			{Value: math.Inf(1), Count: 6},
+		}, time.Now())
+		dp := toExplicitHistogram(d)
+		assert.Equal(t, []float64{0.1, 1}, dp.ExplicitBounds)
+		assert.Equal(t, []uint64{2, 3, 1}, dp.BucketCounts)
+		assert.Equal(t, uint64(6), dp.Count)
+		assert.False(t, dp.HasSum)
+	})
+}
+
+func TestToExponentialHistogram(t *testing.T) {
+	d := wf.NewFrequencyDistribution("latency", "node1", nil, []wf.Centroid{
+		{Value: 0, Count: 1},
+		{Value: 1, Count: 2},
+		{Value: 1.05, Count: 1},
+		{Value: 4, Count: 3},
+		{Value: -2, Count: 1},
+	}, time.Now())
+	dp := toExponentialHistogram(d)
+
+	assert.Equal(t, uint64(8), dp.Count)
+	assert.InDelta(t, 13.05, dp.Sum, 1e-9)
+	assert.Equal(t, uint64(1), dp.ZeroCount)
+	assert.Equal(t, int32(exponentialScale), dp.Scale)
+	// 1 falls in bucket -1, 1.05 in bucket 0 and 4 in bucket 15 at scale 3
+	assert.Equal(t, int32(-1), dp.Positive.Offset)
+	assert.Equal(t, []uint64{2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3}, dp.Positive.BucketCounts)
+	assert.Equal(t, buckets{Offset: 7, BucketCounts: []uint64{1}}, dp.Negative)
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+const (
+	// nativeSchema is the resolution of the exponential buckets. Bucket boundaries grow by a factor of 2^(2^-3).
+	nativeSchema = 3
+
+	// centroids with an absolute value at or below this threshold are counted in the zero bucket
+	zeroThreshold = 1e-128
+)
+
+// toNativeHistogram maps the centroids of a frequency distribution onto the exponential buckets of a
+// Prometheus native histogram. Each centroid is counted in the bucket that contains its value.
+func toNativeHistogram(d *wf.Distribution, timestamp int64) histogram {
+	h := histogram{
+		Schema:        nativeSchema,
+		ZeroThreshold: zeroThreshold,
+		ResetHint:     resetHintGauge,
+		Timestamp:     timestamp,
+	}
+	positive := map[int32]float64{}
+	negative := map[int32]float64{}
+	for _, centroid := range d.Centroids {
+		if centroid.Count == 0 {
+			continue
+		}
+		h.Count += centroid.Count
+		h.Sum += centroid.Value * centroid.Count
+		switch {
+		case math.Abs(centroid.Value) <= zeroThreshold:
+			h.ZeroCount += centroid.Count
+		case centroid.Value > 0:
+			positive[bucketIndex(centroid.Value)] += centroid.Count
+		default:
+			negative[bucketIndex(-centroid.Value)] += centroid.Count
+		}
+	}
+	h.PositiveSpans, h.PositiveCounts = toSpans(positive)
+	h.NegativeSpans, h.NegativeCounts = toSpans(negative)
+	return h
+}
+
+// bucketIndex returns the index of the bucket (base^(i-1), base^i] containing v for the native schema.
+func bucketIndex(v float64) int32 {
+	return int32(math.Ceil(math.Log2(v) * math.Exp2(nativeSchema)))
+}
+
+// toSpans converts sparse bucket counts into spans of consecutive buckets and their absolute counts.
+func toSpans(buckets map[int32]float64) ([]bucketSpan, []float64) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+	indexes := make([]int32, 0, len(buckets))
+	for index := range buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	var spans []bucketSpan
+	counts := make([]float64, 0, len(indexes))
+	for i, index := range indexes {
+		if i == 0 {
+			spans = append(spans, bucketSpan{Offset: index, Length: 1})
+		} else if gap := index - indexes[i-1] - 1; gap > 0 {
+			spans = append(spans, bucketSpan{Offset: gap, Length: 1})
+		} else {
+			spans[len(spans)-1].Length++
+		}
+		counts = append(counts, buckets[index])
+	}
+	return spans, counts
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package remotewrite
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The types below mirror the subset of the Prometheus remote-write protobuf messages (prompb) used by this sink.
+// See https://github.com/prometheus/prometheus/blob/main/prompb/remote.proto and types.proto.
+
+type label struct {
+	Name  string
+	Value string
+}
+
+type sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+type bucketSpan struct {
+	Offset int32
+	Length uint32
+}
+
+// resetHint values of the Histogram message
+const (
+	resetHintUnknown = 0
+	resetHintGauge   = 3
+)
+
+// histogram is a float native histogram
+type histogram struct {
+	Count          float64
+	Sum            float64
+	Schema         int32
+	ZeroThreshold  float64
+	ZeroCount      float64
+	NegativeSpans  []bucketSpan
+	NegativeCounts []float64
+	PositiveSpans  []bucketSpan
+	PositiveCounts []float64
+	ResetHint      int32
+	Timestamp      int64
+}
+
+type timeSeries struct {
+	Labels     []label
+	Samples    []sample
+	Histograms []histogram
+}
+
+// encodeWriteRequest encodes a prometheus.WriteRequest message.
+func encodeWriteRequest(series []timeSeries) []byte {
+	var b []byte
+	for _, ts := range series {
+		b = appendMessage(b, 1, encodeTimeSeries(ts))
+	}
+	return b
+}
+
+func encodeTimeSeries(ts timeSeries) []byte {
+	var b []byte
+	for _, l := range ts.Labels {
+		var lb []byte
+		lb = appendString(lb, 1, l.Name)
+		lb = appendString(lb, 2, l.Value)
+		b = appendMessage(b, 1, lb)
+	}
+	for _, s := range ts.Samples {
+		var sb []byte
+		sb = appendDouble(sb, 1, s.Value)
+		sb = appendInt64(sb, 2, s.Timestamp)
+		b = appendMessage(b, 2, sb)
+	}
+	for _, h := range ts.Histograms {
+		b = appendMessage(b, 4, encodeHistogram(h))
+	}
+	return b
+}
+
+func encodeHistogram(h histogram) []byte {
+	var b []byte
+	b = appendDouble(b, 2, h.Count) // count_float
+	b = appendDouble(b, 3, h.Sum)
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, protowire.EncodeZigZag(int64(h.Schema)))
+	b = appendDouble(b, 5, h.ZeroThreshold)
+	b = appendDouble(b, 7, h.ZeroCount) // zero_count_float
+	for _, span := range h.NegativeSpans {
+		b = appendMessage(b, 8, encodeBucketSpan(span))
+	}
+	b = appendPackedDoubles(b, 10, h.NegativeCounts)
+	for _, span := range h.PositiveSpans {
+		b = appendMessage(b, 11, encodeBucketSpan(span))
+	}
+	b = appendPackedDoubles(b, 13, h.PositiveCounts)
+	if h.ResetHint != resetHintUnknown {
+		b = protowire.AppendTag(b, 14, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(h.ResetHint))
+	}
+	b = appendInt64(b, 15, h.Timestamp)
+	return b
+}
+
+func encodeBucketSpan(span bucketSpan) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, protowire.EncodeZigZag(int64(span.Offset)))
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(span.Length))
+	return b
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func appendInt64(b []byte, num protowire.Number, v int64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendPackedDoubles(b []byte, num protowire.Number, vs []float64) []byte {
+	if len(vs) == 0 {
+		return b
+	}
+	var packed []byte
+	for _, v := range vs {
+		packed = protowire.AppendFixed64(packed, math.Float64bits(v))
+	}
+	return appendMessage(b, num, packed)
+}
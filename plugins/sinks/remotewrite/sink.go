@@ -0,0 +1,217 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package remotewrite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+
+	gm "github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/wavefronthq/go-metrics-wavefront/reporting"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/events"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/httputil"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/plugins/sinks/sinkutil"
+)
+
+const (
+	defaultMaxSeriesPerRequest = 2000
+	defaultMaxRetries          = 3
+	defaultMinBackoff          = 100 * time.Millisecond
+	defaultMaxBackoff          = 5 * time.Second
+	defaultTimeout             = 30 * time.Second
+
+	sourceLabel = "source"
+)
+
+const sinkName = "prometheus_remote_write_sink"
+
+// sinkCounters are the internal metrics of a sink, tagged with the name of the sink
+type sinkCounters struct {
+	sentSeries     gm.Counter
+	errSeries      gm.Counter
+	filteredSeries gm.Counter
+	retries        gm.Counter
+}
+
+func newSinkCounters(name string) sinkCounters {
+	tags := map[string]string{"sink": name}
+	counter := func(key string) gm.Counter {
+		return gm.GetOrRegisterCounter(reporting.EncodeKey(key, tags), gm.DefaultRegistry)
+	}
+	return sinkCounters{
+		sentSeries:     counter("remotewrite.series.sent.count"),
+		errSeries:      counter("remotewrite.series.errors.count"),
+		filteredSeries: counter("remotewrite.series.filtered.count"),
+		retries:        counter("remotewrite.requests.retries.count"),
+	}
+}
+
+type remoteWriteSink struct {
+	url                 string
+	poster              *sinkutil.Poster
+	clusterName         string
+	prefix              string
+	globalTags          map[string]string
+	filters             filter.Filter
+	maxSeriesPerRequest int
+	counters            sinkCounters
+}
+
+// NewRemoteWriteSink creates a sink that ships points and distributions using the Prometheus remote-write protocol.
+func NewRemoteWriteSink(cfg configuration.SinkConfig) (*remoteWriteSink, error) {
+	rwCfg := cfg.RemoteWrite
+	if rwCfg.URL == "" {
+		return nil, fmt.Errorf("url missing for prometheus remote-write sink")
+	}
+	client, err := httputil.NewClient(rwCfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating remote-write client: %v", err)
+	}
+	client.Timeout = configuration.GetDurationValue(rwCfg.Timeout, defaultTimeout)
+
+	counters := newSinkCounters(configuration.GetStringValue(cfg.Name, sinkName))
+	sink := &remoteWriteSink{
+		url: rwCfg.URL,
+		poster: &sinkutil.Poster{
+			Name:       sinkName,
+			Client:     client,
+			MaxRetries: defaultMaxRetries,
+			MinBackoff: configuration.GetDurationValue(rwCfg.MinBackoff, defaultMinBackoff),
+			MaxBackoff: configuration.GetDurationValue(rwCfg.MaxBackoff, defaultMaxBackoff),
+			Retries:    counters.retries,
+		},
+		clusterName:         configuration.GetStringValue(cfg.ClusterName, "k8s-cluster"),
+		globalTags:          cfg.Tags,
+		filters:             filter.FromConfig(cfg.Filters),
+		maxSeriesPerRequest: rwCfg.MaxSeriesPerRequest,
+		counters:            counters,
+	}
+	if cfg.Prefix != "" {
+		sink.prefix = strings.Trim(cfg.Prefix, ".") + "."
+	}
+	if sink.maxSeriesPerRequest <= 0 {
+		sink.maxSeriesPerRequest = defaultMaxSeriesPerRequest
+	}
+	if rwCfg.MaxRetries != nil {
+		sink.poster.MaxRetries = *rwCfg.MaxRetries
+	}
+	return sink, nil
+}
+
+// SetFilters replaces the filters of the sink. It is called from the goroutine exporting to the sink.
+func (sink *remoteWriteSink) SetFilters(filters filter.Filter) {
+	sink.filters = filters
+}
+
+func (sink *remoteWriteSink) Name() string {
+	return sinkName
+}
+
+func (sink *remoteWriteSink) Stop() {
+	sink.poster.Client.CloseIdleConnections()
+}
+
+func (sink *remoteWriteSink) Export(batch *metrics.Batch) {
+	log.WithField("name", sink.Name()).Debugf("received metric points: %d", len(batch.Metrics))
+
+	series := make([]timeSeries, 0, len(batch.Metrics))
+	for _, metric := range batch.Metrics {
+		if metric == nil {
+			continue
+		}
+		ts, ok := sink.toTimeSeries(metric, batch.Timestamp)
+		if ok {
+			series = append(series, ts)
+		}
+	}
+
+	for start := 0; start < len(series); start += sink.maxSeriesPerRequest {
+		end := start + sink.maxSeriesPerRequest
+		if end > len(series) {
+			end = len(series)
+		}
+		if err := sink.send(series[start:end]); err != nil {
+			sink.counters.errSeries.Inc(int64(end - start))
+			log.WithField("name", sink.Name()).Errorf("error sending series: %v", err)
+		} else {
+			sink.counters.sentSeries.Inc(int64(end - start))
+		}
+	}
+}
+
+// ExportEvent is a no-op. The remote-write protocol has no representation for events.
+func (sink *remoteWriteSink) ExportEvent(*events.Event) {
+}
+
+// toTimeSeries converts a point or distribution into a time series without modifying the shared metric.
+func (sink *remoteWriteSink) toTimeSeries(metric wf.Metric, batchTime time.Time) (timeSeries, bool) {
+	tags, ok := sinkutil.Tags(metric, sink.clusterName, sink.globalTags, sink.filters, sink.counters.filteredSeries)
+	if !ok {
+		return timeSeries{}, false
+	}
+
+	switch m := metric.(type) {
+	case *wf.Point:
+		return timeSeries{
+			Labels:  sink.labels(m.Name(), m.Source, tags),
+			Samples: []sample{{Value: m.Value, Timestamp: sinkutil.ToMillis(m.Timestamp, batchTime)}},
+		}, true
+	case *wf.Distribution:
+		d := m.ToFrequency()
+		return timeSeries{
+			Labels:     sink.labels(d.Name(), d.Source, tags),
+			Histograms: []histogram{toNativeHistogram(d, distributionMillis(d, batchTime))},
+		}, true
+	default:
+		log.Debugf("unsupported metric type for remote-write: %T", metric)
+		return timeSeries{}, false
+	}
+}
+
+// distributionMillis returns the timestamp of a distribution in milliseconds, the batch time when it has none
+func distributionMillis(d *wf.Distribution, batchTime time.Time) int64 {
+	if d.Timestamp.IsZero() {
+		return sinkutil.ToMillis(0, batchTime)
+	}
+	return d.Timestamp.UnixNano() / int64(time.Millisecond)
+}
+
+// labels returns the sorted label set for a series. Prometheus requires labels to be sorted by name.
+func (sink *remoteWriteSink) labels(name, source string, tags map[string]string) []label {
+	labels := make([]label, 0, len(tags)+2)
+	labels = append(labels, label{Name: "__name__", Value: util.SanitizePrometheusName(sink.prefix + name)})
+	if source != "" {
+		labels = append(labels, label{Name: sourceLabel, Value: source})
+	}
+	for k, v := range tags {
+		if v == "" || k == sourceLabel {
+			continue
+		}
+		labels = append(labels, label{Name: util.SanitizePrometheusLabelName(k), Value: v})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
+// remoteWriteHeaders are the headers required by the remote-write protocol
+var remoteWriteHeaders = map[string]string{
+	"Content-Encoding":                  "snappy",
+	"Content-Type":                      "application/x-protobuf",
+	"X-Prometheus-Remote-Write-Version": "0.1.0",
+}
+
+func (sink *remoteWriteSink) send(series []timeSeries) error {
+	return sink.poster.Post(sink.url, remoteWriteHeaders, snappy.Encode(nil, encodeWriteRequest(series)))
+}
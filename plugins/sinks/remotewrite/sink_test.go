@@ -0,0 +1,242 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package remotewrite
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+type receiver struct {
+	mtx      sync.Mutex
+	statuses []int
+	requests int
+	series   []decodedSeries
+}
+
+type decodedSeries struct {
+	labels     map[string]string
+	samples    []float64
+	histograms int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.requests++
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	compressed, _ := ioutil.ReadAll(req.Body)
+	body, err := snappy.Decode(nil, compressed)
+	if err != nil || req.Header.Get("Content-Encoding") != "snappy" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for len(body) > 0 {
+		_, _, n := protowire.ConsumeTag(body)
+		body = body[n:]
+		ts, n := protowire.ConsumeBytes(body)
+		body = body[n:]
+		r.series = append(r.series, decodeSeries(ts))
+	}
+}
+
+func decodeSeries(b []byte) decodedSeries {
+	s := decodedSeries{labels: map[string]string{}}
+	for len(b) > 0 {
+		num, _, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		msg, n := protowire.ConsumeBytes(b)
+		b = b[n:]
+		switch num {
+		case 1:
+			var name, value string
+			for len(msg) > 0 {
+				fnum, _, n := protowire.ConsumeTag(msg)
+				msg = msg[n:]
+				v, n := protowire.ConsumeString(msg)
+				msg = msg[n:]
+				if fnum == 1 {
+					name = v
+				} else {
+					value = v
+				}
+			}
+			s.labels[name] = value
+		case 2:
+			_, _, n := protowire.ConsumeTag(msg)
+			v, _ := protowire.ConsumeFixed64(msg[n:])
+			s.samples = append(s.samples, math.Float64frombits(v))
+		case 4:
+			s.histograms++
+		}
+	}
+	return s
+}
+
+func newTestSink(t *testing.T, r *receiver, cfg configuration.SinkConfig) *remoteWriteSink {
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	cfg.RemoteWrite.URL = server.URL
+	cfg.RemoteWrite.MinBackoff = time.Millisecond
+	cfg.ClusterName = "test-cluster"
+	sink, err := NewRemoteWriteSink(cfg)
+	require.NoError(t, err)
+	return sink
+}
+
+func TestRemoteWriteSink(t *testing.T) {
+	t.Run("requires a url", func(t *testing.T) {
+		_, err := NewRemoteWriteSink(configuration.SinkConfig{Type: configuration.PrometheusRemoteWriteSinkType})
+		assert.Error(t, err)
+	})
+
+	t.Run("sends points and distributions as sanitized series", func(t *testing.T) {
+		r := &receiver{}
+		sink := newTestSink(t, r, configuration.SinkConfig{
+			WavefrontSinkConfig: configuration.WavefrontSinkConfig{
+				Transforms: configuration.Transforms{Prefix: "k8s.", Tags: map[string]string{"env": "test"}},
+			},
+		})
+		point := wf.NewPoint("cpu.usage-rate", 2.5, time.Now().Unix(), "node1", map[string]string{"label.app": "web"})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			point,
+			wf.NewFrequencyDistribution("latency", "node1", nil, []wf.Centroid{{Value: 1, Count: 2}, {Value: 4, Count: 1}}, time.Now()),
+		}})
+
+		require.Len(t, r.series, 2)
+		assert.Equal(t, map[string]string{
+			"__name__":  "k8s_cpu_usage_rate",
+			"source":    "node1",
+			"label_app": "web",
+			"cluster":   "test-cluster",
+			"env":       "test",
+		}, r.series[0].labels)
+		assert.Equal(t, []float64{2.5}, r.series[0].samples)
+		assert.Equal(t, "k8s_latency", r.series[1].labels["__name__"])
+		assert.Equal(t, 1, r.series[1].histograms)
+
+		// the shared point is left untouched for other sinks
+		assert.NotContains(t, point.Tags(), "cluster")
+	})
+
+	t.Run("applies filters", func(t *testing.T) {
+		r := &receiver{}
+		sink := newTestSink(t, r, configuration.SinkConfig{
+			WavefrontSinkConfig: configuration.WavefrontSinkConfig{
+				Transforms: configuration.Transforms{Filters: filter.Config{MetricDenyList: []string{"dropped.*"}}},
+			},
+		})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			wf.NewPoint("dropped.metric", 1, 0, "node1", nil),
+			wf.NewPoint("kept.metric", 1, 0, "node1", nil),
+		}})
+		require.Len(t, r.series, 1)
+		assert.Equal(t, "kept_metric", r.series[0].labels["__name__"])
+	})
+
+	t.Run("splits series into batches", func(t *testing.T) {
+		r := &receiver{}
+		sink := newTestSink(t, r, configuration.SinkConfig{
+			RemoteWrite: configuration.RemoteWriteSinkConfig{MaxSeriesPerRequest: 2},
+		})
+		var points []wf.Metric
+		for i := 0; i < 5; i++ {
+			points = append(points, wf.NewPoint("metric", float64(i), 0, "node1", nil))
+		}
+		sink.Export(&metrics.Batch{Metrics: points})
+		assert.Equal(t, 3, r.requests)
+		assert.Len(t, r.series, 5)
+	})
+
+	t.Run("retries recoverable errors", func(t *testing.T) {
+		r := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+		sink := newTestSink(t, r, configuration.SinkConfig{})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 0, "node1", nil)}})
+		assert.Equal(t, 3, r.requests)
+		assert.Len(t, r.series, 1)
+	})
+
+	t.Run("does not retry when maxRetries is 0", func(t *testing.T) {
+		r := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+		maxRetries := 0
+		sink := newTestSink(t, r, configuration.SinkConfig{
+			RemoteWrite: configuration.RemoteWriteSinkConfig{MaxRetries: &maxRetries},
+		})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 0, "node1", nil)}})
+		assert.Equal(t, 1, r.requests)
+		assert.Empty(t, r.series)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		r := &receiver{statuses: []int{http.StatusBadRequest}}
+		sink := newTestSink(t, r, configuration.SinkConfig{})
+		before := sink.counters.errSeries.Count()
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 0, "node1", nil)}})
+		assert.Equal(t, 1, r.requests)
+		assert.Equal(t, before+1, sink.counters.errSeries.Count())
+	})
+
+	t.Run("distributions without a timestamp use the batch time", func(t *testing.T) {
+		sink := newTestSink(t, &receiver{}, configuration.SinkConfig{})
+		batchTime := time.Unix(1600000000, 0)
+		distribution := wf.NewFrequencyDistribution("latency", "node1", nil, []wf.Centroid{{Value: 1, Count: 1}}, time.Time{})
+
+		ts, ok := sink.toTimeSeries(distribution, batchTime)
+		require.True(t, ok)
+		require.Len(t, ts.Histograms, 1)
+		assert.Equal(t, batchTime.UnixMilli(), ts.Histograms[0].Timestamp)
+	})
+
+	t.Run("counts per sink", func(t *testing.T) {
+		tenant1 := newTestSink(t, &receiver{}, configuration.SinkConfig{Name: "tenant1"})
+		tenant2 := newTestSink(t, &receiver{}, configuration.SinkConfig{Name: "tenant2"})
+		before := tenant2.counters.sentSeries.Count()
+		tenant1.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("metric", 1, 0, "node1", nil)}})
+		assert.Equal(t, int64(1), tenant1.counters.sentSeries.Count())
+		assert.Equal(t, before, tenant2.counters.sentSeries.Count())
+	})
+}
+
+func TestToNativeHistogram(t *testing.T) {
+	d := wf.NewFrequencyDistribution("latency", "node1", nil, []wf.Centroid{
+		{Value: 0, Count: 1},
+		{Value: 1, Count: 2},
+		{Value: 1.05, Count: 1},
+		{Value: 4, Count: 3},
+		{Value: -2, Count: 1},
+	}, time.Now())
+	h := toNativeHistogram(d, 1000)
+
+	assert.Equal(t, 8.0, h.Count)
+	assert.InDelta(t, 13.05, h.Sum, 1e-9)
+	assert.Equal(t, 1.0, h.ZeroCount)
+	assert.Equal(t, int32(resetHintGauge), h.ResetHint)
+	// 1 falls in bucket 0, 1.05 in bucket 1 and 4 in bucket 16 at schema 3
+	assert.Equal(t, []bucketSpan{{Offset: 0, Length: 2}, {Offset: 14, Length: 1}}, h.PositiveSpans)
+	assert.Equal(t, []float64{2, 1, 3}, h.PositiveCounts)
+	assert.Equal(t, []bucketSpan{{Offset: 8, Length: 1}}, h.NegativeSpans)
+	assert.Equal(t, []float64{1}, h.NegativeCounts)
+}
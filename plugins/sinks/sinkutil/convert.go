@@ -0,0 +1,92 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sinkutil
+
+import (
+	"math"
+	"time"
+
+	gm "github.com/rcrowley/go-metrics"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+// Tags returns a copy of the tags of a metric with the cluster name and the global tags that the metric does not
+// override. Returns false when the filters reject the metric, in which case filtered is incremented. Tags rejected
+// by the filters are removed.
+func Tags(metric wf.Metric, clusterName string, globalTags map[string]string, filters filter.Filter, filtered gm.Counter) (map[string]string, bool) {
+	tags := make(map[string]string, len(metric.Tags())+len(globalTags)+1)
+	for k, v := range metric.Tags() {
+		tags[k] = v
+	}
+	tags[metrics.LabelCluster.Key] = clusterName
+	for k, v := range globalTags {
+		if _, exists := tags[k]; !exists {
+			tags[k] = v
+		}
+	}
+	if filters != nil {
+		if !filters.MatchMetric(metric.Name(), tags) {
+			filtered.Inc(1)
+			return nil, false
+		}
+		for name := range tags {
+			if !filters.MatchTag(name) {
+				delete(tags, name)
+			}
+		}
+	}
+	return tags, true
+}
+
+// ToMillis normalizes a point timestamp in seconds, milliseconds, microseconds or nanoseconds to milliseconds.
+// Points without a timestamp are stamped with the time of the batch, or the current time without one.
+func ToMillis(ts int64, batchTime time.Time) int64 {
+	switch {
+	case ts <= 0:
+		return orNow(batchTime).UnixNano() / int64(time.Millisecond)
+	case ts < 1e11:
+		return ts * 1e3
+	case ts < 1e14:
+		return ts
+	case ts < 1e17:
+		return ts / 1e3
+	default:
+		return ts / 1e6
+	}
+}
+
+// ToUnixNano normalizes a point timestamp in seconds, milliseconds, microseconds or nanoseconds to nanoseconds.
+// Points without a timestamp are stamped with the time of the batch, or the current time without one.
+func ToUnixNano(ts int64, batchTime time.Time) uint64 {
+	switch {
+	case ts <= 0:
+		return uint64(orNow(batchTime).UnixNano())
+	case ts < 1e11:
+		return uint64(ts) * 1e9
+	case ts < 1e14:
+		return uint64(ts) * 1e6
+	case ts < 1e17:
+		return uint64(ts) * 1e3
+	default:
+		return uint64(ts)
+	}
+}
+
+func orNow(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
+// RoundCount rounds a centroid count to the nearest whole count, negative counts are rounded to zero.
+func RoundCount(count float64) uint64 {
+	if count <= 0 {
+		return 0
+	}
+	return uint64(math.Round(count))
+}
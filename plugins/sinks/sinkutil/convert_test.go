@@ -0,0 +1,61 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sinkutil
+
+import (
+	"testing"
+	"time"
+
+	gm "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+func TestTags(t *testing.T) {
+	point := wf.NewPoint("cpu.usage", 1, 0, "node1", map[string]string{"env": "prod", "pod_id": "123"})
+	globalTags := map[string]string{"env": "global", "region": "us"}
+	filtered := gm.NewCounter()
+
+	tags, ok := Tags(point, "c1", globalTags, nil, filtered)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"cluster": "c1", "env": "prod", "pod_id": "123", "region": "us"}, tags)
+	assert.Equal(t, map[string]string{"env": "prod", "pod_id": "123"}, point.Tags(), "the shared metric is not modified")
+
+	filters := filter.NewGlobFilter(filter.Config{TagExclude: []string{"pod_id"}})
+	tags, ok = Tags(point, "c1", nil, filters, filtered)
+	assert.True(t, ok)
+	assert.NotContains(t, tags, "pod_id")
+
+	filters = filter.NewGlobFilter(filter.Config{MetricDenyList: []string{"cpu.*"}})
+	_, ok = Tags(point, "c1", nil, filters, filtered)
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), filtered.Count())
+}
+
+func TestToMillis(t *testing.T) {
+	batchTime := time.Unix(100, 0)
+	assert.Equal(t, int64(100000), ToMillis(0, batchTime))
+	assert.Equal(t, int64(1600000000000), ToMillis(1600000000, batchTime))
+	assert.Equal(t, int64(1600000000000), ToMillis(1600000000000, batchTime))
+	assert.Equal(t, int64(1600000000000), ToMillis(1600000000000000, batchTime))
+	assert.Equal(t, int64(1600000000000), ToMillis(1600000000000000000, batchTime))
+	assert.InDelta(t, time.Now().UnixNano()/int64(time.Millisecond), ToMillis(0, time.Time{}), 1000)
+}
+
+func TestToUnixNano(t *testing.T) {
+	batchTime := time.Unix(100, 0)
+	assert.Equal(t, uint64(100e9), ToUnixNano(0, batchTime))
+	assert.Equal(t, uint64(1600000000e9), ToUnixNano(1600000000, batchTime))
+	assert.Equal(t, uint64(1600000000e9), ToUnixNano(1600000000000, batchTime))
+	assert.Equal(t, uint64(1600000000e9), ToUnixNano(1600000000000000, batchTime))
+	assert.Equal(t, uint64(1600000000e9), ToUnixNano(1600000000000000000, batchTime))
+}
+
+func TestRoundCount(t *testing.T) {
+	assert.Equal(t, uint64(0), RoundCount(-1))
+	assert.Equal(t, uint64(2), RoundCount(1.5))
+	assert.Equal(t, uint64(1), RoundCount(1.4))
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sinkutil holds the request handling and conversions shared by the sinks that ship data to HTTP endpoints
+// or expose it in Prometheus formats.
+package sinkutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	gm "github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Poster posts request bodies and retries recoverable failures with an exponential backoff
+type Poster struct {
+	Name       string
+	Client     *http.Client
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Retries counts the retried requests
+	Retries gm.Counter
+}
+
+// Post sends the body to the url with the given headers. Network errors, 5xx and 429 responses are retried.
+func (p *Poster) Post(url string, headers map[string]string, body []byte) error {
+	backoff := p.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := p.post(url, headers, body)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(recoverableError); !ok || attempt >= p.MaxRetries {
+			return err
+		}
+		p.Retries.Inc(1)
+		log.WithField("name", p.Name).Debugf("retrying request in %s: %v", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// recoverableError marks errors that are worth retrying: network errors, 5xx and 429 responses.
+type recoverableError struct {
+	error
+}
+
+func (p *Poster) post(url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return recoverableError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+	err = fmt.Errorf("server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return recoverableError{err}
+	}
+	return err
+}
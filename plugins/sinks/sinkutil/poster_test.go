@@ -0,0 +1,53 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sinkutil
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gm "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoster(t *testing.T) {
+	var statuses []int
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "payload", string(body))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		w.WriteHeader(statuses[requests])
+		requests++
+	}))
+	defer server.Close()
+
+	newPoster := func() *Poster {
+		return &Poster{Client: server.Client(), MaxRetries: 2, Retries: gm.NewCounter()}
+	}
+	headers := map[string]string{"Content-Type": "application/x-protobuf"}
+
+	t.Run("retries recoverable errors", func(t *testing.T) {
+		statuses, requests = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, 0
+		poster := newPoster()
+		assert.NoError(t, poster.Post(server.URL, headers, []byte("payload")))
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, int64(2), poster.Retries.Count())
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		statuses, requests = []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, 0
+		assert.Error(t, newPoster().Post(server.URL, headers, []byte("payload")))
+		assert.Equal(t, 3, requests)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		statuses, requests = []int{http.StatusBadRequest}, 0
+		err := newPoster().Post(server.URL, headers, []byte("payload"))
+		assert.Contains(t, err.Error(), "400 Bad Request")
+		assert.Equal(t, 1, requests)
+	})
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sinks
+
+import (
+	"fmt"
+	"regexp"
+
+	gm "github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/wavefronthq/go-metrics-wavefront/reporting"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+type transformRule struct {
+	action      string
+	match       *regexp.Regexp
+	tag         string
+	newTag      string
+	value       string
+	valueMatch  *regexp.Regexp
+	replacement string
+}
+
+func compileTransformRules(cfgs []configuration.TransformRule) ([]transformRule, error) {
+	rules := make([]transformRule, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		rule, err := compileTransformRule(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transform %d: %v", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func compileTransformRule(cfg configuration.TransformRule) (transformRule, error) {
+	rule := transformRule{
+		action:      cfg.Action,
+		tag:         cfg.Tag,
+		newTag:      cfg.NewTag,
+		value:       cfg.Value,
+		replacement: cfg.Replacement,
+	}
+	var err error
+	if cfg.Match != "" {
+		if rule.match, err = anchoredRegexp(cfg.Match); err != nil {
+			return rule, fmt.Errorf("invalid match: %v", err)
+		}
+	}
+	if cfg.ValueMatch != "" {
+		if rule.valueMatch, err = anchoredRegexp(cfg.ValueMatch); err != nil {
+			return rule, fmt.Errorf("invalid valueMatch: %v", err)
+		}
+	}
+
+	switch cfg.Action {
+	case configuration.RenameMetricAction:
+		if rule.match == nil || rule.replacement == "" {
+			return rule, fmt.Errorf("%s requires match and replacement", cfg.Action)
+		}
+		return rule, nil
+	case configuration.AddTagAction, configuration.DropTagAction:
+	case configuration.RenameTagAction:
+		if rule.newTag == "" {
+			return rule, fmt.Errorf("%s requires newTag", cfg.Action)
+		}
+	case configuration.RewriteTagValueAction, configuration.DropByTagValueAction:
+		if rule.valueMatch == nil {
+			return rule, fmt.Errorf("%s requires valueMatch", cfg.Action)
+		}
+	default:
+		return rule, fmt.Errorf("unknown action: %q", cfg.Action)
+	}
+	if rule.tag == "" {
+		return rule, fmt.Errorf("%s requires tag", cfg.Action)
+	}
+	return rule, nil
+}
+
+// ValidateTransformRule checks that a transform rule has a known action, the properties it requires and valid
+// regular expressions
+func ValidateTransformRule(cfg configuration.TransformRule) error {
+	_, err := compileTransformRule(cfg)
+	return err
+}
+
+// anchoredRegexp compiles a regular expression that must match the whole input.
+func anchoredRegexp(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// apply transforms the name and tags of a metric in place. It returns the new name and false if the metric is dropped.
+func (rule transformRule) apply(name string, tags map[string]string) (string, bool) {
+	if rule.match != nil && !rule.match.MatchString(name) {
+		return name, true
+	}
+	switch rule.action {
+	case configuration.RenameMetricAction:
+		return rule.match.ReplaceAllString(name, rule.replacement), true
+	case configuration.AddTagAction:
+		tags[rule.tag] = rule.value
+	case configuration.DropTagAction:
+		delete(tags, rule.tag)
+	case configuration.RenameTagAction:
+		if value, ok := tags[rule.tag]; ok {
+			delete(tags, rule.tag)
+			tags[rule.newTag] = value
+		}
+	case configuration.RewriteTagValueAction:
+		if value, ok := tags[rule.tag]; ok && rule.valueMatch.MatchString(value) {
+			tags[rule.tag] = rule.valueMatch.ReplaceAllString(value, rule.replacement)
+		}
+	case configuration.DropByTagValueAction:
+		if value, ok := tags[rule.tag]; ok && rule.valueMatch.MatchString(value) {
+			return name, false
+		}
+	}
+	return name, true
+}
+
+// transformSink applies the transforms configured for a sink before handing the metrics to it.
+// It relies on the sink manager handing every sink its own copy of the batch.
+type transformSink struct {
+	Sink
+	name    string
+	rules   []transformRule
+	dropped gm.Counter
+}
+
+func newTransformSink(cfg configuration.SinkConfig, sink Sink) (Sink, error) {
+	rules, err := compileTransformRules(cfg.TransformRules)
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.Name
+	if name == "" {
+		name = sink.Name()
+	}
+	return &transformSink{
+		Sink:    sink,
+		name:    name,
+		rules:   rules,
+		dropped: gm.GetOrRegisterCounter(reporting.EncodeKey("sink.transforms.dropped", map[string]string{"sink": name}), gm.DefaultRegistry),
+	}, nil
+}
+
+// update replaces the transforms of the sink and the filters of the wrapped sink
+func (sink *transformSink) update(update sinkUpdate) {
+	sink.rules = update.rules
+	if setter, ok := sink.Sink.(filterSetter); ok {
+		setter.SetFilters(update.filters)
+	}
+}
+
+func (sink *transformSink) Name() string {
+	return sink.name
+}
+
+func (sink *transformSink) Export(batch *metrics.Batch) {
+	if len(sink.rules) == 0 {
+		sink.Sink.Export(batch)
+		return
+	}
+	transformed := make([]wf.Metric, 0, len(batch.Metrics))
+	for _, metric := range batch.Metrics {
+		if metric == nil {
+			continue
+		}
+		if metric = sink.transform(metric); metric != nil {
+			transformed = append(transformed, metric)
+		}
+	}
+	sink.Sink.Export(&metrics.Batch{
+		Timestamp: batch.Timestamp,
+		Sets:      batch.Sets,
+		Metrics:   transformed,
+	})
+}
+
+// transform applies all rules to the metric. It returns nil when the metric is dropped.
+func (sink *transformSink) transform(metric wf.Metric) wf.Metric {
+	name := metric.Name()
+	tags := metric.Tags()
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	for _, rule := range sink.rules {
+		var keep bool
+		if name, keep = rule.apply(name, tags); !keep {
+			sink.dropped.Inc(1)
+			return nil
+		}
+	}
+
+	switch m := metric.(type) {
+	case *wf.Point:
+		m.Metric = name
+		m.SetLabelPairs(nil)
+		m.SetTags(tags)
+		return m
+	case *wf.Distribution:
+		m.SetName(name)
+		m.SetTags(tags)
+		return m
+	default:
+		log.WithField("name", sink.name).Debugf("transforms are not supported for metric type %T", metric)
+		return metric
+	}
+}
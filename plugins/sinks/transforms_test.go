@@ -0,0 +1,197 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sinks
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/events"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+type recordingSink struct {
+	mtx     sync.Mutex
+	batches []*metrics.Batch
+}
+
+func (s *recordingSink) Name() string { return "recording_sink" }
+
+func (s *recordingSink) Stop() {}
+
+func (s *recordingSink) ExportEvent(*events.Event) {}
+
+func (s *recordingSink) Export(batch *metrics.Batch) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.batches = append(s.batches, batch)
+}
+
+func (s *recordingSink) last() *metrics.Batch {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.batches) == 0 {
+		return nil
+	}
+	return s.batches[len(s.batches)-1]
+}
+
+func transformed(t *testing.T, rules []configuration.TransformRule, input ...wf.Metric) []wf.Metric {
+	inner := &recordingSink{}
+	sink, err := newTransformSink(configuration.SinkConfig{Name: "test", TransformRules: rules}, inner)
+	require.NoError(t, err)
+	sink.Export(&metrics.Batch{Metrics: input})
+	return inner.last().Metrics
+}
+
+func TestTransformRules(t *testing.T) {
+	t.Run("renames metrics by regex", func(t *testing.T) {
+		started := time.Now().Add(-time.Hour)
+		cumulative := wf.NewCumulativeDistribution("kubernetes.duration", "node1", nil, nil, time.Now())
+		cumulative.StartTime = started
+		out := transformed(t, []configuration.TransformRule{{
+			Action:      configuration.RenameMetricAction,
+			Match:       `kubernetes\.(.*)`,
+			Replacement: "k8s.$1",
+		}},
+			wf.NewPoint("kubernetes.pod.cpu", 1, 0, "node1", nil),
+			wf.NewPoint("other.kubernetes.pod.cpu", 1, 0, "node1", nil),
+			wf.NewFrequencyDistribution("kubernetes.latency", "node1", nil, nil, time.Now()),
+			cumulative,
+		)
+		require.Len(t, out, 4)
+		assert.Equal(t, "k8s.pod.cpu", out[0].Name())
+		assert.Equal(t, "other.kubernetes.pod.cpu", out[1].Name())
+		assert.Equal(t, "k8s.latency", out[2].Name())
+		assert.Equal(t, "k8s.duration", out[3].Name())
+		assert.True(t, out[3].(*wf.Distribution).Cumulative)
+		assert.Equal(t, started, out[3].(*wf.Distribution).StartTime, "the start time of cumulative distributions is kept")
+	})
+
+	t.Run("adds, drops and renames tags", func(t *testing.T) {
+		out := transformed(t, []configuration.TransformRule{
+			{Action: configuration.AddTagAction, Tag: "tenant", Value: "a"},
+			{Action: configuration.AddTagAction, Match: "node.*", Tag: "scope", Value: "node"},
+			{Action: configuration.DropTagAction, Tag: "pod_id"},
+			{Action: configuration.RenameTagAction, Tag: "namespace_name", NewTag: "namespace"},
+		}, wf.NewPoint("pod.cpu", 1, 0, "node1", map[string]string{"pod_id": "123", "namespace_name": "default"}))
+		require.Len(t, out, 1)
+		assert.Equal(t, map[string]string{"tenant": "a", "namespace": "default"}, out[0].Tags())
+	})
+
+	t.Run("rewrites tag values", func(t *testing.T) {
+		out := transformed(t, []configuration.TransformRule{{
+			Action:      configuration.RewriteTagValueAction,
+			Tag:         "pod_name",
+			ValueMatch:  `(.*)-[a-z0-9]{5}`,
+			Replacement: "$1",
+		}},
+			wf.NewPoint("pod.cpu", 1, 0, "node1", map[string]string{"pod_name": "web-x7k2p"}),
+			wf.NewPoint("pod.cpu", 1, 0, "node1", map[string]string{"pod_name": "web"}),
+		)
+		require.Len(t, out, 2)
+		assert.Equal(t, "web", out[0].Tags()["pod_name"])
+		assert.Equal(t, "web", out[1].Tags()["pod_name"])
+	})
+
+	t.Run("drops metrics by tag value", func(t *testing.T) {
+		sink, err := newTransformSink(configuration.SinkConfig{
+			Name: "drop-test",
+			TransformRules: []configuration.TransformRule{{
+				Action:     configuration.DropByTagValueAction,
+				Tag:        "namespace_name",
+				ValueMatch: "kube-.*",
+			}},
+		}, &recordingSink{})
+		require.NoError(t, err)
+		before := sink.(*transformSink).dropped.Count()
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			wf.NewPoint("pod.cpu", 1, 0, "node1", map[string]string{"namespace_name": "kube-system"}),
+			wf.NewPoint("pod.cpu", 1, 0, "node1", map[string]string{"namespace_name": "default"}),
+			wf.NewPoint("node.cpu", 1, 0, "node1", nil),
+		}})
+		out := sink.(*transformSink).Sink.(*recordingSink).last().Metrics
+		assert.Len(t, out, 2)
+		assert.Equal(t, before+1, sink.(*transformSink).dropped.Count())
+	})
+
+	t.Run("rejects invalid rules", func(t *testing.T) {
+		for _, rule := range []configuration.TransformRule{
+			{Action: "unknown"},
+			{Action: configuration.RenameMetricAction, Match: "a.*"},
+			{Action: configuration.AddTagAction},
+			{Action: configuration.RenameTagAction, Tag: "a"},
+			{Action: configuration.DropByTagValueAction, Tag: "a"},
+			{Action: configuration.DropTagAction, Tag: "a", Match: "("},
+		} {
+			_, err := newTransformSink(configuration.SinkConfig{TransformRules: []configuration.TransformRule{rule}}, &recordingSink{})
+			assert.Error(t, err, "%+v", rule)
+		}
+	})
+
+	t.Run("defaults the name to the wrapped sink", func(t *testing.T) {
+		sink, err := newTransformSink(configuration.SinkConfig{}, &recordingSink{})
+		require.NoError(t, err)
+		assert.Equal(t, "recording_sink", sink.Name())
+	})
+}
+
+func TestSinkManagerIsolatesBatches(t *testing.T) {
+	tenantA := &recordingSink{}
+	tenantB := &recordingSink{}
+	sinkA, err := newTransformSink(configuration.SinkConfig{Name: "tenant-a", TransformRules: []configuration.TransformRule{
+		{Action: configuration.AddTagAction, Tag: "tenant", Value: "a"},
+	}}, tenantA)
+	require.NoError(t, err)
+	sinkB, err := newTransformSink(configuration.SinkConfig{Name: "tenant-b"}, tenantB)
+	require.NoError(t, err)
+
+	manager, _ := NewSinkManager([]Sink{sinkA, sinkB}, time.Second, time.Second)
+	point := wf.NewPoint("pod.cpu", 1, 0, "node1", map[string]string{"pod_name": "web"})
+	manager.Export(&metrics.Batch{Metrics: []wf.Metric{point}})
+
+	assert.Eventually(t, func() bool { return tenantA.last() != nil && tenantB.last() != nil }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "a", tenantA.last().Metrics[0].Tags()["tenant"])
+	assert.NotContains(t, tenantB.last().Metrics[0].Tags(), "tenant")
+	assert.NotSame(t, tenantA.last().Metrics[0], tenantB.last().Metrics[0])
+}
+
+type filteringSink struct {
+	recordingSink
+	filters filter.Filter
+}
+
+func (s *filteringSink) SetFilters(filters filter.Filter) {
+	s.filters = filters
+}
+
+func TestSinkManagerReconfigure(t *testing.T) {
+	inner := &filteringSink{}
+	sink, err := newTransformSink(configuration.SinkConfig{Name: "test"}, inner)
+	require.NoError(t, err)
+	manager, _ := NewSinkManager([]Sink{sink}, time.Second, time.Second)
+	reconfigurable := manager.(Reconfigurable)
+
+	cfg := &configuration.SinkConfig{TransformRules: []configuration.TransformRule{
+		{Action: configuration.AddTagAction, Tag: "env", Value: "prod"},
+	}}
+	cfg.Filters = filter.Config{MetricDenyList: []string{"kube.*"}}
+	require.NoError(t, reconfigurable.Reconfigure([]*configuration.SinkConfig{cfg}))
+
+	manager.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("pod.cpu", 1, 0, "node1", nil)}})
+	assert.Eventually(t, func() bool { return inner.last() != nil }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "prod", inner.last().Metrics[0].Tags()["env"])
+	assert.NotNil(t, inner.filters)
+
+	invalid := &configuration.SinkConfig{TransformRules: []configuration.TransformRule{{Action: "unknown"}}}
+	assert.Error(t, reconfigurable.Reconfigure([]*configuration.SinkConfig{invalid}))
+	assert.Error(t, reconfigurable.Reconfigure([]*configuration.SinkConfig{cfg, cfg}))
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sinks
+
+import (
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/events"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+)
+
+// Sink is implemented by every sink type. The sink manager fans out metric batches and events to them.
+type Sink interface {
+	Name() string
+	Stop()
+	metrics.Sink
+	events.EventSink
+}
+
+// Reconfigurable is implemented by the sink manager to apply transform and filter changes to the running sinks
+type Reconfigurable interface {
+	Reconfigure(cfgs []*configuration.SinkConfig) error
+}
+
+// filterSetter is implemented by sinks that can replace their filters while running
+type filterSetter interface {
+	SetFilters(filters filter.Filter)
+}
@@ -0,0 +1,332 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package wavefront
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gm "github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/wavefronthq/wavefront-sdk-go/histogram"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+const (
+	segmentSuffix = ".wal"
+
+	defaultBufferMaxBytes = 256 * 1024 * 1024
+	defaultBufferMaxAge   = time.Hour
+
+	pointLine        = "point"
+	distributionLine = "distribution"
+
+	// bounds the time spent replaying so a large backlog does not hold up the export of the current batch
+	maxReplayLines = 10000
+)
+
+var (
+	bufferedLines  gm.Gauge
+	bufferedBytes  gm.Gauge
+	replayedLines  gm.Counter
+	droppedBytes   gm.Counter
+	bufferErrCount gm.Counter
+)
+
+func init() {
+	bufferedLines = gm.GetOrRegisterGauge("wavefront.buffer.lines", gm.DefaultRegistry)
+	bufferedBytes = gm.GetOrRegisterGauge("wavefront.buffer.bytes", gm.DefaultRegistry)
+	replayedLines = gm.GetOrRegisterCounter("wavefront.buffer.replayed.count", gm.DefaultRegistry)
+	droppedBytes = gm.GetOrRegisterCounter("wavefront.buffer.dropped.bytes", gm.DefaultRegistry)
+	bufferErrCount = gm.GetOrRegisterCounter("wavefront.buffer.errors.count", gm.DefaultRegistry)
+}
+
+// bufferedLine is a point or distribution handed to the sender, in the form it was handed over.
+type bufferedLine struct {
+	Kind      string               `json:"kind"`
+	Name      string               `json:"name"`
+	Value     float64              `json:"value,omitempty"`
+	Centroids []histogram.Centroid `json:"centroids,omitempty"`
+	// the granularities of a distribution, segments written by earlier versions only hold minute distributions
+	Granularities map[histogram.Granularity]bool `json:"granularities,omitempty"`
+	Timestamp     int64                          `json:"ts"`
+	Source        string                         `json:"source"`
+	Tags          map[string]string              `json:"tags,omitempty"`
+}
+
+func (l bufferedLine) send(to wf.Sender) error {
+	if l.Kind == distributionLine {
+		hgs := l.Granularities
+		if len(hgs) == 0 {
+			hgs = map[histogram.Granularity]bool{histogram.MINUTE: true}
+		}
+		return to.SendDistribution(l.Name, l.Centroids, hgs, l.Timestamp, l.Source, l.Tags)
+	}
+	return to.SendMetric(l.Name, l.Value, l.Timestamp, l.Source, l.Tags)
+}
+
+// retryBuffer is a write-ahead buffer that persists the lines handed to the sender to a local directory.
+// The sender queues lines in memory and flushes them in the background, so each export writes its lines into
+// a new segment that is only removed once a flush confirms they were sent. Segments are replayed oldest first
+// and are discarded once they exceed the configured age or the buffer exceeds its configured size.
+type retryBuffer struct {
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+
+	mtx     sync.Mutex
+	pending []bufferedLine
+	now     func() time.Time
+}
+
+func newRetryBuffer(cfg configuration.RetryBufferConfig) (*retryBuffer, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating retry buffer directory: %v", err)
+	}
+	buf := &retryBuffer{
+		dir:      cfg.Dir,
+		maxBytes: cfg.MaxBytes,
+		maxAge:   cfg.MaxAge,
+		now:      time.Now,
+	}
+	if buf.maxBytes <= 0 {
+		buf.maxBytes = defaultBufferMaxBytes
+	}
+	if buf.maxAge <= 0 {
+		buf.maxAge = defaultBufferMaxAge
+	}
+	buf.updateStats()
+	return buf, nil
+}
+
+// add records a line before it is handed to the sender. Lines are held in memory until commit is called.
+// Returns the line to send, pinned to the current time when it has no timestamp so a replay reports the same point.
+func (b *retryBuffer) add(line bufferedLine) bufferedLine {
+	if line.Timestamp == 0 {
+		line.Timestamp = b.now().Unix()
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.pending = append(b.pending, line)
+	return line
+}
+
+// commit writes the lines added since the last commit into a new segment and returns it.
+// The returned segment has no path when there was nothing to write.
+func (b *retryBuffer) commit() segment {
+	b.mtx.Lock()
+	lines := b.pending
+	b.pending = nil
+	b.mtx.Unlock()
+
+	var written segment
+	if len(lines) > 0 {
+		created := b.now()
+		path := b.segmentPath(created, len(lines))
+		if err := writeSegment(path, lines); err != nil {
+			bufferErrCount.Inc(1)
+			log.Errorf("error writing retry buffer segment: %v", err)
+		} else {
+			written = segment{path: path, created: created, lines: int64(len(lines))}
+		}
+	}
+	b.enforceLimits()
+	b.updateStats()
+	return written
+}
+
+// replay hands the lines of the buffered segments to the sender, oldest first, and returns the segments that were
+// handed over. Whole segments are replayed until the limit is reached. Lines the sender rejects are dropped.
+// The segments stay in the buffer until they are removed after a confirmed flush.
+func (b *retryBuffer) replay(to wf.Sender, limit int) []segment {
+	b.enforceLimits()
+
+	var replayed []segment
+	var sent int64
+	for _, segment := range b.segments() {
+		if sent >= int64(limit) {
+			log.WithField("segments", len(b.segments())-len(replayed)).Debug("retry buffer replay limit reached")
+			break
+		}
+		lines, err := readSegment(segment.path)
+		if err != nil {
+			bufferErrCount.Inc(1)
+			log.Errorf("error reading retry buffer segment %s: %v", segment.path, err)
+			b.drop(segment)
+			continue
+		}
+		for _, line := range lines {
+			if err := line.send(to); err != nil {
+				bufferErrCount.Inc(1)
+				log.WithField("name", line.Name).Debugf("error replaying retry buffer line: %v", err)
+				continue
+			}
+			replayedLines.Inc(1)
+		}
+		sent += int64(len(lines))
+		replayed = append(replayed, segment)
+	}
+	b.updateStats()
+	return replayed
+}
+
+// remove deletes segments whose lines were confirmed sent.
+func (b *retryBuffer) remove(segments ...segment) {
+	for _, s := range segments {
+		if s.path == "" {
+			continue
+		}
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			bufferErrCount.Inc(1)
+			log.Errorf("error removing retry buffer segment: %v", err)
+		}
+	}
+	b.updateStats()
+}
+
+// segment files are named <creation unix nanos>-<line count>.wal so listing the directory is enough to report stats.
+type segment struct {
+	path    string
+	created time.Time
+	size    int64
+	lines   int64
+}
+
+func (b *retryBuffer) segmentPath(created time.Time, lines int) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d-%d%s", created.UnixNano(), lines, segmentSuffix))
+}
+
+// segments returns the segment files in the buffer directory, oldest first.
+func (b *retryBuffer) segments() []segment {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		log.Errorf("error listing retry buffer directory: %v", err)
+		return nil
+	}
+	var segments []segment
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), segmentSuffix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSuffix(file.Name(), segmentSuffix), "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		lines, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{
+			path:    filepath.Join(b.dir, file.Name()),
+			created: time.Unix(0, nanos),
+			size:    file.Size(),
+			lines:   lines,
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].created.Before(segments[j].created)
+	})
+	return segments
+}
+
+// enforceLimits drops segments older than maxAge and then the oldest segments until the buffer fits in maxBytes.
+func (b *retryBuffer) enforceLimits() {
+	segments := b.segments()
+	var total int64
+	for _, s := range segments {
+		total += s.size
+	}
+	cutoff := b.now().Add(-b.maxAge)
+	for _, s := range segments {
+		if !s.created.Before(cutoff) && total <= b.maxBytes {
+			break
+		}
+		b.drop(s)
+		total -= s.size
+	}
+}
+
+func (b *retryBuffer) drop(s segment) {
+	if err := os.Remove(s.path); err != nil {
+		bufferErrCount.Inc(1)
+		log.Errorf("error removing retry buffer segment: %v", err)
+		return
+	}
+	droppedBytes.Inc(s.size)
+	log.WithFields(log.Fields{
+		"segment": filepath.Base(s.path),
+		"bytes":   s.size,
+	}).Warning("dropped retry buffer segment")
+}
+
+func (b *retryBuffer) updateStats() {
+	var lines, size int64
+	for _, s := range b.segments() {
+		size += s.size
+		lines += s.lines
+	}
+	bufferedLines.Update(lines)
+	bufferedBytes.Update(size)
+}
+
+func writeSegment(path string, lines []bufferedLine) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, line := range lines {
+		if err := enc.Encode(line); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readSegment(path string) ([]bufferedLine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []bufferedLine
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var line bufferedLine
+		if err := dec.Decode(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package wavefront
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wavefronthq/wavefront-sdk-go/histogram"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+// flakySender queues lines in memory like the SDK sender and loses them when a flush fails,
+// as happens when its queue overflows or the collector restarts while the proxy is down.
+type flakySender struct {
+	TestSender
+	down     bool
+	failures int64
+	queued   []string
+	names    []string
+	hgs      []map[histogram.Granularity]bool
+}
+
+func (f *flakySender) SendMetric(name string, value float64, ts int64, source string, tags map[string]string) error {
+	f.queued = append(f.queued, name)
+	return nil
+}
+
+func (f *flakySender) SendDistribution(name string, centroids []histogram.Centroid, hgs map[histogram.Granularity]bool, ts int64, source string, tags map[string]string) error {
+	f.queued = append(f.queued, name)
+	f.hgs = append(f.hgs, hgs)
+	return nil
+}
+
+func (f *flakySender) Flush() error {
+	queued := f.queued
+	f.queued = nil
+	if f.down {
+		return errors.New("proxy unavailable")
+	}
+	f.names = append(f.names, queued...)
+	return nil
+}
+
+func (f *flakySender) GetFailureCount() int64 {
+	return f.failures
+}
+
+func newBufferedSink(t *testing.T, cfg configuration.RetryBufferConfig) (*wavefrontSink, *flakySender) {
+	dir, err := ioutil.TempDir("", "wavefront-buffer")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	cfg.Dir = dir
+	buffer, err := newRetryBuffer(cfg)
+	require.NoError(t, err)
+	sender := &flakySender{}
+	return &wavefrontSink{
+		WavefrontClient: sender,
+		ClusterName:     "testCluster",
+		buffer:          buffer,
+		counters:        newSinkCounters("test"),
+	}, sender
+}
+
+func TestRetryBuffer(t *testing.T) {
+	t.Run("replays points lost by the sender once it recovers", func(t *testing.T) {
+		sink, sender := newBufferedSink(t, configuration.RetryBufferConfig{})
+
+		sender.down = true
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{
+			wf.NewPoint("first", 1.0, 0, "src", nil),
+			wf.NewFrequencyDistribution("second", "src", nil, []wf.Centroid{{Value: 1, Count: 2}}, time.Now()),
+		}})
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("third", 1.0, 0, "src", nil)}})
+		assert.Empty(t, sender.names)
+		assert.Len(t, sink.buffer.segments(), 2)
+		assert.Equal(t, int64(3), bufferedLines.Value())
+
+		sender.down = false
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("fourth", 1.0, 0, "src", nil)}})
+		assert.Equal(t, []string{"fourth", "first", "second", "third"}, sender.names)
+		assert.Empty(t, sink.buffer.segments())
+		assert.Equal(t, int64(0), bufferedLines.Value())
+	})
+
+	t.Run("keeps lines when the sender reports failures of background flushes", func(t *testing.T) {
+		sink, sender := newBufferedSink(t, configuration.RetryBufferConfig{})
+		sink.WavefrontClient = &failingInBackground{flakySender: sender}
+
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("first", 1.0, 0, "src", nil)}})
+		assert.Len(t, sink.buffer.segments(), 1)
+
+		sink.WavefrontClient = sender
+		sink.Export(&metrics.Batch{Metrics: []wf.Metric{wf.NewPoint("second", 1.0, 0, "src", nil)}})
+		assert.Equal(t, []string{"second", "first"}, sender.names)
+		assert.Empty(t, sink.buffer.segments())
+	})
+
+	t.Run("keeps replayed segments until the replay is flushed", func(t *testing.T) {
+		sink, sender := newBufferedSink(t, configuration.RetryBufferConfig{})
+		sink.buffer.add(bufferedLine{Kind: pointLine, Name: "first", Timestamp: 1})
+		sink.buffer.commit()
+
+		replayed := sink.buffer.replay(sender, maxReplayLines)
+		require.Len(t, replayed, 1)
+		assert.Equal(t, []string{"first"}, sender.queued)
+		assert.Len(t, sink.buffer.segments(), 1)
+
+		sink.buffer.remove(replayed...)
+		assert.Empty(t, sink.buffer.segments())
+	})
+
+	t.Run("replays whole segments up to the limit per call", func(t *testing.T) {
+		sink, sender := newBufferedSink(t, configuration.RetryBufferConfig{})
+		now := time.Now()
+		for i, names := range [][]string{{"first", "second"}, {"third"}} {
+			sink.buffer.now = func() time.Time { return now.Add(time.Duration(i) * time.Second) }
+			for _, name := range names {
+				sink.buffer.add(bufferedLine{Kind: pointLine, Name: name, Timestamp: 1})
+			}
+			sink.buffer.commit()
+		}
+
+		replayed := sink.buffer.replay(sender, 1)
+		require.Len(t, replayed, 1)
+		assert.Equal(t, []string{"first", "second"}, sender.queued)
+
+		sink.buffer.remove(replayed...)
+		assert.Equal(t, int64(1), bufferedLines.Value())
+		assert.Len(t, sink.buffer.replay(sender, 1), 1)
+		assert.Equal(t, []string{"first", "second", "third"}, sender.queued)
+	})
+
+	t.Run("flushes a batch per call until the handed over lines are sent", func(t *testing.T) {
+		sink, sender := newBufferedSink(t, configuration.RetryBufferConfig{})
+		counting := &countingFlushes{flakySender: sender}
+		sink.WavefrontClient = counting
+		sink.batchSize = 2
+
+		require.NoError(t, sink.flush(0, 5))
+		assert.Equal(t, 3, counting.flushes)
+	})
+
+	t.Run("keeps the granularities of distributions", func(t *testing.T) {
+		sink, sender := newBufferedSink(t, configuration.RetryBufferConfig{})
+		hourly := map[histogram.Granularity]bool{histogram.HOUR: true, histogram.DAY: true}
+		sink.buffer.add(bufferedLine{Kind: distributionLine, Name: "hourly", Granularities: hourly, Timestamp: 1})
+		// segments written before granularities were recorded
+		sink.buffer.add(bufferedLine{Kind: distributionLine, Name: "legacy", Timestamp: 1})
+		sink.buffer.commit()
+
+		sink.buffer.replay(sender, maxReplayLines)
+		assert.Equal(t, []map[histogram.Granularity]bool{hourly, {histogram.MINUTE: true}}, sender.hgs)
+	})
+
+	t.Run("drops segments older than max age", func(t *testing.T) {
+		sink, sender := newBufferedSink(t, configuration.RetryBufferConfig{MaxAge: time.Minute})
+		now := time.Now()
+		sink.buffer.now = func() time.Time { return now.Add(-2 * time.Minute) }
+		sink.buffer.add(bufferedLine{Kind: pointLine, Name: "stale"})
+		sink.buffer.commit()
+		sink.buffer.now = func() time.Time { return now }
+
+		before := droppedBytes.Count()
+		assert.Empty(t, sink.buffer.replay(sender, maxReplayLines))
+		assert.Empty(t, sender.queued)
+		assert.Greater(t, droppedBytes.Count(), before)
+	})
+
+	t.Run("drops oldest segments when over max bytes", func(t *testing.T) {
+		sink, sender := newBufferedSink(t, configuration.RetryBufferConfig{MaxBytes: 100})
+		now := time.Now()
+		for i, name := range []string{"oldest", "newest"} {
+			sink.buffer.now = func() time.Time { return now.Add(time.Duration(i) * time.Second) }
+			sink.buffer.add(bufferedLine{Kind: pointLine, Name: name, Source: "some-source-to-pad-the-line"})
+			sink.buffer.commit()
+		}
+
+		sink.buffer.replay(sender, maxReplayLines)
+		assert.Equal(t, []string{"newest"}, sender.queued)
+	})
+
+	t.Run("pins unset timestamps to the time they were sent", func(t *testing.T) {
+		sink, _ := newBufferedSink(t, configuration.RetryBufferConfig{})
+		now := time.Unix(1000, 0)
+		sink.buffer.now = func() time.Time { return now }
+		assert.Equal(t, int64(1000), sink.buffer.add(bufferedLine{Kind: pointLine, Name: "point"}).Timestamp)
+		sink.buffer.commit()
+
+		segments := sink.buffer.segments()
+		require.Len(t, segments, 1)
+		lines, err := readSegment(segments[0].path)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000), lines[0].Timestamp)
+	})
+}
+
+// failingInBackground flushes successfully but reports a failure, like a background flush rejected by the proxy.
+type failingInBackground struct {
+	*flakySender
+}
+
+func (f *failingInBackground) Flush() error {
+	f.flakySender.queued = nil
+	f.flakySender.failures++
+	return nil
+}
+
+type countingFlushes struct {
+	*flakySender
+	flushes int
+}
+
+func (c *countingFlushes) Flush() error {
+	c.flushes++
+	return c.flakySender.Flush()
+}
@@ -19,6 +19,7 @@ func NewTestWavefrontSink() *wavefrontSink {
 	return &wavefrontSink{
 		WavefrontClient: NewTestSender(),
 		ClusterName:     "testCluster",
+		counters:        newSinkCounters("test"),
 	}
 }
 
@@ -44,7 +45,7 @@ func TestCreateWavefrontSinkWithNoEmptyInputs(t *testing.T) {
 			Prefix: "testPrefix",
 		},
 	}
-	sink, err := NewWavefrontSink(cfg)
+	sink, err := NewWavefrontSink("", cfg)
 	assert.NoError(t, err)
 	assert.NotNil(t, sink)
 	wfSink, ok := sink.(*wavefrontSink)
@@ -62,7 +63,7 @@ func TestPrefix(t *testing.T) {
 			Prefix: "test.",
 		},
 	}
-	sink, err := NewWavefrontSink(cfg)
+	sink, err := NewWavefrontSink("", cfg)
 	assert.NoError(t, err)
 
 	db := metrics.Batch{
@@ -82,7 +83,7 @@ func TestNilPointDataBatch(t *testing.T) {
 			Prefix: "test.",
 		},
 	}
-	sink, err := NewWavefrontSink(cfg)
+	sink, err := NewWavefrontSink("", cfg)
 	assert.NoError(t, err)
 
 	db := metrics.Batch{
@@ -103,7 +104,7 @@ func TestCleansTagsBeforeSending(t *testing.T) {
 			Prefix: "test.",
 		},
 	}
-	sink, err := NewWavefrontSink(cfg)
+	sink, err := NewWavefrontSink("", cfg)
 	assert.NoError(t, err)
 
 	db := metrics.Batch{
@@ -121,6 +122,16 @@ func TestCleansTagsBeforeSending(t *testing.T) {
 	assert.NotContains(t, getMetrics(sink), "emptyTag")
 }
 
+func TestSkipsStalenessMarkers(t *testing.T) {
+	fakeSink := NewTestWavefrontSink()
+	fakeSink.Export(&metrics.Batch{Metrics: []wf.Metric{
+		wf.NewPoint("cpu.idle", wf.StaleNaN, 0, "fakeSource", nil),
+		wf.NewPoint("cpu.used", 1.0, 0, "fakeSource", nil),
+	}})
+	assert.NotContains(t, getMetrics(fakeSink), "cpu.idle")
+	assert.Contains(t, getMetrics(fakeSink), "cpu.used")
+}
+
 func getMetrics(sink WavefrontSink) string {
 	return strings.TrimSpace(sink.(*wavefrontSink).WavefrontClient.(*TestSender).GetReceivedLines())
 }
@@ -15,6 +15,9 @@ import (
 )
 
 type TestSender struct {
+	// satisfies the unexported methods of the sender interface, every exported method is implemented below
+	senders.Sender
+
 	testReceivedLines string
 	mutex             sync.Mutex
 }
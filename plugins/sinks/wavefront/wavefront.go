@@ -8,7 +8,6 @@ import (
 	"math/rand"
 	"os"
 	"runtime/debug"
-	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +23,8 @@ import (
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
 	"github.com/wavefronthq/wavefront-sdk-go/senders"
 
+	"github.com/wavefronthq/go-metrics-wavefront/reporting"
+
 	gm "github.com/rcrowley/go-metrics"
 	log "github.com/sirupsen/logrus"
 )
@@ -36,26 +37,43 @@ const (
 
 const maxWavefrontTags = 19 // the maximum numbers of tags allowed in a wavefront point not including source
 
+const defaultBatchSize = 10000 // the number of lines the sender flushes at once unless configured
+
+const sinkName = "wavefront_sink"
+
 var (
 	excludeTagList = [...]string{"namespace_id", "host_id", "pod_id", "hostname"}
-	sentPoints     gm.Counter
-	errPoints      gm.Counter
-	filteredPoints gm.Counter
-	sentEvents     gm.Counter
-	errEvents      gm.Counter
 	clientType     gm.Gauge
 	sanitizedChars = strings.NewReplacer("+", "-")
 )
 
 func init() {
-	sentPoints = gm.GetOrRegisterCounter("wavefront.points.sent.count", gm.DefaultRegistry)
-	errPoints = gm.GetOrRegisterCounter("wavefront.points.errors.count", gm.DefaultRegistry)
-	filteredPoints = gm.GetOrRegisterCounter("wavefront.points.filtered.count", gm.DefaultRegistry)
-	sentEvents = gm.GetOrRegisterCounter("wavefront.events.sent.count", gm.DefaultRegistry)
-	errEvents = gm.GetOrRegisterCounter("wavefront.events.errors.count", gm.DefaultRegistry)
 	clientType = gm.GetOrRegisterGauge("wavefront.sender.type", gm.DefaultRegistry)
 }
 
+// sinkCounters are the internal metrics of a sink, tagged with the name of the sink
+type sinkCounters struct {
+	sentPoints     gm.Counter
+	errPoints      gm.Counter
+	filteredPoints gm.Counter
+	sentEvents     gm.Counter
+	errEvents      gm.Counter
+}
+
+func newSinkCounters(name string) sinkCounters {
+	tags := map[string]string{"sink": name}
+	counter := func(key string) gm.Counter {
+		return gm.GetOrRegisterCounter(reporting.EncodeKey(key, tags), gm.DefaultRegistry)
+	}
+	return sinkCounters{
+		sentPoints:     counter("wavefront.points.sent.count"),
+		errPoints:      counter("wavefront.points.errors.count"),
+		filteredPoints: counter("wavefront.points.filtered.count"),
+		sentEvents:     counter("wavefront.events.sent.count"),
+		errEvents:      counter("wavefront.events.errors.count"),
+	}
+}
+
 type WavefrontSink interface {
 	Name() string
 	Stop()
@@ -72,6 +90,9 @@ type wavefrontSink struct {
 	forceGC         bool
 	logPercent      float32
 	stopHeartbeat   chan struct{}
+	buffer          *retryBuffer
+	batchSize       int
+	counters        sinkCounters
 }
 
 func (sink *wavefrontSink) SendDistribution(name string, centroids []histogram.Centroid, hgs map[histogram.Granularity]bool, ts int64, source string, tags map[string]string) error {
@@ -80,32 +101,35 @@ func (sink *wavefrontSink) SendDistribution(name string, centroids []histogram.C
 		name = sink.Prefix + "." + name
 	}
 	logTagCleaningReasons(name, cleanTags(tags, maxWavefrontTags))
+	if sink.buffer != nil {
+		return sink.buffer.add(bufferedLine{
+			Kind:          distributionLine,
+			Name:          name,
+			Centroids:     centroids,
+			Granularities: hgs,
+			Timestamp:     ts,
+			Source:        source,
+			Tags:          tags,
+		}).send(sink.WavefrontClient)
+	}
 	return sink.WavefrontClient.SendDistribution(name, centroids, hgs, ts, source, tags)
 }
 
-func NewWavefrontSink(cfg configuration.WavefrontSinkConfig) (WavefrontSink, error) {
+// NewWavefrontSink creates a Wavefront sink. The name tags the internal metrics of the sink and defaults to wavefront_sink.
+func NewWavefrontSink(name string, cfg configuration.WavefrontSinkConfig) (WavefrontSink, error) {
 	storage := &wavefrontSink{
 		ClusterName: configuration.GetStringValue(cfg.ClusterName, "k8s-cluster"),
 		logPercent:  0.01,
+		batchSize:   defaultBatchSize,
+		counters:    newSinkCounters(configuration.GetStringValue(name, sinkName)),
 	}
 
 	if cfg.TestMode {
 		storage.WavefrontClient = NewTestSender()
 		clientType.Update(testClient)
 	} else if cfg.ProxyAddress != "" {
-		s := strings.Split(cfg.ProxyAddress, ":")
-		host, portStr := s[0], s[1]
-		port, err := strconv.Atoi(portStr)
-
-		if err != nil {
-			return nil, fmt.Errorf("error parsing proxy port: %s", err.Error())
-		}
-		storage.WavefrontClient, err = senders.NewProxySender(&senders.ProxyConfiguration{
-			Host:             host,
-			MetricsPort:      port,
-			DistributionPort: port,
-			EventsPort:       port,
-		})
+		var err error
+		storage.WavefrontClient, err = senders.NewSender("http://" + cfg.ProxyAddress)
 		if err != nil {
 			return nil, fmt.Errorf("error creating proxy sender: %s", err.Error())
 		}
@@ -114,13 +138,16 @@ func NewWavefrontSink(cfg configuration.WavefrontSinkConfig) (WavefrontSink, err
 		if len(cfg.Token) == 0 {
 			return nil, fmt.Errorf("token missing for Wavefront sink")
 		}
+		options := []senders.Option{senders.APIToken(cfg.Token)}
+		if cfg.BatchSize > 0 {
+			options = append(options, senders.BatchSize(cfg.BatchSize))
+			storage.batchSize = cfg.BatchSize
+		}
+		if cfg.MaxBufferSize > 0 {
+			options = append(options, senders.MaxBufferSize(cfg.MaxBufferSize))
+		}
 		var err error
-		storage.WavefrontClient, err = senders.NewDirectSender(&senders.DirectConfiguration{
-			Server:        cfg.Server,
-			Token:         cfg.Token,
-			BatchSize:     cfg.BatchSize,
-			MaxBufferSize: cfg.MaxBufferSize,
-		})
+		storage.WavefrontClient, err = senders.NewSender(cfg.Server, options...)
 		if err != nil {
 			return nil, fmt.Errorf("error creating direct sender: %s", err.Error())
 		}
@@ -144,14 +171,27 @@ func NewWavefrontSink(cfg configuration.WavefrontSinkConfig) (WavefrontSink, err
 		storage.logPercent = cfg.ErrorLogPercent
 	}
 
+	if cfg.RetryBuffer.Dir != "" {
+		buffer, err := newRetryBuffer(cfg.RetryBuffer)
+		if err != nil {
+			return nil, err
+		}
+		storage.buffer = buffer
+	}
+
 	// emit heartbeat metric
 	storage.emitHeartbeat(storage.WavefrontClient, cfg)
 
 	return storage, nil
 }
 
+// SetFilters replaces the filters of the sink. It is called from the goroutine exporting to the sink.
+func (sink *wavefrontSink) SetFilters(filters filter.Filter) {
+	sink.filters = filters
+}
+
 func (sink *wavefrontSink) Name() string {
-	return "wavefront_sink"
+	return sinkName
 }
 
 func (sink *wavefrontSink) Stop() {
@@ -166,6 +206,16 @@ func (sink *wavefrontSink) SendMetric(metricName string, value float64, timestam
 	}
 	logTagCleaningReasons(metricName, cleanTags(tags, maxWavefrontTags))
 
+	if sink.buffer != nil {
+		return sink.buffer.add(bufferedLine{
+			Kind:      pointLine,
+			Name:      metricName,
+			Value:     value,
+			Timestamp: timestamp,
+			Source:    source,
+			Tags:      tags,
+		}).send(sink.WavefrontClient)
+	}
 	return sink.WavefrontClient.SendMetric(metricName, value, timestamp, source, tags)
 }
 
@@ -180,34 +230,47 @@ func (sink *wavefrontSink) logVerboseError(f log.Fields, msg string) {
 func (sink *wavefrontSink) Export(batch *metrics.Batch) {
 	log.Debugf("received metric points: %d", len(batch.Metrics))
 
-	before := errPoints.Count()
+	var failures int64
+	if sink.buffer != nil {
+		failures = sink.WavefrontClient.GetFailureCount()
+	}
+
+	before := sink.counters.errPoints.Count()
 	for _, point := range batch.Metrics {
 		if point == nil {
 			continue
 		}
+		if p, ok := point.(*wf.Point); ok && wf.IsStaleMarker(p.Value) {
+			// Wavefront does not accept NaN values, gaps already show that a series stopped reporting
+			continue
+		}
 		point.OverrideTag(metrics.LabelCluster.Key, sink.ClusterName)
 		point.AddTags(sink.globalTags)
-		point = wf.Filter(sink.filters, filteredPoints, point)
+		point = wf.Filter(sink.filters, sink.counters.filteredPoints, point)
 		if point == nil {
 			continue
 		}
 		err := point.Send(sink)
 		if err != nil {
-			errPoints.Inc(1)
+			sink.counters.errPoints.Inc(1)
 			sink.logVerboseError(log.Fields{
 				"name":  point.Name(),
 				"error": err,
 			}, "error sending metric")
 		} else {
-			sentPoints.Inc(1)
+			sink.counters.sentPoints.Inc(1)
 		}
 	}
 
-	after := errPoints.Count()
+	after := sink.counters.errPoints.Count()
 	if after > before {
 		log.WithField("count", after).Warning("Error sending one or more points")
 	}
 
+	if sink.buffer != nil {
+		sink.flushBuffer(failures)
+	}
+
 	// This seems like an odd place for this considering that we still have references to the big
 	// memory user, the Batch. However, moving it until that reference was released actually
 	// reduced the effectiveness of this flag. The garbage collector has some interesting ideas about
@@ -218,6 +281,47 @@ func (sink *wavefrontSink) Export(batch *metrics.Batch) {
 	}
 }
 
+// flushBuffer persists the lines handed to the sender during an export and removes them once a flush confirms
+// they were sent. Buffered lines of earlier exports are replayed once the sender is available again. The replay is
+// capped per export so a large backlog drains over several exports instead of delaying the current batch.
+func (sink *wavefrontSink) flushBuffer(failures int64) {
+	current := sink.buffer.commit()
+	if err := sink.flush(failures, current.lines); err != nil {
+		log.Warningf("Wavefront sender unavailable, buffering points: %v", err)
+		return
+	}
+	sink.buffer.remove(current)
+
+	replayed := sink.buffer.replay(sink.WavefrontClient, maxReplayLines)
+	var lines int64
+	for _, s := range replayed {
+		lines += s.lines
+	}
+	if err := sink.flush(failures, lines); err != nil {
+		log.Warningf("Wavefront sender unavailable, keeping buffered points: %v", err)
+		return
+	}
+	sink.buffer.remove(replayed...)
+}
+
+// flush sends the given number of lines queued in the sender, one batch per call. The sender also flushes in the
+// background and only reports those failures through its failure count, so any new failure fails the flush.
+func (sink *wavefrontSink) flush(failures, lines int64) error {
+	batches := int64(1)
+	if sink.batchSize > 0 {
+		batches += lines / int64(sink.batchSize)
+	}
+	for i := int64(0); i < batches; i++ {
+		if err := sink.WavefrontClient.Flush(); err != nil {
+			return err
+		}
+	}
+	if count := sink.WavefrontClient.GetFailureCount(); count > failures {
+		return fmt.Errorf("%d send failures", count-failures)
+	}
+	return nil
+}
+
 func (sink *wavefrontSink) ExportEvent(ev *events.Event) {
 	ev.Options = append(ev.Options, event.Annotate("cluster", sink.ClusterName))
 	host := sink.ClusterName
@@ -234,9 +338,9 @@ func (sink *wavefrontSink) ExportEvent(ev *events.Event) {
 			"message": ev.Message,
 			"error":   err,
 		}, "error sending event")
-		errEvents.Inc(1)
+		sink.counters.errEvents.Inc(1)
 	} else {
-		sentEvents.Inc(1)
+		sink.counters.sentEvents.Inc(1)
 	}
 }
 
@@ -255,9 +359,10 @@ func (sink *wavefrontSink) emitHeartbeat(sender senders.Sender, cfg configuratio
 	ticker := time.NewTicker(1 * time.Minute)
 	sink.stopHeartbeat = make(chan struct{})
 	source := getDefault(util.GetNodeName(), "wavefront-collector-for-kubernetes")
+	// values resolved from configuration references are never reported
 	tags := map[string]string{
-		"cluster":             cfg.ClusterName,
-		"stats_prefix":        configuration.GetStringValue(cfg.Prefix, "kubernetes."),
+		"cluster":             configuration.Redact(cfg.ClusterName),
+		"stats_prefix":        configuration.Redact(configuration.GetStringValue(cfg.Prefix, "kubernetes.")),
 		"installation_method": util.GetInstallationMethod(),
 	}
 
@@ -291,12 +396,12 @@ func (sink *wavefrontSink) emitHeartbeat(sender senders.Sender, cfg configuratio
 
 func (sink *wavefrontSink) logStatus() {
 	// # events can be large in volume. log a status message periodically
-	sent := sentEvents.Count()
-	errs := errEvents.Count()
+	sent := sink.counters.sentEvents.Count()
+	errs := sink.counters.errEvents.Count()
 	if sent > 0 || errs > 0 {
 		log.WithFields(log.Fields{
-			"sent":   sentEvents.Count(),
-			"errors": errEvents.Count(),
+			"sent":   sink.counters.sentEvents.Count(),
+			"errors": sink.counters.errEvents.Count(),
 		}).Info("Events processed")
 	}
 }
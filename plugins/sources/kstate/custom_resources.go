@@ -0,0 +1,179 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// customResource is a custom resource configuration with its JSONPath expressions parsed
+type customResource struct {
+	resource schema.GroupVersionResource
+	kind     string
+	metrics  []customResourceMetric
+	tags     map[string]*jsonpath.JSONPath
+}
+
+type customResourceMetric struct {
+	name     string
+	path     *jsonpath.JSONPath
+	valueMap map[string]float64
+}
+
+// ValidateCustomResource returns an error if the resource is incomplete or any of its JSONPath expressions is invalid
+func ValidateCustomResource(cfg configuration.CustomResourceConfig) error {
+	_, err := newCustomResource(cfg)
+	return err
+}
+
+func newCustomResource(cfg configuration.CustomResourceConfig) (customResource, error) {
+	if cfg.Version == "" || cfg.Resource == "" {
+		return customResource{}, fmt.Errorf("version and resource are required")
+	}
+	if len(cfg.Metrics) == 0 {
+		return customResource{}, fmt.Errorf("at least one metric is required")
+	}
+	cr := customResource{
+		resource: schema.GroupVersionResource{Group: cfg.Group, Version: cfg.Version, Resource: cfg.Resource},
+		kind:     strings.ToLower(getDefault(cfg.Kind, cfg.Resource)),
+		tags:     make(map[string]*jsonpath.JSONPath, len(cfg.Tags)),
+	}
+	for _, m := range cfg.Metrics {
+		if m.Name == "" {
+			return customResource{}, fmt.Errorf("metric name is required")
+		}
+		path, err := parseJSONPath(m.Name, m.Path)
+		if err != nil {
+			return customResource{}, fmt.Errorf("metric %s: %v", m.Name, err)
+		}
+		cr.metrics = append(cr.metrics, customResourceMetric{name: m.Name, path: path, valueMap: m.ValueMap})
+	}
+	for name, expr := range cfg.Tags {
+		path, err := parseJSONPath(name, expr)
+		if err != nil {
+			return customResource{}, fmt.Errorf("tag %s: %v", name, err)
+		}
+		cr.tags[name] = path
+	}
+	return cr, nil
+}
+
+// parseJSONPath parses an expression such as '.status.replicas', with or without the enclosing braces
+func parseJSONPath(name, expr string) (*jsonpath.JSONPath, error) {
+	if expr == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+	path := jsonpath.New(name).AllowMissingKeys(true)
+	if err := path.Parse(expr); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+func pointsForCustomResource(item interface{}, cr customResource, transforms configuration.Transforms) []wf.Metric {
+	obj, ok := item.(*unstructured.Unstructured)
+	if !ok {
+		log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+		return nil
+	}
+
+	tags := buildTags(cr.kind, obj.GetName(), obj.GetNamespace(), transforms.Tags)
+	for name, path := range cr.tags {
+		if value, found := findValue(path, obj.Object); found {
+			if s, ok := scalarString(value); ok && s != "" {
+				tags[name] = s
+			}
+		}
+	}
+	now := time.Now().Unix()
+
+	var points []wf.Metric
+	for _, m := range cr.metrics {
+		value, found := findValue(m.path, obj.Object)
+		if !found {
+			continue
+		}
+		f, ok := m.floatValue(value)
+		if !ok {
+			log.Debugf("skipping non numeric value of %s for %s %s/%s", m.name, cr.kind, obj.GetNamespace(), obj.GetName())
+			continue
+		}
+		points = append(points, metricPoint(transforms.Prefix, "cr."+cr.kind+"."+m.name, f, now, transforms.Source, tags))
+	}
+	return points
+}
+
+// findValue returns the first value matched by the expression
+func findValue(path *jsonpath.JSONPath, obj map[string]interface{}) (interface{}, bool) {
+	results, err := path.FindResults(obj)
+	if err != nil {
+		return nil, false
+	}
+	for _, result := range results {
+		for _, value := range result {
+			if value.IsValid() && value.CanInterface() {
+				return value.Interface(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// floatValue converts a matched value to the value to report. Strings are looked up in the value map first.
+func (m customResourceMetric) floatValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1.0, true
+		}
+		return 0.0, true
+	case string:
+		if f, ok := m.valueMap[v]; ok {
+			return f, true
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func scalarString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case int64, int, float64, bool:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+func customResourceTypes(cfgs []configuration.CustomResourceConfig) []schema.GroupVersionResource {
+	var resources []schema.GroupVersionResource
+	for _, cfg := range cfgs {
+		resources = append(resources, schema.GroupVersionResource{Group: cfg.Group, Version: cfg.Version, Resource: cfg.Resource})
+	}
+	return resources
+}
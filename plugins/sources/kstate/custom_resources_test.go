@@ -0,0 +1,81 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPointsForCustomResource(t *testing.T) {
+	testTransform := setupTestTransform()
+	cr, err := newCustomResource(configuration.CustomResourceConfig{
+		Group:    "cert-manager.io",
+		Version:  "v1",
+		Resource: "certificates",
+		Kind:     "Certificate",
+		Metrics: []configuration.CustomResourceMetricConfig{
+			{Name: "ready", Path: `.status.conditions[?(@.type=="Ready")].status`, ValueMap: map[string]float64{"True": 1, "False": 0}},
+			{Name: "revision", Path: "{.status.revision}"},
+			{Name: "missing", Path: ".status.notAfterSeconds"},
+		},
+		Tags: map[string]string{"issuer": ".spec.issuerRef.name"},
+	})
+	require.NoError(t, err)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web-tls", "namespace": "ns1"},
+		"spec": map[string]interface{}{
+			"issuerRef": map[string]interface{}{"name": "letsencrypt"},
+		},
+		"status": map[string]interface{}{
+			"revision": int64(3),
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Issuing", "status": "False"},
+				map[string]interface{}{"type": "Ready", "status": "True"},
+			},
+		},
+	}}
+
+	values := map[string]float64{}
+	for _, metric := range pointsForCustomResource(obj, cr, testTransform) {
+		point := metric.(*wf.Point)
+		values[point.Metric] = point.Value
+		assert.Equal(t, "web-tls", point.Tags()["certificate"])
+		assert.Equal(t, "ns1", point.Tags()["namespace_name"])
+		assert.Equal(t, "letsencrypt", point.Tags()["issuer"])
+	}
+	assert.Equal(t, map[string]float64{
+		"testPrefixcr.certificate.ready":    1,
+		"testPrefixcr.certificate.revision": 3,
+	}, values, "fields missing from the object are skipped")
+}
+
+func TestValidateCustomResource(t *testing.T) {
+	valid := configuration.CustomResourceConfig{
+		Version:  "v1",
+		Resource: "widgets",
+		Metrics:  []configuration.CustomResourceMetricConfig{{Name: "replicas", Path: ".spec.replicas"}},
+	}
+	assert.NoError(t, ValidateCustomResource(valid))
+
+	missingResource := valid
+	missingResource.Resource = ""
+	assert.Error(t, ValidateCustomResource(missingResource))
+
+	invalidPath := valid
+	invalidPath.Metrics = []configuration.CustomResourceMetricConfig{{Name: "replicas", Path: ".spec.replicas["}}
+	assert.Error(t, ValidateCustomResource(invalidPath))
+
+	invalidTag := valid
+	invalidTag.Tags = map[string]string{"phase": ""}
+	assert.Error(t, ValidateCustomResource(invalidTag))
+}
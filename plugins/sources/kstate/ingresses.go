@@ -0,0 +1,82 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func pointsForIngress(item interface{}, transforms configuration.Transforms) []wf.Metric {
+	ingress, ok := item.(*networkingv1.Ingress)
+	if !ok {
+		log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+		return nil
+	}
+
+	tags := buildTags("ingress", ingress.Name, ingress.Namespace, transforms.Tags)
+	if ingress.Spec.IngressClassName != nil {
+		tags["ingress_class"] = *ingress.Spec.IngressClassName
+	}
+	now := time.Now().Unix()
+
+	addressAssigned := 0.0
+	if len(ingress.Status.LoadBalancer.Ingress) > 0 {
+		addressAssigned = 1.0
+	}
+	points := []wf.Metric{
+		metricPoint(transforms.Prefix, "ingress.rules", float64(len(ingress.Spec.Rules)), now, transforms.Source, tags),
+		metricPoint(transforms.Prefix, "ingress.address_assigned", addressAssigned, now, transforms.Source, tags),
+	}
+
+	for _, backend := range ingressBackendServices(ingress) {
+		backendTags := make(map[string]string, len(tags)+2)
+		copyTags(tags, backendTags)
+		backendTags["service"] = backend.Name
+		backendTags["service_port"] = backendPort(backend.Port)
+		points = append(points, metricPoint(transforms.Prefix, "ingress.backend", 1.0, now, transforms.Source, backendTags))
+	}
+	return points
+}
+
+// ingressBackendServices returns the distinct services of the default backend and rules of an ingress
+func ingressBackendServices(ingress *networkingv1.Ingress) []*networkingv1.IngressServiceBackend {
+	var backends []*networkingv1.IngressServiceBackend
+	seen := map[string]bool{}
+	add := func(backend *networkingv1.IngressBackend) {
+		if backend == nil || backend.Service == nil {
+			return
+		}
+		key := backend.Service.Name + ":" + backendPort(backend.Service.Port)
+		if !seen[key] {
+			seen[key] = true
+			backends = append(backends, backend.Service)
+		}
+	}
+	add(ingress.Spec.DefaultBackend)
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			add(&rule.HTTP.Paths[i].Backend)
+		}
+	}
+	return backends
+}
+
+func backendPort(port networkingv1.ServiceBackendPort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return strconv.Itoa(int(port.Number))
+}
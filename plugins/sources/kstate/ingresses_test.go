@@ -0,0 +1,78 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPointsForIngress(t *testing.T) {
+	testTransform := setupTestTransform()
+	className := "nginx"
+	web := networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+		Name: "web", Port: networkingv1.ServiceBackendPort{Number: 8080},
+	}}
+	api := networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+		Name: "api", Port: networkingv1.ServiceBackendPort{Name: "http"},
+	}}
+	paths := func(backends ...networkingv1.IngressBackend) *networkingv1.HTTPIngressRuleValue {
+		value := &networkingv1.HTTPIngressRuleValue{}
+		for _, backend := range backends {
+			value.Paths = append(value.Paths, networkingv1.HTTPIngressPath{Path: "/", Backend: backend})
+		}
+		return value
+	}
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "ns1"},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: &className,
+			DefaultBackend:   &web,
+			Rules: []networkingv1.IngressRule{
+				{Host: "shop.example.com", IngressRuleValue: networkingv1.IngressRuleValue{HTTP: paths(web, api)}},
+				{Host: "api.example.com", IngressRuleValue: networkingv1.IngressRuleValue{HTTP: paths(api)}},
+			},
+		},
+	}
+
+	t.Run("without an address", func(t *testing.T) {
+		points := map[string][]*wf.Point{}
+		for _, metric := range pointsForIngress(ingress, testTransform) {
+			point := metric.(*wf.Point)
+			points[point.Metric] = append(points[point.Metric], point)
+		}
+
+		require.Len(t, points["testPrefixingress.rules"], 1)
+		rules := points["testPrefixingress.rules"][0]
+		assert.Equal(t, float64(2), rules.Value)
+		assert.Equal(t, "shop", rules.Tags()["ingress"])
+		assert.Equal(t, "ns1", rules.Tags()["namespace_name"])
+		assert.Equal(t, "nginx", rules.Tags()["ingress_class"])
+		assert.Equal(t, float64(0), points["testPrefixingress.address_assigned"][0].Value)
+
+		backends := map[string]string{}
+		for _, backend := range points["testPrefixingress.backend"] {
+			backends[backend.Tags()["service"]] = backend.Tags()["service_port"]
+		}
+		assert.Equal(t, map[string]string{"web": "8080", "api": "http"}, backends, "backends are reported once")
+	})
+
+	t.Run("with an address", func(t *testing.T) {
+		assigned := ingress.DeepCopy()
+		assigned.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+		for _, metric := range pointsForIngress(assigned, testTransform) {
+			if point := metric.(*wf.Point); point.Metric == "testPrefixingress.address_assigned" {
+				assert.Equal(t, float64(1), point.Value)
+			}
+		}
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"reflect"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func pointsForLimitRange(item interface{}, transforms configuration.Transforms) []wf.Metric {
+	limitRange, ok := item.(*v1.LimitRange)
+	if !ok {
+		log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+		return nil
+	}
+
+	now := time.Now().Unix()
+	var points []wf.Metric
+	for _, limit := range limitRange.Spec.Limits {
+		constraints := []struct {
+			name   string
+			values v1.ResourceList
+		}{
+			{"min", limit.Min},
+			{"max", limit.Max},
+			{"default", limit.Default},
+			{"default_request", limit.DefaultRequest},
+		}
+		for _, constraint := range constraints {
+			for name, quantity := range constraint.values {
+				tags := limitRangeTags(limitRange, limit.Type, name, transforms)
+				value := float64(util.ResourceQuantityValue(name, quantity))
+				points = append(points, metricPoint(transforms.Prefix, "limitrange."+constraint.name, value, now, transforms.Source, tags))
+			}
+		}
+		for name, quantity := range limit.MaxLimitRequestRatio {
+			tags := limitRangeTags(limitRange, limit.Type, name, transforms)
+			ratio := float64(quantity.MilliValue()) / 1000
+			points = append(points, metricPoint(transforms.Prefix, "limitrange.max_limit_request_ratio", ratio, now, transforms.Source, tags))
+		}
+	}
+	return points
+}
+
+func limitRangeTags(limitRange *v1.LimitRange, limitType v1.LimitType, name v1.ResourceName, transforms configuration.Transforms) map[string]string {
+	tags := buildTags("limitrange", limitRange.Name, limitRange.Namespace, transforms.Tags)
+	tags["type"] = string(limitType)
+	tags["resource"] = string(name)
+	return tags
+}
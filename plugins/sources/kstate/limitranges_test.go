@@ -0,0 +1,59 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPointsForLimitRange(t *testing.T) {
+	testTransform := setupTestTransform()
+	limitRange := &v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "ns1"},
+		Spec: v1.LimitRangeSpec{
+			Limits: []v1.LimitRangeItem{
+				{
+					Type:                 v1.LimitTypeContainer,
+					Min:                  v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+					Max:                  v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+					Default:              v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+					DefaultRequest:       v1.ResourceList{v1.ResourceCPU: resource.MustParse("250m")},
+					MaxLimitRequestRatio: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1500m")},
+				},
+				{
+					Type: v1.LimitTypePersistentVolumeClaim,
+					Max:  v1.ResourceList{v1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+		},
+	}
+
+	points := map[string]*wf.Point{}
+	for _, metric := range pointsForLimitRange(limitRange, testTransform) {
+		point := metric.(*wf.Point)
+		points[point.Metric+"/"+point.Tags()["type"]+"/"+point.Tags()["resource"]] = point
+	}
+	assert.Len(t, points, 6)
+
+	min := points["testPrefixlimitrange.min/Container/cpu"]
+	require.NotNil(t, min)
+	assert.Equal(t, float64(100), min.Value, "cpu is in millicores")
+	assert.Equal(t, "limits", min.Tags()["limitrange"])
+	assert.Equal(t, "ns1", min.Tags()["namespace_name"])
+
+	assert.Equal(t, float64(1<<30), points["testPrefixlimitrange.max/Container/memory"].Value)
+	assert.Equal(t, float64(500), points["testPrefixlimitrange.default/Container/cpu"].Value)
+	assert.Equal(t, float64(250), points["testPrefixlimitrange.default_request/Container/cpu"].Value)
+	assert.Equal(t, 1.5, points["testPrefixlimitrange.max_limit_request_ratio/Container/cpu"].Value)
+	assert.Equal(t, float64(10<<30), points["testPrefixlimitrange.max/PersistentVolumeClaim/storage"].Value)
+}
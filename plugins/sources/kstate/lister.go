@@ -4,6 +4,7 @@
 package kstate
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -16,8 +17,15 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
@@ -33,6 +41,18 @@ const (
 	horizontalPodAutoscalers = "horizontalpodautoscalers"
 	nodes                    = "nodes"
 	nonRunningPods           = "pods"
+	lifecyclePods            = "lifecyclepods"
+	imagePulledEvents        = "events"
+	persistentVolumes        = "persistentvolumes"
+	persistentVolumeClaims   = "persistentvolumeclaims"
+	storageClasses           = "storageclasses"
+	services                 = "services"
+	endpoints                = "endpoints"
+	ingresses                = "ingresses"
+	networkPolicies          = "networkpolicies"
+	resourceQuotas           = "resourcequotas"
+	limitRanges              = "limitranges"
+	namespaces               = "namespaces"
 )
 
 var (
@@ -44,6 +64,18 @@ type lister struct {
 	kubeClient kubernetes.Interface
 	informers  map[string]cache.SharedInformer
 	stopCh     chan struct{}
+
+	// guards the informers that depend on the configuration and are replaced when it is reloaded
+	configMtx sync.RWMutex
+	running   bool
+
+	// informers of the configured custom resources
+	custom       map[schema.GroupVersionResource]cache.SharedInformer
+	customStopCh chan struct{}
+
+	// informers of all pods and of the image pull events, only run when the pod lifecycle metrics are enabled
+	lifecycle       map[string]cache.SharedInformer
+	lifecycleStopCh chan struct{}
 }
 
 func newLister(kubeClient kubernetes.Interface) *lister {
@@ -69,6 +101,16 @@ func buildInformers(kubeClient kubernetes.Interface) map[string]cache.SharedInfo
 	m[nodes] = buildInformer(nodes, &v1.Node{}, kubeClient.CoreV1().RESTClient())
 	m[replicationControllers] = buildInformer(replicationControllers, &v1.ReplicationController{}, kubeClient.CoreV1().RESTClient())
 	m[nonRunningPods] = buildInformerWithFieldsSelector(nonRunningPods, &v1.Pod{}, kubeClient.CoreV1().RESTClient(), fields.OneTermNotEqualSelector("status.phase", "Running"))
+	m[persistentVolumes] = buildInformer(persistentVolumes, &v1.PersistentVolume{}, kubeClient.CoreV1().RESTClient())
+	m[persistentVolumeClaims] = buildInformer(persistentVolumeClaims, &v1.PersistentVolumeClaim{}, kubeClient.CoreV1().RESTClient())
+	m[storageClasses] = buildInformer(storageClasses, &storagev1.StorageClass{}, kubeClient.StorageV1().RESTClient())
+	m[services] = buildInformer(services, &v1.Service{}, kubeClient.CoreV1().RESTClient())
+	m[endpoints] = buildInformer(endpoints, &v1.Endpoints{}, kubeClient.CoreV1().RESTClient())
+	m[ingresses] = buildInformer(ingresses, &networkingv1.Ingress{}, kubeClient.NetworkingV1().RESTClient())
+	m[networkPolicies] = buildInformer(networkPolicies, &networkingv1.NetworkPolicy{}, kubeClient.NetworkingV1().RESTClient())
+	m[resourceQuotas] = buildInformer(resourceQuotas, &v1.ResourceQuota{}, kubeClient.CoreV1().RESTClient())
+	m[limitRanges] = buildInformer(limitRanges, &v1.LimitRange{}, kubeClient.CoreV1().RESTClient())
+	m[namespaces] = buildInformer(namespaces, &v1.Namespace{}, kubeClient.CoreV1().RESTClient())
 	return m
 }
 
@@ -81,14 +123,32 @@ func buildInformerWithFieldsSelector(resource string, resType runtime.Object, ge
 	return cache.NewSharedInformer(lw, resType, time.Hour)
 }
 
+func buildLifecycleInformers(kubeClient kubernetes.Interface) map[string]cache.SharedInformer {
+	m := make(map[string]cache.SharedInformer)
+	m[lifecyclePods] = buildInformer("pods", &v1.Pod{}, kubeClient.CoreV1().RESTClient())
+	m[imagePulledEvents] = buildInformerWithFieldsSelector(imagePulledEvents, &v1.Event{}, kubeClient.CoreV1().RESTClient(), fields.OneTermEqualSelector("reason", imagePulledReason))
+	return m
+}
+
 func (l *lister) List(resource string) ([]interface{}, error) {
-	if informer, exists := l.informers[resource]; exists {
+	if informer, exists := l.informer(resource); exists {
 		return informer.GetStore().List(), nil
 	} else {
 		return nil, fmt.Errorf("unsupported resource type: %s", resource)
 	}
 }
 
+// informer returns the informer of a resource, including the pod lifecycle informers when they are enabled
+func (l *lister) informer(resource string) (cache.SharedInformer, bool) {
+	if informer, exists := l.informers[resource]; exists {
+		return informer, true
+	}
+	l.configMtx.RLock()
+	defer l.configMtx.RUnlock()
+	informer, exists := l.lifecycle[resource]
+	return informer, exists
+}
+
 func (l *lister) Resume() {
 	log.Infof("starting kstate lister")
 	l.stopCh = make(chan struct{})
@@ -96,6 +156,12 @@ func (l *lister) Resume() {
 		log.Debugf("starting %s informer", k)
 		go informer.Run(l.stopCh)
 	}
+
+	l.configMtx.Lock()
+	defer l.configMtx.Unlock()
+	l.running = true
+	l.runCustom()
+	l.runLifecycle()
 }
 
 func (l *lister) Pause() {
@@ -103,4 +169,151 @@ func (l *lister) Pause() {
 	if l.stopCh != nil {
 		close(l.stopCh)
 	}
+
+	l.configMtx.Lock()
+	defer l.configMtx.Unlock()
+	l.running = false
+	l.stopCustom()
+	l.stopLifecycle()
+}
+
+// watchCustomResources replaces the informers of the custom resources with informers for the given resources
+func (l *lister) watchCustomResources(dynamicClient dynamic.Interface, resources []schema.GroupVersionResource) {
+	l.configMtx.Lock()
+	defer l.configMtx.Unlock()
+
+	l.stopCustom()
+	l.custom = make(map[schema.GroupVersionResource]cache.SharedInformer, len(resources))
+	for _, resource := range resources {
+		l.custom[resource] = buildDynamicInformer(dynamicClient, resource)
+	}
+	if l.running {
+		l.runCustom()
+	}
+}
+
+func buildDynamicInformer(dynamicClient dynamic.Interface, resource schema.GroupVersionResource) cache.SharedInformer {
+	r := dynamicClient.Resource(resource).Namespace(v1.NamespaceAll)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return r.List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return r.Watch(context.Background(), options)
+		},
+	}
+	return cache.NewSharedInformer(lw, &unstructured.Unstructured{}, time.Hour)
+}
+
+// watchPodLifecycle starts or stops watching all pods and the image pull events for the pod lifecycle metrics
+func (l *lister) watchPodLifecycle(enabled bool) {
+	l.configMtx.Lock()
+	defer l.configMtx.Unlock()
+
+	if enabled == (l.lifecycle != nil) {
+		return
+	}
+	l.stopLifecycle()
+	l.lifecycle = nil
+	if enabled {
+		l.lifecycle = buildLifecycleInformers(l.kubeClient)
+		if l.running {
+			l.runLifecycle()
+		}
+	}
+}
+
+// runLifecycle starts the pod lifecycle informers. The caller must hold configMtx.
+func (l *lister) runLifecycle() {
+	if len(l.lifecycle) == 0 {
+		return
+	}
+	l.lifecycleStopCh = make(chan struct{})
+	for resource, informer := range l.lifecycle {
+		log.Debugf("starting %s informer", resource)
+		go informer.Run(l.lifecycleStopCh)
+	}
+}
+
+// stopLifecycle stops the pod lifecycle informers. The caller must hold configMtx.
+func (l *lister) stopLifecycle() {
+	if l.lifecycleStopCh != nil {
+		close(l.lifecycleStopCh)
+		l.lifecycleStopCh = nil
+	}
+}
+
+// runCustom starts the custom resource informers. The caller must hold configMtx.
+func (l *lister) runCustom() {
+	if len(l.custom) == 0 {
+		return
+	}
+	l.customStopCh = make(chan struct{})
+	for resource, informer := range l.custom {
+		log.Debugf("starting %s informer", resource.String())
+		go informer.Run(l.customStopCh)
+	}
+}
+
+// stopCustom stops the custom resource informers. The caller must hold configMtx.
+func (l *lister) stopCustom() {
+	if l.customStopCh != nil {
+		close(l.customStopCh)
+		l.customStopCh = nil
+	}
+}
+
+// listCustom returns the cached objects of a custom resource
+func (l *lister) listCustom(resource schema.GroupVersionResource) ([]interface{}, error) {
+	l.configMtx.RLock()
+	defer l.configMtx.RUnlock()
+
+	if informer, exists := l.custom[resource]; exists {
+		return informer.GetStore().List(), nil
+	}
+	return nil, fmt.Errorf("unsupported custom resource: %s", resource.String())
+}
+
+// workload returns the kind and name of the top level controller of a pod, empty if the pod has no controller.
+// ReplicaSets and Jobs are resolved to their Deployment and CronJob when they are cached.
+func (l *lister) workload(pod *v1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "", ""
+	}
+	var resource string
+	switch owner.Kind {
+	case "ReplicaSet":
+		resource = replicaSets
+	case "Job":
+		resource = jobs
+	default:
+		return owner.Kind, owner.Name
+	}
+	if item, found := l.get(resource, pod.Namespace, owner.Name); found {
+		if ref := metav1.GetControllerOf(item.(metav1.Object)); ref != nil {
+			return ref.Kind, ref.Name
+		}
+	}
+	return owner.Kind, owner.Name
+}
+
+// get returns a cached object by namespace and name. Nothing is found if the lister is not initialized.
+func (l *lister) get(resource, namespace, name string) (interface{}, bool) {
+	if l == nil {
+		return nil, false
+	}
+	informer, exists := l.informer(resource)
+	if !exists {
+		return nil, false
+	}
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	item, found, err := informer.GetStore().GetByKey(key)
+	if err != nil {
+		return nil, false
+	}
+	return item, found
 }
@@ -0,0 +1,58 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"reflect"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// networkPoliciesHandler counts the network policies of every namespace known to the lister
+func networkPoliciesHandler(l *lister) resourceListHandler {
+	return func(items []interface{}, transforms configuration.Transforms) []wf.Metric {
+		namespaceItems, err := l.List(namespaces)
+		if err != nil {
+			log.Errorf("error listing namespaces: %v", err)
+		}
+		return pointsForNetworkPolicies(items, namespaceItems, transforms)
+	}
+}
+
+// pointsForNetworkPolicies counts the network policies of each namespace, reporting 0 for the namespaces without
+// a policy so that unprotected namespaces can be alerted on
+func pointsForNetworkPolicies(items []interface{}, namespaceItems []interface{}, transforms configuration.Transforms) []wf.Metric {
+	counts := map[string]int{}
+	for _, item := range namespaceItems {
+		namespace, ok := item.(*v1.Namespace)
+		if !ok {
+			log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+			continue
+		}
+		counts[namespace.Name] = 0
+	}
+	for _, item := range items {
+		policy, ok := item.(*networkingv1.NetworkPolicy)
+		if !ok {
+			log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+			continue
+		}
+		counts[policy.Namespace]++
+	}
+
+	now := time.Now().Unix()
+	points := make([]wf.Metric, 0, len(counts))
+	for namespace, count := range counts {
+		tags := buildTags("namespace_name", namespace, "", transforms.Tags)
+		points = append(points, metricPoint(transforms.Prefix, "networkpolicy.count", float64(count), now, transforms.Source, tags))
+	}
+	return points
+}
@@ -0,0 +1,40 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPointsForNetworkPolicies(t *testing.T) {
+	testTransform := setupTestTransform()
+	policy := func(namespace, name string) interface{} {
+		return &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	namespace := func(name string) interface{} {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	points := pointsForNetworkPolicies(
+		[]interface{}{policy("ns1", "deny-all"), policy("ns1", "allow-web"), policy("ns2", "deny-all")},
+		[]interface{}{namespace("ns1"), namespace("ns2"), namespace("ns3")},
+		testTransform,
+	)
+
+	counts := map[string]float64{}
+	for _, metric := range points {
+		point := metric.(*wf.Point)
+		assert.Equal(t, "testPrefixnetworkpolicy.count", point.Metric)
+		counts[point.Tags()["namespace_name"]] = point.Value
+	}
+	assert.Equal(t, map[string]float64{"ns1": 2, "ns2": 1, "ns3": 0}, counts)
+}
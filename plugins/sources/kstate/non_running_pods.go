@@ -12,6 +12,7 @@ import (
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,6 +25,10 @@ func pointsForNonRunningPods(item interface{}, transforms configuration.Transfor
 		log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
 		return nil
 	}
+	// running pods are reported by the kubernetes_source
+	if pod.Status.Phase == v1.PodRunning {
+		return nil
+	}
 
 	sharedTags := make(map[string]string, len(pod.GetLabels())+1)
 	copyLabels(pod.GetLabels(), sharedTags)
@@ -35,6 +40,9 @@ func pointsForNonRunningPods(item interface{}, transforms configuration.Transfor
 	return points
 }
 
+// evictedReason is the status reason of pods evicted by the kubelet
+const evictedReason = "Evicted"
+
 func truncateMessage(message string) string {
 	maxPointTagLength := 255 - len("=") - len("message")
 	if len(message) >= maxPointTagLength {
@@ -104,3 +112,71 @@ func buildContainerStatusMetrics(pod *v1.Pod, sharedTags map[string]string, tran
 	}
 	return points
 }
+
+// evictionCounter counts the evictions of the containers of each workload, or of each pod without a controller.
+// Evicted pods are counted once by UID, the counts keep growing as evicted pods are deleted and replaced.
+type evictionCounter struct {
+	lister  *lister
+	counted map[types.UID]bool
+	counts  map[evictionKey]int64
+}
+
+type evictionKey struct {
+	namespace string
+	kind      string
+	name      string
+	container string
+}
+
+func newEvictionCounter(l *lister) *evictionCounter {
+	return &evictionCounter{
+		lister:  l,
+		counted: map[types.UID]bool{},
+		counts:  map[evictionKey]int64{},
+	}
+}
+
+func (c *evictionCounter) handle(items []interface{}, transforms configuration.Transforms) []wf.Metric {
+	evicted := make(map[types.UID]bool, len(c.counted))
+	for _, item := range items {
+		pod, ok := item.(*v1.Pod)
+		if !ok {
+			log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+			continue
+		}
+		if pod.Status.Reason != evictedReason {
+			continue
+		}
+		evicted[pod.UID] = true
+		if c.counted[pod.UID] {
+			continue
+		}
+		kind, name := c.lister.workload(pod)
+		if kind == "" {
+			name = pod.Name
+		}
+		for _, container := range pod.Spec.Containers {
+			c.counts[evictionKey{namespace: pod.Namespace, kind: kind, name: name, container: container.Name}]++
+		}
+	}
+	// the evicted pods that were deleted cannot be listed again
+	c.counted = evicted
+	return c.points(transforms)
+}
+
+func (c *evictionCounter) points(transforms configuration.Transforms) []wf.Metric {
+	now := time.Now().Unix()
+	points := make([]wf.Metric, 0, len(c.counts))
+	for key, count := range c.counts {
+		tags := buildTags(metrics.LabelContainerName.Key, key.container, key.namespace, transforms.Tags)
+		tags[metrics.LabelMetricSetType.Key] = metrics.MetricSetTypePodContainer
+		if key.kind != "" {
+			tags[metrics.LabelWorkloadKind.Key] = key.kind
+			tags[metrics.LabelWorkloadName.Key] = key.name
+		} else {
+			tags["pod_name"] = key.name
+		}
+		points = append(points, metricPoint(transforms.Prefix, "pod_container.evictions", float64(count), now, transforms.Source, tags))
+	}
+	return points
+}
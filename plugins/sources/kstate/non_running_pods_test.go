@@ -8,10 +8,12 @@ import (
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func setupBasicPod() *v1.Pod {
@@ -265,3 +267,49 @@ func TestPointsForNonRunningPods(t *testing.T) {
 		assert.Equal(t, "waiting", containerMetric.Tags()["status"])
 	})
 }
+
+func TestEvictionCounter(t *testing.T) {
+	controller := true
+	evictedPod := func(uid, name string) *v1.Pod {
+		pod := setupFailedPod()
+		pod.UID = types.UID(uid)
+		pod.Name = name
+		pod.Status.Reason = "Evicted"
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: "StatefulSet", Name: "db", Controller: &controller}}
+		pod.Spec.Containers = []v1.Container{{Name: "testContainerName"}}
+		return pod
+	}
+	bare := evictedPod("uid-3", "bare")
+	bare.OwnerReferences = nil
+	testTransform := setupTestTransform()
+
+	counter := newEvictionCounter(nil)
+	assert.Empty(t, counter.handle([]interface{}{setupFailedPod()}, testTransform))
+
+	points := counter.handle([]interface{}{evictedPod("uid-1", "db-0")}, testTransform)
+	require.Len(t, points, 1)
+	point := points[0].(*wf.Point)
+	assert.Equal(t, "testPrefixpod_container.evictions", point.Metric)
+	assert.Equal(t, 1.0, point.Value)
+	assert.Equal(t, "testContainerName", point.Tags()["container_name"])
+	assert.Equal(t, "ns1", point.Tags()["namespace_name"])
+	assert.Equal(t, "StatefulSet", point.Tags()["workload_kind"])
+	assert.Equal(t, "db", point.Tags()["workload_name"])
+
+	// the same pod is counted once for as long as it is listed
+	points = counter.handle([]interface{}{evictedPod("uid-1", "db-0")}, testTransform)
+	assert.Equal(t, 1.0, points[0].(*wf.Point).Value)
+
+	// the count keeps growing after the evicted pod is deleted and its replacement is evicted
+	points = counter.handle([]interface{}{evictedPod("uid-2", "db-0"), bare}, testTransform)
+	require.Len(t, points, 2)
+	for _, p := range points {
+		point := p.(*wf.Point)
+		if point.Tags()["pod_name"] == "bare" {
+			assert.Equal(t, 1.0, point.Value)
+			assert.Empty(t, point.Tags()["workload_name"])
+		} else {
+			assert.Equal(t, 2.0, point.Value)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"reflect"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func pointsForPersistentVolumeClaim(item interface{}, transforms configuration.Transforms) []wf.Metric {
+	pvc, ok := item.(*v1.PersistentVolumeClaim)
+	if !ok {
+		log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+		return nil
+	}
+
+	tags := buildTags("pvc_name", pvc.Name, pvc.Namespace, transforms.Tags)
+	if pvc.Spec.StorageClassName != nil {
+		tags["storage_class"] = *pvc.Spec.StorageClassName
+	}
+	tags["access_modes"] = accessModes(pvc.Spec.AccessModes)
+	if pvc.Spec.VolumeName != "" {
+		tags["pv_name"] = pvc.Spec.VolumeName
+	}
+	now := time.Now()
+
+	phaseTags := make(map[string]string, len(tags)+1)
+	copyTags(tags, phaseTags)
+	phaseTags["phase"] = string(pvc.Status.Phase)
+	phase := float64(util.ConvertPersistentVolumeClaimPhase(pvc.Status.Phase))
+
+	points := []wf.Metric{
+		metricPoint(transforms.Prefix, "pvc.status.phase", phase, now.Unix(), transforms.Source, phaseTags),
+		metricPoint(transforms.Prefix, "pvc.phase_duration_seconds", now.Sub(phaseStart(pvc)).Seconds(), now.Unix(), transforms.Source, phaseTags),
+	}
+	if request, found := pvc.Spec.Resources.Requests[v1.ResourceStorage]; found {
+		points = append(points, metricPoint(transforms.Prefix, "pvc.request_bytes", float64(request.Value()), now.Unix(), transforms.Source, tags))
+	}
+	if capacity, found := pvc.Status.Capacity[v1.ResourceStorage]; found {
+		points = append(points, metricPoint(transforms.Prefix, "pvc.capacity_bytes", float64(capacity.Value()), now.Unix(), transforms.Source, tags))
+	}
+	return points
+}
+
+// phaseStart returns when a claim entered its current phase. Claims are pending from their creation and Kubernetes
+// does not record when they were bound, so a bound claim is assumed to be bound when its volume was created, or
+// when the claim was created for volumes provisioned ahead of the claim.
+func phaseStart(pvc *v1.PersistentVolumeClaim) time.Time {
+	start := pvc.CreationTimestamp.Time
+	if pvc.Status.Phase != v1.ClaimBound {
+		return start
+	}
+	if item, found := singleton.get(persistentVolumes, "", pvc.Spec.VolumeName); found {
+		if created := item.(*v1.PersistentVolume).CreationTimestamp.Time; created.After(start) {
+			return created
+		}
+	}
+	return start
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPointsForPersistentVolumeClaim(t *testing.T) {
+	testTransform := setupTestTransform()
+	storageClass := "standard"
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "data",
+			Namespace:         "ns1",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Minute)),
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+			AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce, v1.ReadOnlyMany},
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+		Status: v1.PersistentVolumeClaimStatus{Phase: v1.ClaimPending},
+	}
+
+	points := map[string]*wf.Point{}
+	for _, metric := range pointsForPersistentVolumeClaim(pvc, testTransform) {
+		point := metric.(*wf.Point)
+		points[point.Metric] = point
+	}
+	assert.Len(t, points, 3, "no capacity is reported before the claim is bound")
+
+	phase := points["testPrefixpvc.status.phase"]
+	assert.Equal(t, float64(util.PVC_PHASE_PENDING), phase.Value)
+	assert.Equal(t, "Pending", phase.Tags()["phase"])
+	assert.Equal(t, "data", phase.Tags()["pvc_name"])
+	assert.Equal(t, "ns1", phase.Tags()["namespace_name"])
+	assert.Equal(t, "standard", phase.Tags()["storage_class"])
+	assert.Equal(t, "ReadWriteOnce,ReadOnlyMany", phase.Tags()["access_modes"])
+
+	assert.InDelta(t, 60, points["testPrefixpvc.phase_duration_seconds"].Value, 5)
+	assert.Equal(t, float64(1<<30), points["testPrefixpvc.request_bytes"].Value)
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func pointsForPersistentVolume(item interface{}, transforms configuration.Transforms) []wf.Metric {
+	pv, ok := item.(*v1.PersistentVolume)
+	if !ok {
+		log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+		return nil
+	}
+
+	tags := buildTags("pv_name", pv.Name, "", transforms.Tags)
+	tags["storage_class"] = pv.Spec.StorageClassName
+	tags["access_modes"] = accessModes(pv.Spec.AccessModes)
+	tags["reclaim_policy"] = string(pv.Spec.PersistentVolumeReclaimPolicy)
+	if claim := pv.Spec.ClaimRef; claim != nil {
+		tags["pvc_name"] = claim.Name
+		tags["pvc_namespace"] = claim.Namespace
+	}
+	now := time.Now().Unix()
+
+	phaseTags := make(map[string]string, len(tags)+1)
+	copyTags(tags, phaseTags)
+	phaseTags["phase"] = string(pv.Status.Phase)
+	phase := float64(util.ConvertPersistentVolumePhase(pv.Status.Phase))
+
+	points := []wf.Metric{
+		metricPoint(transforms.Prefix, "pv.status.phase", phase, now, transforms.Source, phaseTags),
+	}
+	if capacity, found := pv.Spec.Capacity[v1.ResourceStorage]; found {
+		points = append(points, metricPoint(transforms.Prefix, "pv.capacity_bytes", float64(capacity.Value()), now, transforms.Source, tags))
+	}
+	return points
+}
+
+// accessModes returns the access modes of a volume as a comma separated tag value
+func accessModes(modes []v1.PersistentVolumeAccessMode) string {
+	values := make([]string, len(modes))
+	for i, mode := range modes {
+		values[i] = string(mode)
+	}
+	return strings.Join(values, ",")
+}
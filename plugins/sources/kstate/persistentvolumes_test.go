@@ -0,0 +1,67 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPointsForPersistentVolume(t *testing.T) {
+	testTransform := setupTestTransform()
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+		Spec: v1.PersistentVolumeSpec{
+			StorageClassName:              "standard",
+			AccessModes:                   []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimRetain,
+			Capacity:                      v1.ResourceList{v1.ResourceStorage: resource.MustParse("10Gi")},
+			ClaimRef:                      &v1.ObjectReference{Namespace: "ns1", Name: "data"},
+		},
+		Status: v1.PersistentVolumeStatus{Phase: v1.VolumeBound},
+	}
+
+	points := map[string]*wf.Point{}
+	for _, metric := range pointsForPersistentVolume(pv, testTransform) {
+		point := metric.(*wf.Point)
+		points[point.Metric] = point
+	}
+	require.Len(t, points, 2)
+
+	phase := points["testPrefixpv.status.phase"]
+	assert.Equal(t, float64(util.PV_PHASE_BOUND), phase.Value)
+	assert.Equal(t, "Bound", phase.Tags()["phase"])
+	assert.Equal(t, "pv-1", phase.Tags()["pv_name"])
+	assert.Equal(t, "standard", phase.Tags()["storage_class"])
+	assert.Equal(t, "ReadWriteOnce", phase.Tags()["access_modes"])
+	assert.Equal(t, "Retain", phase.Tags()["reclaim_policy"])
+	assert.Equal(t, "data", phase.Tags()["pvc_name"])
+	assert.Equal(t, "ns1", phase.Tags()["pvc_namespace"])
+	assert.NotContains(t, phase.Tags(), "namespace_name", "volumes are cluster scoped")
+
+	capacity := points["testPrefixpv.capacity_bytes"]
+	assert.Equal(t, float64(10<<30), capacity.Value)
+	assert.NotContains(t, capacity.Tags(), "phase")
+
+	t.Run("unbound volume without capacity", func(t *testing.T) {
+		available := pv.DeepCopy()
+		available.Spec.ClaimRef = nil
+		available.Spec.Capacity = nil
+		available.Status.Phase = v1.VolumeAvailable
+
+		points := pointsForPersistentVolume(available, testTransform)
+		require.Len(t, points, 1)
+		assert.Equal(t, float64(util.PV_PHASE_AVAILABLE), points[0].(*wf.Point).Value)
+		assert.NotContains(t, points[0].Tags(), "pvc_name")
+	})
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"reflect"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// imagePulledReason is the reason of the events reported by the kubelet once an image is pulled
+const imagePulledReason = "Pulled"
+
+// matches the duration in messages such as: Successfully pulled image "nginx" in 1.52s (1.52s including waiting)
+var pullDurationRE = regexp.MustCompile(`^Successfully pulled image .* in ((?:[0-9.]+(?:ns|us|µs|ms|s|m|h))+)`)
+
+// the pod conditions whose latencies are measured from the time the pod was scheduled
+var scheduledLatencies = []struct {
+	metric    string
+	condition v1.PodConditionType
+}{
+	{"pod.lifecycle.initialized_seconds", v1.PodInitialized},
+	{"pod.lifecycle.containers_ready_seconds", v1.ContainersReady},
+	{"pod.lifecycle.ready_seconds", v1.PodReady},
+}
+
+// latencyHandler reports the latencies of the transitions that happened since the previous scrape so that each
+// transition is reported once. Kubernetes records transition times with a precision of seconds, so the windows
+// are truncated to seconds as well.
+type latencyHandler struct {
+	lister    *lister
+	since     time.Time
+	latencies latencies
+}
+
+// latencies reports the latencies of the objects that occurred in a window. The lister resolves the workloads of pods.
+type latencies func(l *lister, items []interface{}, since, until time.Time, transforms configuration.Transforms) []wf.Metric
+
+func newLatencyHandler(l *lister, latencies latencies) *latencyHandler {
+	return &latencyHandler{
+		lister:    l,
+		since:     time.Now().Truncate(time.Second),
+		latencies: latencies,
+	}
+}
+
+func (h *latencyHandler) handle(items []interface{}, transforms configuration.Transforms) []wf.Metric {
+	until := time.Now().Truncate(time.Second)
+	points := h.latencies(h.lister, items, h.since, until, transforms)
+	h.since = until
+	return points
+}
+
+// podLifecycleLatencies reports the time from creation to scheduling and from scheduling to initialization and
+// readiness of the pods that reached these conditions in the window. The readiness of pods with restarted
+// containers is skipped as their conditions reflect the latest restart.
+func podLifecycleLatencies(l *lister, items []interface{}, since, until time.Time, transforms configuration.Transforms) []wf.Metric {
+	samples := latencySamples{}
+	for _, item := range items {
+		pod, ok := item.(*v1.Pod)
+		if !ok {
+			log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+			continue
+		}
+		scheduled := conditionTime(pod, v1.PodScheduled)
+		if scheduled.IsZero() {
+			continue
+		}
+		group := podGroup(l, pod)
+		if inWindow(scheduled, since, until) {
+			samples.add("pod.lifecycle.scheduled_seconds", group, scheduled.Sub(pod.CreationTimestamp.Time))
+		}
+		restarted := hasRestarts(pod)
+		for _, latency := range scheduledLatencies {
+			if restarted && latency.condition != v1.PodInitialized {
+				continue
+			}
+			if t := conditionTime(pod, latency.condition); inWindow(t, since, until) {
+				samples.add(latency.metric, group, t.Sub(scheduled))
+			}
+		}
+	}
+	return samples.distributions(transforms, until)
+}
+
+// imagePullLatencies reports the image pull durations of the Pulled events that occurred in the window
+func imagePullLatencies(l *lister, items []interface{}, since, until time.Time, transforms configuration.Transforms) []wf.Metric {
+	samples := latencySamples{}
+	for _, item := range items {
+		event, ok := item.(*v1.Event)
+		if !ok {
+			log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+			continue
+		}
+		if event.Reason != imagePulledReason || event.InvolvedObject.Kind != "Pod" {
+			continue
+		}
+		if !inWindow(eventTime(event), since, until) {
+			continue
+		}
+		match := pullDurationRE.FindStringSubmatch(event.Message)
+		if match == nil {
+			// images already present on the node are reported with the same reason
+			continue
+		}
+		duration, err := time.ParseDuration(match[1])
+		if err != nil {
+			continue
+		}
+		group := latencyGroup{namespace: event.InvolvedObject.Namespace}
+		if item, found := l.get(lifecyclePods, event.InvolvedObject.Namespace, event.InvolvedObject.Name); found {
+			group = podGroup(l, item.(*v1.Pod))
+		}
+		samples.add("pod.lifecycle.image_pull_seconds", group, duration)
+	}
+	return samples.distributions(transforms, until)
+}
+
+// latencyGroup identifies the pods whose latencies are reported as a distribution
+type latencyGroup struct {
+	namespace string
+	kind      string
+	name      string
+}
+
+func podGroup(l *lister, pod *v1.Pod) latencyGroup {
+	kind, name := l.workload(pod)
+	return latencyGroup{namespace: pod.Namespace, kind: kind, name: name}
+}
+
+type latencyKey struct {
+	metric string
+	group  latencyGroup
+}
+
+// latencySamples are the counts of the latencies in seconds by metric and group
+type latencySamples map[latencyKey]map[float64]float64
+
+func (s latencySamples) add(metric string, group latencyGroup, latency time.Duration) {
+	if latency < 0 {
+		return
+	}
+	key := latencyKey{metric: metric, group: group}
+	if s[key] == nil {
+		s[key] = map[float64]float64{}
+	}
+	s[key][latency.Seconds()]++
+}
+
+func (s latencySamples) distributions(transforms configuration.Transforms, ts time.Time) []wf.Metric {
+	if len(s) == 0 {
+		return nil
+	}
+	result := make([]wf.Metric, 0, len(s))
+	for key, counts := range s {
+		tags := make(map[string]string, len(transforms.Tags)+3)
+		copyTags(transforms.Tags, tags)
+		tags["namespace_name"] = key.group.namespace
+		if key.group.kind != "" {
+			tags[metrics.LabelWorkloadKind.Key] = key.group.kind
+			tags[metrics.LabelWorkloadName.Key] = key.group.name
+		}
+		centroids := make([]wf.Centroid, 0, len(counts))
+		for value, count := range counts {
+			centroids = append(centroids, wf.Centroid{Value: value, Count: count})
+		}
+		result = append(result, wf.NewFrequencyDistribution(transforms.Prefix+key.metric, transforms.Source, tags, centroids, ts))
+	}
+	return result
+}
+
+// conditionTime returns the time a condition of the pod became true, zero if it is not true
+func conditionTime(pod *v1.Pod, conditionType v1.PodConditionType) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == v1.ConditionTrue {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+func hasRestarts(pod *v1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.RestartCount > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func eventTime(event *v1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	return event.EventTime.Time
+}
+
+func inWindow(t, since, until time.Time) bool {
+	return !t.IsZero() && !t.Before(since) && t.Before(until)
+}
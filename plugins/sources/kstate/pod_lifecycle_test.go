@@ -0,0 +1,166 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func latencyPod(name string, created time.Time, restarts int32, offsets map[v1.PodConditionType]time.Duration) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "ns1",
+			CreationTimestamp: metav1.NewTime(created),
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "StatefulSet", Name: "db", Controller: &[]bool{true}[0]}},
+		},
+		Status: v1.PodStatus{
+			Phase:             v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "db", RestartCount: restarts}},
+		},
+	}
+	for conditionType, offset := range offsets {
+		pod.Status.Conditions = append(pod.Status.Conditions, v1.PodCondition{
+			Type:               conditionType,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(created.Add(offset)),
+		})
+	}
+	return pod
+}
+
+func distributionsByName(points []wf.Metric) map[string]*wf.Distribution {
+	result := map[string]*wf.Distribution{}
+	for _, point := range points {
+		d := point.(*wf.Distribution)
+		result[d.Name()] = d
+	}
+	return result
+}
+
+func TestPodLifecycleLatencies(t *testing.T) {
+	testTransform := setupTestTransform()
+	since := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	until := since.Add(time.Minute)
+
+	items := []interface{}{
+		latencyPod("db-0", since, 0, map[v1.PodConditionType]time.Duration{
+			v1.PodScheduled:    2 * time.Second,
+			v1.PodInitialized:  5 * time.Second,
+			v1.ContainersReady: 12 * time.Second,
+			v1.PodReady:        12 * time.Second,
+		}),
+		latencyPod("db-1", since, 0, map[v1.PodConditionType]time.Duration{
+			v1.PodScheduled:   2 * time.Second,
+			v1.PodInitialized: 3 * time.Second,
+		}),
+		// transitioned before the window
+		latencyPod("db-2", since.Add(-time.Hour), 0, map[v1.PodConditionType]time.Duration{
+			v1.PodScheduled: time.Second,
+			v1.PodReady:     10 * time.Second,
+		}),
+		// became ready again after a restart
+		latencyPod("db-3", since.Add(-time.Hour), 2, map[v1.PodConditionType]time.Duration{
+			v1.PodScheduled: time.Second,
+			v1.PodReady:     time.Hour + 30*time.Second,
+		}),
+	}
+
+	distributions := distributionsByName(podLifecycleLatencies(nil, items, since, until, testTransform))
+	assert.Len(t, distributions, 4)
+
+	scheduled := distributions["testPrefixpod.lifecycle.scheduled_seconds"]
+	assert.Equal(t, []wf.Centroid{{Value: 2, Count: 2}}, scheduled.Centroids)
+	assert.Equal(t, map[string]string{
+		"namespace_name": "ns1",
+		"workload_kind":  "StatefulSet",
+		"workload_name":  "db",
+	}, scheduled.Tags())
+	assert.Equal(t, "testSource", scheduled.Source)
+
+	assert.Equal(t, []wf.Centroid{{Value: 1, Count: 1}, {Value: 3, Count: 1}}, distributions["testPrefixpod.lifecycle.initialized_seconds"].Centroids)
+	assert.Equal(t, []wf.Centroid{{Value: 10, Count: 1}}, distributions["testPrefixpod.lifecycle.containers_ready_seconds"].Centroids)
+	assert.Equal(t, []wf.Centroid{{Value: 10, Count: 1}}, distributions["testPrefixpod.lifecycle.ready_seconds"].Centroids)
+}
+
+func TestImagePullLatencies(t *testing.T) {
+	testTransform := setupTestTransform()
+	since := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	until := since.Add(time.Minute)
+
+	pulled := func(message string, at time.Time) *v1.Event {
+		return &v1.Event{
+			InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: "ns1", Name: "db-0"},
+			Reason:         imagePulledReason,
+			Message:        message,
+			LastTimestamp:  metav1.NewTime(at),
+		}
+	}
+	items := []interface{}{
+		pulled(`Successfully pulled image "nginx:1.25" in 1.5s (1.5s including waiting)`, since.Add(10*time.Second)),
+		pulled(`Successfully pulled image "redis:7" in 2m3s`, since.Add(20*time.Second)),
+		pulled(`Container image "busybox" already present on machine`, since.Add(20*time.Second)),
+		pulled(`Successfully pulled image "nginx:1.24" in 4s`, since.Add(-time.Second)),
+	}
+
+	// without the pod the pulls are only grouped by namespace
+	l := &lister{informers: map[string]cache.SharedInformer{}}
+	distributions := distributionsByName(imagePullLatencies(l, items, since, until, testTransform))
+	pulls := distributions["testPrefixpod.lifecycle.image_pull_seconds"]
+	if assert.NotNil(t, pulls) {
+		assert.Equal(t, []wf.Centroid{{Value: 1.5, Count: 1}, {Value: 123, Count: 1}}, pulls.Centroids)
+		assert.Equal(t, map[string]string{"namespace_name": "ns1"}, pulls.Tags())
+	}
+
+	// the workload of the pod is resolved by the given lister
+	pods := cache.NewSharedInformer(nil, &v1.Pod{}, 0)
+	require.NoError(t, pods.GetStore().Add(latencyPod("db-0", since, 0, nil)))
+	l.lifecycle = map[string]cache.SharedInformer{lifecyclePods: pods}
+	distributions = distributionsByName(imagePullLatencies(l, items, since, until, testTransform))
+	pulls = distributions["testPrefixpod.lifecycle.image_pull_seconds"]
+	if assert.NotNil(t, pulls) {
+		assert.Equal(t, map[string]string{
+			"namespace_name": "ns1",
+			"workload_kind":  "StatefulSet",
+			"workload_name":  "db",
+		}, pulls.Tags())
+	}
+}
+
+func TestPodLifecycleMetricsConfiguration(t *testing.T) {
+	l := &lister{kubeClient: fake.NewSimpleClientset(), informers: map[string]cache.SharedInformer{}}
+
+	src, err := NewStateMetricsSource(l, configuration.KubernetesStateSourceConfig{})
+	assert.NoError(t, err)
+	assert.NotContains(t, src.(*stateMetricsSource).listFuncs, lifecyclePods)
+	assert.NotContains(t, src.(*stateMetricsSource).listFuncs, imagePulledEvents)
+
+	src, err = NewStateMetricsSource(l, configuration.KubernetesStateSourceConfig{PodLifecycleMetrics: true})
+	assert.NoError(t, err)
+	assert.Contains(t, src.(*stateMetricsSource).listFuncs, lifecyclePods)
+	assert.Contains(t, src.(*stateMetricsSource).listFuncs, imagePulledEvents)
+
+	_, err = l.List(lifecyclePods)
+	assert.Error(t, err, "all pods are only watched when enabled")
+	l.watchPodLifecycle(true)
+	_, err = l.List(lifecyclePods)
+	assert.NoError(t, err)
+	_, err = l.List(imagePulledEvents)
+	assert.NoError(t, err)
+	l.watchPodLifecycle(false)
+	_, err = l.List(lifecyclePods)
+	assert.Error(t, err)
+}
@@ -24,24 +24,30 @@ import (
 
 type resourceHandler func(interface{}, configuration.Transforms) []wf.Metric
 
+// resourceListHandler builds points from all the objects of a resource type at once, such as counts
+type resourceListHandler func([]interface{}, configuration.Transforms) []wf.Metric
+
 type stateMetricsSource struct {
 	lister     *lister
 	transforms configuration.Transforms
 	source     string
 	filters    filter.Filter
 	funcs      map[string]resourceHandler
+	listFuncs  map[string]resourceListHandler
+	custom     []customResource
 
 	pps gometrics.Counter
 	eps gometrics.Counter
 	fps gometrics.Counter
 }
 
-func NewStateMetricsSource(lister *lister, transforms configuration.Transforms) (metrics.Source, error) {
+func NewStateMetricsSource(lister *lister, cfg configuration.KubernetesStateSourceConfig) (metrics.Source, error) {
 	pt := map[string]string{"type": "kubernetes.state"}
 	ppsKey := reporting.EncodeKey("source.points.collected", pt)
 	epsKey := reporting.EncodeKey("source.collect.errors", pt)
 	fpsKey := reporting.EncodeKey("source.points.filtered", pt)
 
+	transforms := cfg.Transforms
 	transforms.Source = getDefault(util.GetNodeName(), transforms.Source)
 	transforms.Prefix = getDefault(transforms.Prefix, "kubernetes.")
 
@@ -56,12 +62,38 @@ func NewStateMetricsSource(lister *lister, transforms configuration.Transforms)
 	funcs[horizontalPodAutoscalers] = pointsForHPA
 	funcs[nodes] = pointsForNode
 	funcs[nonRunningPods] = pointsForNonRunningPods
+	funcs[persistentVolumes] = pointsForPersistentVolume
+	funcs[persistentVolumeClaims] = pointsForPersistentVolumeClaim
+	funcs[storageClasses] = pointsForStorageClass
+	funcs[services] = pointsForService
+	funcs[ingresses] = pointsForIngress
+	funcs[resourceQuotas] = pointsForResourceQuota
+	funcs[limitRanges] = pointsForLimitRange
+
+	listFuncs := make(map[string]resourceListHandler)
+	listFuncs[networkPolicies] = networkPoliciesHandler(lister)
+	listFuncs[nonRunningPods] = newEvictionCounter(lister).handle
+	if cfg.PodLifecycleMetrics {
+		listFuncs[lifecyclePods] = newLatencyHandler(lister, podLifecycleLatencies).handle
+		listFuncs[imagePulledEvents] = newLatencyHandler(lister, imagePullLatencies).handle
+	}
+
+	var custom []customResource
+	for _, crCfg := range cfg.CustomResources {
+		cr, err := newCustomResource(crCfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid custom resource %s: %v", crCfg.Resource, err)
+		}
+		custom = append(custom, cr)
+	}
 
 	return &stateMetricsSource{
 		lister:     lister,
 		transforms: transforms,
 		filters:    filter.FromConfig(transforms.Filters),
 		funcs:      funcs,
+		listFuncs:  listFuncs,
+		custom:     custom,
 		pps:        gometrics.GetOrRegisterCounter(ppsKey, gometrics.DefaultRegistry),
 		eps:        gometrics.GetOrRegisterCounter(epsKey, gometrics.DefaultRegistry),
 		fps:        gometrics.GetOrRegisterCounter(fpsKey, gometrics.DefaultRegistry),
@@ -96,30 +128,68 @@ func (src *stateMetricsSource) Scrape() (*metrics.Batch, error) {
 			points = wf.FilterAppend(src.filters, src.fps, points, point)
 		}
 	}
+	for resType := range src.listFuncs {
+		for _, point := range src.pointsForResourceList(resType) {
+			points = wf.FilterAppend(src.filters, src.fps, points, point)
+		}
+	}
+	for _, cr := range src.custom {
+		for _, point := range src.pointsForCustomResource(cr) {
+			points = wf.FilterAppend(src.filters, src.fps, points, point)
+		}
+	}
 	result.Metrics = points
 	src.pps.Inc(int64(len(result.Metrics)))
 	return result, nil
 }
 
 func (src *stateMetricsSource) pointsForResource(resType string) []wf.Metric {
-	items, err := src.lister.List(resType)
-	if err != nil {
-		log.Errorf("error listing %s: %v", resType, err)
+	f, ok := src.funcs[resType]
+	if !ok {
 		return nil
 	}
 
+	items := src.list(resType)
+	var points []wf.Metric
+	for _, item := range items {
+		points = append(points, f(item, src.transforms)...)
+	}
+	return points
+}
+
+// pointsForResourceList builds the points of a resource type from all its objects at once
+func (src *stateMetricsSource) pointsForResourceList(resType string) []wf.Metric {
+	f, ok := src.listFuncs[resType]
+	if !ok {
+		return nil
+	}
+
+	items := src.list(resType)
 	if len(items) == 0 {
 		return nil
 	}
+	return f(items, src.transforms)
+}
 
-	f, ok := src.funcs[resType]
-	if !ok {
+func (src *stateMetricsSource) list(resType string) []interface{} {
+	items, err := src.lister.List(resType)
+	if err != nil {
+		log.Errorf("error listing %s: %v", resType, err)
+		return nil
+	}
+	return items
+}
+
+func (src *stateMetricsSource) pointsForCustomResource(cr customResource) []wf.Metric {
+	items, err := src.lister.listCustom(cr.resource)
+	if err != nil {
+		log.Errorf("error listing %s: %v", cr.resource.String(), err)
 		return nil
 	}
 
 	var points []wf.Metric
 	for _, item := range items {
-		points = append(points, f(item, src.transforms)...)
+		points = append(points, pointsForCustomResource(item, cr, src.transforms)...)
 	}
 	return points
 }
@@ -152,13 +222,20 @@ func NewStateProvider(cfg configuration.KubernetesStateSourceConfig) (metrics.So
 		return nil, fmt.Errorf("kubeclient not initialized")
 	}
 
+	if len(cfg.CustomResources) > 0 && cfg.DynamicClient == nil {
+		return nil, fmt.Errorf("dynamic client not initialized")
+	}
+
 	var sources []metrics.Source
-	metricsSource, err := NewStateMetricsSource(newLister(cfg.KubeClient), cfg.Transforms)
+	lister := newLister(cfg.KubeClient)
+	metricsSource, err := NewStateMetricsSource(lister, cfg)
 	if err == nil {
 		sources = append(sources, metricsSource)
 	} else {
 		return nil, fmt.Errorf("error creating source: %v", err)
 	}
+	lister.watchCustomResources(cfg.DynamicClient, customResourceTypes(cfg.CustomResources))
+	lister.watchPodLifecycle(cfg.PodLifecycleMetrics)
 
 	return &stateProvider{
 		sources: sources,
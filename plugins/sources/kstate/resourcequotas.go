@@ -0,0 +1,46 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"reflect"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func pointsForResourceQuota(item interface{}, transforms configuration.Transforms) []wf.Metric {
+	quota, ok := item.(*v1.ResourceQuota)
+	if !ok {
+		log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+		return nil
+	}
+
+	now := time.Now().Unix()
+	var points []wf.Metric
+	for name, hardQuantity := range quota.Status.Hard {
+		tags := buildTags("resourcequota", quota.Name, quota.Namespace, transforms.Tags)
+		tags["resource"] = string(name)
+
+		hard := float64(util.ResourceQuantityValue(name, hardQuantity))
+		points = append(points, metricPoint(transforms.Prefix, "quota.hard", hard, now, transforms.Source, tags))
+
+		usedQuantity, found := quota.Status.Used[name]
+		if !found {
+			continue
+		}
+		used := float64(util.ResourceQuantityValue(name, usedQuantity))
+		points = append(points, metricPoint(transforms.Prefix, "quota.used", used, now, transforms.Source, tags))
+		if hard > 0 {
+			points = append(points, metricPoint(transforms.Prefix, "quota.utilization", used/hard, now, transforms.Source, tags))
+		}
+	}
+	return points
+}
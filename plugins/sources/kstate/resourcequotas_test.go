@@ -0,0 +1,55 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPointsForResourceQuota(t *testing.T) {
+	testTransform := setupTestTransform()
+	quota := &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "ns1"},
+		Status: v1.ResourceQuotaStatus{
+			Hard: v1.ResourceList{
+				v1.ResourceRequestsCPU:    resource.MustParse("2"),
+				v1.ResourceRequestsMemory: resource.MustParse("1Gi"),
+				v1.ResourcePods:           resource.MustParse("10"),
+			},
+			Used: v1.ResourceList{
+				v1.ResourceRequestsCPU: resource.MustParse("500m"),
+				v1.ResourcePods:        resource.MustParse("4"),
+			},
+		},
+	}
+
+	points := map[string]*wf.Point{}
+	for _, metric := range pointsForResourceQuota(quota, testTransform) {
+		point := metric.(*wf.Point)
+		points[point.Metric+"/"+point.Tags()["resource"]] = point
+	}
+	assert.Len(t, points, 7, "no usage is reported for resources without usage")
+
+	hard := points["testPrefixquota.hard/requests.cpu"]
+	require.NotNil(t, hard)
+	assert.Equal(t, float64(2000), hard.Value, "cpu is in millicores")
+	assert.Equal(t, "compute", hard.Tags()["resourcequota"])
+	assert.Equal(t, "ns1", hard.Tags()["namespace_name"])
+
+	assert.Equal(t, float64(500), points["testPrefixquota.used/requests.cpu"].Value)
+	assert.Equal(t, 0.25, points["testPrefixquota.utilization/requests.cpu"].Value)
+	assert.Equal(t, float64(1<<30), points["testPrefixquota.hard/requests.memory"].Value)
+	assert.NotContains(t, points, "testPrefixquota.used/requests.memory")
+	assert.Equal(t, float64(10), points["testPrefixquota.hard/pods"].Value)
+	assert.Equal(t, 0.4, points["testPrefixquota.utilization/pods"].Value)
+}
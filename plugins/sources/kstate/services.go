@@ -0,0 +1,83 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func pointsForService(item interface{}, transforms configuration.Transforms) []wf.Metric {
+	svc, ok := item.(*v1.Service)
+	if !ok {
+		log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+		return nil
+	}
+
+	tags := buildTags("service", svc.Name, svc.Namespace, transforms.Tags)
+	now := time.Now().Unix()
+
+	infoTags := make(map[string]string, len(tags)+1)
+	copyTags(tags, infoTags)
+	infoTags["type"] = string(svc.Spec.Type)
+	points := []wf.Metric{
+		metricPoint(transforms.Prefix, "service.info", 1.0, now, transforms.Source, infoTags),
+	}
+
+	// ExternalName services are resolved through DNS and have no endpoints
+	if svc.Spec.Type == v1.ServiceTypeExternalName {
+		return points
+	}
+	var eps *v1.Endpoints
+	if item, found := singleton.get(endpoints, svc.Namespace, svc.Name); found {
+		eps = item.(*v1.Endpoints)
+	}
+	return append(points, buildServiceEndpointMetrics(svc, eps, tags, transforms, now)...)
+}
+
+// buildServiceEndpointMetrics counts the ready and not ready endpoints of each port of a service. Zero is reported
+// for every port of a service without endpoints.
+func buildServiceEndpointMetrics(svc *v1.Service, eps *v1.Endpoints, tags map[string]string, transforms configuration.Transforms, now int64) []wf.Metric {
+	points := make([]wf.Metric, 0, 2*len(svc.Spec.Ports))
+	for _, port := range svc.Spec.Ports {
+		var ready, notReady int
+		if eps != nil {
+			for _, subset := range eps.Subsets {
+				if hasEndpointPort(subset, port.Name) {
+					ready += len(subset.Addresses)
+					notReady += len(subset.NotReadyAddresses)
+				}
+			}
+		}
+		portTags := make(map[string]string, len(tags)+3)
+		copyTags(tags, portTags)
+		portTags["port_name"] = port.Name
+		portTags["port"] = strconv.Itoa(int(port.Port))
+		portTags["protocol"] = string(port.Protocol)
+		points = append(points,
+			metricPoint(transforms.Prefix, "service.endpoints.ready", float64(ready), now, transforms.Source, portTags),
+			metricPoint(transforms.Prefix, "service.endpoints.not_ready", float64(notReady), now, transforms.Source, portTags),
+		)
+	}
+	return points
+}
+
+// hasEndpointPort returns whether a subset exposes the service port of the given name. Endpoint ports carry the
+// name of their service port, which is empty for services with a single unnamed port.
+func hasEndpointPort(subset v1.EndpointSubset, name string) bool {
+	for _, port := range subset.Ports {
+		if port.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildServiceEndpointMetrics(t *testing.T) {
+	testTransform := setupTestTransform()
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns1"},
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeClusterIP,
+			Ports: []v1.ServicePort{
+				{Name: "http", Port: 80, Protocol: v1.ProtocolTCP},
+				{Name: "metrics", Port: 9090, Protocol: v1.ProtocolTCP},
+			},
+		},
+	}
+	eps := &v1.Endpoints{
+		Subsets: []v1.EndpointSubset{{
+			Addresses:         []v1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}},
+			NotReadyAddresses: []v1.EndpointAddress{{IP: "10.0.0.3"}},
+			Ports:             []v1.EndpointPort{{Name: "http", Port: 8080}},
+		}},
+	}
+
+	counts := func(eps *v1.Endpoints) map[string]float64 {
+		result := map[string]float64{}
+		for _, metric := range buildServiceEndpointMetrics(svc, eps, map[string]string{}, testTransform, 0) {
+			point := metric.(*wf.Point)
+			result[point.Metric+"/"+point.Tags()["port_name"]] = point.Value
+		}
+		return result
+	}
+
+	assert.Equal(t, map[string]float64{
+		"testPrefixservice.endpoints.ready/http":        2,
+		"testPrefixservice.endpoints.not_ready/http":    1,
+		"testPrefixservice.endpoints.ready/metrics":     0,
+		"testPrefixservice.endpoints.not_ready/metrics": 0,
+	}, counts(eps))
+
+	assert.Equal(t, map[string]float64{
+		"testPrefixservice.endpoints.ready/http":        0,
+		"testPrefixservice.endpoints.not_ready/http":    0,
+		"testPrefixservice.endpoints.ready/metrics":     0,
+		"testPrefixservice.endpoints.not_ready/metrics": 0,
+	}, counts(nil), "zero is reported for services without endpoints")
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+
+	storagev1 "k8s.io/api/storage/v1"
+)
+
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+func pointsForStorageClass(item interface{}, transforms configuration.Transforms) []wf.Metric {
+	sc, ok := item.(*storagev1.StorageClass)
+	if !ok {
+		log.Errorf("invalid type: %s", reflect.TypeOf(item).String())
+		return nil
+	}
+
+	tags := buildTags("storage_class", sc.Name, "", transforms.Tags)
+	tags["provisioner"] = sc.Provisioner
+	if sc.ReclaimPolicy != nil {
+		tags["reclaim_policy"] = string(*sc.ReclaimPolicy)
+	}
+	if sc.VolumeBindingMode != nil {
+		tags["volume_binding_mode"] = string(*sc.VolumeBindingMode)
+	}
+	if sc.AllowVolumeExpansion != nil {
+		tags["allow_volume_expansion"] = strconv.FormatBool(*sc.AllowVolumeExpansion)
+	}
+	tags["default"] = strconv.FormatBool(sc.Annotations[defaultStorageClassAnnotation] == "true")
+	now := time.Now().Unix()
+
+	return []wf.Metric{
+		metricPoint(transforms.Prefix, "storageclass.info", 1.0, now, transforms.Source, tags),
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPointsForStorageClass(t *testing.T) {
+	testTransform := setupTestTransform()
+	reclaimPolicy := v1.PersistentVolumeReclaimDelete
+	bindingMode := storagev1.VolumeBindingWaitForFirstConsumer
+	expansion := true
+	sc := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "standard",
+			Annotations: map[string]string{defaultStorageClassAnnotation: "true"},
+		},
+		Provisioner:          "kubernetes.io/gce-pd",
+		ReclaimPolicy:        &reclaimPolicy,
+		VolumeBindingMode:    &bindingMode,
+		AllowVolumeExpansion: &expansion,
+	}
+
+	points := pointsForStorageClass(sc, testTransform)
+	require.Len(t, points, 1)
+	assert.Equal(t, "testPrefixstorageclass.info", points[0].Name())
+	assert.Equal(t, map[string]string{
+		"storage_class":          "standard",
+		"provisioner":            "kubernetes.io/gce-pd",
+		"reclaim_policy":         "Delete",
+		"volume_binding_mode":    "WaitForFirstConsumer",
+		"allow_volume_expansion": "true",
+		"default":                "true",
+	}, points[0].Tags())
+
+	t.Run("optional fields", func(t *testing.T) {
+		points := pointsForStorageClass(&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "slow"},
+			Provisioner: "example.com/nfs",
+		}, testTransform)
+		require.Len(t, points, 1)
+		tags := points[0].Tags()
+		assert.Equal(t, "false", tags["default"])
+		assert.NotContains(t, tags, "reclaim_policy")
+		assert.NotContains(t, tags, "volume_binding_mode")
+		assert.NotContains(t, tags, "allow_volume_expansion")
+	})
+}
@@ -20,7 +20,9 @@ package sources
 import (
 	"fmt"
 	"math/rand"
+	"reflect"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -80,6 +82,7 @@ type SourceManager interface {
 	GetPendingMetrics() []*metrics.Batch
 	SetDefaultCollectionInterval(time.Duration)
 	BuildProviders(config configuration.SourceConfig) error
+	ReloadProviders(config configuration.SourceConfig) error
 	SetClient(kubernetes.Interface)
 }
 
@@ -95,6 +98,9 @@ type sourceManagerImpl struct {
 	responseMtx sync.Mutex
 	response    []*metrics.Batch
 
+	// the providers created from the configuration file keyed by source
+	configuredSources map[string]configuredSource
+
 	client kubernetes.Interface
 }
 
@@ -130,9 +136,11 @@ func (sm *sourceManagerImpl) DiscoveryPluginConfigs() []discovery.PluginConfig {
 
 // BuildProviders creates a new source manager with the configured MetricsSourceProviders
 func (sm *sourceManagerImpl) BuildProviders(cfg configuration.SourceConfig) error {
+	sm.configuredSources = make(map[string]configuredSource)
 	sources := buildProviders(sm.client, cfg)
-	for _, runtime := range sources {
-		sm.AddProvider(runtime)
+	for _, source := range sources {
+		sm.AddProvider(source.provider)
+		sm.configuredSources[source.key] = source
 	}
 	if len(sm.metricsSourceProviders) == 0 {
 		return fmt.Errorf("no available sources to use")
@@ -140,6 +148,42 @@ func (sm *sourceManagerImpl) BuildProviders(cfg configuration.SourceConfig) erro
 	return nil
 }
 
+// ReloadProviders applies a changed source configuration. Only the providers of added, removed or changed sources
+// are created or deleted, the others keep collecting without interruption.
+func (sm *sourceManagerImpl) ReloadProviders(cfg configuration.SourceConfig) error {
+	builders := sourceBuilders(sm.client, cfg)
+	configured := make(map[string]configuredSource, len(builders))
+	for _, builder := range builders {
+		prev, found := sm.configuredSources[builder.key]
+		if found && reflect.DeepEqual(prev.cfg, builder.cfg) {
+			configured[builder.key] = prev
+			continue
+		}
+		source, ok := builder.build()
+		if !ok {
+			continue
+		}
+		if found && prev.provider.Name() != source.provider.Name() {
+			sm.DeleteProvider(prev.provider.Name())
+		}
+		log.WithField("source", builder.key).Info("reloading source")
+		sm.AddProvider(source.provider)
+		configured[builder.key] = source
+	}
+	for key, prev := range sm.configuredSources {
+		if _, found := configured[key]; !found {
+			log.WithField("source", key).Info("removing source")
+			sm.DeleteProvider(prev.provider.Name())
+		}
+	}
+	sm.configuredSources = configured
+
+	if len(configured) == 0 {
+		return fmt.Errorf("no available sources to use")
+	}
+	return nil
+}
+
 func (sm *sourceManagerImpl) SetDefaultCollectionInterval(defaultCollectionInterval time.Duration) {
 	sm.defaultCollectionInterval = defaultCollectionInterval
 }
@@ -217,6 +261,7 @@ func (sm *sourceManagerImpl) DeleteProvider(name string) {
 	}
 
 	for _, source := range provider.GetMetricsSources() {
+		sendStaleMarkers(source, sm.responseChannel)
 		source.Cleanup()
 	}
 
@@ -271,6 +316,7 @@ func scrape(provider metrics.SourceProvider, channel chan *metrics.Batch) {
 				log.Errorf("Error in scraping containers from '%s': %v", source.Name(), err)
 				scrapeErrors.Inc(1)
 			}
+			sendStaleMarkers(source, channel)
 			return
 		}
 
@@ -294,67 +340,155 @@ func scrape(provider metrics.SourceProvider, channel chan *metrics.Batch) {
 	}
 }
 
+// sendStaleMarkers sends the staleness markers of a source that failed to scrape or is removed
+func sendStaleMarkers(source metrics.Source, channel chan *metrics.Batch) {
+	staleSource, ok := source.(metrics.StaleSource)
+	if !ok {
+		return
+	}
+	if dataBatch := staleSource.StaleMarkers(); dataBatch != nil && len(dataBatch.Metrics) > 0 {
+		channel <- dataBatch
+	}
+}
+
 func (sm *sourceManagerImpl) GetPendingMetrics() []*metrics.Batch {
 	response := sm.rotateResponse()
 	sort.Slice(response, func(i, j int) bool { return response[i].Timestamp.Before(response[j].Timestamp) })
 	return response
 }
 
-func buildProviders(client kubernetes.Interface, cfg configuration.SourceConfig) (result []metrics.SourceProvider) {
-	if cfg.SummaryConfig != nil {
-		provider, err := summary.NewSummaryProvider(*cfg.SummaryConfig)
-		result = appendProvider(result, provider, err, cfg.SummaryConfig.Collection)
+// configuredSource is a provider created from the configuration file
+type configuredSource struct {
+	key      string
+	cfg      interface{}
+	provider metrics.SourceProvider
+}
+
+// sourceBuilder creates the provider of a single source of the configuration file. The configuration includes
+// every setting the provider depends on so that changes can be detected.
+type sourceBuilder struct {
+	key        string
+	cfg        interface{}
+	collection configuration.CollectionConfig
+	create     func() (metrics.SourceProvider, error)
+}
+
+func (b sourceBuilder) build() (configuredSource, bool) {
+	provider, err := b.create()
+	if err != nil {
+		log.Errorf("Failed to create source: %v", err)
+		return configuredSource{}, false
+	}
+	if i, ok := provider.(metrics.ConfigurableSourceProvider); ok {
+		i.Configure(b.collection.Interval, b.collection.Timeout)
+	}
+	return configuredSource{key: b.key, cfg: b.cfg, provider: provider}, true
+}
 
-		if cfg.CadvisorConfig != nil {
-			provider, err = cadvisor.NewProvider(*cfg.CadvisorConfig, *cfg.SummaryConfig)
-			result = appendProvider(result, provider, err, cfg.CadvisorConfig.Collection)
+func buildProviders(client kubernetes.Interface, cfg configuration.SourceConfig) (result []configuredSource) {
+	for _, builder := range sourceBuilders(client, cfg) {
+		if source, ok := builder.build(); ok {
+			result = append(result, source)
 		}
-		if cfg.ControlPlaneConfig != nil {
-			provider, err = controlplane.NewProvider(*cfg.ControlPlaneConfig, *cfg.SummaryConfig, client.CoreV1())
-			result = appendProvider(result, provider, err, cfg.ControlPlaneConfig.Collection)
+	}
+	if len(result) == 0 {
+		log.Fatal("No available source to use")
+	}
+	return result
+}
+
+func sourceBuilders(client kubernetes.Interface, cfg configuration.SourceConfig) (result []sourceBuilder) {
+	if summaryCfg := cfg.SummaryConfig; summaryCfg != nil {
+		result = append(result, sourceBuilder{
+			key:        "kubernetes_source",
+			cfg:        *summaryCfg,
+			collection: summaryCfg.Collection,
+			create: func() (metrics.SourceProvider, error) {
+				return summary.NewSummaryProvider(*summaryCfg)
+			},
+		})
+		if cadvisorCfg := cfg.CadvisorConfig; cadvisorCfg != nil {
+			result = append(result, sourceBuilder{
+				key:        "kubernetes_cadvisor_source",
+				cfg:        []interface{}{*cadvisorCfg, *summaryCfg},
+				collection: cadvisorCfg.Collection,
+				create: func() (metrics.SourceProvider, error) {
+					return cadvisor.NewProvider(*cadvisorCfg, *summaryCfg)
+				},
+			})
+		}
+		if controlPlaneCfg := cfg.ControlPlaneConfig; controlPlaneCfg != nil {
+			result = append(result, sourceBuilder{
+				key:        "kubernetes_control_plane_source",
+				cfg:        []interface{}{*controlPlaneCfg, *summaryCfg},
+				collection: controlPlaneCfg.Collection,
+				create: func() (metrics.SourceProvider, error) {
+					return controlplane.NewProvider(*controlPlaneCfg, *summaryCfg, client.CoreV1())
+				},
+			})
 		}
 	}
-	if cfg.SystemdConfig != nil {
-		provider, err := systemd.NewProvider(*cfg.SystemdConfig)
-		result = appendProvider(result, provider, err, cfg.SystemdConfig.Collection)
+	if systemdCfg := cfg.SystemdConfig; systemdCfg != nil {
+		result = append(result, sourceBuilder{
+			key:        "systemd_source",
+			cfg:        *systemdCfg,
+			collection: systemdCfg.Collection,
+			create: func() (metrics.SourceProvider, error) {
+				return systemd.NewProvider(*systemdCfg)
+			},
+		})
 	}
-	if cfg.StatsConfig != nil {
-		provider, err := stats.NewInternalStatsProvider(*cfg.StatsConfig)
-		result = appendProvider(result, provider, err, cfg.StatsConfig.Collection)
+	if statsCfg := cfg.StatsConfig; statsCfg != nil {
+		result = append(result, sourceBuilder{
+			key:        "internal_stats_source",
+			cfg:        *statsCfg,
+			collection: statsCfg.Collection,
+			create: func() (metrics.SourceProvider, error) {
+				return stats.NewInternalStatsProvider(*statsCfg)
+			},
+		})
 	}
-	if cfg.StateConfig != nil {
-		provider, err := kstate.NewStateProvider(*cfg.StateConfig)
-		result = appendProvider(result, provider, err, cfg.StateConfig.Collection)
+	if stateCfg := cfg.StateConfig; stateCfg != nil {
+		result = append(result, sourceBuilder{
+			key:        "kubernetes_state_source",
+			cfg:        *stateCfg,
+			collection: stateCfg.Collection,
+			create: func() (metrics.SourceProvider, error) {
+				return kstate.NewStateProvider(*stateCfg)
+			},
+		})
 	}
+	keys := make(map[string]int)
 	for _, srcCfg := range cfg.TelegrafConfigs {
-		provider, err := telegraf.NewProvider(*srcCfg)
-		result = appendProvider(result, provider, err, srcCfg.Collection)
+		srcCfg := srcCfg
+		result = append(result, sourceBuilder{
+			key:        uniqueKey(keys, "telegraf_sources/"+strings.Join(srcCfg.Plugins, ",")),
+			cfg:        *srcCfg,
+			collection: srcCfg.Collection,
+			create: func() (metrics.SourceProvider, error) {
+				return telegraf.NewProvider(*srcCfg)
+			},
+		})
 	}
 	for _, srcCfg := range cfg.PrometheusConfigs {
-		provider, err := prometheus.NewPrometheusProvider(*srcCfg, prometheus.InstanceFromHost)
-		result = appendProvider(result, provider, err, srcCfg.Collection)
-	}
-
-	if len(result) == 0 {
-		log.Fatal("No available source to use")
+		srcCfg := srcCfg
+		result = append(result, sourceBuilder{
+			key:        uniqueKey(keys, "prometheus_sources/"+srcCfg.URL),
+			cfg:        *srcCfg,
+			collection: srcCfg.Collection,
+			create: func() (metrics.SourceProvider, error) {
+				return prometheus.NewPrometheusProvider(*srcCfg, prometheus.InstanceFromHost)
+			},
+		})
 	}
 	return result
 }
 
-func appendProvider(
-	slice []metrics.SourceProvider,
-	provider metrics.SourceProvider,
-	err error,
-	cfg configuration.CollectionConfig,
-) []metrics.SourceProvider {
-
-	if err != nil {
-		log.Errorf("Failed to create source: %v", err)
-		return slice
-	}
-	slice = append(slice, provider)
-	if i, ok := provider.(metrics.ConfigurableSourceProvider); ok {
-		i.Configure(cfg.Interval, cfg.Timeout)
+// uniqueKey disambiguates sources of the same type configured for the same target
+func uniqueKey(keys map[string]int, key string) string {
+	keys[key]++
+	if count := keys[key]; count > 1 {
+		return fmt.Sprintf("%s#%d", key, count)
 	}
-	return slice
+	return key
 }
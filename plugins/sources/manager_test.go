@@ -21,13 +21,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/discovery"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/options"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
 )
 
 func TestNoTimeout(t *testing.T) {
@@ -233,3 +236,66 @@ func (p *testPluginProvider) GetMetricsSources() []metrics.Source {
 func (p *testPluginProvider) Name() string {
 	return "testPluginProvider"
 }
+
+func TestReloadProviders(t *testing.T) {
+	util.SetAgentType(options.AllAgentType)
+	sm := &sourceManagerImpl{
+		responseChannel:           make(chan *metrics.Batch, 10),
+		metricsSourceProviders:    make(map[string]metrics.SourceProvider),
+		metricsSourceTimers:       make(map[string]*IntervalTimer),
+		metricsSourceQuits:        make(map[string]chan struct{}),
+		defaultCollectionInterval: time.Hour,
+	}
+	defer sm.StopProviders()
+
+	unchanged := &configuration.PrometheusSourceConfig{URL: "http://app:9100/metrics"}
+	changed := &configuration.PrometheusSourceConfig{URL: "http://db:9100/metrics"}
+	removed := &configuration.PrometheusSourceConfig{URL: "http://cache:9100/metrics"}
+	assert.NoError(t, sm.BuildProviders(configuration.SourceConfig{
+		PrometheusConfigs: []*configuration.PrometheusSourceConfig{unchanged, changed, removed},
+	}))
+	assert.Len(t, sm.metricsSourceProviders, 3)
+	app := sm.metricsSourceProviders["prometheus_metrics_provider: http://app:9100/metrics"]
+	db := sm.metricsSourceProviders["prometheus_metrics_provider: http://db:9100/metrics"]
+
+	added := &configuration.PrometheusSourceConfig{URL: "http://web:9100/metrics"}
+	assert.NoError(t, sm.ReloadProviders(configuration.SourceConfig{
+		PrometheusConfigs: []*configuration.PrometheusSourceConfig{
+			{URL: "http://app:9100/metrics"},
+			{URL: "http://db:9100/metrics", Transforms: configuration.Transforms{Prefix: "db."}},
+			added,
+		},
+	}))
+
+	assert.Len(t, sm.metricsSourceProviders, 3)
+	assert.Same(t, app, sm.metricsSourceProviders["prometheus_metrics_provider: http://app:9100/metrics"], "unchanged sources keep their provider")
+	assert.NotSame(t, db, sm.metricsSourceProviders["prometheus_metrics_provider: http://db:9100/metrics"], "changed sources are recreated")
+	assert.Contains(t, sm.metricsSourceProviders, "prometheus_metrics_provider: http://web:9100/metrics")
+	assert.NotContains(t, sm.metricsSourceProviders, "prometheus_metrics_provider: http://cache:9100/metrics")
+
+	assert.Error(t, sm.ReloadProviders(configuration.SourceConfig{}))
+	assert.Empty(t, sm.metricsSourceProviders)
+}
+
+type staleDummySource struct {
+	util.DummyMetricsSource
+}
+
+func (s *staleDummySource) StaleMarkers() *metrics.Batch {
+	return &metrics.Batch{
+		Timestamp: time.Now(),
+		Metrics:   []wf.Metric{wf.NewPoint("stale", wf.StaleNaN, 0, "node1", nil)},
+	}
+}
+
+func TestSendsStaleMarkersOnScrapeError(t *testing.T) {
+	source := &staleDummySource{*util.NewDummyMetricsSourceWithError("s1", 0, true)}
+	provider := util.NewDummyMetricsSourceProvider("dummy_stale", 0, time.Second, source)
+
+	channel := make(chan *metrics.Batch, 1)
+	scrape(provider, channel)
+
+	dataBatch := <-channel
+	assert.Len(t, dataBatch.Metrics, 1)
+	assert.True(t, wf.IsStaleMarker(dataBatch.Metrics[0].(*wf.Point).Value))
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus
+
+import (
+	"math"
+	"time"
+
+	prom "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+// isNativeHistogram reports whether the histogram has sparse buckets, following the client_golang convention
+func isNativeHistogram(h *prom.Histogram) bool {
+	return len(h.GetPositiveSpan()) > 0 || len(h.GetNegativeSpan()) > 0 || h.GetZeroThreshold() > 0 || h.GetZeroCount() > 0 || h.GetZeroCountFloat() > 0
+}
+
+func histogramCount(h *prom.Histogram) float64 {
+	if h.GetSampleCountFloat() > 0 {
+		return h.GetSampleCountFloat()
+	}
+	return float64(h.GetSampleCount())
+}
+
+// buildNativeHistogram converts a native histogram into a cumulative distribution with a centroid per populated bucket
+func (builder *pointBuilder) buildNativeHistogram(name string, m *prom.Metric, now int64, tags map[string]string) wf.Metric {
+	h := m.GetHistogram()
+	distribution := wf.NewCumulativeDistribution(builder.name(name), builder.source, tags, nativeCentroids(h), time.Unix(now, 0))
+	distribution.StartTime = createdTime(h.GetCreatedTimestamp())
+	return distribution
+}
+
+type nativeBucket struct {
+	index int32
+	count float64
+}
+
+// nativeCentroids returns the buckets of a native histogram as centroids of cumulative counts keyed by upper bound
+func nativeCentroids(h *prom.Histogram) []wf.Centroid {
+	schema := h.GetSchema()
+	negative := expandNativeBuckets(h.GetNegativeSpan(), h.GetNegativeDelta(), h.GetNegativeCount())
+	positive := expandNativeBuckets(h.GetPositiveSpan(), h.GetPositiveDelta(), h.GetPositiveCount())
+	centroids := make([]wf.Centroid, 0, len(negative)+len(positive)+1)
+
+	cumulative := 0.0
+	// negative bucket i covers [-base^i, -base^(i-1)), so the highest index holds the smallest values
+	for i := len(negative) - 1; i >= 0; i-- {
+		cumulative += negative[i].count
+		centroids = append(centroids, wf.Centroid{Value: -nativeBucketBound(schema, negative[i].index-1), Count: cumulative})
+	}
+	zeroCount := float64(h.GetZeroCount())
+	if h.GetZeroCountFloat() > 0 {
+		zeroCount = h.GetZeroCountFloat()
+	}
+	if h.GetZeroThreshold() > 0 || zeroCount > 0 {
+		cumulative += zeroCount
+		centroids = append(centroids, wf.Centroid{Value: h.GetZeroThreshold(), Count: cumulative})
+	}
+	// positive bucket i covers (base^(i-1), base^i]
+	for _, bucket := range positive {
+		cumulative += bucket.count
+		centroids = append(centroids, wf.Centroid{Value: nativeBucketBound(schema, bucket.index), Count: cumulative})
+	}
+	return centroids
+}
+
+// expandNativeBuckets resolves the spans and delta encoded (or absolute float) counts into indexed buckets
+func expandNativeBuckets(spans []*prom.BucketSpan, deltas []int64, counts []float64) []nativeBucket {
+	var buckets []nativeBucket
+	var index int32
+	var current int64
+	position := 0
+	for _, span := range spans {
+		index += span.GetOffset()
+		for j := uint32(0); j < span.GetLength(); j++ {
+			var count float64
+			if len(counts) > 0 {
+				if position >= len(counts) {
+					return buckets
+				}
+				count = counts[position]
+			} else {
+				if position >= len(deltas) {
+					return buckets
+				}
+				current += deltas[position]
+				count = float64(current)
+			}
+			buckets = append(buckets, nativeBucket{index: index, count: count})
+			position++
+			index++
+		}
+	}
+	return buckets
+}
+
+// nativeBucketBound returns base^index with base = 2^(2^-schema)
+func nativeBucketBound(schema, index int32) float64 {
+	return math.Exp2(float64(index) * math.Exp2(-float64(schema)))
+}
+
+// startTime returns the created timestamp in unix seconds, or zero when unknown
+func startTime(created *timestamppb.Timestamp) int64 {
+	if created == nil {
+		return 0
+	}
+	return created.GetSeconds()
+}
+
+func createdTime(created *timestamppb.Timestamp) time.Time {
+	if created == nil {
+		return time.Time{}
+	}
+	return created.AsTime()
+}
@@ -0,0 +1,424 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	prom "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const maxOpenMetricsLineLength = 1024 * 1024
+
+var errOpenMetricsEOF = errors.New("# EOF")
+
+// openMetricsSuffixes are the sample name suffixes allowed per OpenMetrics metric type
+var openMetricsSuffixes = map[string][]string{
+	"counter":        {"_total", "_created", ""},
+	"gauge":          {""},
+	"unknown":        {""},
+	"stateset":       {""},
+	"info":           {"_info"},
+	"histogram":      {"_bucket", "_count", "_sum", "_created"},
+	"gaugehistogram": {"_bucket", "_gcount", "_gsum"},
+	"summary":        {"", "_count", "_sum", "_created"},
+}
+
+type openMetricsLabel struct {
+	name  string
+	value string
+}
+
+// openMetricsParser converts the OpenMetrics text format into metric families, handing over one family at a time.
+type openMetricsParser struct {
+	emit func(*prom.MetricFamily)
+
+	name    string
+	typ     string
+	help    string
+	order   []*prom.Metric
+	metrics map[string]*prom.Metric
+}
+
+// parseOpenMetrics parses the OpenMetrics text format. A missing '# EOF' marker is tolerated.
+func parseOpenMetrics(reader io.Reader, emit func(*prom.MetricFamily)) error {
+	parser := &openMetricsParser{emit: emit}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxOpenMetricsLineLength)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		err := parser.parseLine(scanner.Text())
+		if err == errOpenMetricsEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("openmetrics line %d: %v", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	parser.flush()
+	return nil
+}
+
+func (p *openMetricsParser) parseLine(line string) error {
+	if line == "" {
+		return nil
+	}
+	if !strings.HasPrefix(line, "#") {
+		return p.parseSample(line)
+	}
+	if line == "# EOF" {
+		return errOpenMetricsEOF
+	}
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 3 {
+		return nil
+	}
+	switch fields[1] {
+	case "TYPE":
+		if len(fields) < 4 {
+			return fmt.Errorf("missing type for %s", fields[2])
+		}
+		if _, ok := openMetricsSuffixes[fields[3]]; !ok {
+			return fmt.Errorf("unknown type %q for %s", fields[3], fields[2])
+		}
+		p.start(fields[2])
+		p.typ = fields[3]
+	case "HELP":
+		p.start(fields[2])
+		if len(fields) == 4 {
+			p.help = unescapeOpenMetrics(fields[3])
+		}
+	case "UNIT":
+		p.start(fields[2])
+	}
+	return nil
+}
+
+// start begins a new family unless the metadata belongs to the current one
+func (p *openMetricsParser) start(name string) {
+	if p.metrics != nil && p.name == name {
+		return
+	}
+	p.flush()
+	p.name = name
+	p.typ = "unknown"
+	p.help = ""
+	p.order = nil
+	p.metrics = map[string]*prom.Metric{}
+}
+
+func (p *openMetricsParser) flush() {
+	if len(p.order) == 0 {
+		p.metrics = nil
+		return
+	}
+	family := &prom.MetricFamily{Name: strPtr(p.familyName()), Type: p.familyType().Enum(), Metric: p.order}
+	if p.help != "" {
+		family.Help = strPtr(p.help)
+	}
+	p.emit(family)
+	p.order = nil
+	p.metrics = nil
+}
+
+// familyName returns the Prometheus name for the family, which keeps the counter and info suffixes
+func (p *openMetricsParser) familyName() string {
+	switch {
+	case p.typ == "counter" && !strings.HasSuffix(p.name, "_total"):
+		return p.name + "_total"
+	case p.typ == "info" && !strings.HasSuffix(p.name, "_info"):
+		return p.name + "_info"
+	}
+	return p.name
+}
+
+func (p *openMetricsParser) familyType() prom.MetricType {
+	switch p.typ {
+	case "counter":
+		return prom.MetricType_COUNTER
+	case "gauge", "stateset", "info":
+		return prom.MetricType_GAUGE
+	case "histogram":
+		return prom.MetricType_HISTOGRAM
+	case "gaugehistogram":
+		return prom.MetricType_GAUGE_HISTOGRAM
+	case "summary":
+		return prom.MetricType_SUMMARY
+	}
+	return prom.MetricType_UNTYPED
+}
+
+// suffix returns the suffix of the sample name within the current family and false if it belongs to another family
+func (p *openMetricsParser) suffix(name string) (string, bool) {
+	if p.metrics == nil || !strings.HasPrefix(name, p.name) {
+		return "", false
+	}
+	suffix := name[len(p.name):]
+	for _, allowed := range openMetricsSuffixes[p.typ] {
+		if suffix == allowed {
+			return suffix, true
+		}
+	}
+	return "", false
+}
+
+func (p *openMetricsParser) parseSample(line string) error {
+	end := strings.IndexAny(line, "{ ")
+	if end <= 0 {
+		return fmt.Errorf("invalid sample %q", line)
+	}
+	name, rest := line[:end], line[end:]
+	var labels []openMetricsLabel
+	var err error
+	if strings.HasPrefix(rest, "{") {
+		if labels, rest, err = parseOpenMetricsLabels(rest); err != nil {
+			return err
+		}
+	}
+
+	samplePart, exemplarPart := rest, ""
+	if i := strings.Index(rest, " # "); i >= 0 {
+		samplePart, exemplarPart = rest[:i], strings.TrimSpace(rest[i+3:])
+	}
+	fields := strings.Fields(samplePart)
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("invalid sample %q", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %v", name, err)
+	}
+	var timestampMs *int64
+	if len(fields) == 2 {
+		ts, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp for %s: %v", name, err)
+		}
+		timestampMs = int64Ptr(int64(math.Round(ts * 1000)))
+	}
+	var exemplar *prom.Exemplar
+	if exemplarPart != "" {
+		if exemplar, err = parseOpenMetricsExemplar(exemplarPart); err != nil {
+			return fmt.Errorf("invalid exemplar for %s: %v", name, err)
+		}
+	}
+
+	suffix, ok := p.suffix(name)
+	if !ok {
+		p.start(name)
+	}
+	return p.add(suffix, labels, value, timestampMs, exemplar)
+}
+
+func (p *openMetricsParser) add(suffix string, labels []openMetricsLabel, value float64, timestampMs *int64, exemplar *prom.Exemplar) error {
+	var le, quantile string
+	pairs := make([]*prom.LabelPair, 0, len(labels))
+	for _, label := range labels {
+		switch {
+		case label.name == "le" && suffix == "_bucket":
+			le = label.value
+		case label.name == "quantile" && p.typ == "summary" && suffix == "":
+			quantile = label.value
+		default:
+			pairs = append(pairs, &prom.LabelPair{Name: strPtr(label.name), Value: strPtr(label.value)})
+		}
+	}
+	m := p.metric(pairs)
+	if timestampMs != nil && suffix != "_created" && suffix != "_bucket" {
+		m.TimestampMs = timestampMs
+	}
+
+	switch p.typ {
+	case "counter":
+		if suffix == "_created" {
+			m.Counter.CreatedTimestamp = toTimestamp(value)
+		} else {
+			m.Counter.Value = float64Ptr(value)
+			m.Counter.Exemplar = exemplar
+		}
+	case "gauge", "stateset", "info":
+		m.Gauge.Value = float64Ptr(value)
+	case "unknown":
+		m.Untyped.Value = float64Ptr(value)
+	case "histogram", "gaugehistogram":
+		switch suffix {
+		case "_bucket":
+			bound, err := strconv.ParseFloat(le, 64)
+			if err != nil {
+				return fmt.Errorf("invalid le label %q", le)
+			}
+			m.Histogram.Bucket = append(m.Histogram.Bucket, &prom.Bucket{
+				UpperBound:      float64Ptr(bound),
+				CumulativeCount: uint64Ptr(uint64(value)),
+				Exemplar:        exemplar,
+			})
+		case "_count", "_gcount":
+			m.Histogram.SampleCount = uint64Ptr(uint64(value))
+		case "_sum", "_gsum":
+			m.Histogram.SampleSum = float64Ptr(value)
+		case "_created":
+			m.Histogram.CreatedTimestamp = toTimestamp(value)
+		}
+	case "summary":
+		switch suffix {
+		case "":
+			q, err := strconv.ParseFloat(quantile, 64)
+			if err != nil {
+				return fmt.Errorf("invalid quantile label %q", quantile)
+			}
+			m.Summary.Quantile = append(m.Summary.Quantile, &prom.Quantile{Quantile: float64Ptr(q), Value: float64Ptr(value)})
+		case "_count":
+			m.Summary.SampleCount = uint64Ptr(uint64(value))
+		case "_sum":
+			m.Summary.SampleSum = float64Ptr(value)
+		case "_created":
+			m.Summary.CreatedTimestamp = toTimestamp(value)
+		}
+	}
+	return nil
+}
+
+// metric returns the metric of the current family with the given labels, creating it when needed
+func (p *openMetricsParser) metric(pairs []*prom.LabelPair) *prom.Metric {
+	signature := labelSignature(pairs)
+	if m, ok := p.metrics[signature]; ok {
+		return m
+	}
+	m := &prom.Metric{Label: pairs}
+	switch p.familyType() {
+	case prom.MetricType_COUNTER:
+		m.Counter = &prom.Counter{}
+	case prom.MetricType_GAUGE:
+		m.Gauge = &prom.Gauge{}
+	case prom.MetricType_HISTOGRAM, prom.MetricType_GAUGE_HISTOGRAM:
+		m.Histogram = &prom.Histogram{}
+	case prom.MetricType_SUMMARY:
+		m.Summary = &prom.Summary{}
+	default:
+		m.Untyped = &prom.Untyped{}
+	}
+	p.metrics[signature] = m
+	p.order = append(p.order, m)
+	return m
+}
+
+func labelSignature(pairs []*prom.LabelPair) string {
+	parts := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		parts = append(parts, pair.GetName()+"="+pair.GetValue())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\x00")
+}
+
+// parseOpenMetricsLabels parses a '{name="value",...}' label set and returns the remainder of the input
+func parseOpenMetricsLabels(input string) ([]openMetricsLabel, string, error) {
+	var labels []openMetricsLabel
+	i := 1
+	for {
+		for i < len(input) && (input[i] == ' ' || input[i] == ',') {
+			i++
+		}
+		if i >= len(input) {
+			return nil, "", fmt.Errorf("unterminated label set")
+		}
+		if input[i] == '}' {
+			return labels, input[i+1:], nil
+		}
+		eq := strings.IndexByte(input[i:], '=')
+		if eq <= 0 || i+eq+1 >= len(input) || input[i+eq+1] != '"' {
+			return nil, "", fmt.Errorf("invalid label at %q", input[i:])
+		}
+		name := strings.TrimSpace(input[i : i+eq])
+		i += eq + 2
+
+		var value strings.Builder
+		for ; i < len(input) && input[i] != '"'; i++ {
+			if input[i] == '\\' && i+1 < len(input) {
+				i++
+				switch input[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(input[i])
+				}
+				continue
+			}
+			value.WriteByte(input[i])
+		}
+		if i >= len(input) {
+			return nil, "", fmt.Errorf("unterminated label value for %s", name)
+		}
+		i++
+		labels = append(labels, openMetricsLabel{name: name, value: value.String()})
+	}
+}
+
+func parseOpenMetricsExemplar(input string) (*prom.Exemplar, error) {
+	if !strings.HasPrefix(input, "{") {
+		return nil, fmt.Errorf("missing labels")
+	}
+	labels, rest, err := parseOpenMetricsLabels(input)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid exemplar %q", input)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, err
+	}
+	exemplar := &prom.Exemplar{Value: float64Ptr(value)}
+	for _, label := range labels {
+		exemplar.Label = append(exemplar.Label, &prom.LabelPair{Name: strPtr(label.name), Value: strPtr(label.value)})
+	}
+	if len(fields) == 2 {
+		ts, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		exemplar.Timestamp = toTimestamp(ts)
+	}
+	return exemplar, nil
+}
+
+func unescapeOpenMetrics(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\"`, `"`).Replace(s)
+}
+
+// toTimestamp converts unix seconds into a protobuf timestamp
+func toTimestamp(seconds float64) *timestamppb.Timestamp {
+	whole, frac := math.Modf(seconds)
+	return &timestamppb.Timestamp{Seconds: int64(whole), Nanos: int32(frac * 1e9)}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func uint64Ptr(u uint64) *uint64 {
+	return &u
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	prom "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const openMetricsExample = `# TYPE http_requests counter
+# HELP http_requests Requests served.
+http_requests_total{code="200"} 1027 1395066363.000 # {trace_id="abc123"} 1 1395066363.5
+http_requests_created{code="200"} 1395066000.25
+http_requests_total{code="500"} 3
+# TYPE temperature gauge
+# UNIT temperature celsius
+temperature{room="kitchen"} 21.5
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2 # {trace_id="def"} 0.05
+latency_seconds_bucket{le="1"} 5
+latency_seconds_bucket{le="+Inf"} 6
+latency_seconds_count 6
+latency_seconds_sum 3.5
+latency_seconds_created 1395066000
+# TYPE rpc_seconds summary
+rpc_seconds{quantile="0.5",label="a \"quoted\" value"} 0.2
+rpc_seconds_count{label="a \"quoted\" value"} 10
+rpc_seconds_sum{label="a \"quoted\" value"} 2
+# TYPE build info
+build_info{version="1.2.3"} 1
+untyped_metric 7
+# EOF
+ignored_after_eof 1
+`
+
+func parseOpenMetricsFamilies(t *testing.T, input string) map[string]*prom.MetricFamily {
+	families := map[string]*prom.MetricFamily{}
+	err := parseOpenMetrics(strings.NewReader(input), func(mf *prom.MetricFamily) {
+		_, duplicate := families[mf.GetName()]
+		assert.False(t, duplicate, "family %s emitted twice", mf.GetName())
+		families[mf.GetName()] = mf
+	})
+	require.NoError(t, err)
+	return families
+}
+
+func TestParseOpenMetrics(t *testing.T) {
+	families := parseOpenMetricsFamilies(t, openMetricsExample)
+	require.Len(t, families, 6)
+
+	t.Run("counters keep the _total suffix and carry exemplars and created timestamps", func(t *testing.T) {
+		counter := families["http_requests_total"]
+		require.NotNil(t, counter)
+		assert.Equal(t, prom.MetricType_COUNTER, counter.GetType())
+		assert.Equal(t, "Requests served.", counter.GetHelp())
+		require.Len(t, counter.Metric, 2)
+
+		ok := counter.Metric[0]
+		assert.Equal(t, 1027.0, ok.GetCounter().GetValue())
+		assert.Equal(t, int64(1395066363000), ok.GetTimestampMs())
+		assert.Equal(t, int64(1395066000), ok.GetCounter().GetCreatedTimestamp().GetSeconds())
+		assert.Equal(t, int32(250000000), ok.GetCounter().GetCreatedTimestamp().GetNanos())
+		assert.Equal(t, 1.0, ok.GetCounter().GetExemplar().GetValue())
+		assert.Equal(t, "trace_id", ok.GetCounter().GetExemplar().GetLabel()[0].GetName())
+		assert.Equal(t, "abc123", ok.GetCounter().GetExemplar().GetLabel()[0].GetValue())
+
+		assert.Nil(t, counter.Metric[1].GetCounter().GetCreatedTimestamp())
+	})
+
+	t.Run("histograms", func(t *testing.T) {
+		histogram := families["latency_seconds"]
+		require.NotNil(t, histogram)
+		assert.Equal(t, prom.MetricType_HISTOGRAM, histogram.GetType())
+		require.Len(t, histogram.Metric, 1)
+		h := histogram.Metric[0].GetHistogram()
+		require.Len(t, h.Bucket, 3)
+		assert.Equal(t, uint64(2), h.Bucket[0].GetCumulativeCount())
+		assert.Equal(t, "def", h.Bucket[0].GetExemplar().GetLabel()[0].GetValue())
+		assert.Equal(t, uint64(6), h.GetSampleCount())
+		assert.Equal(t, 3.5, h.GetSampleSum())
+		assert.Equal(t, int64(1395066000), h.GetCreatedTimestamp().GetSeconds())
+	})
+
+	t.Run("summaries unescape label values", func(t *testing.T) {
+		summary := families["rpc_seconds"]
+		require.NotNil(t, summary)
+		require.Len(t, summary.Metric, 1)
+		assert.Equal(t, `a "quoted" value`, summary.Metric[0].Label[0].GetValue())
+		assert.Equal(t, 0.5, summary.Metric[0].GetSummary().Quantile[0].GetQuantile())
+		assert.Equal(t, uint64(10), summary.Metric[0].GetSummary().GetSampleCount())
+	})
+
+	t.Run("other types", func(t *testing.T) {
+		assert.Equal(t, prom.MetricType_GAUGE, families["temperature"].GetType())
+		assert.Equal(t, prom.MetricType_GAUGE, families["build_info"].GetType())
+		assert.Equal(t, prom.MetricType_UNTYPED, families["untyped_metric"].GetType())
+		assert.Equal(t, 7.0, families["untyped_metric"].Metric[0].GetUntyped().GetValue())
+	})
+}
+
+func TestParseOpenMetricsErrors(t *testing.T) {
+	for _, input := range []string{
+		"metric{label=\"unterminated} 1\n",
+		"metric not_a_number\n",
+		"# TYPE metric bogus\n",
+		"metric 1 # {trace_id=\"a\"}\n",
+	} {
+		err := parseOpenMetrics(strings.NewReader(input), func(*prom.MetricFamily) {})
+		assert.Error(t, err, input)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/experimental"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/relabel"
 
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
 
@@ -27,6 +28,10 @@ type pointBuilder struct {
 	prefix           string
 	omitBucketSuffix bool
 	tags             map[string]string
+	metricRelabel    relabel.Rules
+	exemplars        bool
+	honorTimestamps  bool
+	maxSampleAge     time.Duration
 	interner         util.StringInterner
 }
 
@@ -37,6 +42,10 @@ func NewPointBuilder(src *prometheusMetricsSource, filtered gometrics.Counter) *
 		omitBucketSuffix: src.omitBucketSuffix,
 		tags:             src.tags,
 		filters:          src.filters,
+		metricRelabel:    src.metricRelabel,
+		exemplars:        src.exemplars,
+		honorTimestamps:  src.honorTimestamps,
+		maxSampleAge:     src.maxSampleAge,
 		filtered:         filtered,
 		interner:         util.NewStringInterner(),
 	}
@@ -46,28 +55,75 @@ func NewPointBuilder(src *prometheusMetricsSource, filtered gometrics.Counter) *
 // build converts a map of prometheus metric families by metric name to a collection of wavefront points
 // build actually never returns an error
 func (builder *pointBuilder) build(metricFamilies map[string]*prom.MetricFamily) ([]wf.Metric, error) {
-	now := time.Now().Unix()
+	scrapeTime := time.Now()
 	var result []wf.Metric
-	for metricName, mf := range metricFamilies {
+	for familyName, mf := range metricFamilies {
 		for _, m := range mf.Metric {
+			now, keep := builder.timestamp(m, scrapeTime)
+			if !keep {
+				expiredPoints.Inc(1)
+				continue
+			}
+			tags := builder.buildTags(m)
 			// Prometheus metric family -> wavefront metric points
 			if mf.GetType() == prom.MetricType_SUMMARY {
-				result = append(result, builder.buildSummaryPoints(metricName, m, now, builder.buildTags(m))...)
-			} else if mf.GetType() == prom.MetricType_HISTOGRAM {
-				if experimental.IsEnabled(experimental.HistogramConversion) {
-					point := builder.buildWFHistogram(metricName, m, now, builder.buildTags(m))
-					result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
+				result = append(result, builder.buildSummaryPoints(familyName, m, now, tags)...)
+			} else if mf.GetType() == prom.MetricType_HISTOGRAM || mf.GetType() == prom.MetricType_GAUGE_HISTOGRAM {
+				if mf.GetType() == prom.MetricType_HISTOGRAM && isNativeHistogram(m.GetHistogram()) {
+					if name, histTags, keep := builder.relabel(familyName, copyOf(tags)); keep {
+						point := builder.buildNativeHistogram(name, m, now, histTags)
+						result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
+					}
+				} else if mf.GetType() == prom.MetricType_HISTOGRAM && experimental.IsEnabled(experimental.HistogramConversion) {
+					if name, histTags, keep := builder.relabel(familyName, copyOf(tags)); keep {
+						point := builder.buildWFHistogram(name, m, now, histTags)
+						result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
+					}
 				}
-				result = append(result, builder.buildHistogramPoints(metricName, m, now, builder.buildTags(m))...)
+				result = append(result, builder.buildHistogramPoints(familyName, m, now, tags)...)
 			} else {
-				result = append(result, builder.buildPoints(metricName, m, now)...)
+				result = append(result, builder.buildPoints(familyName, m, now, tags)...)
 			}
 		}
 	}
 	return result, nil
 }
 
-func (builder *pointBuilder) point(name string, value float64, ts int64, source string, tags map[string]string) wf.Metric {
+// timestamp returns the unix time in seconds to report the metric at.
+// It returns false when the timestamp set by the endpoint is older than the maximum sample age.
+func (builder *pointBuilder) timestamp(m *prom.Metric, scrapeTime time.Time) (int64, bool) {
+	if m.TimestampMs == nil {
+		return scrapeTime.Unix(), true
+	}
+	sampleTime := time.UnixMilli(m.GetTimestampMs())
+	if builder.maxSampleAge > 0 && scrapeTime.Sub(sampleTime) > builder.maxSampleAge {
+		return 0, false
+	}
+	if builder.honorTimestamps {
+		return sampleTime.Unix(), true
+	}
+	return scrapeTime.Unix(), true
+}
+
+// relabel applies the metric relabeling rules to the name and tags of a sample. The name is the Prometheus name of
+// the sample, such as the _bucket, _sum or _count series of a histogram, and the tags include the le and quantile
+// labels. It returns false when the sample is dropped.
+func (builder *pointBuilder) relabel(name string, tags map[string]string) (string, map[string]string, bool) {
+	if len(builder.metricRelabel) == 0 {
+		return name, tags, true
+	}
+	tags = copyOf(tags)
+	tags[relabel.MetricNameLabel] = name
+	if !builder.metricRelabel.Process(tags) || tags[relabel.MetricNameLabel] == "" {
+		builder.filtered.Inc(1)
+		return "", nil, false
+	}
+	name = tags[relabel.MetricNameLabel]
+	relabel.DropReserved(tags)
+	return name, tags, true
+}
+
+func (builder *pointBuilder) point(name string, value float64, ts int64, source string, tags map[string]string) *wf.Point {
 	point := wf.NewPoint(
 		builder.name(name),
 		value,
@@ -85,21 +141,27 @@ func (builder *pointBuilder) name(name string) string {
 }
 
 // Get name and value from metric
-func (builder *pointBuilder) buildPoints(name string, m *prom.Metric, now int64) []wf.Metric {
+func (builder *pointBuilder) buildPoints(name string, m *prom.Metric, now int64, tags map[string]string) []wf.Metric {
 	var result []wf.Metric
+	name, tags, keep := builder.relabel(name, tags)
+	if !keep {
+		return result
+	}
 	if m.Gauge != nil {
 		if !math.IsNaN(m.GetGauge().GetValue()) {
-			point := builder.point(name+".gauge", m.GetGauge().GetValue(), now, builder.source, builder.buildTags(m))
+			point := builder.point(name+".gauge", m.GetGauge().GetValue(), now, builder.source, tags)
 			result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
 		}
 	} else if m.Counter != nil {
 		if !math.IsNaN(m.GetCounter().GetValue()) {
-			point := builder.point(name+".counter", m.GetCounter().GetValue(), now, builder.source, builder.buildTags(m))
+			point := builder.point(name+".counter", m.GetCounter().GetValue(), now, builder.source, tags)
+			point.StartTime = startTime(m.GetCounter().GetCreatedTimestamp())
 			result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
 		}
+		result = builder.appendExemplar(result, name, m.GetCounter().GetExemplar(), now, tags)
 	} else if m.Untyped != nil {
 		if !math.IsNaN(m.GetUntyped().GetValue()) {
-			point := builder.point(name+".value", m.GetUntyped().GetValue(), now, builder.source, builder.buildTags(m))
+			point := builder.point(name+".value", m.GetUntyped().GetValue(), now, builder.source, tags)
 			result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
 		}
 	}
@@ -113,15 +175,15 @@ func (builder *pointBuilder) buildSummaryPoints(name string, m *prom.Metric, now
 		if !math.IsNaN(q.GetValue()) {
 			newTags := copyOf(tags)
 			newTags["quantile"] = fmt.Sprintf("%v", q.GetQuantile())
-			point := builder.point(name, q.GetValue(), now, builder.source, newTags)
-			result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
+			if quantileName, newTags, keep := builder.relabel(name, newTags); keep {
+				point := builder.point(quantileName, q.GetValue(), now, builder.source, newTags)
+				result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
+			}
 		}
 	}
-	point := builder.point(name+".count", float64(m.GetSummary().GetSampleCount()), now, builder.source, tags)
-	result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
-	point = builder.point(name+".sum", m.GetSummary().GetSampleSum(), now, builder.source, tags)
-	result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
-
+	start := startTime(m.GetSummary().GetCreatedTimestamp())
+	result = builder.appendSample(result, name+"_count", float64(m.GetSummary().GetSampleCount()), now, start, tags)
+	result = builder.appendSample(result, name+"_sum", m.GetSummary().GetSampleSum(), now, start, tags)
 	return result
 }
 
@@ -134,26 +196,63 @@ func (builder *pointBuilder) buildWFHistogram(name string, m *prom.Metric, now i
 			Count: float64(buckets[i].GetCumulativeCount()),
 		})
 	}
-	return wf.NewCumulativeDistribution(builder.name(name), builder.source, tags, centroids, time.Unix(now, 0))
+	distribution := wf.NewCumulativeDistribution(builder.name(name), builder.source, tags, centroids, time.Unix(now, 0))
+	distribution.StartTime = createdTime(m.GetHistogram().GetCreatedTimestamp())
+	return distribution
 }
 
 // Get Buckets from histogram metric
 func (builder *pointBuilder) buildHistogramPoints(name string, m *prom.Metric, now int64, tags map[string]string) []wf.Metric {
 	var result []wf.Metric
-	histName := builder.histogramName(name)
+	start := startTime(m.GetHistogram().GetCreatedTimestamp())
 	for _, b := range m.GetHistogram().Bucket {
 		newTags := copyOf(tags)
 		newTags["le"] = fmt.Sprintf("%v", b.GetUpperBound())
-		point := builder.point(histName, float64(b.GetCumulativeCount()), now, builder.source, newTags)
+		bucketName, newTags, keep := builder.relabel(name+"_bucket", newTags)
+		if !keep {
+			continue
+		}
+		baseName := strings.TrimSuffix(bucketName, "_bucket")
+		point := builder.point(builder.histogramName(baseName), float64(b.GetCumulativeCount()), now, builder.source, newTags)
+		point.StartTime = start
 		result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
+		result = builder.appendExemplar(result, baseName, b.GetExemplar(), now, newTags)
 	}
-	point := builder.point(name+".count", float64(m.GetHistogram().GetSampleCount()), now, builder.source, tags)
-	result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
-	point = builder.point(name+".sum", m.GetHistogram().GetSampleSum(), now, builder.source, tags)
-	result = wf.FilterAppend(builder.filters, builder.filtered, result, point)
+	result = builder.appendSample(result, name+"_count", histogramCount(m.GetHistogram()), now, start, tags)
+	result = builder.appendSample(result, name+"_sum", m.GetHistogram().GetSampleSum(), now, start, tags)
 	return result
 }
 
+// appendSample relabels the _count or _sum sample of a summary or histogram and appends it as a point
+func (builder *pointBuilder) appendSample(result []wf.Metric, name string, value float64, now, start int64, tags map[string]string) []wf.Metric {
+	name, tags, keep := builder.relabel(name, tags)
+	if !keep {
+		return result
+	}
+	point := builder.point(name, value, now, builder.source, tags)
+	point.StartTime = start
+	return wf.FilterAppend(builder.filters, builder.filtered, result, point)
+}
+
+// appendExemplar reports an exemplar as a point tagged with the exemplar labels when exemplars are enabled
+func (builder *pointBuilder) appendExemplar(result []wf.Metric, name string, exemplar *prom.Exemplar, now int64, tags map[string]string) []wf.Metric {
+	if !builder.exemplars || exemplar == nil || math.IsNaN(exemplar.GetValue()) {
+		return result
+	}
+	newTags := copyOf(tags)
+	for _, label := range exemplar.GetLabel() {
+		if len(label.GetName()) > 0 && len(label.GetValue()) > 0 {
+			newTags[label.GetName()] = label.GetValue()
+		}
+	}
+	ts := now
+	if exemplar.GetTimestamp() != nil {
+		ts = exemplar.GetTimestamp().GetSeconds()
+	}
+	point := builder.point(name+".exemplar", exemplar.GetValue(), ts, builder.source, newTags)
+	return wf.FilterAppend(builder.filters, builder.filtered, result, point)
+}
+
 // Get labels from metric
 func (builder *pointBuilder) buildTags(m *prom.Metric) map[string]string {
 	tags := make(map[string]string, len(builder.tags)+len(m.Label))
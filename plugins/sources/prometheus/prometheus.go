@@ -5,6 +5,7 @@ package prometheus
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,6 +14,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
@@ -22,6 +24,7 @@ import (
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/httputil"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/leadership"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/relabel"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
 
 	log "github.com/sirupsen/logrus"
@@ -35,6 +38,8 @@ var (
 	collectErrors   gometrics.Counter
 	filteredPoints  gometrics.Counter
 	collectedPoints gometrics.Counter
+	expiredPoints   gometrics.Counter
+	stalePoints     gometrics.Counter
 )
 
 func init() {
@@ -42,6 +47,8 @@ func init() {
 	collectedPoints = gometrics.GetOrRegisterCounter(reporting.EncodeKey("source.points.collected", pt), gometrics.DefaultRegistry)
 	filteredPoints = gometrics.GetOrRegisterCounter(reporting.EncodeKey("source.points.filtered", pt), gometrics.DefaultRegistry)
 	collectErrors = gometrics.GetOrRegisterCounter(reporting.EncodeKey("source.collect.errors", pt), gometrics.DefaultRegistry)
+	expiredPoints = gometrics.GetOrRegisterCounter(reporting.EncodeKey("source.points.expired", pt), gometrics.DefaultRegistry)
+	stalePoints = gometrics.GetOrRegisterCounter(reporting.EncodeKey("source.points.stale", pt), gometrics.DefaultRegistry)
 }
 
 type prometheusMetricsSource struct {
@@ -50,6 +57,12 @@ type prometheusMetricsSource struct {
 	source               string
 	tags                 map[string]string
 	filters              filter.Filter
+	metricRelabel        relabel.Rules
+	exemplars            bool
+	honorTimestamps      bool
+	maxSampleAge         time.Duration
+	staleness            *stalenessTracker
+	accept               string
 	client               *http.Client
 	pps                  gometrics.Counter
 	eps                  gometrics.Counter
@@ -59,12 +72,16 @@ type prometheusMetricsSource struct {
 	omitBucketSuffix bool
 }
 
-func NewPrometheusMetricsSource(metricsURL, prefix, source, discovered string, tags map[string]string, filters filter.Filter, httpCfg httputil.ClientConfig) (metrics.Source, error) {
+func NewPrometheusMetricsSource(metricsURL, prefix, source, discovered string, tags map[string]string, filters filter.Filter, opts ScrapeOptions, httpCfg httputil.ClientConfig) (metrics.Source, error) {
 	client, err := httpClient(metricsURL, httpCfg)
 	if err != nil {
 		log.Errorf("error creating http client: %q", err)
 		return nil, err
 	}
+	accept, err := acceptHeader(opts.Protocols)
+	if err != nil {
+		return nil, err
+	}
 
 	pt := extractTags(tags, discovered, metricsURL)
 	ppsKey := reporting.EncodeKey("target.points.collected", pt)
@@ -78,6 +95,12 @@ func NewPrometheusMetricsSource(metricsURL, prefix, source, discovered string, t
 		source:               source,
 		tags:                 tags,
 		filters:              filters,
+		metricRelabel:        opts.MetricRelabel,
+		exemplars:            opts.Exemplars,
+		honorTimestamps:      opts.HonorTimestamps,
+		maxSampleAge:         opts.MaxSampleAge,
+		staleness:            newStalenessTracker(opts.StalenessMarkers),
+		accept:               accept,
 		client:               client,
 		pps:                  gometrics.GetOrRegisterCounter(ppsKey, gometrics.DefaultRegistry),
 		eps:                  gometrics.GetOrRegisterCounter(epsKey, gometrics.DefaultRegistry),
@@ -133,6 +156,15 @@ func (src *prometheusMetricsSource) Cleanup() {
 	}
 }
 
+// StaleMarkers returns a staleness marker for every series of the last successful scrape of the target
+func (src *prometheusMetricsSource) StaleMarkers() *metrics.Batch {
+	now := time.Now()
+	return &metrics.Batch{
+		Timestamp: now,
+		Metrics:   src.staleness.expire(now.Unix()),
+	}
+}
+
 type HTTPError struct {
 	MetricsURL string
 	Status     string
@@ -148,7 +180,7 @@ func (src *prometheusMetricsSource) Scrape() (*metrics.Batch, error) {
 		Timestamp: time.Now(),
 	}
 
-	resp, err := src.client.Get(src.metricsURL)
+	resp, err := src.get()
 	if err != nil {
 		collectErrors.Inc(1)
 		src.eps.Inc(1)
@@ -165,7 +197,7 @@ func (src *prometheusMetricsSource) Scrape() (*metrics.Batch, error) {
 		return nil, &HTTPError{MetricsURL: src.metricsURL, Status: resp.Status, StatusCode: resp.StatusCode}
 	}
 
-	result.Metrics, err = src.parseMetrics(resp.Body)
+	result.Metrics, err = src.parse(resp.Header.Get("Content-Type"), resp.Body)
 	if err != nil {
 		collectErrors.Inc(1)
 		src.eps.Inc(1)
@@ -173,10 +205,23 @@ func (src *prometheusMetricsSource) Scrape() (*metrics.Batch, error) {
 	}
 	collectedPoints.Inc(int64(result.Points()))
 	src.pps.Inc(int64(result.Points()))
+	result.Metrics = src.staleness.track(result.Metrics, result.Timestamp.Unix())
 
 	return result, nil
 }
 
+// get requests the metrics of the target in one of the accepted scrape protocols
+func (src *prometheusMetricsSource) get() (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, src.metricsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if src.accept != "" {
+		req.Header.Set("Accept", src.accept)
+	}
+	return src.client.Do(req)
+}
+
 // parseMetrics converts serialized prometheus metrics to wavefront points
 // parseMetrics returns an error when IO or parsing fails
 func (src *prometheusMetricsSource) parseMetrics(reader io.Reader) ([]wf.Metric, error) {
@@ -207,6 +252,10 @@ type prometheusProvider struct {
 	lookupInstances   LookupInstances
 	buildSource       func(url url.URL, tags map[string]string) (metrics.Source, error)
 	sources           []metrics.Source
+
+	// the staleness trackers of the targets keyed by metrics URL, nil when staleness markers are disabled
+	stalenessMtx sync.Mutex
+	staleness    map[string]*stalenessTracker
 }
 
 func (p *prometheusProvider) GetMetricsSources() []metrics.Source {
@@ -221,18 +270,84 @@ func (p *prometheusProvider) GetMetricsSources() []metrics.Source {
 		return nil
 	}
 	var sources []metrics.Source
+	current := make(map[string]*stalenessTracker)
 	for _, instance := range instances {
 		metricsURL.Host = instance.Host
 		metricsSource, err := p.buildSource(metricsURL, instance.Tags)
 		if err == nil {
+			p.trackStaleness(metricsSource, current)
 			sources = append(sources, metricsSource)
+		} else if err == errTargetDropped {
+			log.Debugf("target %s of %s dropped by relabeling", metricsURL.String(), p.name)
 		} else {
 			log.Errorf("error creating source: %v", err)
 		}
 	}
+	return append(sources, p.removedTargets(current)...)
+}
+
+// trackStaleness makes the source use the staleness tracker of its target so that series are tracked across scrapes
+func (p *prometheusProvider) trackStaleness(source metrics.Source, current map[string]*stalenessTracker) {
+	src, ok := source.(*prometheusMetricsSource)
+	if !ok || p.staleness == nil {
+		return
+	}
+	p.stalenessMtx.Lock()
+	defer p.stalenessMtx.Unlock()
+	tracker, found := p.staleness[src.metricsURL]
+	if !found {
+		tracker = newStalenessTracker(true)
+		p.staleness[src.metricsURL] = tracker
+	}
+	src.staleness = tracker
+	current[src.metricsURL] = tracker
+}
+
+// removedTargets returns a source reporting the staleness markers of every target that is no longer looked up
+func (p *prometheusProvider) removedTargets(current map[string]*stalenessTracker) []metrics.Source {
+	if p.staleness == nil {
+		return nil
+	}
+	p.stalenessMtx.Lock()
+	defer p.stalenessMtx.Unlock()
+	var sources []metrics.Source
+	for metricsURL, tracker := range p.staleness {
+		if _, found := current[metricsURL]; !found {
+			sources = append(sources, &removedTargetSource{metricsURL: metricsURL, staleness: tracker})
+		}
+	}
+	p.staleness = current
 	return sources
 }
 
+// removedTargetSource reports the staleness markers of a target that is no longer scraped
+type removedTargetSource struct {
+	metricsURL string
+	staleness  *stalenessTracker
+}
+
+func (src *removedTargetSource) AutoDiscovered() bool {
+	return false
+}
+
+func (src *removedTargetSource) Name() string {
+	return fmt.Sprintf("prometheus_source: %s (removed)", src.metricsURL)
+}
+
+func (src *removedTargetSource) Scrape() (*metrics.Batch, error) {
+	return src.StaleMarkers(), nil
+}
+
+func (src *removedTargetSource) StaleMarkers() *metrics.Batch {
+	now := time.Now()
+	return &metrics.Batch{
+		Timestamp: now,
+		Metrics:   src.staleness.expire(now.Unix()),
+	}
+}
+
+func (src *removedTargetSource) Cleanup() {}
+
 func (p *prometheusProvider) Name() string {
 	return p.name
 }
@@ -261,11 +376,37 @@ func NewPrometheusProvider(cfg configuration.PrometheusSourceConfig, lookupInsta
 		return nil, err
 	}
 
+	targetRelabel, err := relabel.Compile(cfg.Relabel)
+	if err != nil {
+		return nil, err
+	}
+	metricRelabel, err := relabel.Compile(cfg.MetricRelabel)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := acceptHeader(cfg.ScrapeProtocols); err != nil {
+		return nil, err
+	}
+	opts := ScrapeOptions{
+		MetricRelabel:    metricRelabel,
+		Protocols:        cfg.ScrapeProtocols,
+		Exemplars:        cfg.Exemplars,
+		HonorTimestamps:  cfg.HonorTimestamps,
+		MaxSampleAge:     cfg.MaxSampleAge,
+		StalenessMarkers: cfg.StalenessMarkers,
+	}
+
+	var staleness map[string]*stalenessTracker
+	if cfg.StalenessMarkers {
+		staleness = make(map[string]*stalenessTracker)
+	}
+
 	return &prometheusProvider{
 		name:              name,
 		useLeaderElection: cfg.UseLeaderElection || discovered == "",
 		URL:               metricsURL,
 		lookupInstances:   lookupInstances,
+		staleness:         staleness,
 		buildSource: func(url url.URL, tags map[string]string) (metrics.Source, error) {
 			copiedTags := map[string]string{}
 			for name, value := range cfg.Tags {
@@ -274,6 +415,9 @@ func NewPrometheusProvider(cfg configuration.PrometheusSourceConfig, lookupInsta
 			for name, value := range tags {
 				copiedTags[name] = value
 			}
+			if !relabelTarget(targetRelabel, &url, copiedTags) {
+				return nil, errTargetDropped
+			}
 			return NewPrometheusMetricsSource(
 				url.String(),
 				cfg.Prefix,
@@ -281,8 +425,36 @@ func NewPrometheusProvider(cfg configuration.PrometheusSourceConfig, lookupInsta
 				discovered,
 				copiedTags,
 				filters,
+				opts,
 				cfg.HTTPClientConfig,
 			)
 		},
 	}, nil
 }
+
+const (
+	addressLabel     = "__address__"
+	schemeLabel      = "__scheme__"
+	metricsPathLabel = "__metrics_path__"
+)
+
+var errTargetDropped = errors.New("target dropped by relabeling")
+
+// relabelTarget applies the target relabeling rules to the tags of a target, exposing the scraped URL through the
+// reserved __address__, __scheme__ and __metrics_path__ labels. It returns false when the target is dropped.
+func relabelTarget(rules relabel.Rules, metricsURL *url.URL, tags map[string]string) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	tags[addressLabel] = metricsURL.Host
+	tags[schemeLabel] = metricsURL.Scheme
+	tags[metricsPathLabel] = metricsURL.Path
+	if !rules.Process(tags) {
+		return false
+	}
+	metricsURL.Host = tags[addressLabel]
+	metricsURL.Scheme = tags[schemeLabel]
+	metricsURL.Path = tags[metricsPathLabel]
+	relabel.DropReserved(tags)
+	return true
+}
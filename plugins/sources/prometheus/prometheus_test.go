@@ -10,20 +10,24 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/leadership"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/metrics"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/options"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/util"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
 
+	"github.com/prometheus/common/expfmt"
 	gm "github.com/rcrowley/go-metrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/filter"
 	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/httputil"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/relabel"
 )
 
 func TestNoFilters(t *testing.T) {
@@ -133,6 +137,34 @@ func TestTagExclude(t *testing.T) {
 	}
 }
 
+func TestScrapedTimestamps(t *testing.T) {
+	recent := time.Now().Add(-time.Minute).Truncate(time.Second)
+	old := time.Now().Add(-time.Hour)
+	body := fmt.Sprintf("recent_metric 1 %d\nold_metric 2 %d\nnow_metric 3\n", recent.UnixMilli(), old.UnixMilli())
+
+	t.Run("scrape time by default", func(t *testing.T) {
+		src := &prometheusMetricsSource{source: "node1"}
+		points, err := src.parseMetrics(strings.NewReader(body))
+		require.NoError(t, err)
+		require.Len(t, points, 3)
+		for _, point := range points {
+			assert.NotEqual(t, recent.Unix(), point.(*wf.Point).Timestamp)
+		}
+	})
+
+	t.Run("honor timestamps and drop old samples", func(t *testing.T) {
+		expired := expiredPoints.Count()
+		src := &prometheusMetricsSource{source: "node1", honorTimestamps: true, maxSampleAge: 10 * time.Minute}
+		points, err := src.parseMetrics(strings.NewReader(body))
+		require.NoError(t, err)
+		byName := metricsByName(points)
+		require.Len(t, byName, 2)
+		assert.Equal(t, recent.Unix(), byName["recent.metric.value"].(*wf.Point).Timestamp)
+		assert.Contains(t, byName, "now.metric.value")
+		assert.Equal(t, expired+1, expiredPoints.Count())
+	})
+}
+
 func BenchmarkMetricPoint(b *testing.B) {
 	filtered := gm.GetOrRegisterCounter("filtered", gm.DefaultRegistry)
 	tempTags := map[string]string{"pod_name": "prometheus_pod_xyz", "namespace_name": "default"}
@@ -159,14 +191,14 @@ http_request_duration_seconds_count{label="good"} 3
 
 func TestDiscoveredPrometheusMetricSource(t *testing.T) {
 	t.Run("static source", func(t *testing.T) {
-		ms, err := NewPrometheusMetricsSource("", "", "", "", map[string]string{}, nil, httputil.ClientConfig{})
+		ms, err := NewPrometheusMetricsSource("", "", "", "", map[string]string{}, nil, ScrapeOptions{}, httputil.ClientConfig{})
 
 		assert.Nil(t, err)
 		assert.False(t, ms.AutoDiscovered(), "prometheus auto-discovery")
 	})
 
 	t.Run("discovered source", func(t *testing.T) {
-		ms, err := NewPrometheusMetricsSource("", "", "", "some-discovery-method", map[string]string{}, nil, httputil.ClientConfig{})
+		ms, err := NewPrometheusMetricsSource("", "", "", "some-discovery-method", map[string]string{}, nil, ScrapeOptions{}, httputil.ClientConfig{})
 
 		assert.Nil(t, err)
 		assert.True(t, ms.AutoDiscovered(), "prometheus auto-discovery")
@@ -376,6 +408,61 @@ func Test_prometheusProvider_GetMetricsSources(t *testing.T) {
 		assert.Contains(t, sources[0].Name(), "http://127.0.0.2:2222/metrics")
 	})
 
+	t.Run("tracks staleness across sources and reports removed targets", func(t *testing.T) {
+		instances := []Instance{{"127.0.0.1:2222", nil}, {"127.0.0.2:2222", nil}}
+		promProvider, _ := NewPrometheusProvider(configuration.PrometheusSourceConfig{
+			URL:              "http://example.local:2222/metrics",
+			Discovered:       "something",
+			StalenessMarkers: true,
+		}, func(_ string) ([]Instance, error) {
+			return instances, nil
+		})
+		util.SetAgentType(options.AllAgentType)
+
+		first := promProvider.GetMetricsSources()
+		require.Len(t, first, 2)
+		first[1].(*prometheusMetricsSource).staleness.track([]wf.Metric{wf.NewPoint("a", 1, 10, "node1", nil)}, 10)
+
+		second := promProvider.GetMetricsSources()
+		require.Len(t, second, 2)
+		assert.Same(t, first[1].(*prometheusMetricsSource).staleness, second[1].(*prometheusMetricsSource).staleness)
+
+		instances = instances[:1]
+		third := promProvider.GetMetricsSources()
+		require.Len(t, third, 2)
+		removed := third[1].(metrics.StaleSource)
+		markers := removed.StaleMarkers().Metrics
+		require.Len(t, markers, 1)
+		assert.True(t, wf.IsStaleMarker(markers[0].(*wf.Point).Value))
+
+		assert.Len(t, promProvider.GetMetricsSources(), 1)
+	})
+
+	t.Run("reports staleness markers when a scrape fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		promProvider, _ := NewPrometheusProvider(configuration.PrometheusSourceConfig{
+			URL:              server.URL + "/metrics",
+			Discovered:       "something",
+			StalenessMarkers: true,
+		}, InstanceFromHost)
+		util.SetAgentType(options.AllAgentType)
+
+		sources := promProvider.GetMetricsSources()
+		require.Len(t, sources, 1)
+		src := sources[0].(*prometheusMetricsSource)
+		src.staleness.track([]wf.Metric{wf.NewPoint("a", 1, 10, "node1", nil)}, 10)
+
+		_, err := src.Scrape()
+		require.Error(t, err)
+		markers := src.StaleMarkers().Metrics
+		require.Len(t, markers, 1)
+		assert.True(t, wf.IsStaleMarker(markers[0].(*wf.Point).Value))
+	})
+
 	t.Run("sends url.Host into LookupInstances", func(t *testing.T) {
 		actualHost := ""
 		promProvider, _ := NewPrometheusProvider(configuration.PrometheusSourceConfig{
@@ -615,3 +702,111 @@ func TestNewPrometheusProvider(t *testing.T) {
 		})
 	})
 }
+
+func TestMetricRelabel(t *testing.T) {
+	replacement := "http_request_seconds_$1"
+	status := "status"
+	rules, err := relabel.Compile([]relabel.Config{
+		{Action: relabel.Drop, SourceLabels: []string{relabel.MetricNameLabel, "label"}, Regex: ".*_sum;bad"},
+		{SourceLabels: []string{relabel.MetricNameLabel}, Regex: "http_request_duration_seconds_(.*)", TargetLabel: relabel.MetricNameLabel, Replacement: &replacement},
+		{Action: relabel.LabelMap, Regex: "label", Replacement: &status},
+		{Action: relabel.LabelDrop, Regex: "label"},
+	})
+	require.NoError(t, err)
+
+	filtered := gm.NewCounter()
+	src := &prometheusMetricsSource{metricRelabel: rules}
+	metricFamilies, err := (&expfmt.TextParser{}).TextToMetricFamilies(testMetricReader())
+	require.NoError(t, err)
+	points, _ := NewPointBuilder(src, filtered).build(metricFamilies)
+
+	require.Len(t, points, 7)
+	assert.Equal(t, int64(1), filtered.Count())
+	for _, point := range points {
+		assert.True(t, strings.HasPrefix(point.Name(), "http.request.seconds"), point.Name())
+		assert.NotContains(t, point.Tags(), "label")
+		assert.NotContains(t, point.Tags(), relabel.MetricNameLabel)
+	}
+	var count wf.Metric
+	for _, point := range points {
+		if point.Name() == "http.request.seconds.count.value" {
+			count = point
+		}
+	}
+	require.NotNil(t, count)
+	assert.Equal(t, "good", count.Tags()["status"])
+}
+
+func TestMetricRelabelSamples(t *testing.T) {
+	countName := "requests_total"
+	rules, err := relabel.Compile([]relabel.Config{
+		{Action: relabel.Drop, SourceLabels: []string{relabel.MetricNameLabel, "le"}, Regex: "latency_seconds_bucket;\\+Inf"},
+		{Action: relabel.Drop, SourceLabels: []string{"quantile"}, Regex: "0.9"},
+		{SourceLabels: []string{relabel.MetricNameLabel}, Regex: "latency_seconds_count", TargetLabel: relabel.MetricNameLabel, Replacement: &countName},
+	})
+	require.NoError(t, err)
+
+	metricsStr := `
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="1"} 1
+latency_seconds_bucket{le="+Inf"} 2
+latency_seconds_sum 3
+latency_seconds_count 2
+# TYPE rpc_seconds summary
+rpc_seconds{quantile="0.5"} 1
+rpc_seconds{quantile="0.9"} 2
+rpc_seconds_sum 3
+rpc_seconds_count 2
+`
+	filtered := gm.NewCounter()
+	src := &prometheusMetricsSource{metricRelabel: rules}
+	metricFamilies, err := (&expfmt.TextParser{}).TextToMetricFamilies(strings.NewReader(metricsStr))
+	require.NoError(t, err)
+	points, _ := NewPointBuilder(src, filtered).build(metricFamilies)
+
+	names := map[string][]map[string]string{}
+	for _, point := range points {
+		names[point.Name()] = append(names[point.Name()], point.Tags())
+	}
+	assert.Equal(t, int64(2), filtered.Count())
+	assert.Equal(t, []map[string]string{{"le": "1"}}, names["latency.seconds.bucket"])
+	assert.Contains(t, names, "requests.total")
+	assert.NotContains(t, names, "latency.seconds.count")
+	assert.Contains(t, names, "latency.seconds.sum")
+	assert.Equal(t, []map[string]string{{"quantile": "0.5"}}, names["rpc.seconds"])
+	assert.Contains(t, names, "rpc.seconds.count")
+}
+
+func TestTargetRelabel(t *testing.T) {
+	lookup := func(_ string) ([]Instance, error) {
+		return []Instance{
+			{"10.0.0.1:8080", map[string]string{"pod": "web-1"}},
+			{"10.0.0.2:8080", map[string]string{"pod": "db-1"}},
+		}, nil
+	}
+	path := "/custom/$1"
+	promProvider, err := NewPrometheusProvider(configuration.PrometheusSourceConfig{
+		URL:        "http://example.local:8080/metrics",
+		Discovered: "something",
+		Relabel: []relabel.Config{
+			{Action: relabel.Keep, SourceLabels: []string{"pod"}, Regex: "web-.*"},
+			{SourceLabels: []string{"__metrics_path__"}, Regex: "/(.*)", TargetLabel: "__metrics_path__", Replacement: &path},
+			{SourceLabels: []string{"__address__"}, Regex: "(.*):.*", TargetLabel: "instance"},
+		},
+	}, lookup)
+	require.NoError(t, err)
+	util.SetAgentType(options.AllAgentType)
+
+	sources := promProvider.GetMetricsSources()
+
+	require.Len(t, sources, 1)
+	src := sources[0].(*prometheusMetricsSource)
+	assert.Equal(t, "http://10.0.0.1:8080/custom/metrics", src.metricsURL)
+	assert.Equal(t, map[string]string{"pod": "web-1", "instance": "10.0.0.1"}, src.tags)
+
+	_, err = NewPrometheusProvider(configuration.PrometheusSourceConfig{
+		URL:     "http://example.local:8080/metrics",
+		Relabel: []relabel.Config{{Action: "unknown"}},
+	}, lookup)
+	assert.Error(t, err)
+}
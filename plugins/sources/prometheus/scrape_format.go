@@ -0,0 +1,122 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	prom "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/configuration"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/relabel"
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+// ScrapeOptions controls how metrics are requested from an endpoint and converted to wavefront metrics
+type ScrapeOptions struct {
+	// MetricRelabel is applied to every scraped series
+	MetricRelabel relabel.Rules
+
+	// Protocols is the ordered list of exposition formats to request. Defaults to protobuf, openmetrics and text.
+	Protocols []string
+
+	// Exemplars enables reporting exemplars as points
+	Exemplars bool
+
+	// HonorTimestamps reports samples at the timestamps set by the endpoint instead of the scrape time
+	HonorTimestamps bool
+
+	// MaxSampleAge drops samples with a timestamp set by the endpoint that are older. Zero disables the check.
+	MaxSampleAge time.Duration
+
+	// StalenessMarkers reports a staleness marker for series that disappear between scrapes
+	StalenessMarkers bool
+}
+
+var defaultScrapeProtocols = []string{
+	configuration.ProtobufScrapeProtocol,
+	configuration.OpenMetricsScrapeProtocol,
+	configuration.TextScrapeProtocol,
+}
+
+var scrapeProtocolMediaTypes = map[string][]string{
+	configuration.ProtobufScrapeProtocol:    {expfmt.ProtoType + ";proto=" + expfmt.ProtoProtocol + ";encoding=delimited"},
+	configuration.OpenMetricsScrapeProtocol: {expfmt.OpenMetricsType + ";version=1.0.0", expfmt.OpenMetricsType + ";version=0.0.1"},
+	configuration.TextScrapeProtocol:        {"text/plain;version=" + expfmt.TextVersion},
+}
+
+// ValidateScrapeProtocols checks that every protocol is one of protobuf, openmetrics or text
+func ValidateScrapeProtocols(protocols []string) error {
+	_, err := acceptHeader(protocols)
+	return err
+}
+
+// acceptHeader returns the Accept header requesting the given protocols in order of preference
+func acceptHeader(protocols []string) (string, error) {
+	if len(protocols) == 0 {
+		protocols = defaultScrapeProtocols
+	}
+	var mediaTypes []string
+	for _, protocol := range protocols {
+		types, ok := scrapeProtocolMediaTypes[strings.ToLower(protocol)]
+		if !ok {
+			return "", fmt.Errorf("unknown scrape protocol: %q", protocol)
+		}
+		mediaTypes = append(mediaTypes, types...)
+	}
+	weight := 1.0
+	accept := make([]string, 0, len(mediaTypes)+1)
+	for _, mediaType := range mediaTypes {
+		accept = append(accept, mediaType+";q="+strconv.FormatFloat(weight, 'f', 2, 64))
+		weight -= 0.1
+	}
+	return strings.Join(append(accept, "*/*;q=0.01"), ","), nil
+}
+
+// parse converts the response body according to its content type. Unknown content types are parsed as text.
+func (src *prometheusMetricsSource) parse(contentType string, reader io.Reader) ([]wf.Metric, error) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == expfmt.ProtoType && params["encoding"] == "delimited":
+		return src.parseProtobuf(reader)
+	case mediaType == expfmt.OpenMetricsType:
+		return src.parseOpenMetrics(reader)
+	default:
+		return src.parseMetrics(reader)
+	}
+}
+
+func (src *prometheusMetricsSource) parseProtobuf(reader io.Reader) ([]wf.Metric, error) {
+	decoder := expfmt.NewDecoder(bufio.NewReader(reader), expfmt.FmtProtoDelim)
+	pointBuilder := NewPointBuilder(src, filteredPoints)
+	var points []wf.Metric
+	for {
+		mf := &prom.MetricFamily{}
+		if err := decoder.Decode(mf); err != nil {
+			if err == io.EOF {
+				return points, nil
+			}
+			return points, fmt.Errorf("reading protobuf format failed: %v", err)
+		}
+		pointsToAdd, _ := pointBuilder.build(map[string]*prom.MetricFamily{mf.GetName(): mf})
+		points = append(points, pointsToAdd...)
+	}
+}
+
+func (src *prometheusMetricsSource) parseOpenMetrics(reader io.Reader) ([]wf.Metric, error) {
+	pointBuilder := NewPointBuilder(src, filteredPoints)
+	var points []wf.Metric
+	err := parseOpenMetrics(reader, func(mf *prom.MetricFamily) {
+		pointsToAdd, _ := pointBuilder.build(map[string]*prom.MetricFamily{mf.GetName(): mf})
+		points = append(points, pointsToAdd...)
+	})
+	return points, err
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	gm "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+func TestAcceptHeader(t *testing.T) {
+	accept, err := acceptHeader(nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(accept, "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=1.00,"), accept)
+	assert.Contains(t, accept, "application/openmetrics-text;version=1.0.0;q=0.90")
+	assert.Contains(t, accept, "text/plain;version=0.0.4;q=0.70")
+
+	accept, err = acceptHeader([]string{"text"})
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain;version=0.0.4;q=1.00,*/*;q=0.01", accept)
+
+	_, err = acceptHeader([]string{"json"})
+	assert.Error(t, err)
+}
+
+func scrapeWith(t *testing.T, src *prometheusMetricsSource, contentType string, body []byte) []wf.Metric {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	src.metricsURL = server.URL
+	src.client = &http.Client{}
+	src.pps = gm.NewCounter()
+	src.accept, _ = acceptHeader(nil)
+	batch, err := src.Scrape()
+	require.NoError(t, err)
+	assert.Equal(t, src.accept, accept)
+	return batch.Metrics
+}
+
+func metricsByName(metrics []wf.Metric) map[string]wf.Metric {
+	byName := map[string]wf.Metric{}
+	for _, metric := range metrics {
+		byName[metric.Name()] = metric
+	}
+	return byName
+}
+
+func TestScrapeProtobuf(t *testing.T) {
+	created := timestamppb.New(time.Unix(1600000000, 0))
+	buf := bytes.NewBuffer(nil)
+	encoder := expfmt.NewEncoder(buf, expfmt.FmtProtoDelim)
+	require.NoError(t, encoder.Encode(&prom.MetricFamily{
+		Name: strPtr("http_requests_total"),
+		Type: prom.MetricType_COUNTER.Enum(),
+		Metric: []*prom.Metric{{
+			Label: []*prom.LabelPair{{Name: strPtr("code"), Value: strPtr("200")}},
+			Counter: &prom.Counter{
+				Value:            float64Ptr(10),
+				CreatedTimestamp: created,
+				Exemplar: &prom.Exemplar{
+					Label: []*prom.LabelPair{{Name: strPtr("trace_id"), Value: strPtr("abc")}},
+					Value: float64Ptr(1),
+				},
+			},
+		}},
+	}))
+	require.NoError(t, encoder.Encode(&prom.MetricFamily{
+		Name: strPtr("latency_seconds"),
+		Type: prom.MetricType_HISTOGRAM.Enum(),
+		Metric: []*prom.Metric{{
+			Histogram: &prom.Histogram{
+				SampleCount:      uint64Ptr(4),
+				SampleSum:        float64Ptr(7),
+				CreatedTimestamp: created,
+				Schema:           int32Ptr(0),
+				ZeroThreshold:    float64Ptr(0.001),
+				ZeroCount:        uint64Ptr(1),
+				PositiveSpan:     []*prom.BucketSpan{{Offset: int32Ptr(1), Length: uint32Ptr(2)}},
+				PositiveDelta:    []int64{2, -1},
+			},
+		}},
+	}))
+
+	t.Run("without exemplars", func(t *testing.T) {
+		src := &prometheusMetricsSource{source: "node1"}
+		byName := metricsByName(scrapeWith(t, src, string(expfmt.FmtProtoDelim), buf.Bytes()))
+
+		require.Len(t, byName, 4)
+		counter := byName["http.requests.total.counter"].(*wf.Point)
+		assert.Equal(t, 10.0, counter.Value)
+		assert.Equal(t, int64(1600000000), counter.StartTime)
+
+		distribution := byName["latency.seconds"].(*wf.Distribution)
+		assert.True(t, distribution.Cumulative)
+		assert.Equal(t, created.AsTime(), distribution.StartTime)
+		assert.Equal(t, []wf.Centroid{{Value: 0.001, Count: 1}, {Value: 2, Count: 3}, {Value: 4, Count: 4}}, distribution.Centroids)
+		assert.Equal(t, 4.0, byName["latency.seconds.count"].(*wf.Point).Value)
+		assert.Equal(t, 7.0, byName["latency.seconds.sum"].(*wf.Point).Value)
+	})
+
+	t.Run("with exemplars", func(t *testing.T) {
+		src := &prometheusMetricsSource{source: "node1", exemplars: true}
+		byName := metricsByName(scrapeWith(t, src, string(expfmt.FmtProtoDelim), buf.Bytes()))
+
+		exemplar := byName["http.requests.total.exemplar"]
+		require.NotNil(t, exemplar)
+		assert.Equal(t, map[string]string{"code": "200", "trace_id": "abc"}, exemplar.Tags())
+	})
+}
+
+func TestScrapeOpenMetrics(t *testing.T) {
+	src := &prometheusMetricsSource{source: "node1"}
+	metrics := scrapeWith(t, src, "application/openmetrics-text; version=1.0.0; charset=utf-8", []byte(openMetricsExample))
+	byName := metricsByName(metrics)
+
+	assert.Contains(t, byName, "http.requests.total.counter")
+	assert.Contains(t, byName, "temperature.gauge")
+	assert.Contains(t, byName, "latency.seconds.bucket")
+	assert.Contains(t, byName, "build.info.gauge")
+	assert.NotContains(t, byName, "http.requests.created.value", "created timestamps are not reported as series")
+	assert.NotContains(t, byName, "latency.seconds")
+	assert.Equal(t, int64(1395066000), byName["latency.seconds.count"].(*wf.Point).StartTime)
+}
+
+func TestNativeCentroids(t *testing.T) {
+	h := &prom.Histogram{
+		Schema:        int32Ptr(1),
+		NegativeSpan:  []*prom.BucketSpan{{Offset: int32Ptr(0), Length: uint32Ptr(1)}},
+		NegativeCount: []float64{2},
+		PositiveSpan:  []*prom.BucketSpan{{Offset: int32Ptr(0), Length: uint32Ptr(1)}, {Offset: int32Ptr(1), Length: uint32Ptr(1)}},
+		PositiveCount: []float64{1, 3},
+	}
+	centroids := nativeCentroids(h)
+
+	require.Len(t, centroids, 3)
+	assert.InDelta(t, -1/math.Sqrt2, centroids[0].Value, 1e-9)
+	assert.Equal(t, 2.0, centroids[0].Count)
+	assert.Equal(t, wf.Centroid{Value: 1, Count: 3}, centroids[1])
+	assert.Equal(t, wf.Centroid{Value: 2, Count: 6}, centroids[2])
+	assert.True(t, isNativeHistogram(h))
+	assert.False(t, isNativeHistogram(&prom.Histogram{Bucket: []*prom.Bucket{{}}}))
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func uint32Ptr(u uint32) *uint32 {
+	return &u
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+type staleSeries struct {
+	name   string
+	source string
+	tags   map[string]string
+}
+
+// stalenessTracker remembers the series of the previous scrape to report staleness markers for the ones that disappear.
+// Only points are tracked, exemplars and distributions are not. A tracker is shared by the sources built for a target.
+type stalenessTracker struct {
+	mtx      sync.Mutex
+	previous map[string]staleSeries
+}
+
+// newStalenessTracker returns nil when staleness markers are disabled
+func newStalenessTracker(enabled bool) *stalenessTracker {
+	if !enabled {
+		return nil
+	}
+	return &stalenessTracker{}
+}
+
+// track appends a staleness marker at the given time for every series of the previous scrape missing from metrics
+func (t *stalenessTracker) track(metrics []wf.Metric, now int64) []wf.Metric {
+	if t == nil {
+		return metrics
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	current := make(map[string]staleSeries, len(t.previous))
+	for _, metric := range metrics {
+		point, ok := metric.(*wf.Point)
+		if !ok || strings.HasSuffix(point.Metric, ".exemplar") {
+			continue
+		}
+		tags := point.Tags()
+		current[staleSeriesKey(point.Metric, point.Source, tags)] = staleSeries{name: point.Metric, source: point.Source, tags: tags}
+	}
+	for key, series := range t.previous {
+		if _, ok := current[key]; !ok {
+			metrics = append(metrics, wf.NewPoint(series.name, wf.StaleNaN, now, series.source, series.tags))
+			stalePoints.Inc(1)
+		}
+	}
+	t.previous = current
+	return metrics
+}
+
+// expire returns a staleness marker at the given time for every series of the previous scrape and forgets them.
+// It is used when a scrape fails and when the target is removed.
+func (t *stalenessTracker) expire(now int64) []wf.Metric {
+	if t == nil {
+		return nil
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	markers := make([]wf.Metric, 0, len(t.previous))
+	for _, series := range t.previous {
+		markers = append(markers, wf.NewPoint(series.name, wf.StaleNaN, now, series.source, series.tags))
+	}
+	stalePoints.Inc(int64(len(markers)))
+	t.previous = nil
+	return markers
+}
+
+func staleSeriesKey(name, source string, tags map[string]string) string {
+	pairs := make([]string, 0, len(tags)+2)
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return name + "\x00" + source + "\x00" + strings.Join(pairs, "\x00")
+}
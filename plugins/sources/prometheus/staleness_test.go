@@ -0,0 +1,61 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wavefronthq/wavefront-collector-for-kubernetes/internal/wf"
+)
+
+func TestStalenessTracker(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		var tracker *stalenessTracker = newStalenessTracker(false)
+		metrics := []wf.Metric{wf.NewPoint("a", 1, 10, "node1", nil)}
+		assert.Equal(t, metrics, tracker.track(metrics, 10))
+	})
+
+	t.Run("reports disappeared series once", func(t *testing.T) {
+		tracker := newStalenessTracker(true)
+		first := tracker.track([]wf.Metric{
+			wf.NewPoint("a", 1, 10, "node1", map[string]string{"pod": "x"}),
+			wf.NewPoint("a", 1, 10, "node1", map[string]string{"pod": "y"}),
+			wf.NewPoint("a.exemplar", 1, 10, "node1", nil),
+		}, 10)
+		require.Len(t, first, 3)
+
+		second := tracker.track([]wf.Metric{
+			wf.NewPoint("a", 2, 20, "node1", map[string]string{"pod": "x"}),
+		}, 20)
+		require.Len(t, second, 2)
+		marker := second[1].(*wf.Point)
+		assert.True(t, wf.IsStaleMarker(marker.Value))
+		assert.Equal(t, int64(20), marker.Timestamp)
+		assert.Equal(t, map[string]string{"pod": "y"}, marker.Tags())
+
+		third := tracker.track([]wf.Metric{
+			wf.NewPoint("a", 3, 30, "node1", map[string]string{"pod": "x"}),
+		}, 30)
+		assert.Len(t, third, 1)
+	})
+	t.Run("expires every tracked series", func(t *testing.T) {
+		tracker := newStalenessTracker(true)
+		tracker.track([]wf.Metric{
+			wf.NewPoint("a", 1, 10, "node1", map[string]string{"pod": "x"}),
+			wf.NewPoint("b", 1, 10, "node1", nil),
+		}, 10)
+
+		markers := tracker.expire(20)
+		require.Len(t, markers, 2)
+		for _, marker := range markers {
+			assert.True(t, wf.IsStaleMarker(marker.(*wf.Point).Value))
+			assert.Equal(t, int64(20), marker.(*wf.Point).Timestamp)
+		}
+		assert.Empty(t, tracker.expire(30))
+		assert.Empty(t, newStalenessTracker(false).expire(30))
+	})
+}
@@ -197,7 +197,7 @@ func (src *summaryMetricsSource) decodePodStats(metrics map[ResourceKey]*Set, no
 	src.decodeMemoryStats(podMetrics, pod.Memory)
 	src.decodeEphemeralStorageStats(podMetrics, pod.EphemeralStorage)
 	for _, vol := range pod.VolumeStats {
-		src.decodeFsStats(podMetrics, VolumeResourcePrefix+vol.Name, &vol.FsStats)
+		src.decodeVolumeStats(podMetrics, &vol)
 	}
 	metrics[PodKey(ref.Namespace, ref.Name)] = podMetrics
 
@@ -336,8 +336,19 @@ func (src *summaryMetricsSource) decodeFsStats(metrics *Set, fsKey string, fs *s
 		log.Trace("missing fs metrics!")
 		return
 	}
+	src.addFsStats(metrics, map[string]string{LabelResourceID.Key: fsKey}, fs)
+}
+
+// decodeVolumeStats adds the filesystem metrics of a pod volume, labeled with its claim for persistent volumes
+func (src *summaryMetricsSource) decodeVolumeStats(metrics *Set, vol *stats.VolumeStats) {
+	fsLabels := map[string]string{LabelResourceID.Key: VolumeResourcePrefix + vol.Name}
+	if vol.PVCRef != nil {
+		fsLabels[LabelPersistentVolumeClaimName.Key] = vol.PVCRef.Name
+	}
+	src.addFsStats(metrics, fsLabels, &vol.FsStats)
+}
 
-	fsLabels := map[string]string{LabelResourceID.Key: fsKey}
+func (src *summaryMetricsSource) addFsStats(metrics *Set, fsLabels map[string]string, fs *stats.FsStats) {
 	src.addLabeledIntMetric(metrics, &MetricFilesystemUsage, fsLabels, fs.UsedBytes)
 	src.addLabeledIntMetric(metrics, &MetricFilesystemLimit, fsLabels, fs.CapacityBytes)
 	src.addLabeledIntMetric(metrics, &MetricFilesystemAvailable, fsLabels, fs.AvailableBytes)
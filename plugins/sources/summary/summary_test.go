@@ -212,6 +212,7 @@ func TestAddSummaryMetrics(t *testing.T) {
 	var mappedVolumeStats = map[string]int64{}
 	for _, labeledMetric := range metrics[volumeInformationMetricsKey].LabeledValues {
 		assert.True(t, strings.HasPrefix("Volume:C", labeledMetric.Labels["resource_id"]))
+		assert.Equal(t, "data-pod2", labeledMetric.Labels["pvc_name"])
 		mappedVolumeStats[labeledMetric.Name] = labeledMetric.IntValue
 	}
 
@@ -226,6 +227,32 @@ func TestAddSummaryMetrics(t *testing.T) {
 	}
 }
 
+func TestDecodeVolumeStats(t *testing.T) {
+	ms := testingSummaryMetricsSource(1234)
+	used := uint64(100)
+	volume := func(name string, pvc *stats.PVCReference) *stats.VolumeStats {
+		return &stats.VolumeStats{Name: name, FsStats: stats.FsStats{UsedBytes: &used}, PVCRef: pvc}
+	}
+
+	t.Run("persistent volume claims are joined by pvc_name", func(t *testing.T) {
+		set := &core.Set{}
+		ms.decodeVolumeStats(set, volume("data", &stats.PVCReference{Name: "data-web-0", Namespace: namespace0}))
+		require.Len(t, set.LabeledValues, 1)
+		assert.Equal(t, core.MetricFilesystemUsage.Name, set.LabeledValues[0].Name)
+		assert.Equal(t, map[string]string{
+			core.LabelResourceID.Key:                "Volume:data",
+			core.LabelPersistentVolumeClaimName.Key: "data-web-0",
+		}, set.LabeledValues[0].Labels)
+	})
+
+	t.Run("other volumes have no pvc_name", func(t *testing.T) {
+		set := &core.Set{}
+		ms.decodeVolumeStats(set, volume("config", nil))
+		require.Len(t, set.LabeledValues, 1)
+		assert.NotContains(t, set.LabeledValues[0].Labels, core.LabelPersistentVolumeClaimName.Key)
+	})
+}
+
 func TestDecodeEphemeralStorageStatsForContainer(t *testing.T) {
 	ms := testingSummaryMetricsSource(1234)
 	rootFs := &stats.FsStats{}
@@ -465,6 +492,7 @@ func getTestStatsSummary() stats.Summary {
 					InodesUsed:     &usedInode,
 					Inodes:         &totalInode,
 				},
+				PVCRef: &stats.PVCReference{Name: "data-pod2", Namespace: namespace0},
 			},
 			},
 		}, {
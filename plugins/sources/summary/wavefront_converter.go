@@ -92,7 +92,7 @@ func (converter *pointConverter) Process(batch *metrics.Batch) (*metrics.Batch,
 			if source == "" {
 				if metricType == "cluster" {
 					source = converter.cluster
-				} else if metricType == "ns" {
+				} else if metricType == "ns" || metricType == "workload" {
 					source = tags["namespace_name"] + "-ns"
 				} else {
 					source = hostname
@@ -5,8 +5,10 @@ package telegraf
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/influxdata/telegraf"
+	telegrafPlugins "github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/toml"
 )
 
@@ -24,3 +26,15 @@ func initPlugin(input telegraf.Input, conf string) (err error) {
 	err = toml.Unmarshal([]byte(conf), input)
 	return
 }
+
+// ValidateConf checks that the named plugin exists and that the optional configuration can be applied to it
+func ValidateConf(name, conf string) error {
+	creator := telegrafPlugins.Inputs[strings.TrimSpace(name)]
+	if creator == nil {
+		return fmt.Errorf("telegraf plugin not found: %s", name)
+	}
+	if conf == "" {
+		return nil
+	}
+	return initPlugin(creator(), conf)
+}